@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var adoptSource string
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <slug> --source <managed-slug>",
+	Short: "Take over an existing list as a managed list",
+	Long: `Registers an existing list, e.g. one maintained by another list-sync tool,
+as the list of a managed definition. Its current items are recorded in the
+state file and later syncs update this list instead of creating a new one.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runAdopt(args[0], adoptSource); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Adopt failed")
+		}
+	},
+}
+
+func init() {
+	adoptCmd.Flags().StringVar(&adoptSource, "source", "", "slug of the managed list definition to link, e.g. trakt-sync-filme (required)")
+	_ = adoptCmd.MarkFlagRequired("source")
+
+	rootCmd.AddCommand(adoptCmd)
+}
+
+func runAdopt(slug, source string) error {
+	if dryRun {
+		log.Info().Str("list", slug).Str("source", source).Msg("DRY RUN: would adopt list")
+		return nil
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+
+	syncer := syncpkg.NewSyncer(client, cfg)
+	syncer.SetState(st)
+
+	listDef, err := syncer.AdoptList(slug, source)
+	if err != nil {
+		return err
+	}
+
+	if err := saveState(st); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("list", slug).
+		Str("source", source).
+		Int("items", len(st.Lists[source].Items)).
+		Msg("List adopted")
+
+	if !listDef.Enabled {
+		log.Warn().Str("source", source).Msg("The linked list is disabled in the config and will not be synced until enabled")
+	}
+	if slug != source {
+		if old, err := client.GetList(cfg.Trakt.Username, source); err == nil && old != nil {
+			log.Warn().Str("list", source).Msg("The previously managed list still exists and is no longer updated; delete it on Trakt if it is not needed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/audit"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditLimit int
+	auditList  string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the log of write operations",
+	Long: `Shows the audit log of every write trakt-sync made to Trakt: created,
+updated and deleted lists, added and removed items, watchlist and history
+removals and token refreshes, with the Trakt IDs involved. Failed calls are
+recorded as well.
+
+The log is stored as audit.log next to the config file and only ever appended to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runAudit(os.Stdout, auditList, auditLimit); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to show audit log")
+		}
+	},
+}
+
+func init() {
+	auditCmd.Flags().IntVarP(&auditLimit, "limit", "n", 50, "show only the most recent entries (0 for all)")
+	auditCmd.Flags().StringVar(&auditList, "list", "", "show only operations on this list slug")
+
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(w io.Writer, list string, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	entries, err := audit.Read(auditLogPath())
+	if err != nil {
+		return err
+	}
+
+	if list != "" {
+		var filtered []audit.Entry
+		for _, entry := range entries {
+			if entry.Target == list {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = filtered
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No write operations recorded.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tOPERATION\tTARGET\tSUMMARY\tRESULT")
+	for _, entry := range entries {
+		result := "ok"
+		if entry.Error != "" {
+			result = "failed: " + strings.ReplaceAll(entry.Error, "\n", " ")
+		}
+		target := entry.Target
+		if target == "" {
+			target = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Local().Format(time.RFC3339), entry.Operation, target, entry.Summary, result)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/maximilian/trakt-sync/internal/trakttest"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchItems       int
+	benchChurn       float64
+	benchRateLimit   int
+	benchFullRefresh bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:    "bench",
+	Short:  "Benchmark a list sync against a fake Trakt server",
+	Long:   "Runs a movie list sync against an in-process fake Trakt API seeded with generated items and reports timing, request counts and memory use. Intended for contributors; no config or credentials are needed.",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBench(); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Benchmark failed")
+		}
+	},
+}
+
+func init() {
+	benchCmd.Flags().IntVar(&benchItems, "items", 5000, "number of items returned by the charts")
+	benchCmd.Flags().Float64Var(&benchChurn, "churn", 0.1, "fraction of list items replaced between the existing list and the charts (0-1)")
+	benchCmd.Flags().IntVar(&benchRateLimit, "rate-limit", 0, "requests per second allowed by the fake server (0 = unlimited)")
+	benchCmd.Flags().BoolVar(&benchFullRefresh, "full-refresh", false, "benchmark a full refresh instead of a diff")
+
+	rootCmd.AddCommand(benchCmd)
+}
+
+func runBench() error {
+	if benchItems <= 0 {
+		return fmt.Errorf("--items must be greater than 0")
+	}
+	if benchChurn < 0 || benchChurn > 1 {
+		return fmt.Errorf("--churn must be between 0 and 1")
+	}
+
+	// Per-list sync logs would dominate the output.
+	if !verbose {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+
+	const username = "bench"
+
+	server := trakttest.NewServer()
+	defer server.Close()
+	server.RateLimit = benchRateLimit
+
+	server.SeedCatalog(benchItems, 0)
+
+	// The existing list overlaps the charts except for the churned items.
+	offset := int(float64(benchItems) * benchChurn)
+	existing := make([]int, 0, benchItems)
+	for id := offset + 1; id <= offset+benchItems; id++ {
+		existing = append(existing, id)
+	}
+	server.SeedList(username, syncpkg.MoviesListSlug, existing...)
+
+	benchCfg := &config.Config{
+		Trakt: config.TraktConfig{Username: username},
+		Sync: config.SyncConfig{
+			Limit:       benchItems,
+			ListPrivacy: "private",
+			Lists:       config.ListSyncConfig{Movies: true},
+		},
+	}
+	if !benchFullRefresh {
+		benchCfg.Sync.LastFullRefresh.Movies = time.Now()
+	}
+
+	client := trakt.NewClient("bench", "", "bench-token", "")
+	client.SetBaseURL(server.URL)
+
+	syncer := syncpkg.NewSyncer(client, benchCfg)
+
+	var listDef syncpkg.ListDefinition
+	for _, def := range syncer.GetListDefinitions() {
+		if def.Slug == syncpkg.MoviesListSlug {
+			listDef = def
+		}
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	if err := syncer.SyncList(listDef); err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	final := server.ListItems(username, syncpkg.MoviesListSlug)
+	if len(final) != benchItems {
+		return fmt.Errorf("list has %d items after sync, expected %d", len(final), benchItems)
+	}
+
+	fmt.Printf("Items:          %d\n", benchItems)
+	fmt.Printf("Churned:        %d\n", offset)
+	fmt.Printf("Full refresh:   %t\n", benchFullRefresh)
+	fmt.Printf("Duration:       %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("Requests:       %d\n", server.Requests())
+	fmt.Printf("Throttled:      %d\n", server.Throttled())
+	fmt.Printf("Allocated:      %.1f MiB\n", float64(after.TotalAlloc-before.TotalAlloc)/(1<<20))
+	fmt.Printf("Allocations:    %d\n", after.Mallocs-before.Mallocs)
+
+	return nil
+}
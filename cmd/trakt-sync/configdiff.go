@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var configDiffAgainst string
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what a proposed config would change",
+	Long:  "Compares the current config with a proposed one and lists the operational changes: lists that would be created, dropped or changed, changed filters and schedule changes. Nothing is applied.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runConfigDiff(configDiffAgainst); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Config diff failed")
+		}
+	},
+}
+
+func init() {
+	configDiffCmd.Flags().StringVar(&configDiffAgainst, "against", "", "proposed config file to compare with")
+	_ = configDiffCmd.MarkFlagRequired("against")
+
+	configCmd.AddCommand(configDiffCmd)
+}
+
+func runConfigDiff(path string) error {
+	proposed, err := config.LoadExisting(path)
+	if err != nil {
+		return fmt.Errorf("proposed config: %w", err)
+	}
+	if err := proposed.Validate(); err != nil {
+		errcode.Log(log.Warn(), err).Msg("Proposed config is invalid and would be rejected")
+	}
+
+	changes := syncpkg.DiffConfigs(cfg, proposed)
+	if len(changes) == 0 {
+		fmt.Println("No operational changes.")
+		return nil
+	}
+
+	for _, area := range []string{"lists", "settings", "schedule"} {
+		printed := false
+		for _, change := range changes {
+			if change.Area != area {
+				continue
+			}
+			if !printed {
+				fmt.Printf("%s:\n", area)
+				printed = true
+			}
+			fmt.Printf("  %s: %s\n", change.Target, change.Detail)
+		}
+	}
+	return nil
+}
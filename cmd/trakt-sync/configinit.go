@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configInitTemplates []string
+	configInitForce     bool
+	configInitList      bool
+)
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a config file, optionally from templates",
+	Long: `Writes a config file with the defaults. Each --template adds the list
+definitions of a built-in template to sync.custom_lists, e.g.
+
+  trakt-sync config init --template netflix-top10-de --template upcoming-premieres
+
+Run with --list-templates to see the available templates.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configInitList {
+			printTemplates()
+			return
+		}
+		if err := runConfigInit(resolvedConfigPath(), configInitForce, configInitTemplates); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Config init failed")
+		}
+	},
+}
+
+func init() {
+	configInitCmd.Flags().StringSliceVar(&configInitTemplates, "template", nil, "template to add, repeatable (see --list-templates)")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "replace an existing config file")
+	configInitCmd.Flags().BoolVar(&configInitList, "list-templates", false, "list the built-in templates and exit")
+
+	configCmd.AddCommand(configInitCmd)
+}
+
+func runConfigInit(path string, force bool, templates []string) error {
+	if err := config.Init(path, force, templates...); err != nil {
+		return err
+	}
+	log.Info().Str("path", path).Strs("templates", templates).Msg("Config file created")
+	return nil
+}
+
+func printTemplates() {
+	for _, template := range config.Templates() {
+		fmt.Printf("%-20s %s\n", template.Name, template.Description)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+)
+
+// crashDirName is the directory of the data directory crash reports go to
+const crashDirName = "crashes"
+
+// maxCrashReports is how many crash reports are kept; older ones are deleted
+const maxCrashReports = 20
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func crashDir() string {
+	return filepath.Join(dataDir(), crashDirName)
+}
+
+// writeCrashReports writes a crash report for every list of result that
+// failed with a panic
+func writeCrashReports(result syncpkg.SyncResult) {
+	for _, failure := range result.Failures {
+		var panicErr *syncpkg.PanicError
+		if errors.As(failure.Err, &panicErr) {
+			writeCrashReport(panicErr)
+		}
+	}
+}
+
+// writeCrashReport saves the panic with its stack trace and build details to
+// the crash directory, to attach to a bug report. Failures are only logged.
+func writeCrashReport(panicErr *syncpkg.PanicError) {
+	now := time.Now().UTC()
+	name := "crash-" + now.Format("20060102T150405.000Z")
+	if panicErr.List != "" {
+		name += "-" + unsafeFileChars.ReplaceAllString(panicErr.List, "_")
+	}
+	path := filepath.Join(crashDir(), name+".txt")
+
+	report := fmt.Sprintf("trakt-sync %s crashed at %s\n%s %s/%s\n\nList: %s\nPanic: %v\n\n%s",
+		Version, now.Format(time.RFC3339), runtime.Version(), runtime.GOOS, runtime.GOARCH,
+		panicErr.List, panicErr.Value, panicErr.Stack)
+	if err := os.MkdirAll(crashDir(), 0o700); err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to write crash report")
+		return
+	}
+	if err := os.WriteFile(path, []byte(report), 0o600); err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to write crash report")
+		return
+	}
+	log.Error().Str("file", path).Msg("Crash report written; please attach it to a bug report")
+	pruneCrashReports()
+}
+
+// pruneCrashReports keeps the newest maxCrashReports reports
+func pruneCrashReports() {
+	paths, err := filepath.Glob(filepath.Join(crashDir(), "crash-*.txt"))
+	if err != nil || len(paths) <= maxCrashReports {
+		return
+	}
+	// The timestamp in the name sorts the reports oldest first.
+	sort.Strings(paths)
+	for _, path := range paths[:len(paths)-maxCrashReports] {
+		if err := os.Remove(path); err != nil {
+			errcode.Log(log.Debug(), err).Str("file", path).Msg("Failed to remove old crash report")
+		}
+	}
+}
+
+// runSyncRecovered is runSync for the daemon: a panic outside the per-list
+// recovery, e.g. in a sync target, becomes an error with a crash report, so
+// the daemon keeps running on its schedule. A run longer than maxRuntime
+// ends after the list at hand (0 = no limit).
+func runSyncRecovered(listsFilter string, maxRuntime time.Duration) (result syncpkg.SyncResult, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := syncpkg.Recovered("", value)
+			errcode.Log(log.Error(), panicErr).Str("stack", string(panicErr.Stack)).Msg("Recovered from panic during sync")
+			writeCrashReport(panicErr)
+			err = panicErr
+		}
+	}()
+	stop := stopCtx
+	if maxRuntime > 0 {
+		var cancel context.CancelFunc
+		stop, cancel = context.WithTimeout(stopCtx, maxRuntime)
+		defer cancel()
+	}
+	return runSyncUntil(stop, listsFilter)
+}
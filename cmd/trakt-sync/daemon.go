@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/leader"
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/rs/zerolog/log"
+)
+
+// configWatchDebounce coalesces the burst of events editors produce on save
+const configWatchDebounce = 500 * time.Millisecond
+
+// defaultDaemonInterval applies when neither --interval nor daemon.interval is set
+const defaultDaemonInterval = 6 * time.Hour
+
+// defaultMaintenanceRetry applies when daemon.maintenance_retry is not set
+const defaultMaintenanceRetry = 15 * time.Minute
+
+// defaultLeaseTTL applies when daemon.leader_election.lease_ttl is not set
+const defaultLeaseTTL = 30 * time.Second
+
+// tokenSharer is a state backend replicas share their tokens through. The
+// backends that hold the leader lease all are.
+type tokenSharer interface {
+	LoadTokens() (*state.Tokens, error)
+	SaveTokens(tokens *state.Tokens) error
+}
+
+// sharedTokens is the state backend of the leader lease while leader
+// election runs; sharedRefreshToken is the refresh token last read from or
+// written to it. Both are guarded by configMu.
+var (
+	sharedTokens       tokenSharer
+	sharedRefreshToken string
+)
+
+// shareTokens writes tokens that changed since they were last shared to the
+// state backend, so the replica taking over next does not start with a
+// refresh token Trakt already rotated. Callers hold configMu.
+func shareTokens() error {
+	if sharedTokens == nil || cfg.Trakt.RefreshToken == "" || cfg.Trakt.RefreshToken == sharedRefreshToken {
+		return nil
+	}
+	err := sharedTokens.SaveTokens(&state.Tokens{
+		AccessToken:  cfg.Trakt.AccessToken,
+		RefreshToken: cfg.Trakt.RefreshToken,
+		ExpiresAt:    cfg.Trakt.TokenExpires.UTC(),
+		ClockOffset:  cfg.Trakt.ClockOffset,
+	})
+	if err != nil {
+		return err
+	}
+	sharedRefreshToken = cfg.Trakt.RefreshToken
+	return nil
+}
+
+// adoptSharedTokens takes over the tokens of the previous leader when they
+// are newer than the own ones, and shares the own ones otherwise. A replica
+// calls it whenever it acquires the lease.
+func adoptSharedTokens() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if sharedTokens == nil {
+		return
+	}
+
+	tokens, err := sharedTokens.LoadTokens()
+	if err != nil {
+		errcode.Log(log.Warn(), err).Msg("Failed to read the shared tokens, using the own ones")
+		return
+	}
+	if tokens == nil || tokens.RefreshToken == cfg.Trakt.RefreshToken || !tokens.ExpiresAt.After(cfg.Trakt.TokenExpires) {
+		if err := shareTokens(); err != nil {
+			errcode.Log(log.Warn(), err).Msg("Failed to share the tokens")
+		}
+		return
+	}
+
+	cfg.Trakt.AccessToken = tokens.AccessToken
+	cfg.Trakt.RefreshToken = tokens.RefreshToken
+	cfg.Trakt.TokenExpires = tokens.ExpiresAt
+	cfg.Trakt.ClockOffset = tokens.ClockOffset
+	sharedRefreshToken = tokens.RefreshToken
+	log.Info().Time("expires_at", tokens.ExpiresAt).Msg("Took over the tokens of the previous leader")
+	if err := writeConfigFile(); err != nil {
+		errcode.Log(log.Warn(), err).Msg("Failed to save the tokens of the previous leader")
+	}
+}
+
+// startLeaderElection campaigns for the leader lease in the shared state
+// backend when daemon.leader_election is enabled, and returns a nil elector
+// otherwise. The backend shares the tokens, too. stop releases the lease and
+// closes the store.
+func startLeaderElection(ctx context.Context) (elector *leader.Elector, stop func(), err error) {
+	election := cfg.Daemon.LeaderElection
+	if !election.Enabled {
+		return nil, func() {}, nil
+	}
+
+	store, err := openStateStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	leaser, ok := store.(leader.Leaser)
+	sharer, shares := store.(tokenSharer)
+	if !ok || !shares {
+		store.Close()
+		return nil, nil, fmt.Errorf("state backend %q does not support leader election", cfg.State.Backend)
+	}
+	configMu.Lock()
+	sharedTokens, sharedRefreshToken = sharer, ""
+	configMu.Unlock()
+
+	ttl := election.LeaseTTL
+	if ttl <= 0 {
+		ttl = defaultLeaseTTL
+	}
+	id := election.ID
+	if id == "" {
+		id = leader.DefaultID()
+	}
+	elector = leader.New(leaser, id, ttl)
+	if !elector.Campaign() {
+		log.Info().Str("id", id).Msg("Another replica leads, standing by")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(done)
+	}()
+	return elector, func() {
+		cancel()
+		<-done
+		configMu.Lock()
+		sharedTokens = nil
+		configMu.Unlock()
+		store.Close()
+	}, nil
+}
+
+// daemonInterval returns the sync interval, preferring an explicit flag
+func daemonInterval(flagInterval time.Duration, flagSet bool) time.Duration {
+	if flagSet {
+		return flagInterval
+	}
+	if cfg.Daemon.Interval > 0 {
+		return cfg.Daemon.Interval
+	}
+	return defaultDaemonInterval
+}
+
+// maintenanceRetry returns how soon the daemon tries again after a run was
+// skipped because Trakt was unavailable
+func maintenanceRetry() time.Duration {
+	if cfg.Daemon.MaintenanceRetry > 0 {
+		return cfg.Daemon.MaintenanceRetry
+	}
+	return defaultMaintenanceRetry
+}
+
+// watchConfigFile signals on the returned channel after the config file was
+// written, created or replaced. The directory is watched because editors
+// often save by renaming a temp file over the original.
+func watchConfigFile(ctx context.Context, path string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	target := filepath.Clean(path)
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != target {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(configWatchDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				errcode.Log(log.Warn(), err).Msg("Config watcher error")
+			case <-debounce:
+				debounce = nil
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// configFileHash returns a hash of the config file, used to ignore change
+// events for writes the daemon made itself (token refresh, sync bookkeeping)
+func configFileHash(path string) [sha256.Size]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(data)
+}
+
+var (
+	// configHash is the hash of the config file as the daemon last loaded
+	// or wrote it; a file with another hash holds an edit not applied yet.
+	// Guarded by configMu.
+	configHash [sha256.Size]byte
+	// trackConfigHash is set in the daemon, which reloads edits
+	trackConfigHash bool
+)
+
+// rememberConfigHash records the config file at path as applied
+func rememberConfigHash(path string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	configHash = configFileHash(path)
+	trackConfigHash = true
+}
+
+// configEdited reports whether the config file at path changed since the
+// daemon last loaded or wrote it
+func configEdited(path string) bool {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return trackConfigHash && configFileHash(path) != configHash
+}
+
+// reloadConfig loads and validates the config file and swaps it in. On any
+// error the current config stays active.
+func reloadConfig(path string) bool {
+	// Hash first: an edit saved while loading then counts as not applied.
+	hash := configFileHash(path)
+	// The file may be gone halfway through an editor's rename-based save;
+	// LoadExisting does not write a default config in its place.
+	newCfg, err := config.LoadExisting(path)
+	if err == nil {
+		err = applyRuntime(newCfg)
+	}
+	if err != nil {
+		errcode.Log(log.Error(), err).Msg("Config reload failed, keeping the current config")
+		return false
+	}
+
+	if err := newCfg.Validate(); err != nil {
+		errcode.Log(log.Error(), err).Msg("Reloaded config is invalid, keeping the current config")
+		return false
+	}
+
+	configMu.Lock()
+	cfg = newCfg
+	configHash = hash
+	configMu.Unlock()
+	setupLogging()
+	warnSchemaProblems()
+	logConfigSummary()
+	log.Info().Msg("Config reloaded")
+	return true
+}
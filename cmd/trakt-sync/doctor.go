@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the setup for common problems",
+	Long: `Checks the config, the stored tokens, the local clock against the Trakt
+API and, with updates.manifest_url set, the release manifest notices, and
+explains how to fix what it finds. Exits with 1 if there are problems.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if problems := runDoctor(); problems > 0 {
+			exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// runDoctor prints the result of each check and returns the number of
+// problems found
+func runDoctor() int {
+	problems := 0
+	report := func(check string, err error, ok string) {
+		if err != nil {
+			problems++
+			fmt.Printf("[FAIL] %s: %v\n", check, err)
+			return
+		}
+		fmt.Printf("[ OK ] %s: %s\n", check, ok)
+	}
+
+	report("Config", cfg.Validate(), "valid")
+
+	var authErr error
+	authOK := ""
+	pending, _ := readNeedsAuth()
+	switch {
+	case !cfg.IsAuthenticated():
+		authErr = errNotAuthenticated
+	case pending != nil:
+		authErr = fmt.Errorf("Trakt rejected the tokens on %s; run 'trakt-sync auth'", pending.Since.Format(time.RFC3339))
+	case cfg.NeedsRefresh():
+		authOK = "token expired or expiring, it is refreshed on the next sync"
+	default:
+		authOK = fmt.Sprintf("token valid until %s", cfg.Trakt.TokenExpires.Format(time.RFC3339))
+	}
+	report("Authentication", authErr, authOK)
+
+	skew, err := checkClock()
+	report("Clock", err, skew)
+
+	if cfg.Updates.ManifestURL != "" {
+		notices, err := checkNotices(resolvedConfigPath())
+		report("Notices", err, notices)
+	}
+	return problems
+}
+
+// checkNotices reports the release manifest notices for this version and
+// config, such as Trakt API changes or deprecated keys, as a problem
+func checkNotices(configPath string) (string, error) {
+	warnings, err := fetchNotices(configPath)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch the release manifest: %w", err)
+	}
+	if len(warnings) == 0 {
+		return "none", nil
+	}
+	return "", fmt.Errorf("%d from the release manifest:\n  ! %s", len(warnings), strings.Join(warnings, "\n  ! "))
+}
+
+// checkClock compares the local clock with the Date header of the Trakt API.
+// A wrong clock breaks token expiry checks and TLS certificate validation.
+func checkClock() (string, error) {
+	server, local, err := newTraktClient("", "").ServerTime()
+	if err != nil {
+		return "", fmt.Errorf("could not read the Trakt clock: %w", err)
+	}
+	offset := config.ClockOffset(server, local)
+	if offset == 0 {
+		return "in sync with Trakt", nil
+	}
+	return "", fmt.Errorf("local clock is %s; enable NTP time sync (e.g. timedatectl set-ntp true); token expiry is corrected by the offset measured at the last token refresh", describeOffset(offset))
+}
+
+// describeOffset words a Trakt clock offset from the local clock's view
+func describeOffset(offset time.Duration) string {
+	if offset > 0 {
+		return fmt.Sprintf("%s behind Trakt", offset)
+	}
+	return fmt.Sprintf("%s ahead of Trakt", -offset)
+}
+
+// warnClockOffset warns after new tokens if the local clock is off
+func warnClockOffset() {
+	if offset := cfg.Trakt.ClockOffset; offset != 0 {
+		log.Warn().
+			Str("clock", describeOffset(offset)).
+			Msg("Local clock is off; token expiry is corrected for it, but enable NTP time sync ('trakt-sync doctor' checks it)")
+	}
+}
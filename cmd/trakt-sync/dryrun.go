@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scopes of --dry-run. A bare --dry-run covers all of them.
+const (
+	scopeAll           = "all"
+	scopeWrites        = "writes"
+	scopeIntegrations  = "integrations"
+	scopeNotifications = "notifications"
+)
+
+// dryRunScopes implements --dry-run[=scope,...]. writes sets dryRun, which
+// keeps Trakt unchanged, and integrations sets dryRunIntegrations, which
+// keeps the Plex watchlist and the Jellyfin collections unchanged, so either
+// side can be tested for real while the other is only logged. notifications
+// sets dryRunNotifications, which logs desktop notifications instead of
+// showing them.
+type dryRunScopes struct{}
+
+func (dryRunScopes) String() string {
+	var scopes []string
+	if dryRun {
+		scopes = append(scopes, scopeWrites)
+	}
+	if dryRunIntegrations {
+		scopes = append(scopes, scopeIntegrations)
+	}
+	if dryRunNotifications {
+		scopes = append(scopes, scopeNotifications)
+	}
+	return strings.Join(scopes, ",")
+}
+
+func (dryRunScopes) Set(value string) error {
+	writes, integrations, notifications := false, false, false
+	for _, scope := range strings.Split(value, ",") {
+		switch strings.TrimSpace(scope) {
+		case scopeAll, "true":
+			writes, integrations, notifications = true, true, true
+		case scopeWrites:
+			writes = true
+		case scopeIntegrations:
+			integrations = true
+		case scopeNotifications:
+			notifications = true
+		case "false":
+		default:
+			return fmt.Errorf("unknown scope %q (want %s, %s, %s or %s)", scope, scopeAll, scopeWrites, scopeIntegrations, scopeNotifications)
+		}
+	}
+	dryRun, dryRunIntegrations, dryRunNotifications = writes, integrations, notifications
+	return nil
+}
+
+func (dryRunScopes) Type() string {
+	return "scopes"
+}
@@ -0,0 +1,2485 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/archive"
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/jellyfin"
+	"github.com/maximilian/trakt-sync/internal/jellyfintest"
+	"github.com/maximilian/trakt-sync/internal/metrics"
+	"github.com/maximilian/trakt-sync/internal/notify"
+	"github.com/maximilian/trakt-sync/internal/parquet"
+	"github.com/maximilian/trakt-sync/internal/plex"
+	"github.com/maximilian/trakt-sync/internal/plextest"
+	"github.com/maximilian/trakt-sync/internal/serve"
+	"github.com/maximilian/trakt-sync/internal/state"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/telemetry"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/maximilian/trakt-sync/internal/trakttest"
+)
+
+// setupE2E writes a config pointing at a fake Trakt server and loads it into
+// the command globals
+func setupE2E(t *testing.T) *trakttest.Server {
+	t.Helper()
+
+	server := trakttest.NewServer()
+	t.Cleanup(server.Close)
+	server.AutoApprove = true
+	server.RequireAuth = true
+	server.SeedCatalog(30, 30)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte(`trakt:
+  client_id: "e2e-client"
+  client_secret: "e2e-secret"
+  username: "e2e"
+  api_url: "` + server.URL + `"
+sync:
+  limit: 10
+  min_rating: 0
+  list_privacy: "private"
+  full_refresh_days: 7
+  lists:
+    movies: true
+    shows: true
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	oldCfgFile, oldCfg := cfgFile, cfg
+	oldDryRun, oldDryRunIntegrations, oldDryRunNotifications := dryRun, dryRunIntegrations, dryRunNotifications
+	t.Cleanup(func() {
+		cfgFile, cfg = oldCfgFile, oldCfg
+		dryRun, dryRunIntegrations, dryRunNotifications = oldDryRun, oldDryRunIntegrations, oldDryRunNotifications
+	})
+
+	cfgFile = path
+	dryRun, dryRunIntegrations, dryRunNotifications = false, false, false
+	reloadE2EConfig(t)
+
+	return server
+}
+
+func reloadE2EConfig(t *testing.T) {
+	t.Helper()
+
+	loaded, err := config.Load(cfgFile)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg = loaded
+}
+
+// authorizeE2E stores a valid token of the fake server in the loaded config
+func authorizeE2E(server *trakttest.Server) {
+	token := server.IssueToken()
+	cfg.Trakt.AccessToken = token.AccessToken
+	cfg.Trakt.RefreshToken = token.RefreshToken
+	cfg.Trakt.TokenExpires = time.Now().Add(24 * time.Hour)
+}
+
+func TestE2EAuthAndSync(t *testing.T) {
+	server := setupE2E(t)
+
+	if err := runAuth(); err != nil {
+		t.Fatalf("auth: %v", err)
+	}
+	reloadE2EConfig(t)
+	if !cfg.IsAuthenticated() {
+		t.Fatal("tokens were not saved to the config")
+	}
+
+	result, err := runSync("")
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if result.Successful != 2 || result.Failed != 0 {
+		t.Fatalf("result = %+v, want 2 successful lists", result)
+	}
+
+	for _, slug := range []string{syncpkg.MoviesListSlug, syncpkg.ShowsListSlug} {
+		if got := len(server.ListItems("e2e", slug)); got != 10 {
+			t.Errorf("%s has %d items, want 10", slug, got)
+		}
+	}
+
+	// A second run only diffs and leaves the lists as they are.
+	reloadE2EConfig(t)
+	if _, err := runSync(""); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if got := len(server.ListItems("e2e", syncpkg.MoviesListSlug)); got != 10 {
+		t.Errorf("movies list has %d items after second sync, want 10", got)
+	}
+}
+
+func TestE2ERefreshesExpiredToken(t *testing.T) {
+	server := setupE2E(t)
+
+	token := server.IssueToken()
+	cfg.Trakt.AccessToken = "expired"
+	cfg.Trakt.RefreshToken = token.RefreshToken
+	cfg.Trakt.TokenExpires = time.Now().Add(-time.Hour)
+
+	result, err := runSync(syncpkg.MoviesListSlug)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if result.Successful != 1 {
+		t.Fatalf("result = %+v, want 1 successful list", result)
+	}
+
+	reloadE2EConfig(t)
+	if cfg.Trakt.RefreshToken == token.RefreshToken || cfg.Trakt.AccessToken == "expired" {
+		t.Fatal("refreshed tokens were not saved to the config")
+	}
+}
+
+func TestE2ERejectsUnauthenticatedSync(t *testing.T) {
+	setupE2E(t)
+
+	if _, err := runSync(""); err == nil {
+		t.Fatal("expected sync without tokens to fail")
+	}
+}
+
+func TestE2ESuffixSyncsSandboxLists(t *testing.T) {
+	server := setupE2E(t)
+
+	authorizeE2E(server)
+
+	syncSuffix = "-test"
+	t.Cleanup(func() { syncSuffix = "" })
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	if server.HasList("e2e", syncpkg.MoviesListSlug) {
+		t.Error("real list was touched by a sandbox sync")
+	}
+	if got := len(server.ListItems("e2e", syncpkg.MoviesListSlug+"-test")); got != 10 {
+		t.Errorf("sandbox list has %d items, want 10", got)
+	}
+}
+
+func TestE2ERenameKeepsSyncingSameList(t *testing.T) {
+	server := setupE2E(t)
+
+	authorizeE2E(server)
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if err := runListRename(syncpkg.MoviesListSlug, "Kino Charts"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync after rename: %v", err)
+	}
+
+	if server.HasList("e2e", syncpkg.MoviesListSlug) {
+		t.Error("sync recreated the list under its old slug")
+	}
+	if got := len(server.ListItems("e2e", "kino-charts")); got != 10 {
+		t.Errorf("renamed list has %d items, want 10", got)
+	}
+}
+
+func TestE2ECloneList(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("alice", "favourites", 1, 2, 3)
+
+	authorizeE2E(server)
+
+	if err := runListClone("alice/favourites", "my-favourites"); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if got := len(server.ListItems("e2e", "my-favourites")); got != 3 {
+		t.Errorf("cloned list has %d items, want 3", got)
+	}
+
+	if err := runListClone("alice/favourites", "my-favourites"); err == nil {
+		t.Error("expected cloning onto an existing list to fail")
+	}
+	if err := runListClone("alice/missing", "other"); err == nil {
+		t.Error("expected cloning a missing list to fail")
+	}
+}
+
+func TestE2EMergeLists(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("e2e", "old-a", 1, 2, 3)
+	server.SeedList("e2e", "old-b", 3, 4)
+	server.SeedList("e2e", "target", 4, 5)
+
+	authorizeE2E(server)
+
+	if err := runListMerge("old-a", "old-b", "target"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if got := len(server.ListItems("e2e", "target")); got != 5 {
+		t.Errorf("target has %d items, want 5", got)
+	}
+
+	if err := runListMerge("old-a", "old-b", "fresh"); err != nil {
+		t.Fatalf("merge into new list: %v", err)
+	}
+	if got := len(server.ListItems("e2e", "fresh")); got != 4 {
+		t.Errorf("new target has %d items, want 4", got)
+	}
+}
+
+func TestE2ECompareWithWatchlist(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("e2e", "picks", 1, 2, 3)
+	server.SeedWatchlist(2, 3, 4)
+	authorizeE2E(server)
+
+	var out bytes.Buffer
+	if err := runListCompare(&out, "picks", syncpkg.WatchlistSlug); err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	want := `In both (2):
+  Movie 2 (1972) [movie]
+  Movie 3 (1973) [movie]
+Only in picks (1):
+  Movie 1 (1971) [movie]
+Only in watchlist (1):
+  Movie 4 (1974) [movie]
+`
+	if out.String() != want {
+		t.Errorf("compare output =\n%s\nwant\n%s", out.String(), want)
+	}
+	if err := runListCompare(io.Discard, "picks", "missing"); err == nil {
+		t.Error("expected comparing a missing list to fail")
+	}
+}
+
+func TestE2EShowList(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("e2e", "picks", 1, 2)
+	authorizeE2E(server)
+
+	var out bytes.Buffer
+	if err := runListShow(&out, "picks", "json", true); err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	var rows []listShowRow
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Title != "Movie 1" || rows[0].Rank != 1 || len(rows[0].Genres) == 0 {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	out.Reset()
+	if err := runListShow(&out, "picks", "csv", false); err != nil {
+		t.Fatalf("show csv: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 3 || lines[0] != "rank,type,title,year,trakt_id" {
+		t.Errorf("csv output = %q", out.String())
+	}
+
+	if err := runListShow(&out, "picks", "xml", false); err == nil {
+		t.Error("expected an unknown output format to fail")
+	}
+}
+
+func TestE2EListLs(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("e2e", "picks", 1, 2)
+	authorizeE2E(server)
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runListLs(&out); err != nil {
+		t.Fatalf("ls: %v", err)
+	}
+
+	managed := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		fields := strings.Fields(line)
+		managed[fields[0]] = fields[len(fields)-1]
+	}
+	if managed["picks"] != "no" || managed[syncpkg.MoviesListSlug] != "yes" {
+		t.Errorf("managed = %v\n%s", managed, out.String())
+	}
+}
+
+func TestE2EAdoptList(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("e2e", "legacy-trending", 1, 2, 500)
+	authorizeE2E(server)
+
+	if err := runAdopt("legacy-trending", syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("adopt: %v", err)
+	}
+
+	st, err := state.Load(state.DefaultPath(cfgFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.Lists[syncpkg.MoviesListSlug]; !got.Adopted || len(got.Items) != 3 {
+		t.Errorf("adopted list state = %+v, want 3 adopted items", got)
+	}
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if server.HasList("e2e", syncpkg.MoviesListSlug) {
+		t.Error("sync created a new list instead of using the adopted one")
+	}
+	if got := len(server.ListItems("e2e", "legacy-trending")); got != 10 {
+		t.Errorf("adopted list has %d items after sync, want 10", got)
+	}
+
+	if err := runAdopt("missing", syncpkg.ShowsListSlug); err == nil {
+		t.Error("expected adopting a missing list to fail")
+	}
+	if err := runAdopt("legacy-trending", syncpkg.ShowsListSlug); err == nil {
+		t.Error("expected adopting an already managed list to fail")
+	}
+}
+
+func TestE2EConflictPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy     string
+		wantManual bool
+	}{
+		{config.ConflictOverwrite, false},
+		{config.ConflictPreserveManual, true},
+		{config.ConflictSkip, true},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			server := setupE2E(t)
+			authorizeE2E(server)
+			cfg.Sync.ConflictPolicies = map[string]string{syncpkg.MoviesListSlug: tc.policy}
+
+			if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+				t.Fatalf("sync: %v", err)
+			}
+			removed := server.ListItems("e2e", syncpkg.MoviesListSlug)[0].Movie.IDs.Trakt
+			server.EditList("e2e", syncpkg.MoviesListSlug, []int{500}, []int{removed})
+
+			if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+				t.Fatalf("second sync: %v", err)
+			}
+			// A skipped list stays skipped on later runs.
+			if tc.policy == config.ConflictSkip {
+				if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+					t.Fatalf("third sync: %v", err)
+				}
+			}
+
+			ids := make(map[int]bool)
+			for _, item := range server.ListItems("e2e", syncpkg.MoviesListSlug) {
+				ids[item.Movie.IDs.Trakt] = true
+			}
+			if ids[500] != tc.wantManual {
+				t.Errorf("manually added item present = %v, want %v", ids[500], tc.wantManual)
+			}
+			if ids[removed] == tc.wantManual {
+				t.Errorf("manually removed item present = %v, want %v", ids[removed], !tc.wantManual)
+			}
+		})
+	}
+}
+
+func TestE2EEmptyListsAreNotCreatedAndDeleted(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.DeleteEmptyAfterRuns = 2
+
+	server.SeedCatalog(0, 0)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if server.HasList("e2e", syncpkg.MoviesListSlug) {
+		t.Fatal("list was created without items")
+	}
+
+	server.SeedCatalog(30, 30)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if !server.HasList("e2e", syncpkg.MoviesListSlug) {
+		t.Fatal("list was not created once items exist")
+	}
+
+	server.SeedCatalog(0, 0)
+	for run := 1; run <= 2; run++ {
+		if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+			t.Fatalf("empty sync %d: %v", run, err)
+		}
+		if exists := server.HasList("e2e", syncpkg.MoviesListSlug); exists != (run < 2) {
+			t.Errorf("after %d empty runs list exists = %v", run, exists)
+		}
+	}
+}
+
+func TestE2EReconcilesListDisplay(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	allowComments := true
+	cfg.Sync.ListDisplay = map[string]config.ListDisplayConfig{
+		syncpkg.MoviesListSlug: {AllowComments: &allowComments, SortBy: "added", SortHow: "desc"},
+	}
+	cfg.Sync.ListPrivacy = "public"
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	list, _ := server.List("e2e", syncpkg.MoviesListSlug)
+	if !list.AllowComments || list.SortBy != "added" || list.SortHow != "desc" || !list.DisplayNumbers {
+		t.Errorf("list display = comments %v, sort %s %s, numbers %v", list.AllowComments, list.SortBy, list.SortHow, list.DisplayNumbers)
+	}
+	if list.Privacy != "public" {
+		t.Errorf("privacy = %q, want public", list.Privacy)
+	}
+}
+
+func TestE2EReorderItems(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 2
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	// The pin is added at the end of the list but belongs first.
+	cfg.Sync.Pins = map[string][]string{syncpkg.MoviesListSlug: {trakttest.Movie(5).IDs.IMDB}}
+	cfg.Sync.Reorder = true
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	var ids []int
+	for _, item := range server.ListItems("e2e", syncpkg.MoviesListSlug) {
+		ids = append(ids, item.Movie.IDs.Trakt)
+	}
+	if want := []int{5, 1, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("list order = %v, want %v", ids, want)
+	}
+
+	// An ordered list is left alone.
+	before, _ := server.List("e2e", syncpkg.MoviesListSlug)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("third sync: %v", err)
+	}
+	if after, _ := server.List("e2e", syncpkg.MoviesListSlug); !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Error("an ordered list was written again")
+	}
+}
+
+func TestE2EListStats(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.ListPrivacy = "public"
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	server.SetListEngagement("e2e", syncpkg.MoviesListSlug, 12, 3)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	st, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := st.ListStats[syncpkg.MoviesListSlug]
+	if len(samples) != 1 || samples[0].Likes != 12 || samples[0].Comments != 3 {
+		t.Fatalf("samples = %+v, want today's 12 likes and 3 comments", samples)
+	}
+
+	// A sample from last week gives the weekly growth.
+	now := time.Now()
+	week := state.ListStatsSample{Date: now.UTC().AddDate(0, 0, -8).Format("2006-01-02"), Likes: 4}
+	st.ListStats[syncpkg.MoviesListSlug] = append([]state.ListStatsSample{week}, samples...)
+	if err := saveState(st); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runListStats(&out, now); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	fields := strings.Fields(strings.Split(out.String(), "\n")[1])
+	if want := []string{syncpkg.MoviesListSlug, "12", "3", "+8", "-", week.Date}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("stats row = %q, want %q", fields, want)
+	}
+}
+
+func TestE2EListComments(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	server.AddComments("e2e", syncpkg.MoviesListSlug,
+		trakt.Comment{ID: 1, Comment: "Great picks, thanks for keeping this updated!", CreatedAt: day, User: trakt.CommentUser{Username: "fan"}},
+		trakt.Comment{ID: 2, Comment: "Stream all of these at https://example.com", CreatedAt: day.Add(time.Hour), User: trakt.CommentUser{Username: "bot"}},
+		trakt.Comment{ID: 3, Comment: "Contact me on Telegram for crypto tips", CreatedAt: day.Add(2 * time.Hour), User: trakt.CommentUser{Username: "bot"}, Replies: 1},
+	)
+
+	var out bytes.Buffer
+	if err := runListComments(&out, syncpkg.MoviesListSlug, true, true); err != nil {
+		t.Fatalf("comments: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "3 ") || !strings.HasPrefix(lines[2], "2 ") {
+		t.Errorf("output = %q, want the two spam comments, newest first", out.String())
+	}
+
+	// The comment with a reply can't be deleted and stays.
+	var left []int64
+	for _, comment := range server.Comments("e2e", syncpkg.MoviesListSlug) {
+		left = append(left, comment.ID)
+	}
+	if want := []int64{1, 3}; !reflect.DeepEqual(left, want) {
+		t.Errorf("comments left = %v, want %v", left, want)
+	}
+
+	if err := runListComments(&out, "not-managed", false, false); err == nil {
+		t.Error("expected an error for a list trakt-sync does not manage")
+	}
+}
+
+func TestE2EShowListLocalizedTitles(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedList("e2e", "picks", 7)
+	authorizeE2E(server)
+	cfg.Trakt.Language = "de"
+
+	var out bytes.Buffer
+	if err := runListShow(&out, "picks", "json", false); err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	var rows []listShowRow
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Title != "Film 7" {
+		t.Errorf("rows = %+v, want the German title", rows)
+	}
+}
+
+func TestE2EAuditLog(t *testing.T) {
+	server := setupE2E(t)
+
+	token := server.IssueToken()
+	cfg.Trakt.AccessToken = "expired"
+	cfg.Trakt.RefreshToken = token.RefreshToken
+	cfg.Trakt.TokenExpires = time.Now().Add(-time.Hour)
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runAudit(&out, "", 0); err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+	for _, want := range []string{"token_refresh", "create_list", "add_items", "10 movies ("} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("audit log does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runAudit(&out, syncpkg.MoviesListSlug, 1); err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "add_items") {
+		t.Errorf("filtered audit log =\n%s", out.String())
+	}
+}
+
+func TestE2ETelemetryIsOptIn(t *testing.T) {
+	setupE2E(t)
+
+	var events []telemetry.Event
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event telemetry.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		events = append(events, event)
+	}))
+	defer endpoint.Close()
+
+	oldCommand, oldSent := telemetryCommand, telemetrySent
+	t.Cleanup(func() { telemetryCommand, telemetrySent = oldCommand, oldSent })
+
+	telemetryCommand, telemetrySent = "list show", false
+	cfg.Telemetry.Endpoint = endpoint.URL
+	reportCommand(true)
+	if len(events) != 0 {
+		t.Fatalf("sent %d events without opt-in", len(events))
+	}
+
+	cfg.Telemetry.Enabled = true
+	reportCommand(false)
+	reportCommand(true)
+	if len(events) != 1 {
+		t.Fatalf("sent %d events, want 1", len(events))
+	}
+	if got := events[0]; got.Command != "list show" || got.Success || got.Version != Version || got.OS == "" {
+		t.Errorf("event = %+v", got)
+	}
+}
+
+func TestE2EStatusNotices(t *testing.T) {
+	setupE2E(t)
+
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"channels": {"stable": {"version": "9.0.0"}},
+			"api_changes": [{"message": "Charts require extended=min.", "fixed_in": "1.0.0"}],
+			"deprecated_keys": [
+				{"key": "sync.lists.shows", "replacement": "sync.sources"},
+				{"key": "watchlist.dry_run"}
+			]
+		}`))
+	}))
+	defer endpoint.Close()
+	cfg.Updates.ManifestURL = endpoint.URL
+
+	var out bytes.Buffer
+	printNotices(&out, cfgFile)
+
+	for _, want := range []string{
+		"Trakt API change: Charts require extended=min.",
+		"Config key sync.lists.shows is deprecated; use sync.sources instead",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("notices do not contain %q:\n%s", want, out.String())
+		}
+	}
+	// The e2e config does not set watchlist keys, and dev builds are not
+	// compared against releases.
+	if strings.Contains(out.String(), "watchlist.dry_run") || strings.Contains(out.String(), "9.0.0") {
+		t.Errorf("unexpected notices:\n%s", out.String())
+	}
+
+	// doctor reports them as a problem next to the missing tokens.
+	notices, err := checkNotices(cfgFile)
+	if err == nil || !strings.Contains(err.Error(), "2 from the release manifest") || notices != "" {
+		t.Errorf("notices check = %q, %v", notices, err)
+	}
+	if problems := runDoctor(); problems != 2 {
+		t.Errorf("expected the missing tokens and the notices as problems, got %d", problems)
+	}
+}
+
+func TestE2EStagingTagsCreatedLists(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	oldStaging := staging
+	t.Cleanup(func() { staging = oldStaging })
+	staging = true
+
+	// trakt.api_url still wins over the staging host, so the fake server
+	// stands in for api-staging.trakt.tv.
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	list, ok := server.List("e2e", syncpkg.MoviesListSlug)
+	if !ok {
+		t.Fatal("movies list was not created")
+	}
+	if !strings.HasPrefix(list.Description, "[staging] ") {
+		t.Errorf("description = %q, want staging tag", list.Description)
+	}
+
+	reloadE2EConfig(t)
+	if cfg.Trakt.Environment != "" {
+		t.Errorf("--staging was saved to the config as %q", cfg.Trakt.Environment)
+	}
+}
+
+func TestE2EStateDirKeepsConfigReadOnly(t *testing.T) {
+	server := setupE2E(t)
+
+	token := server.IssueToken()
+	cfg.Trakt.AccessToken = "expired"
+	cfg.Trakt.RefreshToken = token.RefreshToken
+	cfg.Trakt.TokenExpires = time.Now().Add(-time.Hour)
+
+	oldStateDir := stateDirFlag
+	t.Cleanup(func() { stateDirFlag = oldStateDir })
+	stateDirFlag = filepath.Join(t.TempDir(), "state")
+
+	before, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	after, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("config file was written despite --state-dir")
+	}
+	for _, name := range []string{"runtime.json", "state.json", "audit.log"} {
+		if _, err := os.Stat(filepath.Join(stateDirFlag, name)); err != nil {
+			t.Errorf("%s was not written to the state directory: %v", name, err)
+		}
+	}
+
+	// The refreshed tokens come back from the runtime file.
+	refreshed := cfg.Trakt.AccessToken
+	reloadE2EConfig(t)
+	if err := applyRuntime(cfg); err != nil {
+		t.Fatalf("apply runtime: %v", err)
+	}
+	if cfg.Trakt.AccessToken != refreshed || refreshed == "expired" {
+		t.Errorf("access token = %q, want refreshed %q", cfg.Trakt.AccessToken, refreshed)
+	}
+}
+
+func TestE2EPinnedItems(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	// Movie 25 is outside the top 10; tt9999999 is unknown and skipped.
+	cfg.Sync.Pins = map[string][]string{syncpkg.MoviesListSlug: {"tt0000025", "tt9999999"}}
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", syncpkg.MoviesListSlug)
+	if len(items) != 11 || items[0].Movie.IDs.Trakt != 25 {
+		t.Fatalf("expected pinned movie 25 first in 11 items, got %d items starting with %+v", len(items), items[0].Movie.IDs)
+	}
+
+	st, err := state.Load(stateFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Pins["movie:tt0000025"] != 25 {
+		t.Errorf("pin was not cached in state: %v", st.Pins)
+	}
+	if _, ok := st.Pins["movie:tt9999999"]; ok {
+		t.Errorf("unknown IMDb ID was cached: %v", st.Pins)
+	}
+
+	// Pins stay through a full refresh without being removed.
+	cfg.Sync.LastFullRefresh.Movies = time.Now().Add(-30 * 24 * time.Hour)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	items = server.ListItems("e2e", syncpkg.MoviesListSlug)
+	if len(items) != 11 || items[0].Movie.IDs.Trakt != 25 {
+		t.Errorf("pinned movie moved or was removed by full refresh: %+v", items[0].Movie.IDs)
+	}
+}
+
+func TestE2ERespectManualRemovals(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.RespectManualRemovals = true
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	removed := server.ListItems("e2e", syncpkg.MoviesListSlug)[0].Movie.IDs.Trakt
+	server.EditList("e2e", syncpkg.MoviesListSlug, nil, []int{removed})
+
+	// Tombstoned items stay out across runs, also with the default
+	// overwrite conflict policy.
+	for run := 0; run < 2; run++ {
+		if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+			t.Fatalf("sync %d: %v", run, err)
+		}
+		for _, item := range server.ListItems("e2e", syncpkg.MoviesListSlug) {
+			if item.Movie.IDs.Trakt == removed {
+				t.Fatalf("sync %d re-added manually removed movie %d", run, removed)
+			}
+		}
+	}
+
+	st, err := state.Load(stateFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstones := st.Lists[syncpkg.MoviesListSlug].Tombstones; len(tombstones) != 1 || tombstones[0] != removed {
+		t.Errorf("tombstones = %v, want [%d]", tombstones, removed)
+	}
+
+	// Adding the item back on Trakt lifts the tombstone.
+	server.EditList("e2e", syncpkg.MoviesListSlug, []int{removed}, nil)
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync after re-add: %v", err)
+	}
+	if st, err = state.Load(stateFilePath()); err != nil {
+		t.Fatal(err)
+	}
+	if tombstones := st.Lists[syncpkg.MoviesListSlug].Tombstones; len(tombstones) != 0 {
+		t.Errorf("tombstones after re-add = %v, want none", tombstones)
+	}
+}
+
+func TestE2ESQLiteStateBackend(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.RespectManualRemovals = true
+	cfg.State.Backend = state.BackendSQLite
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	removed := server.ListItems("e2e", syncpkg.MoviesListSlug)[0].Movie.IDs.Trakt
+	server.EditList("e2e", syncpkg.MoviesListSlug, nil, []int{removed})
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	if _, err := os.Stat(stateFilePath()); !os.IsNotExist(err) {
+		t.Errorf("state file was written with the sqlite backend: %v", err)
+	}
+	store, err := state.OpenSQLite(filepath.Join(dataDir(), state.SQLiteFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	st, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstones := st.Lists[syncpkg.MoviesListSlug].Tombstones; len(tombstones) != 1 || tombstones[0] != removed {
+		t.Errorf("tombstones = %v, want [%d]", tombstones, removed)
+	}
+}
+
+func TestE2EReaddCooldown(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.ReaddCooldownDays = 3
+
+	syncWithLimit := func(limit int) {
+		t.Helper()
+		cfg.Sync.Limit = limit
+		if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+			t.Fatalf("sync with limit %d: %v", limit, err)
+		}
+	}
+
+	// Movies 6-10 drop off the chart and come straight back.
+	syncWithLimit(10)
+	syncWithLimit(5)
+	syncWithLimit(10)
+	if items := server.ListItems("e2e", syncpkg.MoviesListSlug); len(items) != 5 {
+		t.Fatalf("expected recently removed movies to stay out, got %d items", len(items))
+	}
+
+	// Once the cooldown is over they are added again.
+	path := stateFilePath()
+	st, err := state.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := st.Lists[syncpkg.MoviesListSlug]
+	if len(entry.RemovedAt) != 5 {
+		t.Fatalf("expected 5 removal timestamps, got %v", entry.RemovedAt)
+	}
+	for id := range entry.RemovedAt {
+		entry.RemovedAt[id] = time.Now().Add(-4 * 24 * time.Hour)
+	}
+	st.Lists[syncpkg.MoviesListSlug] = entry
+	if err := state.Save(st, path); err != nil {
+		t.Fatal(err)
+	}
+
+	syncWithLimit(10)
+	if items := server.ListItems("e2e", syncpkg.MoviesListSlug); len(items) != 10 {
+		t.Errorf("expected movies to return after the cooldown, got %d items", len(items))
+	}
+}
+
+func TestE2EAnimeList(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Anime = config.AnimeListConfig{Enabled: true, Type: "shows", Limit: 3}
+
+	if _, err := runSync(syncpkg.AnimeListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", syncpkg.AnimeListSlug)
+	if len(items) != 3 {
+		t.Fatalf("expected 3 anime shows, got %d", len(items))
+	}
+	for _, item := range items {
+		if show := trakttest.Show(item.Show.IDs.Trakt); show.Genres[0] != syncpkg.AnimeGenre {
+			t.Errorf("%s is not an anime but %s", show.Title, show.Genres[0])
+		}
+	}
+}
+
+func TestE2ECustomList(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.CustomLists = []config.CustomListConfig{
+		{Name: "Popular Shows", Type: "shows", Sources: []string{"popular", "trending"}, Limit: 4},
+	}
+
+	if _, err := runSync("popular-shows"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	list, ok := server.List("e2e", "popular-shows")
+	if !ok {
+		t.Fatal("custom list was not created")
+	}
+	if list.Name != "Popular Shows" || list.Description != "Popular, trending shows" {
+		t.Errorf("list = %q (%q), want name and generated description", list.Name, list.Description)
+	}
+	items := server.ListItems("e2e", "popular-shows")
+	if len(items) != 4 || items[0].Show == nil {
+		t.Errorf("expected the 4 shows shared by both charts, got %d items", len(items))
+	}
+}
+
+func TestE2ECustomListSlugMustMatchName(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.CustomLists = []config.CustomListConfig{
+		{Name: "Popular Shows", Slug: "my-shows", Type: "shows", Sources: []string{"popular"}, Limit: 4},
+	}
+
+	// Trakt would create "popular-shows", which the next run cannot find
+	// under "my-shows", so the config is rejected before anything is written.
+	_, err := runSync("")
+	if err == nil || !strings.Contains(err.Error(), `"popular-shows"`) {
+		t.Fatalf("expected the mismatched slug to be rejected, got %v", err)
+	}
+	if server.HasList("e2e", "popular-shows") {
+		t.Error("list was created despite the mismatched slug")
+	}
+
+	cfg.Sync.CustomLists[0].Slug = "popular-shows"
+	for run := 0; run < 2; run++ {
+		if _, err := runSync("popular-shows"); err != nil {
+			t.Fatalf("sync %d: %v", run+1, err)
+		}
+	}
+	if items := len(server.ListItems("e2e", "popular-shows")); items != 4 {
+		t.Errorf("expected 4 items after two syncs, got %d", items)
+	}
+}
+
+func TestE2ERecommendationsSource(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedHistory(30)
+	authorizeE2E(server)
+	cfg.Sync.CustomLists = []config.CustomListConfig{
+		{Name: "For You", Type: "movies", Sources: []string{"recommendations"}, Limit: 3},
+	}
+
+	if _, err := runSync("for-you"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	var ids []int
+	for _, item := range server.ListItems("e2e", "for-you") {
+		ids = append(ids, item.Movie.IDs.Trakt)
+	}
+	// The fake server recommends the catalog in reverse, without watched titles.
+	if want := []int{29, 28, 27}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("list = %v, want %v", ids, want)
+	}
+}
+
+func TestE2EIMDbListSource(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	export := "Position,Const,Created,Modified,Description,Title,URL,Title Type,IMDb Rating,Runtime (mins),Year\n" +
+		"1,tt0000007,,,,Seven,,Movie,,,2001\n" +
+		"2,tt1000002,,,,A Show,,TV Series,,,2002\n" +
+		"3,tt0000003,,,,Three,,TV Movie,,,2003\n" +
+		"4,tt9999999,,,,Unknown,,Movie,,,2004\n" +
+		"5,tt0000012,,,,An Episode,,TV Episode,,,2005\n"
+	imdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/list/ls000000001/export" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(export))
+	}))
+	defer imdbServer.Close()
+
+	cfg.Sync.CustomLists = []config.CustomListConfig{
+		{Name: "IMDb Picks", Type: "movies", IMDbLists: []string{imdbServer.URL + "/list/ls000000001/"}},
+	}
+	if _, err := runSync("imdb-picks"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	var ids []int
+	for _, item := range server.ListItems("e2e", "imdb-picks") {
+		ids = append(ids, item.Movie.IDs.Trakt)
+	}
+	sort.Ints(ids)
+	// The show, the episode and the ID Trakt does not know are skipped.
+	if want := []int{3, 7}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("list = %v, want %v", ids, want)
+	}
+
+	st, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Pins["movie:tt0000007"] != 7 {
+		t.Errorf("IMDb lookup was not cached in state: %v", st.Pins)
+	}
+}
+
+func TestE2EPinCacheIsKeyedByType(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	// The same IMDb ID resolved for a show list must not be reused for a
+	// movie list.
+	st, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Pins = map[string]int{"show:tt0000025": 3}
+	if err := saveState(st); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Sync.Pins = map[string][]string{syncpkg.MoviesListSlug: {"tt0000025"}}
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if items := server.ListItems("e2e", syncpkg.MoviesListSlug); len(items) == 0 || items[0].Movie == nil || items[0].Movie.IDs.Trakt != 25 {
+		t.Errorf("expected movie 25 pinned first, got %+v", items)
+	}
+	if st, err = loadState(); err != nil {
+		t.Fatal(err)
+	}
+	if st.Pins["show:tt0000025"] != 3 || st.Pins["movie:tt0000025"] != 25 {
+		t.Errorf("pins = %v, want both types cached", st.Pins)
+	}
+}
+
+func TestE2EMDBListSource(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	mdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lists/someone/top-picks/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"rank": 1, "title": "Movie 2", "imdb_id": "tt0000002", "mediatype": "movie"},
+			{"rank": 2, "title": "Movie 10", "imdb_id": "tt0000010", "mediatype": "movie", "release_year": 2010},
+			{"rank": 3, "title": "Show 3", "imdb_id": "tt1000003", "tvdb_id": 903, "mediatype": "show"},
+			{"rank": 4, "title": "Unknown", "imdb_id": "tt9999999", "mediatype": "movie"}
+		]`))
+	}))
+	defer mdbServer.Close()
+
+	cfg.Sync.CustomLists = []config.CustomListConfig{{
+		Name:     "Top Picks",
+		Type:     "movies",
+		Sources:  []string{"trending"},
+		MDBLists: []string{mdbServer.URL + "/lists/someone/top-picks"},
+		Limit:    3,
+	}}
+	if _, err := runSync("top-picks"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	var ids []int
+	for _, item := range server.ListItems("e2e", "top-picks") {
+		ids = append(ids, item.Movie.IDs.Trakt)
+	}
+	sort.Ints(ids)
+	// Movie 2 is also trending and listed once; the show and the movie
+	// Trakt does not know are skipped.
+	if want := []int{1, 2, 3, 10}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("list = %v, want %v", ids, want)
+	}
+}
+
+func TestE2EFamilyPreset(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.CustomLists = []config.CustomListConfig{
+		{Name: "Family Movies", Type: "movies", Sources: []string{"trending"}, Preset: config.PresetFamily},
+	}
+
+	if _, err := runSync("family-movies"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	list, ok := server.List("e2e", "family-movies")
+	if !ok {
+		t.Fatal("family list was not created")
+	}
+	if list.Privacy != "private" {
+		t.Errorf("privacy = %q, want the preset's private", list.Privacy)
+	}
+	items := server.ListItems("e2e", "family-movies")
+	if len(items) == 0 {
+		t.Fatal("family list is empty")
+	}
+	for _, item := range items {
+		movie := trakttest.Movie(item.Movie.IDs.Trakt)
+		if movie.Certification != "g" && movie.Certification != "pg" {
+			t.Errorf("movie %d is rated %s", movie.IDs.Trakt, movie.Certification)
+		}
+		for _, genre := range movie.Genres {
+			if genre == "horror" {
+				t.Errorf("movie %d is a horror title", movie.IDs.Trakt)
+			}
+		}
+	}
+}
+
+func TestE2EConfigInitTemplate(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := runConfigInit(path, false, []string{"upcoming-premieres"}); err != nil {
+		t.Fatalf("config init: %v", err)
+	}
+	if err := runConfigInit(path, false, nil); err == nil {
+		t.Error("config init replaced an existing config without --force")
+	}
+
+	initialized, err := config.LoadExisting(path)
+	if err != nil {
+		t.Fatalf("load initialized config: %v", err)
+	}
+	if len(initialized.Sync.CustomLists) != 2 {
+		t.Fatalf("expected the template's 2 lists, got %+v", initialized.Sync.CustomLists)
+	}
+	cfg.Sync.CustomLists = initialized.Sync.CustomLists
+
+	if _, err := runSync("upcoming-show-premieres"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", "upcoming-show-premieres")
+	if len(items) != 10 || items[0].Show == nil {
+		t.Errorf("expected the next 10 show premieres, got %d items", len(items))
+	}
+}
+
+func TestE2EAnticipatedSource(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.CustomLists = []config.CustomListConfig{
+		{Name: "Anticipated Movies", Type: "movies", Sources: []string{"anticipated"}, Limit: 5},
+	}
+
+	if _, err := runSync("anticipated-movies"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", "anticipated-movies")
+	if len(items) != 5 || items[0].Movie == nil {
+		t.Fatalf("expected the 5 most anticipated movies, got %d items", len(items))
+	}
+	if list, _ := server.List("e2e", "anticipated-movies"); list.Description != "Anticipated movies" {
+		t.Errorf("description = %q", list.Description)
+	}
+}
+
+func TestE2EBoxOffice(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+	cfg.Sync.BoxOffice = true
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	// Trending and watched contribute the top 3, the box office all 10.
+	if items := server.ListItems("e2e", "trakt-sync-filme"); len(items) != 10 {
+		t.Errorf("expected the box office top 10 in the movie list, got %d items", len(items))
+	}
+}
+
+func TestE2EPlexTarget(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	plexServer := plextest.NewServer()
+	t.Cleanup(plexServer.Close)
+	for i := 1; i <= 30; i++ {
+		movie := trakttest.Movie(i)
+		plexServer.Add(movie.Title, movie.Year, plex.TypeMovie)
+	}
+	plexServer.Watch(plexServer.Add("Home Video", 2001, plex.TypeMovie))
+
+	cfg.Sync.Limit = 3
+	cfg.Plex = config.PlexConfig{Token: plextest.Token, APIURL: plexServer.URL, Lists: []string{"trakt-sync-filme"}}
+
+	if _, err := runPlexSync(""); err != nil {
+		t.Fatalf("plex sync: %v", err)
+	}
+	want := []string{"Home Video", "Movie 1", "Movie 2", "Movie 3"}
+	if got := plexServer.Watchlist(); !reflect.DeepEqual(got, want) {
+		t.Errorf("watchlist = %v, want %v", got, want)
+	}
+	if server.HasList("e2e", "trakt-sync-filme") {
+		t.Error("--target plex must not write Trakt lists")
+	}
+
+	// Titles that drop out of the charts leave the watchlist, the user's own
+	// stay, and known titles are not searched again.
+	searches := plexServer.Searches()
+	cfg.Sync.Limit = 2
+	if _, err := runPlexSync(""); err != nil {
+		t.Fatalf("second plex sync: %v", err)
+	}
+	want = []string{"Home Video", "Movie 1", "Movie 2"}
+	if got := plexServer.Watchlist(); !reflect.DeepEqual(got, want) {
+		t.Errorf("watchlist after second sync = %v, want %v", got, want)
+	}
+	if plexServer.Searches() != searches {
+		t.Errorf("expected cached Plex matches, got %d new searches", plexServer.Searches()-searches)
+	}
+}
+
+func TestE2EJellyfinCollections(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	jellyfinServer := jellyfintest.NewServer()
+	t.Cleanup(jellyfinServer.Close)
+	// Odd movies are known by TMDB ID, even ones by IMDb ID; movie 4 is not
+	// in the library.
+	for i := 1; i <= 6; i++ {
+		movie := trakttest.Movie(i)
+		switch {
+		case i == 4:
+		case i%2 == 1:
+			jellyfinServer.Add(movie.Title, jellyfin.TypeMovie, "Tmdb", strconv.Itoa(movie.IDs.TMDB))
+		default:
+			jellyfinServer.Add(movie.Title, jellyfin.TypeMovie, "Imdb", movie.IDs.IMDB)
+		}
+	}
+
+	cfg.Sync.Limit = 4
+	cfg.Targets.Jellyfin = config.JellyfinConfig{
+		Enabled: true,
+		URL:     jellyfinServer.URL,
+		APIKey:  jellyfintest.APIKey,
+		Lists:   []string{syncpkg.MoviesListSlug},
+	}
+
+	if _, err := runSync(""); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	got, ok := jellyfinServer.Collection("Trakt Sync Filme")
+	if want := []string{"Movie 1", "Movie 2", "Movie 3"}; !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("collection = %v (exists: %v), want %v", got, ok, want)
+	}
+
+	// The next sync updates the same collection.
+	cfg.Sync.Limit = 2
+	cfg.Sync.LastFullRefresh.Movies = time.Now().Add(-30 * 24 * time.Hour)
+	if _, err := runSync(""); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	got, _ = jellyfinServer.Collection("Trakt Sync Filme")
+	if want := []string{"Movie 1", "Movie 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("collection after second sync = %v, want %v", got, want)
+	}
+	if n := jellyfinServer.Collections(); n != 1 {
+		t.Errorf("expected one collection, got %d", n)
+	}
+}
+
+func TestE2EDryRunScopes(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	jellyfinServer := jellyfintest.NewServer()
+	t.Cleanup(jellyfinServer.Close)
+	for i := 1; i <= 3; i++ {
+		movie := trakttest.Movie(i)
+		jellyfinServer.Add(movie.Title, jellyfin.TypeMovie, "Tmdb", strconv.Itoa(movie.IDs.TMDB))
+	}
+	cfg.Sync.Limit = 3
+	cfg.Targets.Jellyfin = config.JellyfinConfig{
+		Enabled: true,
+		URL:     jellyfinServer.URL,
+		APIKey:  jellyfintest.APIKey,
+		Lists:   []string{syncpkg.MoviesListSlug},
+	}
+
+	// integrations: Trakt is written, Jellyfin is left alone.
+	if err := (dryRunScopes{}).Set("integrations"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(server.ListItems("e2e", syncpkg.MoviesListSlug)) != 3 {
+		t.Error("expected the Trakt list to be synced")
+	}
+	if n := jellyfinServer.Collections(); n != 0 {
+		t.Errorf("expected no Jellyfin collection, got %d", n)
+	}
+
+	// writes: Jellyfin gets the lists a sync would write, Trakt is left alone.
+	// Like a real sync, collected titles are excluded and pins kept.
+	cfg.Sync.Limit = 2
+	cfg.Sync.ExcludeCollected = true
+	server.SeedCollection(1)
+	cfg.Sync.Pins = map[string][]string{syncpkg.MoviesListSlug: {trakttest.Movie(3).IDs.IMDB}}
+	if err := (dryRunScopes{}).Set("writes"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if n := len(server.ListItems("e2e", syncpkg.MoviesListSlug)); n != 3 {
+		t.Errorf("the Trakt list has %d items after a writes dry run, want 3", n)
+	}
+	got, _ := jellyfinServer.Collection("Trakt Sync Filme")
+	if want := []string{"Movie 2", "Movie 3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("collection = %v, want %v", got, want)
+	}
+
+	// notifications: the sync runs for real, its notification is only logged.
+	cfg.Notifications.Desktop = config.DesktopNotificationsConfig{Enabled: true, OnSuccess: true}
+	shown := 0
+	showNotification = func(title, message string) error {
+		shown++
+		return nil
+	}
+	t.Cleanup(func() { showNotification = notify.Desktop })
+	if err := (dryRunScopes{}).Set("notifications"); err != nil {
+		t.Fatal(err)
+	}
+	if dryRun || dryRunIntegrations {
+		t.Error("notifications scope also set other scopes")
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if n := len(server.ListItems("e2e", syncpkg.MoviesListSlug)); n != 2 {
+		t.Errorf("the Trakt list has %d items after a notifications dry run, want 2", n)
+	}
+	if shown != 0 {
+		t.Errorf("showed %d notifications in a notifications dry run", shown)
+	}
+	for _, value := range []string{"true", "all"} {
+		if err := (dryRunScopes{}).Set(value); err != nil {
+			t.Fatal(err)
+		}
+		if !dryRunNotifications {
+			t.Errorf("--dry-run=%s does not cover notifications", value)
+		}
+	}
+
+	if err := (dryRunScopes{}).Set("charts"); err == nil {
+		t.Error("expected an error for an unknown scope")
+	}
+}
+
+func TestE2EServeFeeds(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+
+	feed := httptest.NewServer(serve.NewHandler(resolveFeedLists, time.Hour))
+	t.Cleanup(feed.Close)
+	getFeed := func(path string, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(feed.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %s", path, resp.Status)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var movies []serve.RadarrMovie
+	getFeed("/radarr?list="+syncpkg.MoviesListSlug, &movies)
+	var imdbIDs []string
+	for _, m := range movies {
+		imdbIDs = append(imdbIDs, m.ImdbID)
+	}
+	if want := []string{"tt0000001", "tt0000002", "tt0000003"}; !reflect.DeepEqual(imdbIDs, want) {
+		t.Errorf("radarr feed = %v, want %v", imdbIDs, want)
+	}
+
+	// Shows carry their TVDB ID through from the Trakt responses.
+	var series []serve.SonarrSeries
+	getFeed("/sonarr", &series)
+	var tvdbIDs []int
+	for _, show := range series {
+		tvdbIDs = append(tvdbIDs, show.TvdbID)
+	}
+	var want []int
+	for id := 1_000_001; id <= 1_000_003; id++ {
+		want = append(want, trakttest.Show(id).IDs.TVDB)
+	}
+	if !reflect.DeepEqual(tvdbIDs, want) {
+		t.Errorf("sonarr feed = %v, want %v", tvdbIDs, want)
+	}
+
+	if server.HasList("e2e", syncpkg.MoviesListSlug) {
+		t.Error("serve must not write Trakt lists")
+	}
+}
+
+func TestE2EErrorSummary(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Trakt.AccessToken = "revoked"
+
+	result, err := runSync("")
+	if !errors.Is(err, syncpkg.ErrAllFailed) {
+		t.Fatalf("expected all lists to fail, got %v", err)
+	}
+
+	var out bytes.Buffer
+	printErrorSummary(&out, result, err)
+	summary := out.String()
+	for _, want := range []string{
+		"Errors (2):",
+		"trakt-sync-filme: [TS-AUTH-002] 401",
+		"trakt-sync-serien: [TS-AUTH-002] 401",
+		"-> the tokens were rejected; run 'trakt-sync auth'",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, summary)
+		}
+	}
+
+	out.Reset()
+	printErrorSummary(&out, syncpkg.SyncResult{}, errNotAuthenticated)
+	if !strings.Contains(out.String(), "sync: [TS-AUTH-001] not authenticated") {
+		t.Errorf("run error missing from summary:\n%s", out.String())
+	}
+}
+
+func TestE2ECrashReport(t *testing.T) {
+	setupE2E(t)
+
+	result := syncpkg.SyncResult{Failed: 1}
+	result.Failures = append(result.Failures, syncpkg.Failure{
+		List: "trakt-sync-filme",
+		Err:  &syncpkg.PanicError{List: "trakt-sync-filme", Value: "boom", Stack: []byte("goroutine 1 [running]:")},
+	})
+	writeCrashReports(result)
+
+	reports, err := filepath.Glob(filepath.Join(crashDir(), "crash-*-trakt-sync-filme.txt"))
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("expected one crash report, got %v (%v)", reports, err)
+	}
+	data, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"List: trakt-sync-filme", "Panic: boom", "goroutine 1 [running]:"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("crash report does not contain %q:\n%s", want, data)
+		}
+	}
+
+	var out bytes.Buffer
+	printErrorSummary(&out, result, nil)
+	if !strings.Contains(out.String(), "[TS-INTERNAL-001] panic: boom") {
+		t.Errorf("panic missing from summary:\n%s", out.String())
+	}
+}
+
+func TestE2EMaintenanceSkipsRun(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	server.Maintenance = true
+
+	result, err := runSync("")
+	if !errors.Is(err, syncpkg.ErrUnavailable) {
+		t.Fatalf("expected the run to be skipped, got %v", err)
+	}
+	if result.Failed != 0 {
+		t.Errorf("maintenance counted as %d failed lists", result.Failed)
+	}
+
+	server.Maintenance = false
+	if _, err := runSync(""); err != nil {
+		t.Fatalf("sync after maintenance: %v", err)
+	}
+	if !server.HasList("e2e", "trakt-sync-filme") {
+		t.Error("list was not synced after maintenance")
+	}
+
+	// Split lists skip the run the same way.
+	cfg.Sync.Split = map[string]config.SplitConfig{syncpkg.MoviesListSlug: {By: "genre"}}
+	server.Maintenance = true
+	result, err = runSync(syncpkg.MoviesListSlug)
+	if !errors.Is(err, syncpkg.ErrUnavailable) {
+		t.Fatalf("expected the split list run to be skipped, got %v", err)
+	}
+	if result.Failed != 0 {
+		t.Errorf("maintenance counted as %d failed split lists", result.Failed)
+	}
+}
+
+func TestE2EDoctor(t *testing.T) {
+	server := setupE2E(t)
+
+	if problems := runDoctor(); problems != 1 {
+		t.Errorf("expected only the missing tokens as a problem, got %d", problems)
+	}
+	authorizeE2E(server)
+	if problems := runDoctor(); problems != 0 {
+		t.Errorf("expected no problems, got %d", problems)
+	}
+
+	skewed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-2*time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer skewed.Close()
+	cfg.Trakt.APIURL = skewed.URL
+	// The Date header has whole seconds.
+	if _, err := checkClock(); err == nil || !strings.Contains(err.Error(), "local clock is 2h0m") || !strings.Contains(err.Error(), "ahead of Trakt") {
+		t.Errorf("expected the clock to be reported 2h ahead, got %v", err)
+	}
+}
+
+func TestE2EWatchedCleanup(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedWatchlist(2, 5)
+	server.SeedHistory(2)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+	cfg.Watchlist.RemoveWatched = true
+	cfg.Watchlist.ExcludeWatched = true
+	cfg.Watchlist.DryRun = false
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", "trakt-sync-filme")
+	if len(items) == 0 {
+		t.Fatal("expected the list to be synced")
+	}
+	for _, item := range items {
+		if item.Movie.IDs.Trakt == 2 {
+			t.Error("watched movie 2 was added to the list")
+		}
+	}
+
+	var watchlist []int
+	for _, item := range server.Watchlist() {
+		watchlist = append(watchlist, item.Movie.IDs.Trakt)
+	}
+	if want := []int{5}; !reflect.DeepEqual(watchlist, want) {
+		t.Errorf("watchlist = %v, want %v", watchlist, want)
+	}
+}
+
+func TestE2EExcludeCollected(t *testing.T) {
+	server := setupE2E(t)
+	server.SeedCollection(1, 3)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+	cfg.Sync.ExcludeCollected = true
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", "trakt-sync-filme")
+	if len(items) == 0 {
+		t.Fatal("expected the list to be synced")
+	}
+	for _, item := range items {
+		if id := item.Movie.IDs.Trakt; id == 1 || id == 3 {
+			t.Errorf("collected movie %d was added to the list", id)
+		}
+	}
+}
+
+func TestE2EItemNotes(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 2
+	cfg.Sync.ItemNotes = map[string]string{"trakt-sync-filme": "#{{.Rank}} on {{.Source}} since {{.Added}}"}
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	var notes []string
+	for _, item := range server.ListItems("e2e", "trakt-sync-filme") {
+		notes = append(notes, item.Notes)
+	}
+	if want := []string{"#1 on trending since " + today, "#2 on trending since " + today}; !reflect.DeepEqual(notes, want) {
+		t.Errorf("notes = %q, want %q", notes, want)
+	}
+}
+
+func TestE2EDescriptionTranslation(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 2
+	cfg.Sync.DescriptionTemplates = map[string]string{"trakt-sync-filme": "{{.Items}} Filme, vorne {{index .Top 0}}"}
+
+	translations := 0
+	libre := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Q, Source, Target string }
+		json.NewDecoder(r.Body).Decode(&req)
+		translations++
+		json.NewEncoder(w).Encode(map[string]string{"translatedText": "[" + req.Source + ">" + req.Target + "] " + req.Q})
+	}))
+	defer libre.Close()
+	cfg.Translation = config.TranslationConfig{Provider: "libretranslate", URL: libre.URL, SourceLanguage: "de", TargetLanguage: "en"}
+
+	for run := 0; run < 2; run++ {
+		if _, err := runSync("trakt-sync-filme"); err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+	}
+	list, _ := server.List("e2e", "trakt-sync-filme")
+	want := "2 Filme, vorne " + server.ListItems("e2e", "trakt-sync-filme")[0].Movie.Title
+	if list.Description != want+"\n\n[de>en] "+want {
+		t.Errorf("description = %q, want the template and its translation", list.Description)
+	}
+	if translations != 1 {
+		t.Errorf("translated %d times, want once while the text is unchanged", translations)
+	}
+
+	// The item count changes the text and with it the translation.
+	cfg.Sync.Limit = 3
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if list, _ := server.List("e2e", "trakt-sync-filme"); !strings.HasPrefix(list.Description, "3 Filme") || translations != 2 {
+		t.Errorf("description = %q after %d translations, want 3 items translated again", list.Description, translations)
+	}
+}
+
+func TestE2ESyncCanceled(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	st, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Pending = map[string]state.PendingWrite{"trakt-sync-filme": {IsMovie: true, Add: []int{1}, Items: []int{1}}}
+	if err := saveState(st); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	oldCtx := runCtx
+	runCtx = ctx
+	t.Cleanup(func() { runCtx = oldCtx })
+
+	result, err := runSync("")
+	if !errors.Is(err, syncpkg.ErrCanceled) {
+		t.Fatalf("err = %v, want ErrCanceled", err)
+	}
+	if code := syncExitCode(result, err); code != 130 {
+		t.Errorf("exit code = %d, want 130", code)
+	}
+	if server.HasList("e2e", "trakt-sync-filme") || result.Failed != 0 {
+		t.Errorf("canceled run synced lists: %+v", result)
+	}
+
+	// Writes queued by an earlier run stay queued for the next one.
+	if st, err = loadState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.Pending["trakt-sync-filme"]; !ok {
+		t.Error("canceled run dropped the queued writes")
+	}
+}
+
+func TestE2EStopFinishesCurrentList(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	// Stop as soon as the first list is being written.
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.OnItemsAdded = func(user, slug string) { cancel() }
+
+	result, err := runSyncUntil(stop, "")
+	if !errors.Is(err, syncpkg.ErrCanceled) {
+		t.Fatalf("err = %v, want ErrCanceled", err)
+	}
+	if result.Successful != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v, want the first list synced", result)
+	}
+	if got := len(server.ListItems("e2e", "trakt-sync-filme")); got != 10 {
+		t.Errorf("first list has %d items, want all 10", got)
+	}
+	if server.HasList("e2e", "trakt-sync-serien") {
+		t.Error("the second list was synced after the stop")
+	}
+
+	st, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Lists["trakt-sync-filme"].Items) != 10 {
+		t.Errorf("state = %+v, want the written list recorded", st.Lists)
+	}
+}
+
+func TestE2EStopSkipsListBeingFetched(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	// Stop while the list is fetched, e.g. as quiet hours begin: nothing
+	// of it may be written.
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.Write([]byte(`[{"rank": 1, "title": "Movie 2", "imdb_id": "tt0000002", "mediatype": "movie"}]`))
+	}))
+	defer mdbServer.Close()
+	cfg.Sync.CustomLists = []config.CustomListConfig{{
+		Name:     "Top Picks",
+		Type:     "movies",
+		Sources:  []string{"trending"},
+		MDBLists: []string{mdbServer.URL + "/lists/someone/top-picks"},
+	}}
+
+	result, err := runSyncUntil(stop, "top-picks")
+	if !errors.Is(err, syncpkg.ErrCanceled) {
+		t.Fatalf("err = %v, want ErrCanceled", err)
+	}
+	if result.Successful != 0 || result.Failed != 0 {
+		t.Errorf("result = %+v, want the list skipped", result)
+	}
+	if server.HasList("e2e", "top-picks") {
+		t.Error("the list was written after the stop")
+	}
+}
+
+func TestE2EQueuesWritesWhileOffline(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	before := len(server.ListItems("e2e", "trakt-sync-filme"))
+
+	cfg.Sync.Limit = 5
+	server.DropWrites = true
+	result, err := runSync("trakt-sync-filme")
+	if err == nil || result.Pending != 1 {
+		t.Fatalf("offline sync: pending = %d, err = %v", result.Pending, err)
+	}
+	if got := len(server.ListItems("e2e", "trakt-sync-filme")); got != before {
+		t.Fatalf("list changed while offline: %d items, want %d", got, before)
+	}
+
+	// Any later run sends the queued changes first, whichever lists it syncs.
+	server.DropWrites = false
+	result, err = runSync("trakt-sync-serien")
+	if err != nil {
+		t.Fatalf("sync after reconnect: %v", err)
+	}
+	if result.Pending != 0 {
+		t.Errorf("pending = %d after reconnect, want 0", result.Pending)
+	}
+	if got := len(server.ListItems("e2e", "trakt-sync-filme")); got <= before {
+		t.Errorf("queued changes were not sent: %d items, had %d", got, before)
+	}
+	st, err := loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Pending) != 0 {
+		t.Errorf("state still holds pending writes: %+v", st.Pending)
+	}
+}
+
+func TestE2EDaemonReauth(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Daemon.Reauth = true
+	cfg.Notifications.Desktop.Enabled = true
+	var notified []string
+	showNotification = func(title, message string) error {
+		if title == needsAuthTitle {
+			notified = append(notified, message)
+		}
+		return nil
+	}
+	t.Cleanup(func() { showNotification = notify.Desktop })
+	auth := newReauth(resolvedConfigPath())
+
+	server.RevokeTokens()
+	result, err := runSync("trakt-sync-filme")
+	if err == nil {
+		t.Fatal("expected the sync to fail with revoked tokens")
+	}
+	auth.afterRun(result, err)
+
+	pending, err := readNeedsAuth()
+	if err != nil || pending == nil {
+		t.Fatalf("needs auth state was not recorded: %v", err)
+	}
+	if pending.UserCode == "" || pending.VerificationURL == "" {
+		t.Errorf("device code was not recorded: %+v", pending)
+	}
+	if len(notified) != 1 || !strings.Contains(notified[0], pending.UserCode) || !strings.Contains(notified[0], pending.VerificationURL) {
+		t.Errorf("expected one notification with the code, got %q", notified)
+	} else if strings.Contains(notified[0], "tray") {
+		t.Errorf("notification mentions the tray outside tray mode: %q", notified[0])
+	}
+	if !auth.blocked() {
+		t.Error("expected runs to be blocked until re-authentication")
+	}
+
+	// The fake approves the device code on the first poll.
+	select {
+	case token := <-auth.done:
+		if !auth.complete(token) {
+			t.Fatal("re-authentication did not complete")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for re-authentication")
+	}
+
+	if pending, _ := readNeedsAuth(); pending != nil {
+		t.Errorf("needs auth state was not cleared: %+v", pending)
+	}
+	if auth.blocked() {
+		t.Error("runs are still blocked after re-authentication")
+	}
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync after re-authentication: %v", err)
+	}
+}
+
+func TestE2EWatchedPeriod(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+	cfg.Sync.WatchedPeriod = "all"
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	// The fake ranks the all-time chart in reverse catalog order.
+	var ids []int
+	for _, item := range server.ListItems("e2e", "trakt-sync-filme") {
+		ids = append(ids, item.Movie.IDs.Trakt)
+	}
+	sort.Ints(ids)
+	if want := []int{1, 2, 3, 28, 29, 30}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected trending %v plus the all-time chart, got %v", want[:3], ids)
+	}
+}
+
+func TestE2EMinYear(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Limit = 3
+	cfg.Sync.MinYear = 1990
+	// The box office chart ignores the years filter, so the syncer has to
+	// drop its older movies itself.
+	cfg.Sync.BoxOffice = true
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", "trakt-sync-filme")
+	if len(items) == 0 {
+		t.Fatal("expected movies released since 1990 in the list")
+	}
+	for _, item := range items {
+		if movie := trakttest.Movie(item.Movie.IDs.Trakt); movie.Year < 1990 {
+			t.Errorf("movie %d from %d is older than sync.min_year", movie.IDs.Trakt, movie.Year)
+		}
+	}
+}
+
+func TestE2ELanguageAndCountryFilters(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.Languages = []string{"de"}
+	cfg.Sync.Countries = []string{"de", "fr"}
+
+	if _, err := runSync("trakt-sync-filme"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	items := server.ListItems("e2e", "trakt-sync-filme")
+	if len(items) == 0 {
+		t.Fatal("expected German movies in the list")
+	}
+	for _, item := range items {
+		movie := trakttest.Movie(item.Movie.IDs.Trakt)
+		if movie.Language != "de" || (movie.Country != "de" && movie.Country != "fr") {
+			t.Errorf("movie %d is in %s from %s", movie.IDs.Trakt, movie.Language, movie.Country)
+		}
+	}
+}
+
+func TestE2EStreamingTop10(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Sync.StreamingTop10 = config.StreamingTop10Config{Services: []string{"netflix", "disney_plus"}, Country: "de", Type: "shows"}
+
+	if _, err := runSync(""); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	for service, slug := range map[string]string{"netflix": "trakt-sync-top-10-netflix", "disney_plus": "trakt-sync-top-10-disney"} {
+		items := server.ListItems("e2e", slug)
+		if len(items) != 10 {
+			t.Errorf("%s: expected 10 shows, got %d", slug, len(items))
+		}
+		for _, item := range items {
+			if got := trakttest.WatchNow(item.Show.IDs.Trakt); got != service {
+				t.Errorf("%s: show %d streams on %s", slug, item.Show.IDs.Trakt, got)
+			}
+		}
+	}
+}
+
+func TestE2EMirrorList(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	server.SeedList("trakt", "official-picks", 5, 6, 7)
+	source, _ := server.List("trakt", "official-picks")
+	cfg.Sync.Mirrors = []config.MirrorConfig{{ListID: source.IDs.Trakt, Name: "Official Picks", Type: "movies"}}
+
+	mirrored := func() []int {
+		var ids []int
+		for _, item := range server.ListItems("e2e", "official-picks") {
+			ids = append(ids, item.Movie.IDs.Trakt)
+		}
+		return ids
+	}
+
+	if _, err := runSync("official-picks"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := mirrored(); !reflect.DeepEqual(got, []int{5, 6, 7}) {
+		t.Errorf("mirror = %v, want [5 6 7]", got)
+	}
+
+	server.EditList("trakt", "official-picks", []int{8}, []int{5})
+	if _, err := runSync("official-picks"); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if got := mirrored(); !reflect.DeepEqual(got, []int{6, 7, 8}) {
+		t.Errorf("mirror after the source changed = %v, want [6 7 8]", got)
+	}
+}
+
+func TestE2EChartArchive(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Archive.Enabled = true
+
+	for i := 0; i < 2; i++ {
+		if _, err := runSync(""); err != nil {
+			t.Fatalf("sync %d: %v", i+1, err)
+		}
+	}
+
+	snapshots, err := archive.Read(archiveDir())
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	if len(snapshots) != 8 {
+		t.Fatalf("expected trending and watched for movies and shows in 2 runs, got %d snapshots", len(snapshots))
+	}
+	entries, err := snapshots[0].Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 10 || entries[0].Rank != 1 || entries[0].Watchers == 0 {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	var out bytes.Buffer
+	if err := runStats(&out, "movies/trending", 3); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "movies/trending: 2 snapshots") || !strings.HasPrefix(lines[2], "Movie 1 ") {
+		t.Errorf("stats output:\n%s", out.String())
+	}
+}
+
+func TestE2ELetterboxdExport(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	server.SeedList("e2e", "picks", 5, 3)
+
+	path := filepath.Join(t.TempDir(), "picks.csv")
+	if err := runExport("picks", exportLetterboxd, path); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+
+	want := [][]string{{"Title", "Year", "imdbID"}}
+	for _, id := range []int{5, 3} {
+		movie := trakttest.Movie(id)
+		want = append(want, []string{movie.Title, strconv.Itoa(movie.Year), movie.IDs.IMDB})
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("csv = %v, want %v", records, want)
+	}
+
+	if err := runExport("picks", "imdb", path); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestE2EStatsExport(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Archive.Enabled = true
+
+	if _, err := runSync(""); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "charts.csv")
+	if err := runStatsExport(path, archive.FormatCSV, "shows/watched"); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 11 {
+		t.Fatalf("expected a header and 10 rows, got %d records", len(records))
+	}
+	if got := strings.Join(records[0], ","); got != "date,source,rank,title,year,trakt_id,watchers" {
+		t.Errorf("header = %s", got)
+	}
+	if row := records[1]; row[1] != "shows/watched" || row[2] != "1" || !strings.HasPrefix(row[3], "Show ") || row[6] == "0" {
+		t.Errorf("first row = %v", row)
+	}
+
+	path = filepath.Join(t.TempDir(), "charts.parquet")
+	if err := runStatsExport(path, archive.FormatParquet, "shows/watched"); err != nil {
+		t.Fatalf("export parquet: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, err := parquet.Read(data)
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	if len(columns) != 7 || columns[3].Name != "title" || columns[5].Name != "trakt_id" {
+		t.Fatalf("parquet columns = %+v", columns)
+	}
+	// The Parquet export holds the same rows as the CSV one.
+	if got := len(columns[3].Strings); got != 10 {
+		t.Fatalf("parquet has %d rows, want 10", got)
+	}
+	for i, record := range records[1:] {
+		if title, id := columns[3].Strings[i], columns[5].Ints[i]; title != record[3] || strconv.FormatInt(id, 10) != record[5] {
+			t.Errorf("parquet row %d = %s (%d), csv has %s (%s)", i, title, id, record[3], record[5])
+		}
+	}
+
+	if err := runStatsExport(path, "xlsx", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestE2EMetricsCountListWrites(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	daemonMetrics = metrics.New()
+	t.Cleanup(func() { daemonMetrics = nil })
+
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	daemonMetrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "trakt_sync_items_added_total 10\n") {
+		t.Errorf("expected 10 added items in /metrics, got:\n%s", body)
+	}
+}
+
+func TestE2EDesktopNotifications(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	var titles []string
+	showNotification = func(title, message string) error {
+		titles = append(titles, title)
+		return nil
+	}
+	t.Cleanup(func() { showNotification = notify.Desktop })
+
+	// Disabled by default.
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	cfg.Notifications.Desktop.Enabled = true
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(titles) != 0 {
+		t.Fatalf("expected no notification for successful syncs, got %q", titles)
+	}
+
+	cfg.Notifications.Desktop.OnSuccess = true
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(titles) != 1 || titles[0] != "trakt-sync: sync finished" {
+		t.Fatalf("expected a notification for the finished sync, got %q", titles)
+	}
+
+	server.RevokeTokens()
+	result, err := runSync(syncpkg.MoviesListSlug)
+	if len(titles) != 2 || !strings.Contains(titles[1], "failed") {
+		t.Errorf("expected a notification for the failed sync, got %q", titles)
+	}
+
+	// Without daemon.reauth there is no code, just the command to run.
+	var message string
+	showNotification = func(title, msg string) error {
+		titles, message = append(titles, title), msg
+		return nil
+	}
+	newReauth(resolvedConfigPath()).afterRun(result, err)
+	if len(titles) != 3 || titles[2] != needsAuthTitle {
+		t.Fatalf("expected a notification that Trakt needs authorization, got %q", titles)
+	}
+	if !strings.Contains(message, "trakt-sync auth") || strings.Contains(message, "tray") {
+		t.Errorf("unexpected needs auth message %q", message)
+	}
+
+	// Runs that end before syncing notify too.
+	titles = nil
+	cfg.Trakt.AccessToken, cfg.Trakt.RefreshToken = "", ""
+	if _, err := runSync(syncpkg.MoviesListSlug); !errors.Is(err, errNotAuthenticated) {
+		t.Fatalf("expected errNotAuthenticated, got %v", err)
+	}
+	if len(titles) != 1 || titles[0] != "trakt-sync: sync failed" || !strings.Contains(message, "auth") {
+		t.Fatalf("expected a notification for the unauthenticated sync, got %q: %q", titles, message)
+	}
+
+	// A dry run notifies like a sync, unless it covers notifications.
+	titles = nil
+	if err := (dryRunScopes{}).Set("writes"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(titles) != 1 || titles[0] != "trakt-sync: sync finished" {
+		t.Fatalf("expected a notification for the dry run, got %q", titles)
+	}
+	if err := (dryRunScopes{}).Set("all"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(titles) != 1 {
+		t.Errorf("a bare --dry-run showed a notification: %q", titles)
+	}
+}
+
+func TestE2EDaemonReloadsConfigEditedDuringSync(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+	cfg.Daemon.WatchConfig = true
+
+	oldStopCtx := stopCtx
+	stop, cancel := context.WithCancel(context.Background())
+	stopCtx = stop
+	t.Cleanup(func() {
+		cancel()
+		stopCtx = oldStopCtx
+		configMu.Lock()
+		trackConfigHash = false
+		configMu.Unlock()
+	})
+
+	// Save an edit while the first list is being written, before the sync
+	// saves its own bookkeeping.
+	var edit sync.Once
+	server.OnItemsAdded = func(user, slug string) {
+		edit.Do(func() {
+			data, err := os.ReadFile(cfgFile)
+			if err == nil {
+				err = os.WriteFile(cfgFile, bytes.Replace(data, []byte("limit: 10"), []byte("limit: 5"), 1), 0600)
+			}
+			if err != nil {
+				t.Errorf("edit config: %v", err)
+			}
+		})
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- runDaemon(time.Hour, true) }()
+
+	limit := func() int {
+		configMu.Lock()
+		defer configMu.Unlock()
+		return cfg.Sync.Limit
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for limit() != 5 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("daemon: %v", err)
+	}
+
+	if got := limit(); got != 5 {
+		t.Errorf("sync.limit = %d after the edit, want 5", got)
+	}
+	saved, err := config.LoadExisting(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Sync.Limit != 5 {
+		t.Errorf("the sync overwrote the edit: sync.limit = %d in the file", saved.Sync.Limit)
+	}
+	if !saved.IsAuthenticated() || saved.Sync.LastFullRefresh.Movies.IsZero() {
+		t.Error("expected the tokens and the full refresh time written into the edited file")
+	}
+}
+
+func TestE2EDaemonStartsWatcherOnReload(t *testing.T) {
+	server := setupE2E(t)
+	authorizeE2E(server)
+
+	oldStopCtx := stopCtx
+	stop, cancel := context.WithCancel(context.Background())
+	stopCtx = stop
+	t.Cleanup(func() {
+		cancel()
+		stopCtx = oldStopCtx
+		configMu.Lock()
+		trackConfigHash = false
+		configMu.Unlock()
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- runDaemon(time.Hour, true) }()
+
+	current := func() *config.Config {
+		configMu.Lock()
+		defer configMu.Unlock()
+		return cfg
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				cancel()
+				<-done
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	// The initial sync is done once its bookkeeping is in the file.
+	waitFor("the initial sync", func() bool {
+		saved, err := config.LoadExisting(cfgFile)
+		return err == nil && !saved.Sync.LastFullRefresh.Shows.IsZero()
+	})
+
+	// Turn the watcher on with a SIGHUP reload.
+	data, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("watch_config: false")) {
+		t.Fatalf("expected daemon.watch_config in the saved config:\n%s", data)
+	}
+	data = bytes.Replace(data, []byte("watch_config: false"), []byte("watch_config: true"), 1)
+	if err := os.WriteFile(cfgFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the SIGHUP reload", func() bool { return current().Daemon.WatchConfig })
+
+	// A later edit is picked up without another signal.
+	data, err = os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfgFile, bytes.Replace(data, []byte("limit: 10"), []byte("limit: 5"), 1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the watched edit", func() bool { return current().Sync.Limit == 5 })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("daemon: %v", err)
+	}
+}
+
+func TestE2ELeaderFailoverAfterTokenRefresh(t *testing.T) {
+	server := setupE2E(t)
+	token := server.IssueToken()
+	shared := filepath.Join(t.TempDir(), state.SQLiteFileName)
+
+	oldStateDir, oldStopCtx := stateDirFlag, stopCtx
+	daemonMetrics = metrics.New()
+	t.Cleanup(func() {
+		stateDirFlag, stopCtx = oldStateDir, oldStopCtx
+		daemonMetrics = nil
+		configMu.Lock()
+		trackConfigHash = false
+		configMu.Unlock()
+	})
+
+	// Both replicas start with the same expired access token, each with its
+	// own state directory, and share the state in SQLite.
+	replica := func(id string) {
+		stateDirFlag = filepath.Join(t.TempDir(), id)
+		cfg.Trakt.AccessToken = "expired"
+		cfg.Trakt.RefreshToken = token.RefreshToken
+		cfg.Trakt.TokenExpires = time.Now().Add(-time.Hour)
+		cfg.State.Backend = state.BackendSQLite
+		cfg.State.Path = shared
+		cfg.Daemon.LeaderElection = config.LeaderElectionConfig{Enabled: true, ID: id, LeaseTTL: time.Minute}
+	}
+
+	// The leader refreshes the tokens while syncing, then shuts down.
+	replica("primary")
+	elector, stopElection, err := startLeaderElection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !elector.IsLeader() {
+		t.Fatal("primary did not get the free lease")
+	}
+	if _, err := runSync(syncpkg.MoviesListSlug); err != nil {
+		t.Fatalf("leader sync: %v", err)
+	}
+	stopElection()
+	if cfg.Trakt.RefreshToken == token.RefreshToken {
+		t.Fatal("leader did not refresh the tokens")
+	}
+
+	// The standby takes over holding the refresh token Trakt rotated.
+	reloadE2EConfig(t)
+	replica("standby")
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopCtx = stop
+	done := make(chan error, 1)
+	go func() { done <- runDaemon(time.Hour, true) }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for daemonMetrics.Health().LastSync == nil && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("daemon: %v", err)
+	}
+
+	if health := daemonMetrics.Health(); health.LastResult != "success" {
+		t.Fatalf("standby sync after failover = %+v, want success", health)
+	}
+	if pending, _ := readNeedsAuth(); pending != nil {
+		t.Errorf("standby needs authorization after failover: %+v", pending)
+	}
+	runtime := &config.Config{}
+	if err := config.LoadRuntime(runtime, filepath.Join(stateDirFlag, config.RuntimeFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.Trakt.RefreshToken == "" || runtime.Trakt.RefreshToken == token.RefreshToken {
+		t.Errorf("standby saved refresh token %q, want the leader's", runtime.Trakt.RefreshToken)
+	}
+}
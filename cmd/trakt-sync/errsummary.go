@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// errNotAuthenticated is returned by commands that need tokens before any
+// were stored
+var errNotAuthenticated error = errcode.New(errcode.NotAuthenticated, "not authenticated. Run 'trakt-sync auth' first")
+
+// printErrorSummary lists every failed list of a run with its error code,
+// root cause and how to fix it, so nobody has to dig through the log. err is
+// the error of the run itself, if any.
+func printErrorSummary(w io.Writer, result syncpkg.SyncResult, err error) {
+	failures := result.Failures
+	if err != nil && !errors.Is(err, syncpkg.ErrAllFailed) {
+		failures = append(failures, syncpkg.Failure{Err: err})
+	}
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nErrors (%d):\n", len(failures))
+	for _, failure := range failures {
+		name := failure.List
+		if name == "" {
+			name = "sync"
+		}
+		fmt.Fprintf(w, "  %s: [%s] %s\n", name, errcode.Of(failure.Err), rootCause(failure.Err))
+		if fix := remedy(failure.Err); fix != "" {
+			fmt.Fprintf(w, "    -> %s\n", fix)
+		}
+	}
+}
+
+// rootCause describes the innermost error: the Trakt response if there was
+// one, otherwise the error at the end of the wrap chain
+func rootCause(err error) string {
+	var apiErr *trakt.APIError
+	if errors.As(err, &apiErr) {
+		text := http.StatusText(apiErr.Status)
+		if apiErr.Code != "" {
+			text = apiErr.Code
+		}
+		if apiErr.Description != "" && len(apiErr.Description) <= 200 {
+			return fmt.Sprintf("%d %s: %s", apiErr.Status, text, apiErr.Description)
+		}
+		return fmt.Sprintf("%d %s", apiErr.Status, text)
+	}
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err.Error()
+		}
+		err = inner
+	}
+}
+
+// remedy suggests how to fix an error, or returns "" if there is no advice
+func remedy(err error) string {
+	switch errcode.Of(err) {
+	case errcode.InvalidConfig:
+		return "fix the config; 'trakt-sync config validate' lists all problems"
+	case errcode.ReadOnly:
+		return "trakt-sync runs with --read-only; drop the flag to write"
+	case errcode.NotAuthenticated:
+		return "run 'trakt-sync auth'"
+	case errcode.TokensRejected:
+		return "the tokens were rejected; run 'trakt-sync auth'"
+	case errcode.Forbidden:
+		return "check trakt.client_id and that the Trakt app is still active"
+	case errcode.NotFound:
+		return "check the list slugs, list IDs and trakt.username in the config"
+	case errcode.AccountLimit:
+		return "the Trakt account limit was hit (lists or items per list); remove lists, lower sync.limit or upgrade to VIP"
+	case errcode.AccountLocked:
+		return "the Trakt account is locked; contact Trakt support"
+	case errcode.RateLimited:
+		return "rate limited by Trakt; sync less often or fewer lists per run"
+	case errcode.ServerError:
+		return "Trakt is having problems; try again later"
+	case errcode.Panic:
+		return fmt.Sprintf("this is a bug; please report it with the crash report from %s", crashDir())
+	case errcode.Network:
+		return "Trakt could not be reached; check the network connection and trakt.api_url"
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// exportLetterboxd is the Letterboxd import CSV format
+const exportLetterboxd = "letterboxd"
+
+var (
+	exportFormat string
+	exportOut    string
+	exportList   string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a movie list for other services",
+	Long: `Exports the movies of a Trakt list in the import format of another service.
+--format letterboxd writes a CSV with the columns Title, Year and imdbID, which
+Letterboxd imports into a list (Lists > New list > Import).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runExport(exportList, exportFormat, exportOut); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Export failed")
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", exportLetterboxd, "export format: letterboxd")
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "file to write (default: stdout)")
+	exportCmd.Flags().StringVar(&exportList, "list", syncpkg.MoviesListSlug, "slug of the movie list to export")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// runExport writes the movies of the list slug to path or stdout
+func runExport(slug, format, path string) error {
+	if format != exportLetterboxd {
+		return fmt.Errorf("unknown format %q, use %s", format, exportLetterboxd)
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+	list, err := client.GetList(cfg.Trakt.Username, slug)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return fmt.Errorf("list %s not found", slug)
+	}
+	items, _, err := client.GetListItemsInListOrder(cfg.Trakt.Username, slug, false)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		return writeLetterboxd(os.Stdout, items)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := writeLetterboxd(file, items); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	log.Info().Str("list", slug).Str("file", path).Msg("List exported")
+	return nil
+}
+
+// writeLetterboxd writes the movies of a list as a Letterboxd import CSV.
+// Letterboxd only has films, so shows are skipped.
+func writeLetterboxd(w io.Writer, items []trakt.ListItem) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"Title", "Year", "imdbID"})
+
+	skipped := 0
+	for _, item := range items {
+		if item.Movie == nil {
+			skipped++
+			continue
+		}
+		year := ""
+		if item.Movie.Year > 0 {
+			year = strconv.Itoa(item.Movie.Year)
+		}
+		out.Write([]string{item.Movie.Title, year, item.Movie.IDs.IMDB})
+	}
+	if skipped > 0 {
+		log.Warn().Int("skipped", skipped).Msg("Letterboxd only imports films; skipping shows")
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
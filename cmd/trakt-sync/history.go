@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyWindow time.Duration
+	historyType   string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Watch history maintenance",
+	Long:  "Commands for maintaining your Trakt watch history.",
+}
+
+var historyDedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Remove duplicate plays from history",
+	Long:  "Scans your Trakt history for repeated plays of the same movie or episode within a time window and removes the duplicates.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runHistoryDedupe(historyType, historyWindow); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("History dedupe failed")
+		}
+	},
+}
+
+func init() {
+	historyDedupeCmd.Flags().DurationVar(&historyWindow, "window", 10*time.Minute, "plays of the same item within this window count as duplicates")
+	historyDedupeCmd.Flags().StringVar(&historyType, "type", "", "restrict to movies or episodes (default: both)")
+
+	historyCmd.AddCommand(historyDedupeCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistoryDedupe(mediaType string, window time.Duration) error {
+	switch mediaType {
+	case "", "movies", "episodes":
+	default:
+		return fmt.Errorf("invalid --type %q (expected movies or episodes)", mediaType)
+	}
+	if window <= 0 {
+		return fmt.Errorf("--window must be greater than 0")
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	history, err := client.GetHistory(mediaType)
+	if err != nil {
+		return err
+	}
+
+	duplicates := syncpkg.FindDuplicatePlays(history, window)
+	log.Info().
+		Int("plays", len(history)).
+		Int("duplicates", len(duplicates)).
+		Dur("window", window).
+		Msg("Scanned watch history")
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(duplicates))
+	for _, dup := range duplicates {
+		log.Info().
+			Str("title", syncpkg.HistoryItemTitle(dup.Entry)).
+			Time("watched_at", dup.Entry.WatchedAt).
+			Time("original_at", dup.Original.WatchedAt).
+			Int64("history_id", dup.Entry.ID).
+			Msg("Duplicate play")
+		ids = append(ids, dup.Entry.ID)
+	}
+
+	if dryRun {
+		log.Info().Int("count", len(ids)).Msg("DRY RUN: would remove duplicate plays")
+		return nil
+	}
+
+	resp, err := client.RemoveHistoryEntries(ids)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("movies", resp.Deleted.Movies).
+		Int("episodes", resp.Deleted.Episodes).
+		Msg("Removed duplicate plays")
+	return nil
+}
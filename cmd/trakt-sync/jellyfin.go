@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/jellyfin"
+	"github.com/maximilian/trakt-sync/internal/state"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+)
+
+// syncJellyfin mirrors the lists of a sync into Jellyfin collections named
+// like the Trakt lists, matching titles by TMDB or IMDb ID. It reports
+// whether st changed. Failures are logged; the Trakt sync stands either way.
+func syncJellyfin(synced []syncpkg.SyncedList, st *state.State) bool {
+	target := cfg.Targets.Jellyfin
+	var lists []syncpkg.SyncedList
+	for _, list := range synced {
+		if len(target.Lists) == 0 || containsSlug(target.Lists, list.Slug) {
+			lists = append(lists, list)
+		}
+	}
+	if len(lists) == 0 {
+		return false
+	}
+
+	client := jellyfin.NewClient(target.URL, target.APIKey)
+	client.SetReadOnly(readOnly)
+	client.SetContext(runCtx)
+	library, err := client.Library()
+	if err != nil {
+		errcode.Log(log.Error(), err).Msg("Jellyfin collection sync failed")
+		return false
+	}
+	collections, err := client.Collections()
+	if err != nil {
+		errcode.Log(log.Error(), err).Msg("Jellyfin collection sync failed")
+		return false
+	}
+	index := jellyfin.NewIndex(library)
+
+	changed := false
+	for _, list := range lists {
+		titles := make([]jellyfin.Title, 0, len(list.IDs))
+		for _, ids := range list.IDs {
+			titles = append(titles, jellyfin.Title{Name: ids.Slug, TMDB: ids.TMDB, IMDB: ids.IMDB, Movie: list.IsMovie})
+		}
+
+		known := st.JellyfinCollections[list.Slug]
+		collectionID := jellyfin.FindCollection(collections, known, list.Name)
+		collectionID, result, err := jellyfin.SyncCollection(client, index, collectionID, list.Name, titles, dryRunIntegrations)
+		if collectionID != "" && collectionID != known {
+			if st.JellyfinCollections == nil {
+				st.JellyfinCollections = make(map[string]string)
+			}
+			st.JellyfinCollections[list.Slug] = collectionID
+			changed = true
+		}
+		if err != nil {
+			errcode.Log(log.Error(), err).Str("list", list.Slug).Msg("Failed to update Jellyfin collection")
+			continue
+		}
+		log.Info().
+			Str("list", list.Slug).
+			Str("collection", list.Name).
+			Bool("created", result.Created).
+			Int("added", result.Added).
+			Int("removed", result.Removed).
+			Int("unmatched", result.Unmatched).
+			Msg("Jellyfin collection updated")
+	}
+	return changed
+}
+
+func containsSlug(slugs []string, slug string) bool {
+	for _, s := range slugs {
+		if s == slug {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Manage lists",
+	Long:  "Commands for inspecting and managing your Trakt lists, including those maintained by trakt-sync.",
+}
+
+var listRenameCmd = &cobra.Command{
+	Use:   "rename <slug> <new-name>",
+	Short: "Rename a managed list",
+	Long:  "Renames a managed list on Trakt and remembers its new slug in the state file, so later syncs keep updating it instead of creating a new list under the old name.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListRename(args[0], args[1]); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("List rename failed")
+		}
+	},
+}
+
+var listCloneCmd = &cobra.Command{
+	Use:   "clone <source-user>/<slug> <new-slug>",
+	Short: "Copy a list into your account",
+	Long:  "Copies the movies and shows of any list you can access into a new list under your account.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListClone(args[0], args[1]); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("List clone failed")
+		}
+	},
+}
+
+var mergeInto string
+
+var listMergeCmd = &cobra.Command{
+	Use:   "merge <slug-a> <slug-b> --into <target>",
+	Short: "Merge two lists into a target list",
+	Long: `Adds the union of two of your lists to a target list, skipping items the
+target already contains. The target is created if it does not exist.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListMerge(args[0], args[1], mergeInto); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("List merge failed")
+		}
+	},
+}
+
+var listCompareCmd = &cobra.Command{
+	Use:   "compare <slug-a> <slug-b>",
+	Short: "Compare two lists",
+	Long:  "Prints the items two of your lists have in common and the items only one of them contains. Use \"watchlist\" to compare against your watchlist.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListCompare(os.Stdout, args[0], args[1]); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("List compare failed")
+		}
+	},
+}
+
+var (
+	showOutput   string
+	showExtended bool
+)
+
+var listShowCmd = &cobra.Command{
+	Use:   "show <slug>",
+	Short: "Print the contents of a list",
+	Long:  "Prints the items of one of your lists as a table, JSON or CSV. With --extended, ratings and genres are fetched as well.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListShow(os.Stdout, args[0], showOutput, showExtended); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("List show failed")
+		}
+	},
+}
+
+var listLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List all your Trakt lists",
+	Long:  "Prints all lists of your Trakt account with item counts, privacy and likes, and whether trakt-sync manages them.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListLs(os.Stdout); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Listing lists failed")
+		}
+	},
+}
+
+var (
+	commentsSpamOnly bool
+	commentsDelete   bool
+)
+
+var listCommentsCmd = &cobra.Command{
+	Use:   "comments <slug>",
+	Short: "Review and delete spam comments on a managed list",
+	Long: `Prints the comments on one of your managed lists and flags likely spam:
+comments with links, typical spam phrases or text posted more than once.
+With --delete, the flagged comments are deleted. Trakt refuses to delete
+comments with replies and may refuse comments of other users; turn comments
+off with sync.list_display.<slug>.allow_comments: false in that case.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListComments(os.Stdout, args[0], commentsSpamOnly, commentsDelete); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("List comments failed")
+		}
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listRenameCmd)
+	listCmd.AddCommand(listCloneCmd)
+	listCmd.AddCommand(listMergeCmd)
+	listCmd.AddCommand(listCompareCmd)
+	listCmd.AddCommand(listShowCmd)
+	listCmd.AddCommand(listLsCmd)
+	listCmd.AddCommand(listCommentsCmd)
+
+	listMergeCmd.Flags().StringVar(&mergeInto, "into", "", "Slug of the target list (required)")
+	_ = listMergeCmd.MarkFlagRequired("into")
+
+	listShowCmd.Flags().StringVarP(&showOutput, "output", "o", "table", "Output format: table, json or csv")
+	listShowCmd.Flags().BoolVar(&showExtended, "extended", false, "Fetch ratings and genres")
+	listCommentsCmd.Flags().BoolVar(&commentsSpamOnly, "spam", false, "Only print comments flagged as spam")
+	listCommentsCmd.Flags().BoolVar(&commentsDelete, "delete", false, "Delete the comments flagged as spam")
+	rootCmd.AddCommand(listCmd)
+}
+
+func runListRename(slug, newName string) error {
+	if dryRun {
+		log.Info().Str("list", slug).Str("name", newName).Msg("DRY RUN: would rename list")
+		return nil
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+
+	syncer := syncpkg.NewSyncer(client, cfg)
+	syncer.SetState(st)
+
+	newSlug, err := syncer.RenameList(slug, newName)
+	if err != nil {
+		return err
+	}
+
+	if err := saveState(st); err != nil {
+		return err
+	}
+
+	log.Info().Str("old_slug", slug).Str("new_slug", newSlug).Str("name", newName).Msg("List renamed")
+	return nil
+}
+
+func runListClone(source, newSlug string) error {
+	sourceUser, sourceSlug, ok := strings.Cut(source, "/")
+	if !ok || sourceUser == "" || sourceSlug == "" {
+		return fmt.Errorf("source must be <user>/<slug>, got %q", source)
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		items, err := client.GetListItems(sourceUser, sourceSlug)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("source", source).Str("list", newSlug).Int("items", len(items)).Msg("DRY RUN: would clone list")
+		return nil
+	}
+
+	slug, count, err := syncpkg.NewSyncer(client, cfg).CloneList(sourceUser, sourceSlug, newSlug)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("source", source).Str("list", slug).Int("items", count).Msg("List cloned")
+	return nil
+}
+
+func runListMerge(slugA, slugB, target string) error {
+	if target == "" {
+		return fmt.Errorf("--into is required")
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.Info().Str("lists", slugA+", "+slugB).Str("into", target).Msg("DRY RUN: would merge lists")
+		return nil
+	}
+
+	added, err := syncpkg.NewSyncer(client, cfg).MergeLists(slugA, slugB, target)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("lists", slugA+", "+slugB).Str("into", target).Int("added", added).Msg("Lists merged")
+	return nil
+}
+
+func runListCompare(w io.Writer, slugA, slugB string) error {
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	comparison, err := syncpkg.NewSyncer(client, cfg).CompareLists(slugA, slugB)
+	if err != nil {
+		return err
+	}
+
+	localizer := trakt.NewLocalizer(client, cfg.Trakt.Language)
+	localizer.Localize(comparison.Both)
+	localizer.Localize(comparison.OnlyA)
+	localizer.Localize(comparison.OnlyB)
+
+	printListItems(w, "In both", comparison.Both)
+	printListItems(w, "Only in "+slugA, comparison.OnlyA)
+	printListItems(w, "Only in "+slugB, comparison.OnlyB)
+	return nil
+}
+
+func printListItems(w io.Writer, heading string, items []trakt.ListItem) {
+	fmt.Fprintf(w, "%s (%d):\n", heading, len(items))
+	for _, item := range items {
+		fmt.Fprintf(w, "  %s\n", formatListItem(item))
+	}
+}
+
+// formatListItem renders a movie or show as "Title (Year)"
+func formatListItem(item trakt.ListItem) string {
+	title, year, kind := "", 0, "movie"
+	switch {
+	case item.Movie != nil:
+		title, year = item.Movie.Title, item.Movie.Year
+	case item.Show != nil:
+		title, year, kind = item.Show.Title, item.Show.Year, "show"
+	default:
+		return item.Type
+	}
+	if year == 0 {
+		return fmt.Sprintf("%s [%s]", title, kind)
+	}
+	return fmt.Sprintf("%s (%d) [%s]", title, year, kind)
+}
+
+// listShowRow is one list item as printed by list show
+type listShowRow struct {
+	Rank    int      `json:"rank"`
+	Type    string   `json:"type"`
+	Title   string   `json:"title"`
+	Year    int      `json:"year,omitempty"`
+	TraktID int      `json:"trakt_id"`
+	Rating  float64  `json:"rating,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+}
+
+func runListShow(w io.Writer, slug, output string, extended bool) error {
+	switch output {
+	case "table", "json", "csv":
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or csv)", output)
+	}
+
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	list, err := client.GetList(cfg.Trakt.Username, slug)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return fmt.Errorf("list %s not found", slug)
+	}
+
+	items, _, err := client.GetListItemsInListOrder(cfg.Trakt.Username, slug, extended)
+	if err != nil {
+		return err
+	}
+	trakt.NewLocalizer(client, cfg.Trakt.Language).Localize(items)
+
+	return writeListItems(w, items, output, extended)
+}
+
+func writeListItems(w io.Writer, items []trakt.ListItem, output string, extended bool) error {
+	rows := make([]listShowRow, 0, len(items))
+	for _, item := range items {
+		row := listShowRow{Rank: item.Rank, Type: item.Type}
+		switch {
+		case item.Movie != nil:
+			row.Title, row.Year, row.TraktID = item.Movie.Title, item.Movie.Year, item.Movie.IDs.Trakt
+			row.Rating, row.Genres = item.Movie.Rating, item.Movie.Genres
+		case item.Show != nil:
+			row.Title, row.Year, row.TraktID = item.Show.Title, item.Show.Year, item.Show.IDs.Trakt
+			row.Rating, row.Genres = item.Show.Rating, item.Show.Genres
+		default:
+			continue
+		}
+		if !extended {
+			row.Rating, row.Genres = 0, nil
+		}
+		rows = append(rows, row)
+	}
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"rank", "type", "title", "year", "trakt_id"}
+		if extended {
+			header = append(header, "rating", "genres")
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			record := []string{strconv.Itoa(row.Rank), row.Type, row.Title, strconv.Itoa(row.Year), strconv.Itoa(row.TraktID)}
+			if extended {
+				record = append(record, strconv.FormatFloat(row.Rating, 'f', 1, 64), strings.Join(row.Genres, "|"))
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		if extended {
+			fmt.Fprintln(tw, "#\tTITLE\tYEAR\tRATING\tGENRES")
+		} else {
+			fmt.Fprintln(tw, "#\tTITLE\tYEAR")
+		}
+		for _, row := range rows {
+			if extended {
+				fmt.Fprintf(tw, "%d\t%s\t%d\t%.1f\t%s\n", row.Rank, row.Title, row.Year, row.Rating, strings.Join(row.Genres, ", "))
+			} else {
+				fmt.Fprintf(tw, "%d\t%s\t%d\n", row.Rank, row.Title, row.Year)
+			}
+		}
+		return tw.Flush()
+	}
+}
+
+func runListLs(w io.Writer) error {
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	lists, err := client.GetUserLists(cfg.Trakt.Username)
+	if err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	syncer := syncpkg.NewSyncer(client, cfg)
+	syncer.SetState(st)
+	managed := syncer.ManagedSlugs()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SLUG\tNAME\tITEMS\tPRIVACY\tLIKES\tMANAGED")
+	for _, list := range lists {
+		isManaged := "no"
+		if managed[list.IDs.Slug] {
+			isManaged = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d\t%s\n", list.IDs.Slug, list.Name, list.ItemCount, list.Privacy, list.Likes, isManaged)
+	}
+	return tw.Flush()
+}
+
+// commentPreviewLength is the length comments are cut to in the table
+const commentPreviewLength = 60
+
+func runListComments(w io.Writer, slug string, spamOnly, deleteSpam bool) error {
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	syncer := syncpkg.NewSyncer(client, cfg)
+	syncer.SetState(st)
+	if !syncer.ManagedSlugs()[slug] {
+		return fmt.Errorf("%s is not a list managed by trakt-sync", slug)
+	}
+
+	comments, err := client.GetListComments(cfg.Trakt.Username, slug)
+	if err != nil {
+		return err
+	}
+	spam := syncpkg.SpamComments(comments)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tDATE\tLIKES\tSPAM\tCOMMENT")
+	for _, comment := range comments {
+		reason, flagged := spam[comment.ID]
+		if spamOnly && !flagged {
+			continue
+		}
+		if !flagged {
+			reason = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n", comment.ID, comment.User.Username, comment.CreatedAt.Format("2006-01-02"),
+			comment.Likes, reason, commentPreview(comment.Comment))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if !deleteSpam {
+		return nil
+	}
+	deleted, failed := 0, 0
+	for _, comment := range comments {
+		if _, flagged := spam[comment.ID]; !flagged {
+			continue
+		}
+		if dryRun {
+			log.Info().Int64("comment", comment.ID).Str("user", comment.User.Username).Msg("DRY RUN: would delete comment")
+			continue
+		}
+		if err := client.DeleteComment(comment.ID); err != nil {
+			errcode.Log(log.Warn(), err).Int64("comment", comment.ID).Str("user", comment.User.Username).Msg("Trakt refused to delete the comment")
+			failed++
+			continue
+		}
+		deleted++
+	}
+	if failed > 0 {
+		log.Warn().Int("failed", failed).
+			Msgf("Some comments could not be deleted; set sync.list_display.%s.allow_comments: false to turn comments off", slug)
+	}
+	if !dryRun {
+		log.Info().Str("list", slug).Int("deleted", deleted).Msg("Spam comments deleted")
+	}
+	return nil
+}
+
+// commentPreview returns comment on one line, cut to commentPreviewLength
+func commentPreview(comment string) string {
+	comment = strings.Join(strings.Fields(comment), " ")
+	if runes := []rune(comment); len(runes) > commentPreviewLength {
+		return string(runes[:commentPreviewLength-1]) + "…"
+	}
+	return comment
+}
@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/maximilian/trakt-sync/internal/archive"
 	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/manifest"
+	"github.com/maximilian/trakt-sync/internal/metrics"
+	"github.com/maximilian/trakt-sync/internal/power"
 	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/tokenstore"
 	"github.com/maximilian/trakt-sync/internal/trakt"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -24,17 +32,41 @@ var (
 	cfgFile string
 	verbose bool
 	dryRun  bool
+	staging bool
 	cfg     *config.Config
 
-	servicePath     string
-	serviceUser     string
-	serviceInterval time.Duration
+	// dryRunIntegrations only logs the changes a run would make to the Plex
+	// watchlist and Jellyfin collections; dryRun does the same for Trakt
+	dryRunIntegrations bool
+	// dryRunNotifications logs desktop notifications instead of showing them
+	dryRunNotifications bool
+
+	// readOnly blocks every write to Trakt, Plex and Jellyfin in the API
+	// clients themselves
+	readOnly bool
+
+	noCreateConfig bool
+
+	logOutput io.Writer
+
+	syncSuffix string
+
+	recordPath string
+	replayPath string
+	transport  http.RoundTripper
+
+	authJSON      bool
+	authResume    string
+	authInterval  int
+	authExpiresIn int
+	tokenFilePath string
 )
 
 func main() {
+	handleSignals()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -43,22 +75,49 @@ var rootCmd = &cobra.Command{
 	Short: "Sync Trakt.tv lists with trending and streaming charts",
 	Long:  "A tool to automatically synchronize Trakt.tv lists with top trending and most watched movies and shows.",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		if cmd.Name() == "version" {
+		// Keep stdout clean for commands that print machine-readable output.
+		if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Value.String() == "true" {
+			logOutput = os.Stderr
+		}
+
+		// 'config init' creates the config file itself.
+		if cmd.Name() == "version" || cmd.Name() == "bench" || cmd.Name() == "schema" || cmd.Name() == "init" {
 			setupLogging()
 			return
 		}
 
 		var err error
-		cfg, err = config.Load(cfgFile)
+		if noCreateConfig {
+			cfg, err = config.LoadExisting(cfgFile)
+		} else {
+			cfg, err = config.Load(cfgFile)
+		}
+		if err == nil {
+			err = applyRuntime(cfg)
+		}
 		if err != nil {
 			// Setup basic logging first to show error
 			setupLogging()
-			log.Fatal().Err(err).Msg("Failed to load config")
+			errcode.Log(log.Fatal(), err).Msg("Failed to load config")
 		}
 
 		// Setup logging with config-based settings
 		setupLogging()
 		logConfigSummary()
+		telemetryCommand = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+
+		// 'config validate' reports schema problems as errors itself.
+		if cmd.Name() != "validate" {
+			warnSchemaProblems()
+		}
+
+		if err := setupCassette(); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to set up cassette")
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		flushTokens()
+		reportCommand(true)
 	},
 }
 
@@ -67,8 +126,39 @@ var authCmd = &cobra.Command{
 	Short: "Authenticate with Trakt.tv",
 	Long:  "Initiates OAuth2 device flow to authenticate with Trakt.tv and stores tokens.",
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := runAuth(); err != nil {
-			log.Fatal().Err(err).Msg("Authentication failed")
+		var err error
+		switch {
+		case authJSON:
+			err = runAuthJSON()
+		case authResume != "":
+			err = runAuthResume(authResume, authInterval, authExpiresIn)
+		default:
+			err = runAuth()
+		}
+		if err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Authentication failed")
+		}
+	},
+}
+
+var authExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export tokens to a file",
+	Long:  "Writes the stored Trakt tokens to a JSON file (mode 0600) so they can be imported on another machine.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runAuthExport(tokenFilePath); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Token export failed")
+		}
+	},
+}
+
+var authImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import tokens from a file",
+	Long:  "Reads Trakt tokens written by 'auth export' and stores them in the config.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runAuthImport(tokenFilePath); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Token import failed")
 		}
 	},
 }
@@ -80,15 +170,43 @@ var syncCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		lists, err := cmd.Flags().GetString("lists")
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to parse lists flag")
+			errcode.Log(log.Fatal(), err).Msg("Failed to parse lists flag")
+		}
+		waitForAuth, err := cmd.Flags().GetBool("wait-for-auth")
+		if err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to parse wait-for-auth flag")
+		}
+		if waitForAuth && !dryRun && !cfg.IsAuthenticated() {
+			log.Info().Msg("Not authenticated yet, starting device authorization")
+			if err := runAuth(); err != nil {
+				errcode.Log(log.Error(), err).Msg("Authentication failed")
+				exit(3)
+			}
+		}
+		var result syncpkg.SyncResult
+		switch syncTarget {
+		case targetTrakt:
+			result, err = runSync(lists)
+		case targetPlex:
+			result, err = runPlexSync(lists)
+		default:
+			err = fmt.Errorf("unknown target %q, use %s or %s", syncTarget, targetTrakt, targetPlex)
+		}
+		if errors.Is(err, syncpkg.ErrUnavailable) {
+			log.Info().Msg("Trakt is unavailable, sync skipped; try again later")
+			return
+		}
+		if errors.Is(err, syncpkg.ErrCanceled) {
+			log.Info().Msg("Sync canceled")
+			exit(syncExitCode(result, err))
 		}
-		result, err := runSync(lists)
 		if err != nil {
-			log.Error().Err(err).Msg("Sync failed")
+			errcode.Log(log.Error(), err).Msg("Sync failed")
 		}
+		printErrorSummary(os.Stderr, result, err)
 		exitCode := syncExitCode(result, err)
 		if exitCode != 0 {
-			os.Exit(exitCode)
+			exit(exitCode)
 		}
 	},
 }
@@ -99,8 +217,12 @@ var daemonCmd = &cobra.Command{
 	Long:  "Runs continuously and syncs lists at the specified interval.",
 	Run: func(cmd *cobra.Command, args []string) {
 		interval, _ := cmd.Flags().GetDuration("interval")
-		if err := runDaemon(interval); err != nil {
-			log.Fatal().Err(err).Msg("Daemon failed")
+		err := runDaemon(interval, cmd.Flags().Changed("interval"))
+		if errors.Is(err, syncpkg.ErrCanceled) {
+			exit(130)
+		}
+		if err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Daemon failed")
 		}
 	},
 }
@@ -117,33 +239,59 @@ var statusCmd = &cobra.Command{
 var configValidateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate configuration",
-	Long:  "Validates the configuration file.",
+	Long:  "Validates the configuration file against the schema and checks all values.",
 	Run: func(cmd *cobra.Command, args []string) {
+		problems, err := config.CheckSchema(resolvedConfigPath())
+		if err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to check config schema")
+		}
+
 		if err := cfg.Validate(); err != nil {
-			log.Error().Err(err).Msg("Configuration is invalid")
-			os.Exit(1)
+			var validationErrs config.ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				errcode.Log(log.Fatal(), err).Msg("Configuration is invalid")
+			}
+			reported := make(map[string]bool, len(problems))
+			for _, problem := range problems {
+				reported[problem.Path] = true
+			}
+			for _, validationErr := range validationErrs {
+				if !reported[validationErr.Path] {
+					problems = append(problems, validationErr)
+				}
+			}
+		}
+
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				log.Error().Str("path", problem.Path).Msg(problem.Message)
+			}
+			log.Error().Int("errors", len(problems)).Msg("Configuration is invalid")
+			exit(1)
 		}
 		log.Info().Msg("Configuration is valid")
 	},
 }
 
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the config JSON Schema",
+	Long:  "Prints a JSON Schema for the config file, e.g. for editor autocomplete via yaml-language-server.",
+	Run: func(cmd *cobra.Command, args []string) {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(config.GenerateSchema()); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to encode schema")
+		}
+	},
+}
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configuration commands",
 	Long:  "Commands for managing configuration.",
 }
 
-var installServiceCmd = &cobra.Command{
-	Use:   "install-service",
-	Short: "Install systemd service file",
-	Long:  "Generates a systemd service file for running trakt-sync in daemon mode.",
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := runInstallService(servicePath, serviceUser, serviceInterval); err != nil {
-			log.Fatal().Err(err).Msg("Failed to install systemd service")
-		}
-	},
-}
-
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version",
@@ -156,29 +304,49 @@ var versionCmd = &cobra.Command{
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default: ~/.config/trakt-sync/config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
-	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "show what would happen without making changes")
+	rootCmd.PersistentFlags().Var(dryRunScopes{}, "dry-run", "show what would happen without making changes; limit it to Trakt with =writes, to Plex and Jellyfin with =integrations or to desktop notifications with =notifications")
+	rootCmd.PersistentFlags().Lookup("dry-run").NoOptDefVal = scopeAll
+	rootCmd.PersistentFlags().StringVar(&stateDirFlag, "state-dir", "", "directory for tokens, state and the audit log, keeping the config file read-only (env: "+stateDirEnv+")")
+	rootCmd.PersistentFlags().BoolVar(&noCreateConfig, "no-create-config", false, "fail if the config file does not exist instead of creating a default one")
+	rootCmd.PersistentFlags().BoolVar(&staging, "staging", false, "use the Trakt staging API (same as trakt.environment: staging)")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "refuse every API call that would change data, e.g. for monitoring deployments")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record sanitized API interactions to this cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "answer API requests from this cassette file instead of Trakt")
+
+	authCmd.Flags().BoolVar(&authJSON, "json", false, "print the device code as JSON and exit without waiting")
+	authCmd.Flags().StringVar(&authResume, "resume", "", "poll for a token using a device code from 'auth --json'")
+	authCmd.Flags().IntVar(&authInterval, "interval", 5, "polling interval in seconds when resuming")
+	authCmd.Flags().IntVar(&authExpiresIn, "expires-in", 600, "seconds to keep polling when resuming")
+	authCmd.MarkFlagsMutuallyExclusive("json", "resume")
+	authExportCmd.Flags().StringVar(&tokenFilePath, "file", "tokens.json", "token file to write")
+	authImportCmd.Flags().StringVar(&tokenFilePath, "file", "tokens.json", "token file to read")
+	authCmd.AddCommand(authExportCmd)
+	authCmd.AddCommand(authImportCmd)
 
 	syncCmd.Flags().String("lists", "", "comma-separated list slugs to sync (e.g., trakt-sync-filme,trakt-sync-serien)")
+	syncCmd.Flags().StringVar(&syncSuffix, "suffix", "", "sync into sandbox lists whose slugs carry this suffix (e.g. -test)")
+	syncCmd.Flags().StringVar(&syncTarget, "target", targetTrakt, "where to sync the lists: trakt, or plex for the Plex watchlist")
+	syncCmd.Flags().Bool("wait-for-auth", false, "start the device flow and wait for authorization if not authenticated yet")
 
-	daemonCmd.Flags().Duration("interval", 6*time.Hour, "sync interval")
-
-	installServiceCmd.Flags().StringVar(&servicePath, "path", "/etc/systemd/system/trakt-sync.service", "systemd service file path")
-	installServiceCmd.Flags().StringVar(&serviceUser, "user", "trakt-sync", "systemd service user")
-	installServiceCmd.Flags().DurationVar(&serviceInterval, "interval", 6*time.Hour, "sync interval for the service")
+	daemonCmd.Flags().Duration("interval", defaultDaemonInterval, "sync interval (overrides daemon.interval)")
 
 	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
 
 	rootCmd.AddCommand(authCmd)
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(daemonCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(configCmd)
-	rootCmd.AddCommand(installServiceCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 func setupLogging() {
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"})
+	out := logOutput
+	if out == nil {
+		out = os.Stdout
+	}
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: out, TimeFormat: "2006-01-02 15:04:05"})
 
 	level := zerolog.InfoLevel
 	format := "text"
@@ -207,8 +375,9 @@ func setupLogging() {
 	zerolog.SetGlobalLevel(level)
 
 	if format == "json" {
-		log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+		log.Logger = zerolog.New(out).With().Timestamp().Logger()
 	}
+	log.Logger = log.Logger.Hook(telemetryHook{})
 }
 
 func logConfigSummary() {
@@ -216,10 +385,7 @@ func logConfigSummary() {
 		return
 	}
 
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = config.DefaultConfigPath()
-	}
+	configPath := resolvedConfigPath()
 
 	log.Info().
 		Str("config_file", configPath).
@@ -229,9 +395,39 @@ func logConfigSummary() {
 		Int("full_refresh_days", cfg.Sync.FullRefreshDays).
 		Bool("movies", cfg.Sync.Lists.Movies).
 		Bool("shows", cfg.Sync.Lists.Shows).
+		Bool("ratings", cfg.Sync.RatingsList.Enabled).
+		Bool("recently_watched", cfg.Sync.RecentlyWatched.Enabled).
+		Bool("anime", cfg.Sync.Anime.Enabled).
 		Str("log_level", cfg.Logging.Level).
 		Str("log_format", cfg.Logging.Format).
 		Msg("Loaded configuration")
+
+	if useStaging() {
+		log.Info().Str("api_url", trakt.StagingBaseURL).Msg("Using the Trakt staging environment")
+	}
+	if readOnly {
+		log.Info().Msg("Read-only mode: writes to Trakt, Plex and Jellyfin are blocked")
+	}
+}
+
+// warnSchemaProblems logs unknown keys and mistyped values in the config file
+func warnSchemaProblems() {
+	problems, err := config.CheckSchema(resolvedConfigPath())
+	if err != nil {
+		errcode.Log(log.Warn(), err).Msg("Failed to check config schema")
+		return
+	}
+	for _, problem := range problems {
+		log.Warn().Str("path", problem.Path).Str("problem", problem.Message).Msg("Config does not match schema")
+	}
+}
+
+// resolvedConfigPath returns the config file in use, falling back to the default path
+func resolvedConfigPath() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	return config.DefaultConfigPath()
 }
 
 func runAuth() error {
@@ -239,7 +435,7 @@ func runAuth() error {
 		return fmt.Errorf("config validation failed: %w", err)
 	}
 
-	client := trakt.NewClient(cfg.Trakt.ClientID, cfg.Trakt.ClientSecret, "", "")
+	client := newTraktClient("", "")
 
 	deviceResp, err := client.GetDeviceCode()
 	if err != nil {
@@ -251,88 +447,272 @@ func runAuth() error {
 	fmt.Printf("And enter this code: %s\n\n", deviceResp.UserCode)
 	fmt.Println("Waiting for authorization...")
 
-	tokenResp, err := client.PollForToken(deviceResp.DeviceCode, deviceResp.Interval, deviceResp.ExpiresIn)
+	return completeAuth(client, deviceResp.DeviceCode, deviceResp.Interval, deviceResp.ExpiresIn)
+}
+
+// runAuthJSON requests a device code, prints it as JSON and returns without
+// polling so that an external frontend can drive the rest of the flow.
+func runAuthJSON() error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+
+	client := newTraktClient("", "")
+
+	deviceResp, err := client.GetDeviceCode()
 	if err != nil {
 		return err
 	}
 
-	cfg.Trakt.AccessToken = tokenResp.AccessToken
-	cfg.Trakt.RefreshToken = tokenResp.RefreshToken
-	cfg.Trakt.TokenExpires = time.Unix(tokenResp.CreatedAt, 0).Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(deviceResp)
+}
+
+// runAuthResume polls for a token using a device code obtained earlier via
+// 'auth --json'.
+func runAuthResume(deviceCode string, interval, expiresIn int) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
 
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = config.DefaultConfigPath()
+	client := newTraktClient("", "")
+
+	log.Info().Msg("Waiting for authorization...")
+	return completeAuth(client, deviceCode, interval, expiresIn)
+}
+
+func completeAuth(client *trakt.Client, deviceCode string, interval, expiresIn int) error {
+	tokenResp, err := client.PollForToken(deviceCode, interval, expiresIn)
+	if err != nil {
+		return err
 	}
 
-	if err := config.Save(cfg, configPath); err != nil {
+	cfg.SetTokens(tokenResp.AccessToken, tokenResp.RefreshToken, tokenResp.IssuedAt(), time.Now(), tokenResp.ExpiresAt())
+	warnClockOffset()
+
+	if err := saveConfig(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
+	clearNeedsAuth()
 
 	log.Info().Msg("Authentication successful! Tokens saved to config.")
 	return nil
 }
 
-func runSync(listsFilter string) (syncpkg.SyncResult, error) {
-	if err := cfg.Validate(); err != nil {
-		return syncpkg.SyncResult{}, fmt.Errorf("config validation failed: %w", err)
+func runAuthExport(path string) error {
+	if !cfg.IsAuthenticated() {
+		return errNotAuthenticated
 	}
 
-	if !dryRun && !cfg.IsAuthenticated() {
-		return syncpkg.SyncResult{}, fmt.Errorf("not authenticated. Run 'trakt-sync auth' first")
+	if err := config.ExportTokens(cfg, path); err != nil {
+		return err
 	}
 
-	client := trakt.NewClient(
-		cfg.Trakt.ClientID,
-		cfg.Trakt.ClientSecret,
-		cfg.Trakt.AccessToken,
-		cfg.Trakt.RefreshToken,
-	)
+	log.Warn().Str("file", path).Msg("Token file contains credentials with full account access; keep it private and delete it after importing")
+	log.Info().Str("file", path).Msg("Tokens exported")
+	return nil
+}
 
-	if !dryRun {
-		client.SetTokenRefreshCallback(func(accessToken, refreshToken string, expiresAt time.Time) {
-			cfg.Trakt.AccessToken = accessToken
-			cfg.Trakt.RefreshToken = refreshToken
-			cfg.Trakt.TokenExpires = expiresAt
+func runAuthImport(path string) error {
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+		log.Warn().Str("file", path).Str("mode", info.Mode().Perm().String()).Msg("Token file is readable by other users")
+	}
 
-			configPath := cfgFile
-			if configPath == "" {
-				configPath = config.DefaultConfigPath()
-			}
+	tokens, err := config.ImportTokens(path)
+	if err != nil {
+		return err
+	}
 
-			if err := config.Save(cfg, configPath); err != nil {
-				log.Error().Err(err).Msg("Failed to save refreshed tokens")
-			}
-		})
+	if tokens.Username != "" && cfg.Trakt.Username != "" && !strings.EqualFold(tokens.Username, cfg.Trakt.Username) {
+		log.Warn().
+			Str("token_user", tokens.Username).
+			Str("config_user", cfg.Trakt.Username).
+			Msg("Imported tokens belong to a different username than the config")
+	}
 
-		if cfg.NeedsRefresh() {
-			log.Info().Msg("Access token expired, refreshing...")
-			if _, err := client.RefreshAccessToken(); err != nil {
-				return syncpkg.SyncResult{}, fmt.Errorf("failed to refresh token: %w", err)
-			}
+	tokens.Apply(cfg)
+
+	if err := saveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	clearNeedsAuth()
+
+	log.Info().Str("file", path).Msg("Tokens imported and saved to config. Consider deleting the token file.")
+	return nil
+}
+
+// setupCassette prepares the --record or --replay transport
+func setupCassette() error {
+	switch {
+	case recordPath != "" && replayPath != "":
+		return fmt.Errorf("--record and --replay cannot be used together")
+	case recordPath != "":
+		log.Warn().Str("file", recordPath).Msg("Recording API interactions; review the cassette before sharing it")
+		transport = trakt.NewRecorder(recordPath)
+	case replayPath != "":
+		replayer, err := trakt.NewReplayer(replayPath)
+		if err != nil {
+			return err
 		}
+		log.Info().Str("file", replayPath).Msg("Replaying API interactions from cassette")
+		transport = replayer
 	}
+	return nil
+}
 
-	if listsFilter != "" {
-		requestedLists := strings.Split(listsFilter, ",")
-		cfg.Sync.Lists = config.ListSyncConfig{}
-		for _, listSlug := range requestedLists {
-			listSlug = strings.TrimSpace(listSlug)
-			switch listSlug {
-			case "trakt-sync-filme":
-				cfg.Sync.Lists.Movies = true
-			case "trakt-sync-serien":
-				cfg.Sync.Lists.Shows = true
-			default:
-				log.Warn().Str("list", listSlug).Msg("Unknown list slug")
-			}
+// newTraktClient returns a client for the configured app credentials and API
+// URL, using the cassette transport when recording or replaying
+func newTraktClient(accessToken, refreshToken string) *trakt.Client {
+	client := trakt.NewClient(cfg.Trakt.ClientID, cfg.Trakt.ClientSecret, accessToken, refreshToken)
+	if useStaging() {
+		client.UseStaging()
+	}
+	if cfg.Trakt.APIURL != "" {
+		client.SetBaseURL(cfg.Trakt.APIURL)
+	}
+	if transport != nil {
+		client.SetTransport(transport)
+	}
+	client.SetAuditLog(auditLogPath())
+	client.SetReadOnly(readOnly)
+	client.SetContext(runCtx)
+	client.SetMetrics(daemonMetrics)
+	return client
+}
+
+// useStaging reports whether --staging or trakt.environment selects the
+// Trakt staging API. The flag is not written to the config file.
+func useStaging() bool {
+	return staging || cfg.Trakt.Environment == config.EnvironmentStaging
+}
+
+// tokenWriteDelay coalesces token refreshes of concurrent list syncs into a
+// single config write
+const tokenWriteDelay = 2 * time.Second
+
+// tokens persists the refreshed tokens of the client built by newClient
+var tokens *tokenstore.Store
+
+// saveTokens writes refreshed tokens to the config, or the runtime file of
+// the state directory
+func saveTokens(t tokenstore.Tokens) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg.SetTokens(t.AccessToken, t.RefreshToken, t.IssuedAt, t.ReceivedAt, t.ExpiresAt)
+	warnClockOffset()
+	return writeConfig()
+}
+
+// flushTokens writes refreshed tokens that are still waiting for the
+// debounce delay
+func flushTokens() {
+	if tokens == nil {
+		return
+	}
+	if err := tokens.Flush(); err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to save refreshed tokens")
+	}
+}
+
+// newClient builds a Trakt client from the loaded config. With persistTokens
+// set, refreshed tokens are written back to the config file and an expired
+// token is refreshed up front.
+func newClient(persistTokens bool) (*trakt.Client, error) {
+	client := newTraktClient(cfg.Trakt.AccessToken, cfg.Trakt.RefreshToken)
+
+	if !persistTokens {
+		return client, nil
+	}
+
+	tokens = tokenstore.New(tokenWriteDelay, saveTokens)
+	tokens.OnError(func(err error) {
+		errcode.Log(log.Error(), err).Msg("Failed to save refreshed tokens")
+	})
+	client.SetTokenRefreshCallback(func(accessToken, refreshToken string, issuedAt, expiresAt time.Time) {
+		err := tokens.Update(tokenstore.Tokens{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			IssuedAt:     issuedAt,
+			ReceivedAt:   time.Now(),
+			ExpiresAt:    expiresAt,
+		})
+		if err != nil {
+			errcode.Log(log.Error(), err).Msg("Failed to save refreshed tokens")
+		}
+	})
+
+	if cfg.NeedsRefresh() {
+		log.Info().Msg("Access token expired, refreshing...")
+		if _, err := client.RefreshAccessToken(); err != nil {
+			return nil, fmt.Errorf("failed to refresh token: %w", err)
 		}
+		// The old refresh token is spent, so do not wait for the debounce.
+		flushTokens()
+	}
+
+	return client, nil
+}
+
+// newAuthenticatedClient validates the config and returns a client for
+// commands that always need a logged-in user
+func newAuthenticatedClient() (*trakt.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if !cfg.IsAuthenticated() {
+		return nil, errNotAuthenticated
+	}
+
+	return newClient(true)
+}
+
+func runSync(listsFilter string) (syncpkg.SyncResult, error) {
+	return runSyncUntil(stopCtx, listsFilter)
+}
+
+// runSyncUntil syncs the lists, or those in listsFilter, and ends the run
+// after the list at hand once stop is done
+func runSyncUntil(stop context.Context, listsFilter string) (result syncpkg.SyncResult, err error) {
+	// Runs that end early notify as well: a user syncing by hand needs to
+	// see a rejected config or missing tokens most of all.
+	defer func() { notifySyncResult(result, err) }()
+
+	if err := cfg.Validate(); err != nil {
+		return syncpkg.SyncResult{}, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if !dryRun && !cfg.IsAuthenticated() {
+		return syncpkg.SyncResult{}, errNotAuthenticated
+	}
+
+	client, err := newClient(!dryRun)
+	if err != nil {
+		return syncpkg.SyncResult{}, err
 	}
 
 	syncer := syncpkg.NewSyncer(client, cfg)
+	syncer.SetContext(runCtx)
+	syncer.SetStopContext(stop)
+
+	st, err := loadState()
+	if err != nil {
+		return syncpkg.SyncResult{}, err
+	}
+	syncer.SetState(st)
+
+	if err := syncer.SetSuffix(syncSuffix); err != nil {
+		return syncpkg.SyncResult{}, err
+	}
+
+	if listsFilter != "" {
+		applyListFilter(syncer, splitListSlugs(listsFilter))
+	}
 
 	if dryRun {
-		log.Info().Msg("DRY RUN: No API calls will be made")
+		log.Info().Msg("DRY RUN: Trakt lists are read but not written")
 		result := syncpkg.SyncResult{}
 		for _, listDef := range syncer.GetListDefinitions() {
 			if !listDef.Enabled {
@@ -342,80 +722,358 @@ func runSync(listsFilter string) (syncpkg.SyncResult, error) {
 			result.Successful++
 			log.Info().Str("list", listDef.Slug).Int("limit", cfg.Sync.Limit).Msg("DRY RUN: would sync list")
 		}
+		// With --dry-run=writes, Jellyfin gets the lists a sync would write.
+		if cfg.Targets.Jellyfin.Enabled && !dryRunIntegrations && syncSuffix == "" {
+			previews, err := syncer.PreviewLists()
+			if err != nil {
+				return result, err
+			}
+			if syncJellyfin(previews, st) {
+				if saveErr := saveState(st); saveErr != nil {
+					errcode.Log(log.Warn(), saveErr).Str("store", stateLocation()).Msg("Failed to save state")
+				}
+			}
+		}
 		return result, nil
 	}
 
-	result, err := syncer.SyncAll()
+	started := time.Now()
+	syncing.Store(true)
+	result, err = syncer.SyncAll()
+	syncing.Store(false)
+	writeCrashReports(result)
+	// A stopped run only saves what it did.
+	canceled := errors.Is(err, syncpkg.ErrCanceled)
+
+	if cfg.Archive.Enabled {
+		if archiveErr := archive.WriteRun(archiveDir(), started, syncer.Snapshots()); archiveErr != nil {
+			errcode.Log(log.Warn(), archiveErr).Msg("Failed to archive chart responses")
+		}
+	}
+
+	// The watchlist has no sandbox copy, so leave it alone in sandbox runs.
+	if cfg.Watchlist.PruneAfterDays > 0 && syncSuffix == "" && !canceled {
+		if _, pruneErr := syncer.PruneWatchlist(cfg.Watchlist.DryRun); pruneErr != nil {
+			errcode.Log(log.Error(), pruneErr).Msg("Watchlist pruning failed")
+		}
+	}
+	if cfg.Watchlist.RemoveWatched && syncSuffix == "" && !canceled {
+		if _, cleanErr := syncer.RemoveWatchedFromWatchlist(cfg.Watchlist.DryRun); cleanErr != nil {
+			errcode.Log(log.Error(), cleanErr).Msg("Watchlist cleanup failed")
+		}
+	}
+
+	// Collections have no sandbox copy either.
+	stateDirty := syncer.StateDirty()
+	if cfg.Targets.Jellyfin.Enabled && syncSuffix == "" && !canceled {
+		if syncJellyfin(syncer.Synced(), st) {
+			stateDirty = true
+		}
+	}
 
 	if !dryRun && syncer.ConfigDirty() {
-		configPath := cfgFile
-		if configPath == "" {
-			configPath = config.DefaultConfigPath()
+		if saveErr := saveConfig(); saveErr != nil {
+			errcode.Log(log.Warn(), saveErr).Msg("Failed to save sync state (next sync may trigger full refresh)")
 		}
+	}
 
-		if saveErr := config.Save(cfg, configPath); saveErr != nil {
-			log.Warn().Err(saveErr).Msg("Failed to save sync state (next sync may trigger full refresh)")
+	if stateDirty {
+		if saveErr := saveState(st); saveErr != nil {
+			errcode.Log(log.Warn(), saveErr).Str("store", stateLocation()).Msg("Failed to save state")
 		}
 	}
+	flushTokens()
 
 	return result, err
 }
 
-func runDaemon(interval time.Duration) error {
+// splitListSlugs parses the comma-separated --lists flag
+func splitListSlugs(listsFilter string) []string {
+	var slugs []string
+	for _, listSlug := range strings.Split(listsFilter, ",") {
+		if listSlug = strings.TrimSpace(listSlug); listSlug != "" {
+			slugs = append(slugs, listSlug)
+		}
+	}
+	return slugs
+}
+
+// applyListFilter restricts the syncer to slugs, warning about unknown ones
+func applyListFilter(syncer *syncpkg.Syncer, slugs []string) {
+	for _, listSlug := range syncer.SetListFilter(slugs) {
+		log.Warn().Str("list", listSlug).Msg("Unknown list slug")
+	}
+}
+
+func runDaemon(flagInterval time.Duration, flagSet bool) error {
 	if !dryRun && !cfg.IsAuthenticated() {
-		return fmt.Errorf("not authenticated. Run 'trakt-sync auth' first")
+		return errNotAuthenticated
 	}
 
+	interval := daemonInterval(flagInterval, flagSet)
 	log.Info().Dur("interval", interval).Msg("Starting daemon mode")
 
-	// Set up graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
+	// The first SIGINT or SIGTERM lets a running sync finish the list at
+	// hand, saves the state and stops the daemon; a second one aborts the list
+	ctx, cancel := context.WithCancel(stopCtx)
 	defer cancel()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	configPath := resolvedConfigPath()
+	// Edits saved from here on are reloaded, never overwritten.
+	rememberConfigHash(configPath)
+	// The watcher follows daemon.watch_config, so a reload can turn it on
+	// or off.
+	var configChanges <-chan struct{}
+	var stopWatching context.CancelFunc
+	watchConfig := func(enabled bool) {
+		if enabled == (stopWatching != nil) {
+			return
+		}
+		if !enabled {
+			stopWatching()
+			stopWatching, configChanges = nil, nil
+			log.Info().Msg("Stopped watching config file")
+			return
+		}
+		watchCtx, stop := context.WithCancel(ctx)
+		changes, err := watchConfigFile(watchCtx, configPath)
+		if err != nil {
+			stop()
+			errcode.Log(log.Warn(), err).Msg("Failed to watch config file, use SIGHUP to reload")
+			return
+		}
+		log.Info().Str("file", configPath).Msg("Watching config file for changes")
+		stopWatching, configChanges = stop, changes
+	}
+	watchConfig(cfg.Daemon.WatchConfig)
 
-	go func() {
-		sig := <-sigChan
-		log.Info().Str("signal", sig.String()).Msg("Received shutdown signal, stopping gracefully...")
-		cancel()
-	}()
+	if cfg.Daemon.MetricsAddress != "" {
+		if daemonMetrics == nil {
+			daemonMetrics = metrics.New()
+		}
+		if err := serveMetrics(ctx, cfg.Daemon.MetricsAddress, daemonMetrics); err != nil {
+			return err
+		}
+	}
+
+	elector, stopElection, err := startLeaderElection(ctx)
+	if err != nil {
+		return err
+	}
+	defer stopElection()
+	// Standby replicas keep the schedule but leave syncing to the leader. A
+	// replica that took over the lease picks up the leader's tokens first.
+	term := 0
+	leading := func() bool {
+		if elector == nil {
+			return true
+		}
+		if !elector.IsLeader() {
+			log.Info().Msg("Standby replica, skipping sync")
+			return false
+		}
+		if next := elector.Term(); next != term {
+			term = next
+			adoptSharedTokens()
+		}
+		return true
+	}
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// retry fires early after a run skipped for a Trakt outage or one that
+	// queued writes while the network was down, and at the end of quiet hours
+	var retry <-chan time.Time
+	// interrupted is set when a second signal aborted a list
+	interrupted := false
+	auth := newReauth(configPath)
+	syncOnce := func(failedMsg string) {
+		if auth.blocked() {
+			return
+		}
+		now := time.Now()
+		if until, quiet := cfg.Daemon.QuietUntil(now); quiet {
+			log.Info().Time("until", until).Msgf("Quiet hours, deferring sync until %s", until.Format("15:04"))
+			retry = time.After(until.Sub(now))
+			return
+		}
+		if reason := powerSkipReason(); reason != "" {
+			log.Info().Msgf("%s, skipping sync", reason)
+			if delay := maintenanceRetry(); delay < interval {
+				retry = time.After(delay)
+			}
+			return
+		}
+		// A sync still running when quiet hours begin stops after the list
+		// at hand, like one reaching daemon.max_runtime.
+		maxRuntime, quietStop := cfg.Daemon.MaxRuntime, false
+		if next := cfg.Daemon.NextQuietHours(now); !next.IsZero() && (maxRuntime == 0 || next.Sub(now) < maxRuntime) {
+			maxRuntime, quietStop = next.Sub(now), true
+		}
+		result, err := runSyncRecovered("", maxRuntime)
+		if errors.Is(err, syncpkg.ErrCanceled) {
+			daemonMetrics.RecordSync(metrics.Run{
+				Finished:   time.Now(),
+				Duration:   result.Duration,
+				Successful: result.Successful,
+				Failed:     result.Failed,
+				Stopped:    true,
+			})
+			interrupted = runCtx.Err() != nil
+			switch {
+			case ctx.Err() != nil:
+			case quietStop:
+				until, _ := cfg.Daemon.QuietUntil(time.Now())
+				log.Warn().Int("synced", result.Successful).Time("until", until).
+					Msg("Quiet hours began, the remaining lists are synced when they end")
+				retry = time.After(time.Until(until))
+			default:
+				log.Warn().Dur("max_runtime", cfg.Daemon.MaxRuntime).Int("synced", result.Successful).
+					Msg("Sync reached daemon.max_runtime, the remaining lists are synced in the next run")
+			}
+			return
+		}
+		daemonMetrics.RecordSync(metrics.Run{
+			Finished:   time.Now(),
+			Duration:   result.Duration,
+			Successful: result.Successful,
+			Failed:     result.Failed,
+			Err:        err,
+		})
+		auth.afterRun(result, err)
+		switch {
+		case errors.Is(err, syncpkg.ErrUnavailable):
+			if delay := maintenanceRetry(); delay < interval {
+				log.Info().Dur("retry_in", delay).Msg("Trakt is unavailable, retrying before the next scheduled sync")
+				retry = time.After(delay)
+			}
+			return
+		case err != nil:
+			errcode.Log(log.Error(), err).Msg(failedMsg)
+		}
+		if result.Pending > 0 {
+			if delay := maintenanceRetry(); delay < interval {
+				log.Info().Dur("retry_in", delay).Int("lists", result.Pending).Msg("List changes are queued, retrying before the next scheduled sync")
+				retry = time.After(delay)
+			}
+		}
+	}
+
 	// Initial sync
-	if _, err := runSync(""); err != nil {
-		log.Error().Err(err).Msg("Initial sync failed")
+	if leading() {
+		syncOnce("Initial sync failed")
+	}
+
+	reload := func() {
+		if !reloadConfig(configPath) {
+			return
+		}
+		watchConfig(cfg.Daemon.WatchConfig)
+		if next := daemonInterval(flagInterval, flagSet); next != interval {
+			interval = next
+			ticker.Reset(interval)
+			log.Info().Dur("interval", interval).Msg("Sync interval changed")
+		}
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
+			if interrupted {
+				log.Info().Msg("Daemon aborted")
+				return syncpkg.ErrCanceled
+			}
 			log.Info().Msg("Daemon stopped gracefully")
 			return nil
+		case <-hupChan:
+			log.Info().Msg("Received SIGHUP, reloading config")
+			reload()
+		case <-configChanges:
+			if !configEdited(configPath) {
+				continue
+			}
+			log.Info().Msg("Config file changed, reloading")
+			reload()
 		case <-ticker.C:
-			if _, err := runSync(""); err != nil {
-				log.Error().Err(err).Msg("Sync failed")
+			retry = nil
+			if !leading() {
+				continue
 			}
+			syncOnce("Sync failed")
+		case <-retry:
+			retry = nil
+			if !leading() {
+				continue
+			}
+			syncOnce("Sync failed")
+		case <-syncRequests:
+			retry = nil
+			if !leading() {
+				continue
+			}
+			syncOnce("Sync failed")
+		case token := <-auth.done:
+			if !auth.complete(token) || !leading() {
+				continue
+			}
+			syncOnce("Sync failed")
 		}
 	}
 }
 
-func runStatus() {
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = config.DefaultConfigPath()
+// powerSkipReason returns why daemon.skip_on_battery or
+// daemon.skip_on_metered skip the next sync, or "". A failed check never
+// skips a sync.
+func powerSkipReason() string {
+	if cfg.Daemon.SkipOnBattery {
+		onBattery, err := power.OnBattery()
+		if err != nil {
+			errcode.Log(log.Debug(), err).Msg("Failed to check the power supply")
+		} else if onBattery {
+			return "Running on battery"
+		}
 	}
+	if cfg.Daemon.SkipOnMetered {
+		metered, err := power.Metered()
+		if err != nil {
+			errcode.Log(log.Debug(), err).Msg("Failed to check for a metered connection")
+		} else if metered {
+			return "On a metered connection"
+		}
+	}
+	return ""
+}
+
+func runStatus() {
+	configPath := resolvedConfigPath()
 
 	fmt.Println("Trakt Sync Status")
 	fmt.Println("=================")
 	fmt.Printf("Config file: %s\n", configPath)
+	if dir := resolvedStateDir(); dir != "" {
+		fmt.Printf("State directory: %s\n", dir)
+	}
 	fmt.Printf("Username: %s\n", cfg.Trakt.Username)
+	if useStaging() {
+		fmt.Printf("Environment: staging (%s)\n", trakt.StagingBaseURL)
+	}
 	fmt.Printf("Authenticated: %v\n", cfg.IsAuthenticated())
+	if pending, err := readNeedsAuth(); err == nil && pending != nil {
+		fmt.Printf("Needs re-authentication: YES, since %s (%s)\n", pending.Since.Format(time.RFC3339), pending.Reason)
+		if pending.UserCode != "" && time.Now().Before(pending.CodeExpiresAt) {
+			fmt.Printf("  Visit %s and enter code %s (expires %s)\n", pending.VerificationURL, pending.UserCode, pending.CodeExpiresAt.Format(time.RFC3339))
+		}
+	}
 
 	if cfg.IsAuthenticated() {
 		fmt.Printf("Token expires: %s\n", cfg.Trakt.TokenExpires.Format(time.RFC3339))
+		if cfg.Trakt.ClockOffset != 0 {
+			fmt.Printf("Clock offset: local clock %s\n", describeOffset(cfg.Trakt.ClockOffset))
+		}
 		if cfg.NeedsRefresh() {
 			fmt.Println("Token needs refresh: YES")
 		} else {
@@ -423,69 +1081,81 @@ func runStatus() {
 		}
 	}
 
-	fmt.Println("\nEnabled Lists:")
-	if cfg.Sync.Lists.Movies {
-		fmt.Println("  - trakt-sync-filme")
+	syncer := syncpkg.NewSyncer(nil, cfg)
+	if st, err := loadState(); err == nil {
+		syncer.SetState(st)
 	}
-	if cfg.Sync.Lists.Shows {
-		fmt.Println("  - trakt-sync-serien")
+
+	fmt.Println("\nEnabled Lists:")
+	for _, listDef := range syncer.GetListDefinitions() {
+		if listDef.Enabled {
+			fmt.Printf("  - %s\n", listDef.Slug)
+		}
 	}
 
 	fmt.Printf("\nSync limit: %d items per source\n", cfg.Sync.Limit)
 	fmt.Printf("Min rating: %d%%\n", cfg.Sync.MinRating)
 	fmt.Printf("List privacy: %s\n", cfg.Sync.ListPrivacy)
 	fmt.Printf("Full refresh: every %d days\n", cfg.Sync.FullRefreshDays)
-}
 
-func runInstallService(path, user string, interval time.Duration) error {
-	if strings.TrimSpace(user) == "" {
-		return fmt.Errorf("service user must not be empty")
+	if cfg.Telemetry.Enabled {
+		fmt.Printf("\nTelemetry: enabled, reporting command, outcome, version and OS to %s\n", cfg.Telemetry.Endpoint)
+	} else {
+		fmt.Println("\nTelemetry: disabled")
 	}
-	if strings.TrimSpace(path) == "" {
-		return fmt.Errorf("service path must not be empty")
+
+	if cfg.Updates.ManifestURL != "" {
+		printNotices(os.Stdout, configPath)
 	}
+}
 
-	// Validate path to prevent directory traversal
-	if !filepath.IsAbs(path) {
-		return fmt.Errorf("service path must be absolute")
+// printNotices fetches the release manifest and prints the warnings that
+// apply to this version and config
+func printNotices(w io.Writer, configPath string) {
+	warnings, err := fetchNotices(configPath)
+	if err != nil {
+		fmt.Fprintf(w, "\nNotices: unavailable (%v)\n", err)
+		return
 	}
-	if strings.Contains(path, "..") {
-		return fmt.Errorf("service path must not contain '..'")
+	if len(warnings) == 0 {
+		fmt.Fprintln(w, "\nNotices: none")
+		return
 	}
+	fmt.Fprintln(w, "\nNotices:")
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  ! %s\n", warning)
+	}
+}
 
-	serviceFile := fmt.Sprintf(`[Unit]
-Description=Trakt List Sync Service
-After=network-online.target
-Wants=network-online.target
-
-[Service]
-Type=simple
-User=%s
-ExecStart=/usr/local/bin/trakt-sync daemon --interval %s
-Restart=on-failure
-RestartSec=30
-
-[Install]
-WantedBy=multi-user.target
-`, user, interval.String())
-
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("failed to create service directory: %w", err)
+// fetchNotices returns the warnings of the release manifest that apply to
+// this version and config
+func fetchNotices(configPath string) ([]string, error) {
+	m, err := manifest.Fetch(cfg.Updates.ManifestURL)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := os.WriteFile(path, []byte(serviceFile), 0644); err != nil {
-		return fmt.Errorf("failed to write service file: %w", err)
+	keys, err := config.FileKeys(configPath)
+	if err != nil {
+		errcode.Log(log.Debug(), err).Msg("Failed to read config keys for deprecation notices")
 	}
 
-	log.Info().Str("path", path).Msg("Systemd service installed")
-	return nil
+	channel := cfg.Updates.Channel
+	if channel == "" {
+		channel = "stable"
+	}
+	return m.Warnings(channel, Version, keys), nil
 }
 
 func syncExitCode(result syncpkg.SyncResult, err error) int {
+	// Exit code 130: canceled by SIGINT or SIGTERM
 	// Exit code 2: all lists failed or critical error
 	// Exit code 1: partial failure (some lists synced)
 	// Exit code 0: success
 	if err != nil {
+		if errors.Is(err, syncpkg.ErrCanceled) {
+			return 130 // Interrupted, like a shell reports Ctrl+C
+		}
 		if errors.Is(err, syncpkg.ErrAllFailed) {
 			return 2 // All lists failed
 		}
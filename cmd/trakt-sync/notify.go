@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/notify"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+)
+
+// showNotification shows a desktop notification; tests replace it
+var showNotification = notify.Desktop
+
+// notifyDesktop shows a notification if notifications.desktop is enabled,
+// or logs it with --dry-run. Failures are only logged.
+func notifyDesktop(title, message string) {
+	if !cfg.Notifications.Desktop.Enabled {
+		return
+	}
+	if dryRunNotifications {
+		log.Info().Str("title", title).Str("message", message).Msg("DRY RUN: would show desktop notification")
+		return
+	}
+	if err := showNotification(title, message); err != nil {
+		errcode.Log(log.Warn(), err).Msg("Failed to show desktop notification")
+	}
+}
+
+// notifySyncResult notifies of a failed sync, or of any sync with
+// notifications.desktop.on_success. Stopped runs and runs skipped during a
+// Trakt outage are left out.
+func notifySyncResult(result syncpkg.SyncResult, err error) {
+	switch {
+	case errors.Is(err, syncpkg.ErrCanceled), errors.Is(err, syncpkg.ErrUnavailable):
+	case err != nil:
+		notifyDesktop("trakt-sync: sync failed", err.Error())
+	case result.Failed > 0:
+		var lines []string
+		for _, failure := range result.Failures {
+			lines = append(lines, failure.List+": "+failure.Err.Error())
+		}
+		notifyDesktop(fmt.Sprintf("trakt-sync: %d of %d lists failed", result.Failed, result.Total), strings.Join(lines, "\n"))
+	case cfg.Notifications.Desktop.OnSuccess:
+		notifyDesktop("trakt-sync: sync finished", fmt.Sprintf("Synced %d lists in %s", result.Successful, result.Duration.Round(time.Second)))
+	}
+}
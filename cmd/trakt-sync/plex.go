@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/plex"
+	"github.com/maximilian/trakt-sync/internal/state"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+)
+
+// Sync targets of sync --target
+const (
+	targetTrakt = "trakt"
+	targetPlex  = "plex"
+)
+
+var syncTarget string
+
+// runPlexSync resolves the enabled lists like a sync would and puts their
+// titles on the Plex watchlist instead of writing Trakt lists. The lists
+// come from --lists, plex.lists or all enabled lists.
+func runPlexSync(listsFilter string) (syncpkg.SyncResult, error) {
+	startTime := time.Now()
+	if err := cfg.Validate(); err != nil {
+		return syncpkg.SyncResult{}, fmt.Errorf("config validation failed: %w", err)
+	}
+	if cfg.Plex.Token == "" {
+		return syncpkg.SyncResult{}, fmt.Errorf("plex.token is required for --target plex")
+	}
+	if !dryRun && !cfg.IsAuthenticated() {
+		return syncpkg.SyncResult{}, errNotAuthenticated
+	}
+
+	client, err := newClient(!dryRun)
+	if err != nil {
+		return syncpkg.SyncResult{}, err
+	}
+	syncer := syncpkg.NewSyncer(client, cfg)
+
+	st, err := loadState()
+	if err != nil {
+		return syncpkg.SyncResult{}, err
+	}
+	syncer.SetState(st)
+
+	if listsFilter != "" {
+		applyListFilter(syncer, splitListSlugs(listsFilter))
+	} else if len(cfg.Plex.Lists) > 0 {
+		applyListFilter(syncer, cfg.Plex.Lists)
+	}
+
+	result := syncpkg.SyncResult{Total: 1}
+	resolved, err := syncer.ResolveLists()
+	if err != nil {
+		result.Failed++
+		return result, err
+	}
+
+	plexClient := plex.NewClient(cfg.Plex.Token)
+	plexClient.SetReadOnly(readOnly)
+	plexClient.SetContext(runCtx)
+	if cfg.Plex.APIURL != "" {
+		plexClient.SetBaseURL(cfg.Plex.APIURL)
+	}
+	if st.Plex == nil {
+		st.Plex = &state.PlexState{}
+	}
+
+	changes, err := plex.SyncWatchlist(plexClient, plexTitles(resolved), st.Plex, dryRunIntegrations)
+	if !dryRunIntegrations {
+		if saveErr := saveState(st); saveErr != nil {
+			errcode.Log(log.Warn(), saveErr).Str("store", stateLocation()).Msg("Failed to save state")
+		}
+	}
+	flushTokens()
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		result.Failed++
+		return result, fmt.Errorf("failed to sync Plex watchlist: %w", err)
+	}
+	result.Successful++
+
+	log.Info().
+		Int("added", changes.Added).
+		Int("removed", changes.Removed).
+		Int("unmatched", changes.Unmatched).
+		Dur("duration", result.Duration).
+		Msg("Plex watchlist sync complete")
+	return result, nil
+}
+
+// plexTitles flattens resolved lists into the titles for the watchlist
+func plexTitles(lists []syncpkg.ResolvedList) []plex.Title {
+	var titles []plex.Title
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		mediaType := plex.TypeShow
+		if list.IsMovie {
+			mediaType = plex.TypeMovie
+		}
+		for _, item := range list.Items {
+			key := fmt.Sprintf("%s:%d", mediaType, item.IDs.Trakt)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			titles = append(titles, plex.Title{TraktID: item.IDs.Trakt, Title: item.Title, Year: item.Year, Type: mediaType})
+		}
+	}
+	return titles
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// needsAuthFileName is the file in the data directory that records that
+// Trakt rejected the tokens for good
+const needsAuthFileName = "needs-auth.json"
+
+// needsAuth is the daemon's "needs auth" state: Trakt rejected the tokens and
+// a refresh did not help, usually because the app was revoked on trakt.tv
+type needsAuth struct {
+	Since  time.Time `json:"since"`
+	Reason string    `json:"reason"`
+	// UserCode and VerificationURL are set while a device authorization
+	// started for daemon.reauth is waiting for the user
+	UserCode        string    `json:"user_code,omitempty"`
+	VerificationURL string    `json:"verification_url,omitempty"`
+	CodeExpiresAt   time.Time `json:"code_expires_at,omitempty"`
+}
+
+func needsAuthPath() string {
+	return filepath.Join(dataDir(), needsAuthFileName)
+}
+
+// readNeedsAuth returns the recorded "needs auth" state, or nil if the
+// tokens were not rejected
+func readNeedsAuth() (*needsAuth, error) {
+	data, err := os.ReadFile(needsAuthPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state needsAuth
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", needsAuthFileName, err)
+	}
+	return &state, nil
+}
+
+// writeNeedsAuth records state so status, doctor and other processes see it.
+// Failures are only logged.
+func writeNeedsAuth(state *needsAuth) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(dataDir(), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(needsAuthPath(), append(data, '\n'), 0o600)
+	}
+	if err != nil {
+		errcode.Log(log.Warn(), err).Msg("Failed to record that Trakt needs re-authentication")
+	}
+}
+
+func clearNeedsAuth() {
+	if err := os.Remove(needsAuthPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		errcode.Log(log.Warn(), err).Msg("Failed to clear the re-authentication marker")
+	}
+}
+
+// tokensRejected reports whether a run failed because Trakt refused the
+// tokens, for the run as a whole or for any list
+func tokensRejected(result syncpkg.SyncResult, err error) bool {
+	if errcode.Of(err) == errcode.TokensRejected {
+		return true
+	}
+	for _, failure := range result.Failures {
+		if errcode.Of(failure.Err) == errcode.TokensRejected {
+			return true
+		}
+	}
+	return false
+}
+
+// tokensRevoked tells a revoked authorization from an access token that was
+// merely rejected early: it refreshes the tokens and reports whether Trakt
+// refused the refresh as well
+func tokensRevoked() bool {
+	expired := cfg.NeedsRefresh()
+	client, err := newClient(true)
+	if err == nil && !expired {
+		_, err = client.RefreshAccessToken()
+		flushTokens()
+	}
+	if err != nil && errcode.Of(err) != errcode.TokensRejected {
+		errcode.Log(log.Warn(), err).Msg("Failed to refresh the rejected tokens")
+	}
+	return errcode.Of(err) == errcode.TokensRejected
+}
+
+// reauth keeps the daemon from syncing with revoked tokens until the user
+// authorizes again, either with 'trakt-sync auth' or, with daemon.reauth,
+// through a device authorization the daemon starts itself
+type reauth struct {
+	configPath string
+	state      *needsAuth
+	// rejected is the refresh token Trakt refused
+	rejected string
+	polling  bool
+	// done receives the tokens of the device authorization, or nil if it
+	// failed or expired
+	done chan *trakt.TokenResponse
+}
+
+func newReauth(configPath string) *reauth {
+	return &reauth{configPath: configPath, done: make(chan *trakt.TokenResponse, 1)}
+}
+
+// afterRun enters the "needs auth" state if the run failed on revoked tokens,
+// and otherwise clears a state left by an earlier daemon
+func (r *reauth) afterRun(result syncpkg.SyncResult, err error) {
+	if !tokensRejected(result, err) || !tokensRevoked() {
+		if r.state == nil {
+			clearNeedsAuth()
+		}
+		return
+	}
+
+	reason := "Trakt rejected the tokens"
+	if err != nil {
+		reason = err.Error()
+	} else if len(result.Failures) > 0 {
+		reason = result.Failures[0].Err.Error()
+	}
+	r.state = &needsAuth{Since: time.Now().UTC(), Reason: reason}
+	r.rejected = cfg.Trakt.RefreshToken
+	writeNeedsAuth(r.state)
+	log.Error().
+		Str("code", string(errcode.TokensRejected)).
+		Msg("Trakt rejected the tokens, syncing is paused until you run 'trakt-sync auth'")
+	if !r.prompt() {
+		notifyDesktop(needsAuthTitle, "Syncing is paused until you run 'trakt-sync auth'.")
+	}
+}
+
+// blocked reports whether the next run has to wait for a new authorization.
+// Tokens written by 'trakt-sync auth' in the meantime end the wait.
+func (r *reauth) blocked() bool {
+	if r.state == nil {
+		return false
+	}
+	if r.reloadTokens() {
+		log.Info().Msg("Found new tokens, resuming sync")
+		r.resolve()
+		return false
+	}
+	log.Warn().Time("since", r.state.Since).Msg("Trakt needs re-authentication, skipping sync")
+	r.prompt()
+	return true
+}
+
+// reloadTokens reads the tokens from the config file, or the runtime file
+// of the state directory, and adopts them unless they are the rejected ones.
+// A config reload may have picked them up already.
+func (r *reauth) reloadTokens() bool {
+	fresh, err := config.LoadExisting(r.configPath)
+	if err == nil {
+		err = applyRuntime(fresh)
+	}
+	if err != nil {
+		errcode.Log(log.Debug(), err).Msg("Failed to reload tokens")
+		return false
+	}
+	if fresh.Trakt.RefreshToken == "" || fresh.Trakt.RefreshToken == r.rejected {
+		return false
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	cfg.Trakt.AccessToken = fresh.Trakt.AccessToken
+	cfg.Trakt.RefreshToken = fresh.Trakt.RefreshToken
+	cfg.Trakt.TokenExpires = fresh.Trakt.TokenExpires
+	cfg.Trakt.ClockOffset = fresh.Trakt.ClockOffset
+	return true
+}
+
+// needsAuthTitle is the title of the notification that syncing is paused
+const needsAuthTitle = "trakt-sync: Trakt needs authorization"
+
+// prompt starts a device authorization for daemon.reauth, or in tray mode,
+// unless one is waiting for the user already. The code is logged, recorded
+// for status and sent as a desktop notification. prompt reports whether a
+// device authorization waits for the user.
+func (r *reauth) prompt() bool {
+	if !(cfg.Daemon.Reauth || trayMode) {
+		return false
+	}
+	if r.polling {
+		return true
+	}
+
+	client := newTraktClient("", "")
+	device, err := client.GetDeviceCode()
+	if err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to start re-authentication")
+		return false
+	}
+
+	r.state.UserCode = device.UserCode
+	r.state.VerificationURL = device.VerificationURL
+	r.state.CodeExpiresAt = time.Now().Add(time.Duration(device.ExpiresIn) * time.Second).UTC()
+	writeNeedsAuth(r.state)
+	log.Warn().
+		Str("url", device.VerificationURL).
+		Str("user_code", device.UserCode).
+		Time("expires_at", r.state.CodeExpiresAt).
+		Msgf("Trakt needs re-authentication: visit %s and enter code %s", device.VerificationURL, device.UserCode)
+	message := fmt.Sprintf("Syncing is paused: visit %s and enter code %s.", device.VerificationURL, device.UserCode)
+	if trayMode {
+		message += " Authorize in the tray menu opens the page."
+	}
+	notifyDesktop(needsAuthTitle, message)
+
+	r.polling = true
+	go func() {
+		token, err := client.PollForToken(device.DeviceCode, device.Interval, device.ExpiresIn)
+		if err != nil {
+			errcode.Log(log.Warn(), err).Msg("Re-authentication did not complete")
+			token = nil
+		}
+		r.done <- token
+	}()
+	return true
+}
+
+// complete handles the end of a device authorization started by prompt. It
+// reports whether new tokens were saved, so the daemon can sync right away.
+func (r *reauth) complete(token *trakt.TokenResponse) bool {
+	r.polling = false
+	if token == nil || r.state == nil {
+		return false
+	}
+
+	cfg.SetTokens(token.AccessToken, token.RefreshToken, token.IssuedAt(), time.Now(), token.ExpiresAt())
+	if err := saveConfig(); err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to save the new tokens")
+	}
+	log.Info().Msg("Re-authentication successful, resuming sync")
+	r.resolve()
+	return true
+}
+
+// resolve leaves the "needs auth" state
+func (r *reauth) resolve() {
+	r.state = nil
+	clearNeedsAuth()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/metrics"
+	"github.com/maximilian/trakt-sync/internal/serve"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// defaultServeCacheTTL applies when serve.cache_ttl is not set
+const defaultServeCacheTTL = time.Hour
+
+var serveAddress string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the lists as Radarr and Sonarr import list feeds",
+	Long: `Runs an HTTP server that exposes the items of the enabled lists as a
+StevenLu-style custom list for Radarr at /radarr and a custom import list for
+Sonarr at /sonarr (all movie or show lists, or one with ?list=<slug>). Lists
+are resolved like a sync would, without writing Trakt lists, and cached for
+serve.cache_ttl.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		address := cfg.Serve.Address
+		if cmd.Flags().Changed("address") {
+			address = serveAddress
+		}
+		if err := runServe(address); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Server failed")
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddress, "address", ":7979", "listen address (overrides serve.address)")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(address string) error {
+	if _, err := newAuthenticatedClient(); err != nil {
+		return err
+	}
+
+	ttl := cfg.Serve.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultServeCacheTTL
+	}
+	server := &http.Server{
+		Addr:              address,
+		Handler:           serve.NewHandler(resolveFeedLists, ttl),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-runCtx.Done()
+		log.Info().Msg("Stopping server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Info().Str("address", address).Dur("cache_ttl", ttl).Msg("Serving list feeds")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// daemonMetrics collects the metrics of daemon.metrics_address; nil
+// otherwise, which records nothing
+var daemonMetrics *metrics.Metrics
+
+// serveMetrics serves /healthz and /metrics of m on address until ctx ends
+func serveMetrics(ctx context.Context, address string, m *metrics.Metrics) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on daemon.metrics_address: %w", err)
+	}
+	server := &http.Server{
+		Handler:           m.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errcode.Log(log.Error(), err).Msg("Metrics server failed")
+		}
+	}()
+
+	log.Info().Str("address", listener.Addr().String()).Msg("Serving /healthz and /metrics")
+	return nil
+}
+
+// resolveFeedLists resolves the enabled lists for the feeds
+func resolveFeedLists() ([]syncpkg.ResolvedList, error) {
+	client, err := newClient(true)
+	if err != nil {
+		return nil, err
+	}
+	st, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	syncer := syncpkg.NewSyncer(client, cfg)
+	syncer.SetState(st)
+	defer flushTokens()
+	return syncer.ResolveLists()
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// defaultServiceStateDir holds tokens, state and the audit log of the
+// service when no --state-dir is given
+const defaultServiceStateDir = "/var/lib/trakt-sync"
+
+// Init systems install-service can generate a service for
+const (
+	platformSystemd = "systemd"
+	platformOpenRC  = "openrc"
+	platformSysV    = "sysv"
+	// platformSynology prints a script for the DSM Task Scheduler
+	platformSynology = "synology"
+)
+
+// Synology defaults on the first volume, which survives DSM updates unlike
+// the root filesystem
+const (
+	synologyConfigPath = "/volume1/trakt-sync/config.yaml"
+	synologyStateDir   = "/volume1/trakt-sync/state"
+)
+
+// defaultServicePaths are the service file locations per platform
+var defaultServicePaths = map[string]string{
+	platformSystemd:  "/etc/systemd/system/trakt-sync.service",
+	platformOpenRC:   "/etc/init.d/trakt-sync",
+	platformSysV:     "/etc/init.d/trakt-sync",
+	platformSynology: "/volume1/trakt-sync/trakt-sync-task.sh",
+}
+
+// serviceOptions describes the systemd unit written by install-service
+type serviceOptions struct {
+	// Platform is the init system: systemd, openrc, sysv or synology
+	Platform string
+	Path     string
+	User     string
+	Interval time.Duration
+	// ConfigPath and StateDir are embedded in ExecStart
+	ConfigPath string
+	StateDir   string
+	// DynamicUser lets systemd allocate the service user
+	DynamicUser bool
+	Enable      bool
+	Start       bool
+	DryRun      bool
+}
+
+var serviceOpts = serviceOptions{}
+
+var installServiceCmd = &cobra.Command{
+	Use:   "install-service",
+	Short: "Install systemd service file",
+	Long: `Generates a hardened systemd service file for running trakt-sync in daemon mode.
+
+The unit runs as a dynamic user with a read-only view of the system
+(ProtectSystem=strict, ProtectHome, NoNewPrivileges and more). Only the state
+directory is writable, so tokens, state and the audit log live there; the
+config file is passed with --config and never written.
+
+With --platform openrc or sysv an init script for systems without systemd
+(e.g. Alpine- or Gentoo-based NAS systems) is written to /etc/init.d instead.
+It runs the daemon as --user with the same --config and state directory.
+
+With --platform synology a script for the DSM Task Scheduler is written to
+/volume1/trakt-sync together with a default config there, and the steps to
+schedule it are printed. Task Scheduler runs it as a one-off sync.
+
+With --dry-run the service file is printed instead of installed. --enable and
+--start register and start the service with the platform's tools.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := serviceOpts
+		if !cmd.Flags().Changed("path") {
+			opts.Path = defaultServicePaths[opts.Platform]
+		}
+		opts.ConfigPath = resolvedConfigPath()
+		if opts.Platform == platformSynology {
+			// DSM has no trakt-sync user; Task Scheduler tasks usually run as root.
+			if cfgFile == "" {
+				opts.ConfigPath = synologyConfigPath
+			}
+			if !cmd.Flags().Changed("user") {
+				opts.User = "root"
+			}
+		}
+		opts.StateDir = resolvedStateDir()
+		opts.DryRun = dryRun
+		if err := runInstallService(os.Stdout, opts); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to install service")
+		}
+	},
+}
+
+func init() {
+	installServiceCmd.Flags().StringVar(&serviceOpts.Platform, "platform", platformSystemd, "init system: systemd, openrc, sysv or synology")
+	installServiceCmd.Flags().StringVar(&serviceOpts.Path, "path", defaultServicePaths[platformSystemd], "service file path (default depends on --platform)")
+	installServiceCmd.Flags().StringVar(&serviceOpts.User, "user", "trakt-sync", "service user")
+	installServiceCmd.Flags().DurationVar(&serviceOpts.Interval, "interval", 6*time.Hour, "sync interval for the service")
+	installServiceCmd.Flags().BoolVar(&serviceOpts.DynamicUser, "dynamic-user", true, "let systemd allocate the service user (DynamicUser=yes)")
+	installServiceCmd.Flags().BoolVar(&serviceOpts.Enable, "enable", false, "enable the service at boot after installing")
+	installServiceCmd.Flags().BoolVar(&serviceOpts.Start, "start", false, "start the service after installing")
+
+	rootCmd.AddCommand(installServiceCmd)
+}
+
+// runServiceCommand runs an init system tool such as systemctl; tests
+// replace it
+var runServiceCommand = func(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func runInstallService(w io.Writer, opts serviceOptions) error {
+	if opts.Platform == "" {
+		opts.Platform = platformSystemd
+	}
+	if _, ok := defaultServicePaths[opts.Platform]; !ok {
+		return fmt.Errorf("unknown platform %q (expected systemd, openrc, sysv or synology)", opts.Platform)
+	}
+	if opts.Platform == platformSynology && (opts.Enable || opts.Start) {
+		return fmt.Errorf("--enable and --start are not supported for synology; schedule the script in the DSM Task Scheduler")
+	}
+	if strings.TrimSpace(opts.User) == "" {
+		return fmt.Errorf("service user must not be empty")
+	}
+	if strings.TrimSpace(opts.Path) == "" {
+		return fmt.Errorf("service path must not be empty")
+	}
+
+	// Validate path to prevent directory traversal
+	if !filepath.IsAbs(opts.Path) {
+		return fmt.Errorf("service path must be absolute")
+	}
+	if strings.Contains(opts.Path, "..") {
+		return fmt.Errorf("service path must not contain '..'")
+	}
+
+	if opts.StateDir == "" {
+		opts.StateDir = defaultServiceStateDir
+		if opts.Platform == platformSynology {
+			opts.StateDir = synologyStateDir
+		}
+	}
+	if !filepath.IsAbs(opts.StateDir) || strings.Contains(opts.StateDir, "..") {
+		return fmt.Errorf("state directory must be an absolute path without '..'")
+	}
+
+	configPath, err := filepath.Abs(opts.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	opts.ConfigPath = configPath
+
+	// ProtectHome=yes hides home directories from a dynamic user entirely.
+	systemdDynamic := opts.Platform == platformSystemd && opts.DynamicUser
+	if systemdDynamic && underHome(opts.ConfigPath) {
+		return fmt.Errorf("config file %s is hidden from the service by ProtectHome; move it out of the home directory (e.g. /etc/trakt-sync/config.yaml) or use --dynamic-user=false", opts.ConfigPath)
+	}
+
+	content, mode := serviceUnit(opts), os.FileMode(0644)
+	switch opts.Platform {
+	case platformOpenRC:
+		content, mode = openRCScript(opts), 0755
+	case platformSysV:
+		content, mode = sysVScript(opts), 0755
+	case platformSynology:
+		content, mode = synologyScript(opts), 0755
+	}
+
+	if opts.DryRun {
+		log.Info().Str("platform", opts.Platform).Str("path", opts.Path).Msg("DRY RUN: would install service")
+		if _, err := io.WriteString(w, content); err != nil {
+			return err
+		}
+		if opts.Platform == platformSynology {
+			printSynologySteps(w, opts)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create service directory: %w", err)
+	}
+
+	if err := os.WriteFile(opts.Path, []byte(content), mode); err != nil {
+		return fmt.Errorf("failed to write service file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(opts.Path, mode); err != nil {
+		return fmt.Errorf("failed to set service file permissions: %w", err)
+	}
+	log.Info().Str("platform", opts.Platform).Str("path", opts.Path).Msg("Service installed")
+
+	// systemd creates StateDirectory= for the dynamic user itself, and the
+	// init scripts create theirs on start; other directories must exist for
+	// ReadWritePaths=.
+	if opts.Platform == platformSystemd && (stateDirectoryName(opts.StateDir) == "" || !opts.DynamicUser) {
+		if err := os.MkdirAll(opts.StateDir, 0700); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+		log.Info().Str("dir", opts.StateDir).Str("user", opts.User).Msg("Make sure the service user owns the state directory")
+	}
+
+	if opts.Platform == platformSynology {
+		if err := os.MkdirAll(opts.StateDir, 0700); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+		if _, err := os.Stat(opts.ConfigPath); errors.Is(err, os.ErrNotExist) {
+			// Load writes the defaults to a missing config file.
+			if _, err := config.Load(opts.ConfigPath); err != nil {
+				return fmt.Errorf("failed to create default config: %w", err)
+			}
+			log.Info().Str("path", opts.ConfigPath).Msg("Created default config; add your Trakt credentials and run 'auth' before the first scheduled run")
+		}
+		printSynologySteps(w, opts)
+	}
+
+	for _, step := range serviceCommands(opts) {
+		if err := runServiceCommand(step[0], step[1:]...); err != nil {
+			return fmt.Errorf("%s failed: %w", strings.Join(step, " "), err)
+		}
+	}
+	return nil
+}
+
+// serviceCommands returns the commands that register (--enable) and start
+// (--start) an installed service
+func serviceCommands(opts serviceOptions) [][]string {
+	if !opts.Enable && !opts.Start {
+		return nil
+	}
+
+	name := filepath.Base(opts.Path)
+	var steps [][]string
+	switch opts.Platform {
+	case platformSystemd:
+		steps = append(steps, []string{"systemctl", "daemon-reload"})
+		if opts.Enable {
+			steps = append(steps, []string{"systemctl", "enable", name})
+		}
+		if opts.Start {
+			steps = append(steps, []string{"systemctl", "start", name})
+		}
+	case platformOpenRC:
+		if opts.Enable {
+			steps = append(steps, []string{"rc-update", "add", name, "default"})
+		}
+		if opts.Start {
+			steps = append(steps, []string{"rc-service", name, "start"})
+		}
+	case platformSysV:
+		if opts.Enable {
+			steps = append(steps, []string{"update-rc.d", name, "defaults"})
+		}
+		if opts.Start {
+			steps = append(steps, []string{opts.Path, "start"})
+		}
+	}
+	return steps
+}
+
+// daemonArgs are the arguments the service passes to trakt-sync
+func daemonArgs(opts serviceOptions) string {
+	return fmt.Sprintf("daemon --config %s --no-create-config --state-dir %s --interval %s",
+		systemdQuote(opts.ConfigPath), systemdQuote(opts.StateDir), opts.Interval.String())
+}
+
+// serviceUnit renders the hardened unit file
+func serviceUnit(opts serviceOptions) string {
+	execStart := "/usr/local/bin/trakt-sync " + daemonArgs(opts)
+
+	var b strings.Builder
+	b.WriteString(`[Unit]
+Description=Trakt List Sync Service
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+`)
+	fmt.Fprintf(&b, "User=%s\n", opts.User)
+	if opts.DynamicUser {
+		b.WriteString("DynamicUser=yes\n")
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", execStart)
+	b.WriteString(`ExecReload=/bin/kill -HUP $MAINPID
+Restart=on-failure
+RestartSec=30
+
+# Hardening: everything but the state directory is read-only
+NoNewPrivileges=yes
+ProtectSystem=strict
+`)
+	if opts.DynamicUser {
+		b.WriteString("ProtectHome=yes\n")
+	} else {
+		b.WriteString("ProtectHome=read-only\n")
+	}
+	if name := stateDirectoryName(opts.StateDir); name != "" && opts.DynamicUser {
+		fmt.Fprintf(&b, "StateDirectory=%s\nStateDirectoryMode=0700\n", name)
+	}
+	fmt.Fprintf(&b, "ReadWritePaths=%s\n", systemdQuote(opts.StateDir))
+	b.WriteString(`PrivateTmp=yes
+PrivateDevices=yes
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectControlGroups=yes
+RestrictAddressFamilies=AF_INET AF_INET6 AF_UNIX
+RestrictNamespaces=yes
+LockPersonality=yes
+SystemCallArchitectures=native
+
+[Install]
+WantedBy=multi-user.target
+`)
+	return b.String()
+}
+
+// openRCScript renders an OpenRC init script
+func openRCScript(opts serviceOptions) string {
+	return fmt.Sprintf(`#!/sbin/openrc-run
+
+name="trakt-sync"
+description="Trakt List Sync Service"
+command="/usr/local/bin/trakt-sync"
+command_args=%s
+command_user=%s
+command_background=true
+pidfile="/run/${RC_SVCNAME}.pid"
+output_log="/var/log/${RC_SVCNAME}.log"
+error_log="/var/log/${RC_SVCNAME}.log"
+extra_started_commands="reload"
+
+depend() {
+	need net
+	after firewall
+}
+
+start_pre() {
+	checkpath --directory --owner "${command_user}" --mode 0700 %s
+	checkpath --file --owner "${command_user}" --mode 0640 "${output_log}"
+}
+
+reload() {
+	ebegin "Reloading ${RC_SVCNAME}"
+	start-stop-daemon --signal HUP --pidfile "${pidfile}"
+	eend $?
+}
+`, shellQuote(daemonArgs(opts)), shellQuote(opts.User), shellQuote(opts.StateDir))
+}
+
+// sysVScript renders an LSB init script for sysvinit
+func sysVScript(opts serviceOptions) string {
+	return fmt.Sprintf(`#!/bin/sh
+### BEGIN INIT INFO
+# Provides:          trakt-sync
+# Required-Start:    $network $remote_fs
+# Required-Stop:     $network $remote_fs
+# Default-Start:     2 3 4 5
+# Default-Stop:      0 1 6
+# Short-Description: Trakt List Sync Service
+### END INIT INFO
+
+DAEMON=/usr/local/bin/trakt-sync
+DAEMON_ARGS=%s
+RUN_AS=%s
+STATE_DIR=%s
+PIDFILE=/var/run/trakt-sync.pid
+LOGFILE=/var/log/trakt-sync.log
+
+is_running() {
+	[ -f "$PIDFILE" ] && kill -0 "$(cat "$PIDFILE")" 2>/dev/null
+}
+
+start() {
+	if is_running; then
+		echo "trakt-sync is already running"
+		return 0
+	fi
+	mkdir -p "$STATE_DIR" && chown "$RUN_AS" "$STATE_DIR" && chmod 0700 "$STATE_DIR" || return 1
+	touch "$LOGFILE" && chown "$RUN_AS" "$LOGFILE" || return 1
+	echo "Starting trakt-sync"
+	start-stop-daemon --start --quiet --background --make-pidfile --pidfile "$PIDFILE" \
+		--chuid "$RUN_AS" --exec /bin/sh -- -c "exec $DAEMON $DAEMON_ARGS >>$LOGFILE 2>&1"
+}
+
+stop() {
+	echo "Stopping trakt-sync"
+	start-stop-daemon --stop --quiet --retry TERM/30/KILL/5 --pidfile "$PIDFILE"
+	rm -f "$PIDFILE"
+}
+
+case "$1" in
+	start)
+		start
+		;;
+	stop)
+		stop
+		;;
+	restart)
+		stop
+		start
+		;;
+	reload)
+		is_running && kill -HUP "$(cat "$PIDFILE")"
+		;;
+	status)
+		if is_running; then
+			echo "trakt-sync is running"
+		else
+			echo "trakt-sync is not running"
+			exit 3
+		fi
+		;;
+	*)
+		echo "Usage: $0 {start|stop|restart|reload|status}"
+		exit 1
+		;;
+esac
+`, shellQuote(daemonArgs(opts)), shellQuote(opts.User), shellQuote(opts.StateDir))
+}
+
+// synologyScript renders the script the DSM Task Scheduler runs
+func synologyScript(opts serviceOptions) string {
+	return fmt.Sprintf(`#!/bin/sh
+# Run by the Synology DSM Task Scheduler as a user-defined script.
+# Each run syncs once; the schedule replaces the daemon's interval.
+exec /usr/local/bin/trakt-sync sync --config %s --no-create-config --state-dir %s
+`, shellQuote(opts.ConfigPath), shellQuote(opts.StateDir))
+}
+
+// printSynologySteps explains how to schedule the script in DSM
+func printSynologySteps(w io.Writer, opts serviceOptions) {
+	fmt.Fprintf(w, `
+To schedule the sync in DSM:
+  1. Control Panel > Task Scheduler > Create > Scheduled Task > User-defined script
+  2. General: task name "trakt-sync", user %s
+  3. Schedule: run daily, repeat every %s (or the closest interval DSM offers)
+  4. Task Settings > Run command:
+       sh %s
+`, opts.User, opts.Interval, shellQuote(opts.Path))
+}
+
+// shellQuote quotes a value for a POSIX shell assignment
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// stateDirectoryName returns the StateDirectory= name for directories under
+// /var/lib, which systemd creates and hands to the dynamic user, or ""
+func stateDirectoryName(dir string) string {
+	rel, err := filepath.Rel("/var/lib", filepath.Clean(dir))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return rel
+}
+
+func underHome(path string) bool {
+	for _, home := range []string{"/home", "/root"} {
+		if path == home || strings.HasPrefix(path, home+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// systemdQuote quotes a path for ExecStart= and ReadWritePaths= if needed
+func systemdQuote(value string) string {
+	if !strings.ContainsAny(value, " \t\"'\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstallServiceHardenedUnit(t *testing.T) {
+	dir := t.TempDir()
+	opts := serviceOptions{
+		Path:        filepath.Join(dir, "trakt-sync.service"),
+		User:        "trakt-sync",
+		Interval:    time.Hour,
+		ConfigPath:  "/etc/trakt-sync/config.yaml",
+		DynamicUser: true,
+	}
+
+	var out bytes.Buffer
+	opts.DryRun = true
+	if err := runInstallService(&out, opts); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(opts.Path); !os.IsNotExist(err) {
+		t.Fatal("dry run wrote the unit file")
+	}
+	for _, want := range []string{
+		"ExecStart=/usr/local/bin/trakt-sync daemon --config /etc/trakt-sync/config.yaml --no-create-config --state-dir /var/lib/trakt-sync --interval 1h0m0s\n",
+		"DynamicUser=yes\n",
+		"NoNewPrivileges=yes\n",
+		"ProtectSystem=strict\n",
+		"ProtectHome=yes\n",
+		"StateDirectory=trakt-sync\n",
+		"ReadWritePaths=/var/lib/trakt-sync\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("unit does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	var calls [][]string
+	oldRun := runServiceCommand
+	t.Cleanup(func() { runServiceCommand = oldRun })
+	runServiceCommand = func(name string, args ...string) error {
+		calls = append(calls, append([]string{name}, args...))
+		return nil
+	}
+
+	opts.DryRun = false
+	opts.Enable, opts.Start = true, true
+	if err := runInstallService(&out, opts); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if _, err := os.Stat(opts.Path); err != nil {
+		t.Fatalf("unit file was not written: %v", err)
+	}
+	want := [][]string{
+		{"systemctl", "daemon-reload"},
+		{"systemctl", "enable", "trakt-sync.service"},
+		{"systemctl", "start", "trakt-sync.service"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("systemctl calls = %v, want %v", calls, want)
+	}
+}
+
+func TestInstallServiceStateDirAndHome(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := filepath.Join(dir, "state")
+	opts := serviceOptions{
+		Path:       filepath.Join(dir, "trakt-sync.service"),
+		User:       "trakt-sync",
+		Interval:   time.Hour,
+		ConfigPath: "/home/me/.config/trakt-sync/config.yaml",
+		StateDir:   stateDir,
+	}
+
+	opts.DynamicUser = true
+	if err := runInstallService(&bytes.Buffer{}, opts); err == nil {
+		t.Error("expected an error for a config under /home with a dynamic user")
+	}
+
+	opts.DynamicUser = false
+	if err := runInstallService(&bytes.Buffer{}, opts); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	unit, err := os.ReadFile(opts.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ProtectHome=read-only\n", "ReadWritePaths=" + stateDir + "\n"} {
+		if !strings.Contains(string(unit), want) {
+			t.Errorf("unit does not contain %q:\n%s", want, unit)
+		}
+	}
+	if strings.Contains(string(unit), "DynamicUser") || strings.Contains(string(unit), "StateDirectory=") {
+		t.Errorf("unit without dynamic user:\n%s", unit)
+	}
+	if _, err := os.Stat(stateDir); err != nil {
+		t.Errorf("state directory was not created: %v", err)
+	}
+
+	opts.StateDir = "relative/state"
+	if err := runInstallService(&bytes.Buffer{}, opts); err == nil {
+		t.Error("expected an error for a relative state directory")
+	}
+}
+
+func TestInstallServiceInitScripts(t *testing.T) {
+	for _, platform := range []string{platformOpenRC, platformSysV} {
+		t.Run(platform, func(t *testing.T) {
+			opts := serviceOptions{
+				Platform:    platform,
+				Path:        filepath.Join(t.TempDir(), "trakt-sync"),
+				User:        "media",
+				Interval:    time.Hour,
+				ConfigPath:  "/home/media/trakt-sync/config.yaml",
+				DynamicUser: true,
+			}
+			if err := runInstallService(&bytes.Buffer{}, opts); err != nil {
+				t.Fatalf("install: %v", err)
+			}
+
+			info, err := os.Stat(opts.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0755 {
+				t.Errorf("script mode = %v, want 0755", info.Mode().Perm())
+			}
+			script, err := os.ReadFile(opts.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{
+				"'daemon --config /home/media/trakt-sync/config.yaml --no-create-config --state-dir /var/lib/trakt-sync --interval 1h0m0s'",
+				"'media'",
+			} {
+				if !strings.Contains(string(script), want) {
+					t.Errorf("script does not contain %q:\n%s", want, script)
+				}
+			}
+			if sh, err := exec.LookPath("sh"); err == nil {
+				if out, err := exec.Command(sh, "-n", opts.Path).CombinedOutput(); err != nil {
+					t.Errorf("script has syntax errors: %v\n%s", err, out)
+				}
+			}
+		})
+	}
+
+	if err := runInstallService(&bytes.Buffer{}, serviceOptions{Platform: "launchd", Path: "/tmp/x", User: "me"}); err == nil {
+		t.Error("expected an error for an unknown platform")
+	}
+}
+
+func TestInstallServiceSynology(t *testing.T) {
+	dir := t.TempDir()
+	opts := serviceOptions{
+		Platform:   platformSynology,
+		Path:       filepath.Join(dir, "trakt-sync-task.sh"),
+		User:       "root",
+		Interval:   6 * time.Hour,
+		ConfigPath: filepath.Join(dir, "config.yaml"),
+		StateDir:   filepath.Join(dir, "state"),
+	}
+
+	var out bytes.Buffer
+	if err := runInstallService(&out, opts); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	script, err := os.ReadFile(opts.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "exec /usr/local/bin/trakt-sync sync --config '" + opts.ConfigPath + "' --no-create-config --state-dir '" + opts.StateDir + "'\n"
+	if !strings.Contains(string(script), want) {
+		t.Errorf("script does not contain %q:\n%s", want, script)
+	}
+	if !strings.Contains(out.String(), "sh '"+opts.Path+"'") {
+		t.Errorf("Task Scheduler steps do not contain the command:\n%s", out.String())
+	}
+	for _, path := range []string{opts.ConfigPath, opts.StateDir} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was not created: %v", path, err)
+		}
+	}
+
+	opts.Enable = true
+	if err := runInstallService(&out, opts); err == nil {
+		t.Error("expected an error for --enable on synology")
+	}
+}
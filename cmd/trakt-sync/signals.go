@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// stopCtx is canceled by the first SIGINT or SIGTERM. A running sync
+	// finishes the list at hand and skips the rest.
+	stopCtx = context.Background()
+	// runCtx is canceled once the command has to end right away: by the
+	// first signal outside a sync, by the second during one. Trakt requests,
+	// retry waits and syncs stop when it is.
+	runCtx = context.Background()
+
+	// syncing is set while lists are synced
+	syncing atomic.Bool
+)
+
+// handleSignals sets up stopCtx and runCtx. Once runCtx is canceled, the
+// next signal kills the process as usual.
+func handleSignals() {
+	stopping, stop := context.WithCancel(context.Background())
+	running, abort := context.WithCancel(context.Background())
+	stopCtx, runCtx = stopping, running
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		stop()
+		if syncing.Load() {
+			log.Info().Str("signal", sig.String()).Msg("Received shutdown signal, finishing the current list (send it again to abort)")
+			sig = <-signals
+		}
+		log.Info().Str("signal", sig.String()).Msg("Received shutdown signal, stopping")
+		abort()
+		signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+	}()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/maximilian/trakt-sync/internal/audit"
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/state"
+)
+
+// stateDirEnv sets the state directory like --state-dir
+const stateDirEnv = "TRAKT_SYNC_STATE_DIR"
+
+var stateDirFlag string
+
+// resolvedStateDir returns the state directory from --state-dir or
+// TRAKT_SYNC_STATE_DIR, or "" when mutable data lives next to the config
+func resolvedStateDir() string {
+	if stateDirFlag != "" {
+		return stateDirFlag
+	}
+	return os.Getenv(stateDirEnv)
+}
+
+// dataDir returns the directory for state, the audit log and, with a state
+// directory, tokens
+func dataDir() string {
+	if dir := resolvedStateDir(); dir != "" {
+		return dir
+	}
+	return filepath.Dir(resolvedConfigPath())
+}
+
+func stateFilePath() string {
+	return filepath.Join(dataDir(), state.FileName)
+}
+
+// openStateStore opens the state backend selected by state.backend
+func openStateStore() (state.Store, error) {
+	switch cfg.State.Backend {
+	case state.BackendSQLite:
+		path := cfg.State.Path
+		if path == "" {
+			path = filepath.Join(dataDir(), state.SQLiteFileName)
+		}
+		return state.OpenSQLite(path)
+	case state.BackendRedis:
+		return state.OpenRedis(state.RedisOptions{
+			Address:  cfg.State.Redis.Address,
+			Password: cfg.State.Redis.Password,
+			DB:       cfg.State.Redis.DB,
+			Key:      cfg.State.Redis.Key,
+		})
+	default:
+		return state.NewFileStore(stateFilePath()), nil
+	}
+}
+
+// loadState reads the state from the configured backend
+func loadState() (*state.State, error) {
+	store, err := openStateStore()
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return store.Load()
+}
+
+// saveState writes the state to the configured backend
+func saveState(st *state.State) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return store.Save(st)
+}
+
+// stateLocation describes the configured state backend for log messages
+func stateLocation() string {
+	switch cfg.State.Backend {
+	case state.BackendSQLite, state.BackendRedis:
+		return cfg.State.Backend
+	default:
+		return stateFilePath()
+	}
+}
+
+func auditLogPath() string {
+	return filepath.Join(dataDir(), audit.FileName)
+}
+
+// configMu serializes config writes of the token store and the sync
+var configMu sync.Mutex
+
+// saveConfig persists tokens and sync bookkeeping: to the runtime file of
+// the state directory if there is one, so the config file is never
+// written, or to the config file otherwise
+func saveConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return writeConfig()
+}
+
+// writeConfig does the work of saveConfig; callers hold configMu. With
+// leader election, the tokens are shared with the other replicas, too.
+func writeConfig() error {
+	if err := writeConfigFile(); err != nil {
+		return err
+	}
+	return shareTokens()
+}
+
+// writeConfigFile writes the runtime file or the config file. In the daemon,
+// a config file edited since it was loaded is not overwritten: the runtime
+// values are written into the edit, which the daemon then reloads.
+func writeConfigFile() error {
+	if dir := resolvedStateDir(); dir != "" {
+		return config.SaveRuntime(cfg, filepath.Join(dir, config.RuntimeFileName))
+	}
+	path := resolvedConfigPath()
+	if !trackConfigHash || configFileHash(path) == configHash {
+		if err := config.Save(cfg, path); err != nil {
+			return err
+		}
+		configHash = configFileHash(path)
+		return nil
+	}
+
+	edited, err := config.LoadExisting(path)
+	if err == nil {
+		err = edited.Validate()
+	}
+	if err != nil {
+		return fmt.Errorf("config file was edited and is invalid, not overwriting it: %w", err)
+	}
+	config.CopyRuntime(edited, cfg)
+	return config.Save(edited, path)
+}
+
+// applyRuntime overlays the runtime file of the state directory on a loaded
+// config
+func applyRuntime(c *config.Config) error {
+	dir := resolvedStateDir()
+	if dir == "" {
+		return nil
+	}
+	return config.LoadRuntime(c, filepath.Join(dir, config.RuntimeFileName))
+}
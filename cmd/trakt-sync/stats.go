@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/archive"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsSource string
+	statsTop    int
+
+	statsExportFormat string
+	statsExportFile   string
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the chart archive",
+	Long: `Summarizes the chart responses stored with archive.enabled: per chart the
+number of snapshots and the titles that charted most often, with their best
+rank and peak watchers.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runStats(os.Stdout, statsSource, statsTop); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Stats failed")
+		}
+	},
+}
+
+var statsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the chart archive as CSV or Parquet",
+	Long: `Exports the chart archive as one row per title and snapshot with the columns
+date, source, rank, title, year, trakt_id and watchers, for analysis in a
+spreadsheet or notebook.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runStatsExport(statsExportFile, statsExportFormat, statsSource); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Export failed")
+		}
+	},
+}
+
+var statsListsCmd = &cobra.Command{
+	Use:   "lists",
+	Short: "Show likes and comments of public lists over time",
+	Long: `Shows the likes and comments of your public managed lists as recorded by
+each sync, with their growth over the last 7 and 30 days.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListStats(os.Stdout, time.Now()); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Stats failed")
+		}
+	},
+}
+
+func init() {
+	statsCmd.PersistentFlags().StringVar(&statsSource, "source", "", "only this chart, e.g. movies/trending")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "titles to show per chart")
+	statsExportCmd.Flags().StringVar(&statsExportFormat, "format", archive.FormatCSV, "export format: "+strings.Join(archive.Formats, " or "))
+	statsExportCmd.Flags().StringVar(&statsExportFile, "file", "", "file to write (default: stdout)")
+
+	statsCmd.AddCommand(statsExportCmd)
+	statsCmd.AddCommand(statsListsCmd)
+	rootCmd.AddCommand(statsCmd)
+}
+
+func archiveDir() string {
+	return filepath.Join(dataDir(), archive.DirName)
+}
+
+// runStatsExport writes the archived snapshots, optionally of one source, to
+// path or stdout
+func runStatsExport(path, format, source string) error {
+	if format != archive.FormatCSV && format != archive.FormatParquet {
+		return fmt.Errorf("unknown format %q, use %s", format, strings.Join(archive.Formats, " or "))
+	}
+
+	snapshots, err := archive.Read(archiveDir())
+	if err != nil {
+		return err
+	}
+	if source != "" {
+		var filtered []archive.Snapshot
+		for _, snapshot := range snapshots {
+			if snapshot.Source == source {
+				filtered = append(filtered, snapshot)
+			}
+		}
+		snapshots = filtered
+	}
+	rows, err := archive.Rows(snapshots)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		return archive.Export(os.Stdout, format, rows)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := archive.Export(f, format, rows); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	log.Info().Str("file", path).Int("rows", len(rows)).Msg("Exported chart archive")
+	return nil
+}
+
+// chartTitleStats is how one title fared in a chart across snapshots
+type chartTitleStats struct {
+	title        string
+	year         int
+	appearances  int
+	bestRank     int
+	peakWatchers int
+}
+
+func runStats(w io.Writer, source string, top int) error {
+	snapshots, err := archive.Read(archiveDir())
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		fmt.Fprintf(w, "No archived charts in %s. Enable archive.enabled and sync to start collecting.\n", archiveDir())
+		return nil
+	}
+
+	bySource := make(map[string][]archive.Snapshot)
+	var sources []string
+	for _, snapshot := range snapshots {
+		if source != "" && snapshot.Source != source {
+			continue
+		}
+		if _, ok := bySource[snapshot.Source]; !ok {
+			sources = append(sources, snapshot.Source)
+		}
+		bySource[snapshot.Source] = append(bySource[snapshot.Source], snapshot)
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no archived snapshots of %s", source)
+	}
+	sort.Strings(sources)
+
+	for i, src := range sources {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		runs := bySource[src]
+		fmt.Fprintf(w, "%s: %d snapshots from %s to %s\n", src, len(runs),
+			runs[0].Time.Local().Format("2006-01-02 15:04"), runs[len(runs)-1].Time.Local().Format("2006-01-02 15:04"))
+
+		titles, err := summarizeChart(runs)
+		if err != nil {
+			return err
+		}
+		if top > 0 && len(titles) > top {
+			titles = titles[:top]
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TITLE\tYEAR\tSNAPSHOTS\tBEST RANK\tPEAK WATCHERS")
+		for _, title := range titles {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", title.title, title.year, title.appearances, title.bestRank, title.peakWatchers)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarizeChart aggregates the snapshots of one chart per title, most
+// frequent first
+func summarizeChart(snapshots []archive.Snapshot) ([]chartTitleStats, error) {
+	byID := make(map[int]*chartTitleStats)
+	for _, snapshot := range snapshots {
+		entries, err := snapshot.Entries()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			stats, ok := byID[entry.TraktID]
+			if !ok {
+				stats = &chartTitleStats{title: entry.Title, year: entry.Year, bestRank: entry.Rank}
+				byID[entry.TraktID] = stats
+			}
+			stats.appearances++
+			if entry.Rank < stats.bestRank {
+				stats.bestRank = entry.Rank
+			}
+			if entry.Watchers > stats.peakWatchers {
+				stats.peakWatchers = entry.Watchers
+			}
+		}
+	}
+
+	titles := make([]chartTitleStats, 0, len(byID))
+	for _, stats := range byID {
+		titles = append(titles, *stats)
+	}
+	sort.Slice(titles, func(i, j int) bool {
+		if titles[i].appearances != titles[j].appearances {
+			return titles[i].appearances > titles[j].appearances
+		}
+		if titles[i].bestRank != titles[j].bestRank {
+			return titles[i].bestRank < titles[j].bestRank
+		}
+		return titles[i].title < titles[j].title
+	})
+	return titles, nil
+}
+
+// runListStats prints the latest likes and comments of each tracked list and
+// how they changed in the last 7 and 30 days as of now
+func runListStats(w io.Writer, now time.Time) error {
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if len(st.ListStats) == 0 {
+		fmt.Fprintln(w, "No list stats yet. Likes and comments are recorded for public lists on every sync.")
+		return nil
+	}
+
+	slugs := make([]string, 0, len(st.ListStats))
+	for slug := range st.ListStats {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIST\tLIKES\tCOMMENTS\tLIKES 7D\tLIKES 30D\tSINCE")
+	for _, slug := range slugs {
+		samples := st.ListStats[slug]
+		if len(samples) == 0 {
+			continue
+		}
+		latest := samples[len(samples)-1]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n", slug, latest.Likes, latest.Comments,
+			likesGrowth(samples, now, 7), likesGrowth(samples, now, 30), samples[0].Date)
+	}
+	return tw.Flush()
+}
+
+// likesGrowth formats the likes gained since the last sample at least days
+// old, or "-" if the list was not tracked back then
+func likesGrowth(samples []state.ListStatsSample, now time.Time, days int) string {
+	cutoff := now.UTC().AddDate(0, 0, -days).Format("2006-01-02")
+	for i := len(samples) - 1; i >= 0; i-- {
+		if samples[i].Date <= cutoff {
+			return fmt.Sprintf("%+d", samples[len(samples)-1].Likes-samples[i].Likes)
+		}
+	}
+	return "-"
+}
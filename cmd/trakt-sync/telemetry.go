@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/telemetry"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// telemetryCommand is the running command, e.g. "list show"
+	telemetryCommand string
+	telemetrySent    bool
+)
+
+// reportCommand sends the outcome of the running command when the user
+// opted in to telemetry. Only the first outcome of a run is reported.
+func reportCommand(success bool) {
+	if telemetrySent || telemetryCommand == "" || cfg == nil || !cfg.Telemetry.Enabled {
+		return
+	}
+	telemetrySent = true
+
+	event := telemetry.NewEvent(telemetryCommand, success, Version)
+	if err := telemetry.Send(cfg.Telemetry.Endpoint, event); err != nil {
+		errcode.Log(log.Debug(), err).Msg("Failed to send telemetry")
+	}
+}
+
+// exit reports the outcome of the command and exits with code
+func exit(code int) {
+	reportCommand(code == 0)
+	os.Exit(code)
+}
+
+// telemetryHook reports commands that end with log.Fatal as failed
+type telemetryHook struct{}
+
+func (telemetryHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.FatalLevel {
+		reportCommand(false)
+	}
+}
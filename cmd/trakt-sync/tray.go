@@ -0,0 +1,271 @@
+//go:build !darwin || cgo
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"fyne.io/systray"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// trayRefresh is how often the tray icon picks up the daemon's status
+const trayRefresh = 2 * time.Second
+
+// trayMenu holds the menu items the tray updates
+type trayMenu struct {
+	status    *systray.MenuItem
+	authorize *systray.MenuItem
+	syncNow   *systray.MenuItem
+	quit      *systray.MenuItem
+	// authURL is where authorize leads, if shown
+	authURL string
+	// shown is the status on display
+	shown string
+}
+
+// runTray runs the daemon in the background of a tray icon until Quit is
+// clicked or a signal stops it
+func runTray(flagInterval time.Duration, flagSet bool) error {
+	trayMode = true
+	syncRequests = make(chan struct{}, 1)
+	if daemonMetrics == nil {
+		daemonMetrics = metrics.New()
+	}
+	// Quit stops the daemon like SIGTERM: the list at hand is finished.
+	ctx, quit := context.WithCancel(stopCtx)
+	defer quit()
+	stopCtx = ctx
+
+	var daemonErr error
+	systray.Run(func() {
+		menu := newTrayMenu()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if !dryRun && !cfg.IsAuthenticated() {
+				if err := trayAuthorize(ctx); err != nil {
+					daemonErr = err
+					return
+				}
+			}
+			daemonErr = runDaemon(flagInterval, flagSet)
+		}()
+		go menu.run(quit, done)
+	}, nil)
+
+	if errors.Is(daemonErr, context.Canceled) {
+		return nil
+	}
+	return daemonErr
+}
+
+func newTrayMenu() *trayMenu {
+	systray.SetIcon(trayIcon(trayColorIdle))
+	systray.SetTooltip("trakt-sync")
+	menu := &trayMenu{
+		status:    systray.AddMenuItem("Starting...", ""),
+		authorize: systray.AddMenuItem("", "Open trakt.tv to authorize trakt-sync"),
+		syncNow:   systray.AddMenuItem("Sync now", "Sync all lists now"),
+	}
+	systray.AddSeparator()
+	menu.quit = systray.AddMenuItem("Quit", "Stop syncing and quit")
+	menu.status.Disable()
+	menu.authorize.Hide()
+	return menu
+}
+
+// run handles clicks and refreshes the status until the daemon ends
+func (m *trayMenu) run(quit context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(trayRefresh)
+	defer ticker.Stop()
+	m.refresh()
+	for {
+		select {
+		case <-done:
+			systray.Quit()
+			return
+		case <-m.syncNow.ClickedCh:
+			select {
+			case syncRequests <- struct{}{}:
+			default:
+			}
+		case <-m.authorize.ClickedCh:
+			if m.authURL != "" {
+				openURL(m.authURL)
+			}
+		case <-m.quit.ClickedCh:
+			m.setStatus("Stopping...", trayColorIdle)
+			quit()
+		case <-ticker.C:
+			m.refresh()
+		}
+	}
+}
+
+// refresh shows the daemon's status in the icon, its tooltip and the menu
+func (m *trayMenu) refresh() {
+	state, err := readNeedsAuth()
+	if err != nil {
+		errcode.Log(log.Debug(), err).Msg("Failed to read the re-authentication state")
+	}
+	if state != nil {
+		m.setStatus("Trakt needs authorization", trayColorWarning)
+		if state.UserCode != "" {
+			m.showAuthorize(state.VerificationURL, state.UserCode)
+		}
+		return
+	}
+	m.authorize.Hide()
+	m.authURL = ""
+
+	if syncing.Load() {
+		m.setStatus("Syncing...", trayColorIdle)
+		return
+	}
+	health := daemonMetrics.Health()
+	switch {
+	case health.LastSync == nil:
+		m.setStatus("No sync yet", trayColorIdle)
+	case health.LastResult == "failed":
+		m.setStatus(fmt.Sprintf("Last sync %s failed", health.LastSync.Local().Format("15:04")), trayColorError)
+	case health.LastResult == "partial":
+		m.setStatus(fmt.Sprintf("Last sync %s: %d synced, %d failed", health.LastSync.Local().Format("15:04"), health.Successful, health.Failed), trayColorWarning)
+	default:
+		m.setStatus(fmt.Sprintf("Last sync %s: %d lists synced", health.LastSync.Local().Format("15:04"), health.Successful), trayColorOK)
+	}
+}
+
+// setStatus shows status unless it is on display already
+func (m *trayMenu) setStatus(status string, c color.RGBA) {
+	if status == m.shown {
+		return
+	}
+	m.shown = status
+	m.status.SetTitle(status)
+	systray.SetTooltip("trakt-sync: " + status)
+	systray.SetIcon(trayIcon(c))
+}
+
+func (m *trayMenu) showAuthorize(url, code string) {
+	if url == m.authURL {
+		return
+	}
+	m.authURL = url
+	m.authorize.SetTitle(fmt.Sprintf("Authorize: enter %s at %s", code, url))
+	m.authorize.Show()
+}
+
+// trayAuthorize runs the device authorization before the first sync. The
+// code is recorded like a re-authentication prompt, so the menu shows it.
+func trayAuthorize(ctx context.Context) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	client := newTraktClient("", "")
+	client.SetContext(ctx)
+	device, err := client.GetDeviceCode()
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Str("url", device.VerificationURL).
+		Str("user_code", device.UserCode).
+		Msgf("Not authenticated yet: visit %s and enter code %s", device.VerificationURL, device.UserCode)
+	writeNeedsAuth(&needsAuth{
+		Since:           time.Now().UTC(),
+		Reason:          "not authenticated yet",
+		UserCode:        device.UserCode,
+		VerificationURL: device.VerificationURL,
+		CodeExpiresAt:   time.Now().Add(time.Duration(device.ExpiresIn) * time.Second).UTC(),
+	})
+	openURL(device.VerificationURL)
+
+	if err := completeAuth(client, device.DeviceCode, device.Interval, device.ExpiresIn); err != nil {
+		clearNeedsAuth()
+		return err
+	}
+	return nil
+}
+
+// openURL opens url in the default browser. Failures are only logged.
+func openURL(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		errcode.Log(log.Warn(), err).Str("url", url).Msg("Failed to open the browser")
+		return
+	}
+	go cmd.Wait()
+}
+
+// Colors of the tray icon by status
+var (
+	trayColorIdle    = color.RGBA{0x88, 0x88, 0x88, 0xff}
+	trayColorOK      = color.RGBA{0x2e, 0xa0, 0x43, 0xff}
+	trayColorWarning = color.RGBA{0xe0, 0x8e, 0x0b, 0xff}
+	trayColorError   = color.RGBA{0xd0, 0x31, 0x2d, 0xff}
+)
+
+// trayIconSize is the edge of the tray icon in pixels
+const trayIconSize = 32
+
+// trayIcon returns the tray icon in color c as PNG, or as an ICO wrapping
+// the PNG on Windows
+func trayIcon(c color.RGBA) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, trayIconImage(c))
+	if runtime.GOOS != "windows" {
+		return buf.Bytes()
+	}
+	return icoWithPNG(buf.Bytes(), trayIconSize)
+}
+
+// trayIconImage draws the tray icon, a dot in color c
+func trayIconImage(c color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, trayIconSize, trayIconSize))
+	center := float64(trayIconSize-1) / 2
+	radius := float64(trayIconSize)/2 - 2
+	for y := 0; y < trayIconSize; y++ {
+		for x := 0; x < trayIconSize; x++ {
+			dx, dy := float64(x)-center, float64(y)-center
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+// icoWithPNG wraps a square PNG image of size pixels in an ICO file
+func icoWithPNG(data []byte, size int) []byte {
+	var buf bytes.Buffer
+	// ICONDIR: reserved, type 1 (icon), one image
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, 1})
+	// ICONDIRENTRY: width, height, no palette, reserved, one plane, 32 bits
+	// per pixel, the size of the PNG and its offset after the two headers
+	buf.Write([]byte{byte(size), byte(size), 0, 0})
+	binary.Write(&buf, binary.LittleEndian, [2]uint16{1, 32})
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(data)), 6 + 16})
+	buf.Write(data)
+	return buf.Bytes()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// trayMode makes the daemon prompt for re-authentication without
+	// daemon.reauth, since the tray shows the code
+	trayMode bool
+	// syncRequests receives "Sync now" clicks of the tray; nil otherwise
+	syncRequests chan struct{}
+)
+
+var trayCmd = &cobra.Command{
+	Use:   "tray",
+	Short: "Run the daemon with a system tray icon",
+	Long: `Runs the daemon with an icon in the system tray (Windows, macOS, and
+Linux desktops with StatusNotifierItem support). The menu shows the result of
+the last sync, syncs on demand with "Sync now" and shows the code to enter on
+trakt.tv when the daemon is not or no longer authorized.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Failed to parse interval flag")
+		}
+		err = runTray(interval, cmd.Flags().Changed("interval"))
+		if errors.Is(err, syncpkg.ErrCanceled) {
+			exit(130)
+		}
+		if err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Tray mode failed")
+		}
+	},
+}
+
+func init() {
+	trayCmd.Flags().Duration("interval", defaultDaemonInterval, "sync interval (overrides daemon.interval)")
+	rootCmd.AddCommand(trayCmd)
+}
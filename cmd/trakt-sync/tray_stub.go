@@ -0,0 +1,13 @@
+//go:build darwin && !cgo
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// runTray fails in builds without the native tray, which needs cgo on macOS
+func runTray(flagInterval time.Duration, flagSet bool) error {
+	return errors.New("tray mode needs a cgo build on macOS")
+}
@@ -0,0 +1,39 @@
+//go:build !darwin || cgo
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"testing"
+)
+
+func TestIcoWithPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, trayIconImage(trayColorOK)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	ico := icoWithPNG(data, trayIconSize)
+
+	if got := binary.LittleEndian.Uint16(ico[2:4]); got != 1 {
+		t.Errorf("expected ICO type 1, got %d", got)
+	}
+	if ico[6] != trayIconSize || ico[7] != trayIconSize {
+		t.Errorf("expected a %dx%d entry, got %dx%d", trayIconSize, trayIconSize, ico[6], ico[7])
+	}
+	size := binary.LittleEndian.Uint32(ico[14:18])
+	offset := binary.LittleEndian.Uint32(ico[18:22])
+	if int(size) != len(data) || int(offset)+int(size) != len(ico) {
+		t.Fatalf("entry points at %d bytes at %d in a %d byte file", size, offset, len(ico))
+	}
+	img, err := png.Decode(bytes.NewReader(ico[offset:]))
+	if err != nil {
+		t.Fatalf("embedded PNG: %v", err)
+	}
+	r, g, b, _ := img.At(trayIconSize/2, trayIconSize/2).RGBA()
+	if byte(r>>8) != trayColorOK.R || byte(g>>8) != trayColorOK.G || byte(b>>8) != trayColorOK.B {
+		t.Errorf("expected the dot in %v, got %v", trayColorOK, img.At(trayIconSize/2, trayIconSize/2))
+	}
+}
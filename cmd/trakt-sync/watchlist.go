@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var watchlistCmd = &cobra.Command{
+	Use:   "watchlist",
+	Short: "Watchlist maintenance",
+	Long:  "Commands for maintaining your Trakt watchlist.",
+}
+
+var watchlistCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove watched items from the watchlist",
+	Long:  "Removes the movies you played and the shows with a played episode from your Trakt watchlist, based on your watch history.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runWatchlistClean(); err != nil {
+			errcode.Log(log.Fatal(), err).Msg("Watchlist cleanup failed")
+		}
+	},
+}
+
+func init() {
+	watchlistCmd.AddCommand(watchlistCleanCmd)
+	rootCmd.AddCommand(watchlistCmd)
+}
+
+func runWatchlistClean() error {
+	client, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = syncpkg.NewSyncer(client, cfg).RemoveWatchedFromWatchlist(dryRun)
+	return err
+}
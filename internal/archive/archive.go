@@ -0,0 +1,168 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DirName is the name of the archive directory in the data directory
+const DirName = "archive"
+
+// fileTimeFormat names run files so they sort chronologically
+const fileTimeFormat = "20060102T150405.000Z"
+
+// Snapshot is one chart response recorded during a sync run
+type Snapshot struct {
+	Time time.Time `json:"time"`
+	// Source is the chart, e.g. movies/trending or shows/watched
+	Source string `json:"source"`
+	// Response is the chart as returned by the Trakt API
+	Response json.RawMessage `json:"response"`
+}
+
+// Entry is one title of a snapshot
+type Entry struct {
+	Rank    int
+	Title   string
+	Year    int
+	TraktID int
+	// Watchers is the number of people watching right now (trending) or
+	// this week (watched)
+	Watchers int
+}
+
+// Entries decodes the titles of the snapshot in chart order
+func (s Snapshot) Entries() ([]Entry, error) {
+	var items []struct {
+		Watchers     int        `json:"watchers"`
+		WatcherCount int        `json:"watcher_count"`
+		Movie        *mediaInfo `json:"movie"`
+		Show         *mediaInfo `json:"show"`
+	}
+	if err := json.Unmarshal(s.Response, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode %s snapshot: %w", s.Source, err)
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for i, item := range items {
+		media := item.Movie
+		if media == nil {
+			media = item.Show
+		}
+		if media == nil {
+			continue
+		}
+		watchers := item.Watchers
+		if watchers == 0 {
+			watchers = item.WatcherCount
+		}
+		entries = append(entries, Entry{
+			Rank:     i + 1,
+			Title:    media.Title,
+			Year:     media.Year,
+			TraktID:  media.IDs.Trakt,
+			Watchers: watchers,
+		})
+	}
+	return entries, nil
+}
+
+type mediaInfo struct {
+	Title string `json:"title"`
+	Year  int    `json:"year"`
+	IDs   struct {
+		Trakt int `json:"trakt"`
+	} `json:"ids"`
+}
+
+// WriteRun stores the snapshots of one run as a gzip-compressed JSONL file
+// in dir. Files of earlier runs are never rewritten.
+func WriteRun(dir string, started time.Time, snapshots []Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "charts-"+started.UTC().Format(fileTimeFormat)+".jsonl.gz")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+
+	zw := gzip.NewWriter(f)
+	encoder := json.NewEncoder(zw)
+	for _, snapshot := range snapshots {
+		if err := encoder.Encode(snapshot); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// Read returns all snapshots in dir, oldest first. A missing directory
+// yields no snapshots.
+func Read(dir string) ([]Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "charts-*.jsonl.gz"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive: %w", err)
+	}
+	sort.Strings(paths)
+
+	var snapshots []Snapshot
+	for _, path := range paths {
+		run, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, run...)
+	}
+	return snapshots, nil
+}
+
+func readFile(path string) ([]Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	defer zr.Close()
+
+	var snapshots []Snapshot
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", filepath.Base(path), line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return snapshots, nil
+}
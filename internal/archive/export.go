@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/parquet"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatCSV, FormatParquet}
+
+// exportColumns are the columns of an export, in order
+var exportColumns = []string{"date", "source", "rank", "title", "year", "trakt_id", "watchers"}
+
+// Row is one title of one snapshot in an export
+type Row struct {
+	Time   time.Time
+	Source string
+	Entry
+}
+
+// Rows flattens snapshots into one row per title and snapshot
+func Rows(snapshots []Snapshot) ([]Row, error) {
+	var rows []Row
+	for _, snapshot := range snapshots {
+		entries, err := snapshot.Entries()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			rows = append(rows, Row{Time: snapshot.Time, Source: snapshot.Source, Entry: entry})
+		}
+	}
+	return rows, nil
+}
+
+// Export writes rows in the given format
+func Export(w io.Writer, format string, rows []Row) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, rows)
+	case FormatParquet:
+		return writeParquet(w, rows)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+func writeCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			row.Time.UTC().Format(time.RFC3339),
+			row.Source,
+			strconv.Itoa(row.Rank),
+			row.Title,
+			strconv.Itoa(row.Year),
+			strconv.Itoa(row.TraktID),
+			strconv.Itoa(row.Watchers),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeParquet(w io.Writer, rows []Row) error {
+	var (
+		times    = make([]time.Time, len(rows))
+		sources  = make([]string, len(rows))
+		ranks    = make([]int64, len(rows))
+		titles   = make([]string, len(rows))
+		years    = make([]int64, len(rows))
+		ids      = make([]int64, len(rows))
+		watchers = make([]int64, len(rows))
+	)
+	for i, row := range rows {
+		times[i] = row.Time
+		sources[i] = row.Source
+		ranks[i] = int64(row.Rank)
+		titles[i] = row.Title
+		years[i] = int64(row.Year)
+		ids[i] = int64(row.TraktID)
+		watchers[i] = int64(row.Watchers)
+	}
+
+	return parquet.Write(w, []parquet.Column{
+		parquet.Time(exportColumns[0], times),
+		parquet.String(exportColumns[1], sources),
+		parquet.Int(exportColumns[2], ranks),
+		parquet.String(exportColumns[3], titles),
+		parquet.Int(exportColumns[4], years),
+		parquet.Int(exportColumns[5], ids),
+		parquet.Int(exportColumns[6], watchers),
+	})
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Operations recorded in the audit log
+const (
+	CreateList      = "create_list"
+	UpdateList      = "update_list"
+	DeleteList      = "delete_list"
+	AddItems        = "add_items"
+	RemoveItems     = "remove_items"
+	ReorderItems    = "reorder_items"
+	RemoveWatchlist = "remove_watchlist"
+	RemoveHistory   = "remove_history"
+	DeleteComment   = "delete_comment"
+	TokenRefresh    = "token_refresh"
+)
+
+// Entry is one write operation against the Trakt API
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Target is the list slug, or the watchlist/history for sync endpoints
+	Target  string `json:"target,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	// Error is set when the call failed
+	Error string `json:"error,omitempty"`
+}
+
+// FileName is the name of the audit log in the data directory
+const FileName = "audit.log"
+
+// DefaultPath returns the audit log location next to the given config file
+func DefaultPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+var mu sync.Mutex
+
+// Append adds an entry to the audit log, one JSON object per line. Existing
+// entries are never rewritten.
+func Append(path string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Read returns all entries of the audit log, oldest first. A missing file
+// yields no entries.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
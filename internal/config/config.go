@@ -1,22 +1,141 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/imdb"
+	"github.com/maximilian/trakt-sync/internal/mdblist"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Trakt   TraktConfig   `mapstructure:"trakt"`
-	Sync    SyncConfig    `mapstructure:"sync"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Trakt     TraktConfig     `mapstructure:"trakt"`
+	Sync      SyncConfig      `mapstructure:"sync"`
+	Watchlist WatchlistConfig `mapstructure:"watchlist"`
+	Daemon    DaemonConfig    `mapstructure:"daemon"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Telemetry TelemetryConfig `mapstructure:"telemetry"`
+	Updates   UpdatesConfig   `mapstructure:"updates"`
+	Archive   ArchiveConfig   `mapstructure:"archive"`
+	Plex      PlexConfig      `mapstructure:"plex"`
+	State     StateConfig     `mapstructure:"state"`
+	Targets   TargetsConfig   `mapstructure:"targets"`
+	Serve     ServeConfig     `mapstructure:"serve"`
+
+	Translation   TranslationConfig   `mapstructure:"translation"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+
+	// tokenIssued and tokenReceived date the tokens set by SetTokens in this
+	// process, by the Trakt and the local monotonic clock
+	tokenIssued   time.Time
+	tokenReceived time.Time
+}
+
+// ServeConfig controls the list feeds of trakt-sync serve
+type ServeConfig struct {
+	// Address is the listen address, e.g. ":7979"
+	Address string `mapstructure:"address"`
+	// CacheTTL is how long resolved lists are served before they are
+	// fetched again (0 = 1h)
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// TargetsConfig sets up media servers that get a copy of the synced lists
+type TargetsConfig struct {
+	Jellyfin JellyfinConfig `mapstructure:"jellyfin"`
+}
+
+// JellyfinConfig mirrors synced lists into Jellyfin (or Emby) collections
+type JellyfinConfig struct {
+	// Enabled updates a collection per synced list after each sync
+	Enabled bool `mapstructure:"enabled"`
+	// URL is the server address, e.g. http://jellyfin:8096
+	URL    string `mapstructure:"url"`
+	APIKey string `mapstructure:"api_key"`
+	// Lists are the list slugs to mirror (default: all synced lists)
+	Lists []string `mapstructure:"lists"`
+}
+
+// StateConfig selects where the state between runs is stored
+type StateConfig struct {
+	// Backend is file (state.json in the data directory), sqlite or redis
+	Backend string `mapstructure:"backend"`
+	// Path is the SQLite database (default: state.db in the data directory)
+	Path  string           `mapstructure:"path"`
+	Redis RedisStateConfig `mapstructure:"redis"`
+}
+
+// RedisStateConfig locates the state in Redis
+type RedisStateConfig struct {
+	// Address is host:port of the Redis server
+	Address  string `mapstructure:"address"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	// Key holds the state document (default: trakt-sync:state)
+	Key string `mapstructure:"key"`
+}
+
+// PlexConfig sets up pushing synced lists to a Plex watchlist with
+// sync --target plex
+type PlexConfig struct {
+	// Token is the Plex account token (X-Plex-Token)
+	Token string `mapstructure:"token"`
+	// APIURL overrides the Plex Discover API base URL
+	APIURL string `mapstructure:"api_url"`
+	// Lists are the list slugs whose items go on the watchlist (default: all
+	// enabled lists)
+	Lists []string `mapstructure:"lists"`
+}
+
+// NotificationsConfig sets up notifications about finished syncs
+type NotificationsConfig struct {
+	Desktop DesktopNotificationsConfig `mapstructure:"desktop"`
+}
+
+// DesktopNotificationsConfig shows native desktop notifications through
+// notify-send, osascript or a PowerShell toast
+type DesktopNotificationsConfig struct {
+	// Enabled notifies of failed syncs and of Trakt rejecting the tokens
+	Enabled bool `mapstructure:"enabled"`
+	// OnSuccess also notifies of syncs without failed lists
+	OnSuccess bool `mapstructure:"on_success"`
+}
+
+// TranslationConfig sets up the translation provider for bilingual list
+// descriptions
+type TranslationConfig struct {
+	// Provider is deepl or libretranslate (empty = off)
+	Provider string `mapstructure:"provider"`
+	// URL is the provider endpoint; required for LibreTranslate, DeepL picks
+	// its free or pro endpoint from the API key
+	URL    string `mapstructure:"url"`
+	APIKey string `mapstructure:"api_key"`
+	// SourceLanguage is the language of the description templates (default
+	// de), TargetLanguage the language they are translated to (default en)
+	SourceLanguage string `mapstructure:"source_language"`
+	TargetLanguage string `mapstructure:"target_language"`
+}
+
+// ArchiveConfig controls the chart archive
+type ArchiveConfig struct {
+	// Enabled stores the trending and watched chart responses of every sync
+	// in the archive directory next to the state file
+	Enabled bool `mapstructure:"enabled"`
 }
 
 // TraktConfig holds Trakt.tv API credentials and tokens
@@ -27,22 +146,172 @@ type TraktConfig struct {
 	AccessToken  string    `mapstructure:"access_token"`
 	RefreshToken string    `mapstructure:"refresh_token"`
 	TokenExpires time.Time `mapstructure:"token_expires_at"`
+	// ClockOffset is how far the Trakt clock was ahead of the local clock
+	// when the tokens were issued. TokenExpires is Trakt time, so expiry is
+	// checked against the local clock plus this offset.
+	ClockOffset time.Duration `mapstructure:"clock_offset"`
+	// APIURL overrides the Trakt API base URL, e.g. for a local fake server
+	APIURL string `mapstructure:"api_url"`
+	// Language shows localized titles in output, e.g. "de" (empty = original)
+	Language string `mapstructure:"language"`
+	// Environment selects the Trakt API: production or staging
+	Environment string `mapstructure:"environment"`
 }
 
 // SyncConfig defines sync behavior
 type SyncConfig struct {
-	Limit           int              `mapstructure:"limit"`
-	MinRating       int              `mapstructure:"min_rating"`
-	ListPrivacy     string           `mapstructure:"list_privacy"`
-	FullRefreshDays int              `mapstructure:"full_refresh_days"`
-	LastFullRefresh FullRefreshState `mapstructure:"last_full_refresh"`
-	Lists           ListSyncConfig   `mapstructure:"lists"`
+	Limit     int `mapstructure:"limit"`
+	MinRating int `mapstructure:"min_rating"`
+	// WatchedPeriod is the time window of the most watched charts: daily,
+	// weekly, monthly, yearly or all
+	WatchedPeriod string `mapstructure:"watched_period"`
+	// MinYear keeps titles released before this year out of chart sources
+	// (0 = no limit)
+	MinYear int `mapstructure:"min_year"`
+	// ExcludeCollected keeps titles in the user's collection out of the
+	// generated lists
+	ExcludeCollected bool `mapstructure:"exclude_collected"`
+	// MaxAgeYears keeps titles older than this many years out of chart
+	// sources (0 = no limit)
+	MaxAgeYears int `mapstructure:"max_age_years"`
+	// Languages keeps only chart titles originally in these two-letter
+	// languages
+	Languages []string `mapstructure:"languages"`
+	// Countries keeps only chart titles from these two-letter countries
+	Countries       []string              `mapstructure:"countries"`
+	ListPrivacy     string                `mapstructure:"list_privacy"`
+	FullRefreshDays int                   `mapstructure:"full_refresh_days"`
+	LastFullRefresh FullRefreshState      `mapstructure:"last_full_refresh"`
+	Lists           ListSyncConfig        `mapstructure:"lists"`
+	RatingsList     RatingsListConfig     `mapstructure:"ratings_list"`
+	RecentlyWatched RecentlyWatchedConfig `mapstructure:"recently_watched"`
+	Anime           AnimeListConfig       `mapstructure:"anime"`
+	// StreamingTop10 generates one top 10 list per streaming service
+	StreamingTop10 StreamingTop10Config `mapstructure:"streaming_top10"`
+	// CustomLists are additional lists built from chart sources
+	CustomLists []CustomListConfig `mapstructure:"custom_lists"`
+	// Mirrors copy public Trakt lists, e.g. official ones, into managed lists
+	Mirrors []MirrorConfig `mapstructure:"mirrors"`
+	// Split fans a list out into one list per group, keyed by list slug
+	Split map[string]SplitConfig `mapstructure:"split"`
+	// MinItems skips removals when a source returns fewer items (0 = disabled)
+	MinItems         int                    `mapstructure:"min_items"`
+	AnomalyDetection AnomalyDetectionConfig `mapstructure:"anomaly_detection"`
+	// Sources sets client-side thresholds for the chart sources
+	Sources SourcesConfig `mapstructure:"sources"`
+	// BoxOffice also merges the weekend's top 10 box office movies into the
+	// movie list
+	BoxOffice bool `mapstructure:"box_office"`
+	// ListDisplay sets Trakt display options per list, keyed by list slug
+	ListDisplay map[string]ListDisplayConfig `mapstructure:"list_display"`
+	// Reorder ranks the items of each list in source order after a sync,
+	// e.g. trending before most watched
+	Reorder bool `mapstructure:"reorder"`
+	// DeleteEmptyAfterRuns deletes a list after this many consecutive runs
+	// left it empty (0 = never)
+	DeleteEmptyAfterRuns int `mapstructure:"delete_empty_after_runs"`
+	// ConflictPolicy decides what happens when a managed list was edited
+	// outside trakt-sync: overwrite, preserve_manual or skip
+	ConflictPolicy string `mapstructure:"conflict_policy"`
+	// ConflictPolicies overrides ConflictPolicy per list, keyed by list slug
+	ConflictPolicies map[string]string `mapstructure:"conflict_policies"`
+	// RespectManualRemovals never re-adds items someone removed from a
+	// managed list on Trakt
+	RespectManualRemovals bool `mapstructure:"respect_manual_removals"`
+	// ReaddCooldownDays keeps items trakt-sync removed from a list out of it
+	// for this many days, even if their source returns them again (0 = off)
+	ReaddCooldownDays int `mapstructure:"readd_cooldown_days"`
+	// Pins are IMDb IDs that are always in a list, keyed by list slug
+	Pins map[string][]string `mapstructure:"pins"`
+	// ItemNotes are Go templates for the notes of items added to a list,
+	// keyed by list slug, with the fields .Source, .Rank, .Added, .Title,
+	// .Year and .List. Notes require Trakt VIP.
+	ItemNotes map[string]string `mapstructure:"item_notes"`
+	// DescriptionTemplates are Go templates for list descriptions, keyed by
+	// list slug, with the fields .List, .Items, .Top and .Updated. With a
+	// translation provider, the translation is appended.
+	DescriptionTemplates map[string]string `mapstructure:"description_templates"`
+}
+
+// NoteData holds the fields of a sync.item_notes template
+type NoteData struct {
+	// Source names where the item came from, e.g. "trending", "IMDb
+	// ls012345678" or "pin"; empty for lists without named sources
+	Source string
+	// Rank is the position of the item in the list, starting at 1
+	Rank int
+	// Added is the date the item was added, as 2006-01-02
+	Added string
+	Title string
+	Year  int
+	// List is the name of the list
+	List string
+}
+
+// DescriptionData holds the fields of a sync.description_templates template
+type DescriptionData struct {
+	// List is the name of the list
+	List string
+	// Items is the number of items in the list
+	Items int
+	// Top are the titles of the first items in list order
+	Top []string
+	// Updated is the date of the sync, as 2006-01-02
+	Updated string
+}
+
+// ListDisplayConfig holds the Trakt display options of a list. Unset options
+// are left as they are on Trakt; new lists are numbered, without comments and
+// sorted by rank.
+type ListDisplayConfig struct {
+	DisplayNumbers *bool  `mapstructure:"display_numbers"`
+	AllowComments  *bool  `mapstructure:"allow_comments"`
+	SortBy         string `mapstructure:"sort_by"`
+	SortHow        string `mapstructure:"sort_how"`
+}
+
+// AnomalyDetectionConfig defines when chart sources are flagged as anomalous
+type AnomalyDetectionConfig struct {
+	// IdenticalRuns warns after this many consecutive unchanged results (0 = disabled)
+	IdenticalRuns int `mapstructure:"identical_runs"`
+	// FullChurnRuns warns after this many consecutive completely replaced results (0 = disabled)
+	FullChurnRuns int `mapstructure:"full_churn_runs"`
+}
+
+// SourcesConfig holds the chart source thresholds for movies and shows
+type SourcesConfig struct {
+	Movies ChartSourcesConfig `mapstructure:"movies"`
+	Shows  ChartSourcesConfig `mapstructure:"shows"`
+}
+
+// ChartSourcesConfig holds the thresholds of the charts of one media type
+type ChartSourcesConfig struct {
+	Trending TrendingSourceConfig `mapstructure:"trending"`
+	Watched  WatchedSourceConfig  `mapstructure:"watched"`
+}
+
+// TrendingSourceConfig filters the trending chart
+type TrendingSourceConfig struct {
+	// MinWatchers drops items with fewer people watching right now (0 = disabled)
+	MinWatchers int `mapstructure:"min_watchers"`
+}
+
+// WatchedSourceConfig filters the most watched chart
+type WatchedSourceConfig struct {
+	// MinPlays drops items played fewer times in sync.watched_period
+	// (0 = disabled)
+	MinPlays int `mapstructure:"min_plays"`
+	// MinWatcherCount drops items fewer people watched in
+	// sync.watched_period (0 = disabled)
+	MinWatcherCount int `mapstructure:"min_watcher_count"`
 }
 
 // FullRefreshState keeps track of weekly full refresh timestamps.
 type FullRefreshState struct {
-	Movies time.Time `mapstructure:"movies"`
-	Shows  time.Time `mapstructure:"shows"`
+	Movies time.Time `mapstructure:"movies" json:"movies"`
+	Shows  time.Time `mapstructure:"shows" json:"shows"`
+	// Lists holds timestamps for all other lists, keyed by list slug
+	Lists map[string]time.Time `mapstructure:"lists" json:"lists,omitempty"`
 }
 
 // ListSyncConfig defines which lists to sync
@@ -51,12 +320,224 @@ type ListSyncConfig struct {
 	Shows  bool `mapstructure:"shows"`
 }
 
+// RatingsListConfig defines the list built from the user's own ratings
+type RatingsListConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Type      string `mapstructure:"type"`
+	MinRating int    `mapstructure:"min_rating"`
+	Year      int    `mapstructure:"year"`
+	Limit     int    `mapstructure:"limit"`
+	Privacy   string `mapstructure:"privacy"`
+}
+
+// RecentlyWatchedConfig defines the list built from the user's watch history
+type RecentlyWatchedConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Type    string `mapstructure:"type"`
+	Limit   int    `mapstructure:"limit"`
+	Days    int    `mapstructure:"days"`
+	Privacy string `mapstructure:"privacy"`
+}
+
+// CustomListConfig defines a list built from chart sources
+type CustomListConfig struct {
+	// Slug defaults to the slug Trakt derives from Name
+	Slug        string `mapstructure:"slug"`
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	// Enabled defaults to true
+	Enabled *bool  `mapstructure:"enabled"`
+	Type    string `mapstructure:"type"`
+	// Sources are the charts merged into the list: trending, popular, watched
+	Sources []string `mapstructure:"sources"`
+	// IMDbLists are public IMDb lists merged in after the chart sources: list
+	// IDs like ls012345678, list URLs or paths of CSV exports
+	IMDbLists []string `mapstructure:"imdb_lists"`
+	// MDBLists are public MDBList lists merged in after the IMDb lists, as
+	// user/slug or list URLs
+	MDBLists []string `mapstructure:"mdblists"`
+	// Limit overrides sync.limit per source when greater than 0
+	Limit   int    `mapstructure:"limit"`
+	Privacy string `mapstructure:"privacy"`
+	// Preset fills in the filters below and privacy, e.g. family
+	Preset string `mapstructure:"preset"`
+	// Certifications keeps only titles with these US certifications, e.g.
+	// g and pg for movies or tv-g and tv-pg for shows
+	Certifications []string `mapstructure:"certifications"`
+	// ExcludeGenres drops titles with any of these genre slugs
+	ExcludeGenres []string `mapstructure:"exclude_genres"`
+	// Countries keeps only titles from these two-letter country codes
+	Countries []string `mapstructure:"countries"`
+	// Networks keeps only shows of these networks, e.g. Netflix
+	Networks []string `mapstructure:"networks"`
+}
+
+// MirrorConfig defines a managed list that mirrors a public Trakt list
+type MirrorConfig struct {
+	// ListID is the Trakt ID of the list to mirror
+	ListID int    `mapstructure:"list_id"`
+	Name   string `mapstructure:"name"`
+	// Enabled defaults to true
+	Enabled *bool `mapstructure:"enabled"`
+	// Type selects the movies or shows of the list; managed lists hold one kind
+	Type string `mapstructure:"type"`
+	// Limit keeps only the first items of the list (0 = all)
+	Limit   int    `mapstructure:"limit"`
+	Privacy string `mapstructure:"privacy"`
+}
+
+// PresetFamily is the custom list preset for kids and family lists
+const PresetFamily = "family"
+
+// listPresets are the filters a custom list preset expands into
+var listPresets = map[string]CustomListConfig{
+	PresetFamily: {
+		Privacy: "private",
+		// Trakt filters by US ratings; FSK 0 and 6 titles are rated G or PG.
+		Certifications: []string{"g", "pg", "tv-y", "tv-y7", "tv-g", "tv-pg"},
+		ExcludeGenres:  []string{"horror", "thriller", "crime", "war"},
+	},
+}
+
+// Expanded returns the list with its preset applied. Values set on the list
+// take precedence over the preset's.
+func (l CustomListConfig) Expanded() CustomListConfig {
+	preset, ok := listPresets[l.Preset]
+	if !ok {
+		return l
+	}
+	if l.Privacy == "" {
+		l.Privacy = preset.Privacy
+	}
+	if len(l.Certifications) == 0 {
+		l.Certifications = preset.Certifications
+	}
+	if len(l.ExcludeGenres) == 0 {
+		l.ExcludeGenres = preset.ExcludeGenres
+	}
+	return l
+}
+
+// AnimeListConfig defines the list of trending and most watched anime
+type AnimeListConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Type    string `mapstructure:"type"`
+	Limit   int    `mapstructure:"limit"`
+	Privacy string `mapstructure:"privacy"`
+	// MappingURL points to an anime ID mapping (JSON array with themoviedb_id,
+	// imdb_id, anilist_id and mal_id). When set, only titles it lists are
+	// kept, which leaves out western animation tagged as anime.
+	MappingURL string `mapstructure:"mapping_url"`
+}
+
+// StreamingTop10Config defines the per-service top 10 lists. Each service
+// gets a list of the titles most watched this week that are available on it
+// in Country, approximating the service's own top 10.
+type StreamingTop10Config struct {
+	// Services are the streaming services to build lists for, e.g. netflix
+	Services []string `mapstructure:"services"`
+	// Country is the two-letter code of the country to check availability in
+	Country string `mapstructure:"country"`
+	Type    string `mapstructure:"type"`
+	Privacy string `mapstructure:"privacy"`
+}
+
+// StreamingServices maps the supported Trakt watchnow service slugs to their
+// display names
+var StreamingServices = map[string]string{
+	"netflix":        "Netflix",
+	"disney_plus":    "Disney+",
+	"amazon_prime":   "Prime Video",
+	"apple_tv_plus":  "Apple TV+",
+	"paramount_plus": "Paramount+",
+}
+
+// SplitConfig defines how a list is fanned out into multiple lists
+type SplitConfig struct {
+	// By selects the grouping: genre, decade or year
+	By string `mapstructure:"by"`
+	// Genres restricts genre fan-out to these genre slugs (empty = all)
+	Genres []string `mapstructure:"genres"`
+	// NameTemplate is a Go template for child list names with the fields
+	// .Name (parent list name), .Group (e.g. "Horror", "1990s") and .Key
+	NameTemplate string `mapstructure:"name_template"`
+}
+
+// WatchlistConfig defines watchlist maintenance run after each sync
+type WatchlistConfig struct {
+	// PruneAfterDays removes watchlist items older than this many days (0 = disabled)
+	PruneAfterDays int `mapstructure:"prune_after_days"`
+	// RemoveWatched removes watchlist items that are in the watch history
+	RemoveWatched bool `mapstructure:"remove_watched"`
+	// ExcludeWatched drops watched movies and shows from the synced lists
+	ExcludeWatched bool `mapstructure:"exclude_watched"`
+	DryRun         bool `mapstructure:"dry_run"`
+}
+
+// DaemonConfig defines daemon mode behavior
+type DaemonConfig struct {
+	// Interval between syncs; the --interval flag takes precedence (0 = 6h)
+	Interval time.Duration `mapstructure:"interval"`
+	// WatchConfig reloads the config file when it changes
+	WatchConfig bool `mapstructure:"watch_config"`
+	// MaintenanceRetry is how soon a run skipped during a Trakt outage is
+	// retried, if sooner than the interval (0 = 15m)
+	MaintenanceRetry time.Duration `mapstructure:"maintenance_retry"`
+	// MaxRuntime ends a sync that runs longer after the list at hand; the
+	// next run syncs the rest (0 = no limit)
+	MaxRuntime time.Duration `mapstructure:"max_runtime"`
+	// QuietHours are local time windows like "23:00-07:00" without syncs;
+	// a run due in one is deferred to its end
+	QuietHours []string `mapstructure:"quiet_hours"`
+	// SkipOnBattery skips scheduled syncs while a laptop runs on battery
+	SkipOnBattery bool `mapstructure:"skip_on_battery"`
+	// SkipOnMetered skips scheduled syncs while NetworkManager reports a
+	// metered connection
+	SkipOnMetered bool `mapstructure:"skip_on_metered"`
+	// MetricsAddress is the listen address of /healthz and the Prometheus
+	// /metrics, e.g. ":9464" (empty = off)
+	MetricsAddress string `mapstructure:"metrics_address"`
+	// Reauth starts a device authorization when Trakt rejects the tokens for
+	// good, logging the code to enter on trakt.tv
+	Reauth bool `mapstructure:"reauth"`
+	// LeaderElection lets only one of several daemon replicas sync
+	LeaderElection LeaderElectionConfig `mapstructure:"leader_election"`
+}
+
+// LeaderElectionConfig sets up the lease of redundant daemons on a shared
+// sqlite or redis state backend
+type LeaderElectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// LeaseTTL is how long a lease lasts without renewal, i.e. how soon a
+	// standby takes over from a failed leader (0 = 30s)
+	LeaseTTL time.Duration `mapstructure:"lease_ttl"`
+	// ID names this replica (default: host name and process ID)
+	ID string `mapstructure:"id"`
+}
+
 // LoggingConfig defines logging behavior
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
 }
 
+// TelemetryConfig defines the opt-in anonymous usage reports
+type TelemetryConfig struct {
+	// Enabled posts the command, its outcome, version and OS after each run
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint receives the reports as JSON
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// UpdatesConfig defines the opt-in release manifest checked by status
+type UpdatesConfig struct {
+	// ManifestURL is fetched for new releases, Trakt API changes and
+	// deprecated config keys (empty = disabled)
+	ManifestURL string `mapstructure:"manifest_url"`
+	// Channel selects the releases to announce: stable or beta
+	Channel string `mapstructure:"channel"`
+}
+
 // DefaultConfigPath returns the default config file path
 func DefaultConfigPath() string {
 	home, err := os.UserHomeDir()
@@ -66,8 +547,19 @@ func DefaultConfigPath() string {
 	return filepath.Join(home, ".config", "trakt-sync", "config.yaml")
 }
 
-// Load reads and parses the config file
+// Load reads and parses the config file. A missing file is created from the
+// defaults first.
 func Load(configPath string) (*Config, error) {
+	return load(configPath, true)
+}
+
+// LoadExisting reads and parses the config file like Load, but fails if the
+// file does not exist instead of creating it or its directory
+func LoadExisting(configPath string) (*Config, error) {
+	return load(configPath, false)
+}
+
+func load(configPath string, create bool) (*Config, error) {
 	if configPath == "" {
 		configPath = DefaultConfigPath()
 	}
@@ -78,27 +570,31 @@ func Load(configPath string) (*Config, error) {
 
 	setDefaults(v)
 
-	configDir := filepath.Dir(configPath)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
 	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			if err := createDefaultConfig(configPath); err != nil {
-				return nil, fmt.Errorf("failed to create default config: %w", err)
-			}
-			if err := v.ReadInConfig(); err != nil {
-				return nil, fmt.Errorf("failed to read config: %w", err)
-			}
-		} else {
+		if !isNotFound(err) {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		if !create {
+			return nil, fmt.Errorf("config file %s does not exist", configPath)
+		}
+		if err := createDefaultConfig(configPath); err != nil {
+			return nil, fmt.Errorf("failed to create default config: %w", err)
+		}
+		if err := v.ReadInConfig(); err != nil {
 			return nil, fmt.Errorf("failed to read config: %w", err)
 		}
 	}
 
 	var cfg Config
-	decodeHook := mapstructure.ComposeDecodeHookFunc(stringToTimeHook())
+	decodeHook := mapstructure.ComposeDecodeHookFunc(
+		stringToTimeHook(),
+		stringToDurationHook(),
+	)
 	if err := v.Unmarshal(&cfg, viper.DecodeHook(decodeHook)); err != nil {
+		// The schema check points at the offending keys more clearly.
+		if problems := checkSettings(v); len(problems) > 0 {
+			return nil, fmt.Errorf("invalid config: %w", problems)
+		}
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
@@ -135,41 +631,664 @@ func Save(cfg *Config, configPath string) error {
 	} else {
 		v.Set("trakt.token_expires_at", cfg.Trakt.TokenExpires.Format(time.RFC3339))
 	}
+	// Only written for clocks that are off, which most are not.
+	if cfg.Trakt.ClockOffset != 0 {
+		v.Set("trakt.clock_offset", cfg.Trakt.ClockOffset.String())
+	}
+	// Only written when set so regular configs keep pointing at the real API.
+	if cfg.Trakt.APIURL != "" {
+		v.Set("trakt.api_url", cfg.Trakt.APIURL)
+	}
+	v.Set("trakt.language", cfg.Trakt.Language)
+	v.Set("trakt.environment", cfg.Trakt.Environment)
 
 	v.Set("sync.limit", cfg.Sync.Limit)
 	v.Set("sync.min_rating", cfg.Sync.MinRating)
+	v.Set("sync.watched_period", cfg.Sync.WatchedPeriod)
+	v.Set("sync.min_year", cfg.Sync.MinYear)
+	v.Set("sync.exclude_collected", cfg.Sync.ExcludeCollected)
+	v.Set("sync.max_age_years", cfg.Sync.MaxAgeYears)
+	v.Set("sync.languages", cfg.Sync.Languages)
+	v.Set("sync.countries", cfg.Sync.Countries)
 	v.Set("sync.list_privacy", privacy)
 	v.Set("sync.full_refresh_days", cfg.Sync.FullRefreshDays)
+	v.Set("sync.min_items", cfg.Sync.MinItems)
+	v.Set("sync.list_display", listDisplaySettings(cfg.Sync.ListDisplay))
+	v.Set("sync.reorder", cfg.Sync.Reorder)
+	v.Set("sync.delete_empty_after_runs", cfg.Sync.DeleteEmptyAfterRuns)
+	v.Set("sync.conflict_policy", cfg.Sync.ConflictPolicy)
+	v.Set("sync.conflict_policies", stringMapSettings(cfg.Sync.ConflictPolicies))
+	v.Set("sync.respect_manual_removals", cfg.Sync.RespectManualRemovals)
+	v.Set("sync.readd_cooldown_days", cfg.Sync.ReaddCooldownDays)
+	v.Set("sync.pins", pinSettings(cfg.Sync.Pins))
+	v.Set("sync.item_notes", stringMapSettings(cfg.Sync.ItemNotes))
+	v.Set("sync.description_templates", stringMapSettings(cfg.Sync.DescriptionTemplates))
+	v.Set("sync.anomaly_detection.identical_runs", cfg.Sync.AnomalyDetection.IdenticalRuns)
+	v.Set("sync.anomaly_detection.full_churn_runs", cfg.Sync.AnomalyDetection.FullChurnRuns)
+	v.Set("sync.box_office", cfg.Sync.BoxOffice)
+	v.Set("sync.sources.movies.trending.min_watchers", cfg.Sync.Sources.Movies.Trending.MinWatchers)
+	v.Set("sync.sources.shows.trending.min_watchers", cfg.Sync.Sources.Shows.Trending.MinWatchers)
+	v.Set("sync.sources.movies.watched.min_plays", cfg.Sync.Sources.Movies.Watched.MinPlays)
+	v.Set("sync.sources.movies.watched.min_watcher_count", cfg.Sync.Sources.Movies.Watched.MinWatcherCount)
+	v.Set("sync.sources.shows.watched.min_plays", cfg.Sync.Sources.Shows.Watched.MinPlays)
+	v.Set("sync.sources.shows.watched.min_watcher_count", cfg.Sync.Sources.Shows.Watched.MinWatcherCount)
 	v.Set("sync.last_full_refresh.movies", formatTimeOrEmpty(cfg.Sync.LastFullRefresh.Movies))
 	v.Set("sync.last_full_refresh.shows", formatTimeOrEmpty(cfg.Sync.LastFullRefresh.Shows))
+	v.Set("sync.last_full_refresh.lists", formatTimeMap(cfg.Sync.LastFullRefresh.Lists))
 	v.Set("sync.lists.movies", cfg.Sync.Lists.Movies)
 	v.Set("sync.lists.shows", cfg.Sync.Lists.Shows)
+	v.Set("sync.ratings_list.enabled", cfg.Sync.RatingsList.Enabled)
+	v.Set("sync.ratings_list.type", cfg.Sync.RatingsList.Type)
+	v.Set("sync.ratings_list.min_rating", cfg.Sync.RatingsList.MinRating)
+	v.Set("sync.ratings_list.year", cfg.Sync.RatingsList.Year)
+	v.Set("sync.ratings_list.limit", cfg.Sync.RatingsList.Limit)
+	v.Set("sync.ratings_list.privacy", cfg.Sync.RatingsList.Privacy)
+	v.Set("sync.recently_watched.enabled", cfg.Sync.RecentlyWatched.Enabled)
+	v.Set("sync.recently_watched.type", cfg.Sync.RecentlyWatched.Type)
+	v.Set("sync.recently_watched.limit", cfg.Sync.RecentlyWatched.Limit)
+	v.Set("sync.recently_watched.days", cfg.Sync.RecentlyWatched.Days)
+	v.Set("sync.recently_watched.privacy", cfg.Sync.RecentlyWatched.Privacy)
+	v.Set("sync.custom_lists", customListSettings(cfg.Sync.CustomLists))
+	v.Set("sync.mirrors", mirrorSettings(cfg.Sync.Mirrors))
+	v.Set("sync.anime.enabled", cfg.Sync.Anime.Enabled)
+	v.Set("sync.anime.type", cfg.Sync.Anime.Type)
+	v.Set("sync.anime.limit", cfg.Sync.Anime.Limit)
+	v.Set("sync.anime.privacy", cfg.Sync.Anime.Privacy)
+	v.Set("sync.anime.mapping_url", cfg.Sync.Anime.MappingURL)
+	v.Set("sync.streaming_top10.services", cfg.Sync.StreamingTop10.Services)
+	v.Set("sync.streaming_top10.country", cfg.Sync.StreamingTop10.Country)
+	v.Set("sync.streaming_top10.type", cfg.Sync.StreamingTop10.Type)
+	v.Set("sync.streaming_top10.privacy", cfg.Sync.StreamingTop10.Privacy)
+
+	v.Set("sync.split", splitSettings(cfg.Sync.Split))
+
+	v.Set("watchlist.prune_after_days", cfg.Watchlist.PruneAfterDays)
+	v.Set("watchlist.remove_watched", cfg.Watchlist.RemoveWatched)
+	v.Set("watchlist.exclude_watched", cfg.Watchlist.ExcludeWatched)
+	v.Set("watchlist.dry_run", cfg.Watchlist.DryRun)
+
+	v.Set("daemon.interval", formatDurationOrEmpty(cfg.Daemon.Interval))
+	v.Set("daemon.watch_config", cfg.Daemon.WatchConfig)
+	v.Set("daemon.maintenance_retry", formatDurationOrEmpty(cfg.Daemon.MaintenanceRetry))
+	v.Set("daemon.max_runtime", formatDurationOrEmpty(cfg.Daemon.MaxRuntime))
+	v.Set("daemon.quiet_hours", cfg.Daemon.QuietHours)
+	v.Set("daemon.skip_on_battery", cfg.Daemon.SkipOnBattery)
+	v.Set("daemon.skip_on_metered", cfg.Daemon.SkipOnMetered)
+	v.Set("daemon.metrics_address", cfg.Daemon.MetricsAddress)
+	v.Set("daemon.reauth", cfg.Daemon.Reauth)
+	v.Set("daemon.leader_election.enabled", cfg.Daemon.LeaderElection.Enabled)
+	v.Set("daemon.leader_election.lease_ttl", formatDurationOrEmpty(cfg.Daemon.LeaderElection.LeaseTTL))
+	if cfg.Daemon.LeaderElection.ID != "" {
+		v.Set("daemon.leader_election.id", cfg.Daemon.LeaderElection.ID)
+	}
 
 	v.Set("logging.level", cfg.Logging.Level)
 	v.Set("logging.format", cfg.Logging.Format)
 
+	v.Set("archive.enabled", cfg.Archive.Enabled)
+	v.Set("plex.token", cfg.Plex.Token)
+	if cfg.Plex.APIURL != "" {
+		v.Set("plex.api_url", cfg.Plex.APIURL)
+	}
+	v.Set("plex.lists", cfg.Plex.Lists)
+	v.Set("targets.jellyfin.enabled", cfg.Targets.Jellyfin.Enabled)
+	v.Set("targets.jellyfin.url", cfg.Targets.Jellyfin.URL)
+	v.Set("targets.jellyfin.api_key", cfg.Targets.Jellyfin.APIKey)
+	v.Set("targets.jellyfin.lists", cfg.Targets.Jellyfin.Lists)
+	v.Set("translation.provider", cfg.Translation.Provider)
+	v.Set("translation.url", cfg.Translation.URL)
+	v.Set("translation.api_key", cfg.Translation.APIKey)
+	v.Set("translation.source_language", cfg.Translation.SourceLanguage)
+	v.Set("translation.target_language", cfg.Translation.TargetLanguage)
+	v.Set("notifications.desktop.enabled", cfg.Notifications.Desktop.Enabled)
+	v.Set("notifications.desktop.on_success", cfg.Notifications.Desktop.OnSuccess)
+	v.Set("serve.address", cfg.Serve.Address)
+	v.Set("serve.cache_ttl", formatDurationOrEmpty(cfg.Serve.CacheTTL))
+	v.Set("state.backend", cfg.State.Backend)
+	if cfg.State.Path != "" {
+		v.Set("state.path", cfg.State.Path)
+	}
+	if cfg.State.Backend == "redis" {
+		v.Set("state.redis.address", cfg.State.Redis.Address)
+		v.Set("state.redis.password", cfg.State.Redis.Password)
+		v.Set("state.redis.db", cfg.State.Redis.DB)
+		v.Set("state.redis.key", cfg.State.Redis.Key)
+	}
+	v.Set("telemetry.enabled", cfg.Telemetry.Enabled)
+	v.Set("telemetry.endpoint", cfg.Telemetry.Endpoint)
+
+	v.Set("updates.manifest_url", cfg.Updates.ManifestURL)
+	v.Set("updates.channel", cfg.Updates.Channel)
+
 	return v.WriteConfigAs(configPath)
 }
 
-// Validate checks if the config is valid
+// ValidationError describes one invalid config value
+type ValidationError struct {
+	// Path is the YAML path of the offending key, e.g. sync.limit
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + " " + e.Message
+}
+
+// ValidationErrors collects every problem found by Validate
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrorCode implements errcode.Coder
+func (e ValidationErrors) ErrorCode() errcode.Code {
+	return errcode.InvalidConfig
+}
+
+func (e *ValidationErrors) add(path, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	listPrivacies    = []string{"private", "friends", "public"}
+	listTypes        = []string{"movies", "shows"}
+	chartSources     = []string{"trending", "popular", "watched", "anticipated", "premieres", "recommendations"}
+	watchedPeriods   = []string{"daily", "weekly", "monthly", "yearly", "all"}
+	splitKinds       = []string{"genre", "decade", "year"}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+	logFormats       = []string{"text", "json"}
+	listSortBy       = []string{"rank", "added", "title", "released", "runtime", "popularity", "percentage", "votes", "my_rating", "random", "watched", "collected"}
+	listSortHow      = []string{"asc", "desc"}
+	conflictPolicies = []string{ConflictOverwrite, ConflictPreserveManual, ConflictSkip}
+	releaseChannels  = []string{"stable", "beta"}
+	environments     = []string{EnvironmentProduction, EnvironmentStaging}
+	stateBackends    = []string{"file", "sqlite", "redis"}
+	// translationProviders mirrors the providers of package translate
+	translationProviders = []string{"deepl", "libretranslate"}
+)
+
+// Conflict policies for managed lists that were edited outside trakt-sync
+const (
+	ConflictOverwrite      = "overwrite"
+	ConflictPreserveManual = "preserve_manual"
+	ConflictSkip           = "skip"
+)
+
+// Trakt API environments
+const (
+	EnvironmentProduction = "production"
+	EnvironmentStaging    = "staging"
+)
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+var imdbIDPattern = regexp.MustCompile(`^tt[0-9]+$`)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func streamingServiceSlugs() []string {
+	slugs := make([]string, 0, len(StreamingServices))
+	for slug := range StreamingServices {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(listPresets))
+	for name := range listPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTemplate parses a template the way the syncer does and executes it
+// against sample, so unknown fields fail here and not on every sync
+func checkTemplate(name, text string, sample interface{}) error {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(io.Discard, sample)
+}
+
+// Validate checks if the config is valid. All problems are reported at once
+// as ValidationErrors.
 func (c *Config) Validate() error {
+	var errs ValidationErrors
+
 	if c.Trakt.ClientID == "" {
-		return fmt.Errorf("trakt.client_id is required")
+		errs.add("trakt.client_id", "is required")
 	}
 	if c.Trakt.ClientSecret == "" {
-		return fmt.Errorf("trakt.client_secret is required")
+		errs.add("trakt.client_secret", "is required")
 	}
 	if c.Trakt.Username == "" {
-		return fmt.Errorf("trakt.username is required")
+		errs.add("trakt.username", "is required")
+	}
+	if c.Trakt.APIURL != "" {
+		u, err := url.Parse(c.Trakt.APIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("trakt.api_url", "must be an http(s) URL")
+		}
+	}
+	if env := c.Trakt.Environment; env != "" && !oneOf(env, environments) {
+		errs.add("trakt.environment", "must be production or staging, got %q", env)
+	}
+	if lang := c.Trakt.Language; lang != "" && !languagePattern.MatchString(lang) {
+		errs.add("trakt.language", "must be a two-letter ISO 639-1 code such as de, got %q", lang)
 	}
+
 	if c.Sync.Limit <= 0 {
-		return fmt.Errorf("sync.limit must be greater than 0")
+		errs.add("sync.limit", "must be greater than 0")
+	}
+	if c.Sync.MinRating < 0 || c.Sync.MinRating > 100 {
+		errs.add("sync.min_rating", "must be between 0 and 100")
+	}
+	if period := c.Sync.WatchedPeriod; period != "" && !oneOf(period, watchedPeriods) {
+		errs.add("sync.watched_period", "must be one of %s, got %q", strings.Join(watchedPeriods, ", "), period)
+	}
+	if c.Sync.MinYear != 0 && (c.Sync.MinYear < 1800 || c.Sync.MinYear > 9999) {
+		errs.add("sync.min_year", "must be a four-digit year, got %d", c.Sync.MinYear)
+	}
+	if c.Sync.MaxAgeYears < 0 {
+		errs.add("sync.max_age_years", "must not be negative")
+	}
+	for i, lang := range c.Sync.Languages {
+		if !languagePattern.MatchString(lang) {
+			errs.add(fmt.Sprintf("sync.languages[%d]", i), "must be a two-letter ISO 639-1 code such as de, got %q", lang)
+		}
+	}
+	for i, country := range c.Sync.Countries {
+		if !languagePattern.MatchString(country) {
+			errs.add(fmt.Sprintf("sync.countries[%d]", i), "must be a two-letter lowercase country code, got %q", country)
+		}
 	}
-	if strings.TrimSpace(c.Sync.ListPrivacy) == "" {
-		return fmt.Errorf("sync.list_privacy is required")
+	if privacy := strings.TrimSpace(c.Sync.ListPrivacy); privacy == "" {
+		errs.add("sync.list_privacy", "is required")
+	} else if !oneOf(privacy, listPrivacies) {
+		errs.add("sync.list_privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), privacy)
 	}
 	if c.Sync.FullRefreshDays <= 0 {
-		return fmt.Errorf("sync.full_refresh_days must be greater than 0")
+		errs.add("sync.full_refresh_days", "must be greater than 0")
+	}
+	if c.Sync.MinItems < 0 {
+		errs.add("sync.min_items", "must not be negative")
+	}
+	displaySlugs := make([]string, 0, len(c.Sync.ListDisplay))
+	for slug := range c.Sync.ListDisplay {
+		displaySlugs = append(displaySlugs, slug)
+	}
+	sort.Strings(displaySlugs)
+	for _, slug := range displaySlugs {
+		display := c.Sync.ListDisplay[slug]
+		if display.SortBy != "" && !oneOf(display.SortBy, listSortBy) {
+			errs.add("sync.list_display."+slug+".sort_by", "must be one of %s, got %q", strings.Join(listSortBy, ", "), display.SortBy)
+		}
+		if display.SortHow != "" && !oneOf(display.SortHow, listSortHow) {
+			errs.add("sync.list_display."+slug+".sort_how", "must be asc or desc, got %q", display.SortHow)
+		}
+	}
+	if c.Sync.DeleteEmptyAfterRuns < 0 {
+		errs.add("sync.delete_empty_after_runs", "must not be negative")
+	}
+	if c.Sync.ReaddCooldownDays < 0 {
+		errs.add("sync.readd_cooldown_days", "must not be negative")
+	}
+	if policy := c.Sync.ConflictPolicy; policy != "" && !oneOf(policy, conflictPolicies) {
+		errs.add("sync.conflict_policy", "must be one of %s, got %q", strings.Join(conflictPolicies, ", "), policy)
+	}
+	policySlugs := make([]string, 0, len(c.Sync.ConflictPolicies))
+	for slug := range c.Sync.ConflictPolicies {
+		policySlugs = append(policySlugs, slug)
+	}
+	sort.Strings(policySlugs)
+	for _, slug := range policySlugs {
+		if policy := c.Sync.ConflictPolicies[slug]; !oneOf(policy, conflictPolicies) {
+			errs.add("sync.conflict_policies."+slug, "must be one of %s, got %q", strings.Join(conflictPolicies, ", "), policy)
+		}
+	}
+	pinSlugs := make([]string, 0, len(c.Sync.Pins))
+	for slug := range c.Sync.Pins {
+		pinSlugs = append(pinSlugs, slug)
+	}
+	sort.Strings(pinSlugs)
+	for _, slug := range pinSlugs {
+		for i, id := range c.Sync.Pins[slug] {
+			if !imdbIDPattern.MatchString(id) {
+				errs.add(fmt.Sprintf("sync.pins.%s[%d]", slug, i), "must be an IMDb ID like tt0111161, got %q", id)
+			}
+		}
+	}
+	noteSlugs := make([]string, 0, len(c.Sync.ItemNotes))
+	for slug := range c.Sync.ItemNotes {
+		noteSlugs = append(noteSlugs, slug)
+	}
+	sort.Strings(noteSlugs)
+	sampleNote := NoteData{Source: "trending", Rank: 1, Added: "2024-01-01", Title: "Title", Year: 2024, List: "List"}
+	for _, slug := range noteSlugs {
+		if err := checkTemplate(slug, c.Sync.ItemNotes[slug], sampleNote); err != nil {
+			errs.add("sync.item_notes."+slug, "is invalid: %v", err)
+		}
+	}
+	descriptionSlugs := make([]string, 0, len(c.Sync.DescriptionTemplates))
+	for slug := range c.Sync.DescriptionTemplates {
+		descriptionSlugs = append(descriptionSlugs, slug)
+	}
+	sort.Strings(descriptionSlugs)
+	sampleDescription := DescriptionData{List: "List", Items: 1, Top: []string{"Title"}, Updated: "2024-01-01"}
+	for _, slug := range descriptionSlugs {
+		if err := checkTemplate(slug, c.Sync.DescriptionTemplates[slug], sampleDescription); err != nil {
+			errs.add("sync.description_templates."+slug, "is invalid: %v", err)
+		}
+	}
+	if c.Sync.AnomalyDetection.IdenticalRuns < 0 {
+		errs.add("sync.anomaly_detection.identical_runs", "must not be negative")
+	}
+	if c.Sync.AnomalyDetection.FullChurnRuns < 0 {
+		errs.add("sync.anomaly_detection.full_churn_runs", "must not be negative")
+	}
+	for _, kind := range listTypes {
+		sources := c.Sync.Sources.Movies
+		if kind == "shows" {
+			sources = c.Sync.Sources.Shows
+		}
+		prefix := "sync.sources." + kind
+		if sources.Trending.MinWatchers < 0 {
+			errs.add(prefix+".trending.min_watchers", "must not be negative")
+		}
+		if sources.Watched.MinPlays < 0 {
+			errs.add(prefix+".watched.min_plays", "must not be negative")
+		}
+		if sources.Watched.MinWatcherCount < 0 {
+			errs.add(prefix+".watched.min_watcher_count", "must not be negative")
+		}
+	}
+
+	if ratings := c.Sync.RatingsList; ratings.Enabled {
+		if !oneOf(ratings.Type, listTypes) {
+			errs.add("sync.ratings_list.type", "must be movies or shows, got %q", ratings.Type)
+		}
+		if ratings.MinRating < 1 || ratings.MinRating > 10 {
+			errs.add("sync.ratings_list.min_rating", "must be between 1 and 10")
+		}
+		if ratings.Privacy != "" && !oneOf(ratings.Privacy, listPrivacies) {
+			errs.add("sync.ratings_list.privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), ratings.Privacy)
+		}
+	}
+
+	if recent := c.Sync.RecentlyWatched; recent.Enabled {
+		if !oneOf(recent.Type, listTypes) {
+			errs.add("sync.recently_watched.type", "must be movies or shows, got %q", recent.Type)
+		}
+		if recent.Days < 0 {
+			errs.add("sync.recently_watched.days", "must not be negative")
+		}
+		if recent.Privacy != "" && !oneOf(recent.Privacy, listPrivacies) {
+			errs.add("sync.recently_watched.privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), recent.Privacy)
+		}
+	}
+
+	customSlugs := make(map[string]bool, len(c.Sync.CustomLists))
+	for i, list := range c.Sync.CustomLists {
+		path := fmt.Sprintf("sync.custom_lists[%d]", i)
+		if list.Name == "" && list.Slug == "" {
+			errs.add(path+".name", "is required")
+		}
+		if list.Slug != "" && !slugPattern.MatchString(list.Slug) {
+			errs.add(path+".slug", "must contain only lowercase letters, digits and dashes, got %q", list.Slug)
+		} else if list.Slug != "" && list.Name != "" && Slugify(list.Name) != list.Slug {
+			// Trakt derives the slug from the name; a list created under
+			// another slug would never be found again.
+			errs.add(path+".slug", "must be %q, the slug Trakt derives from the name %q, got %q", Slugify(list.Name), list.Name, list.Slug)
+		}
+		if list.Slug != "" {
+			if customSlugs[list.Slug] {
+				errs.add(path+".slug", "%q is used by another custom list", list.Slug)
+			}
+			customSlugs[list.Slug] = true
+		}
+		if !oneOf(list.Type, listTypes) {
+			errs.add(path+".type", "must be movies or shows, got %q", list.Type)
+		}
+		if len(list.Sources) == 0 && len(list.IMDbLists) == 0 && len(list.MDBLists) == 0 {
+			errs.add(path+".sources", "must name at least one of %s, or set imdb_lists or mdblists", strings.Join(chartSources, ", "))
+		}
+		for j, source := range list.Sources {
+			if !oneOf(source, chartSources) {
+				errs.add(fmt.Sprintf("%s.sources[%d]", path, j), "must be one of %s, got %q", strings.Join(chartSources, ", "), source)
+			}
+		}
+		for j, imdbList := range list.IMDbLists {
+			if !imdb.ValidList(imdbList) {
+				errs.add(fmt.Sprintf("%s.imdb_lists[%d]", path, j), "must be an IMDb list ID like ls012345678, a list URL or a .csv file, got %q", imdbList)
+			}
+		}
+		for j, mdbList := range list.MDBLists {
+			if !mdblist.ValidList(mdbList) {
+				errs.add(fmt.Sprintf("%s.mdblists[%d]", path, j), "must be user/slug or an MDBList list URL, got %q", mdbList)
+			}
+		}
+		if list.Limit < 0 {
+			errs.add(path+".limit", "must not be negative")
+		}
+		if list.Privacy != "" && !oneOf(list.Privacy, listPrivacies) {
+			errs.add(path+".privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), list.Privacy)
+		}
+		if _, ok := listPresets[list.Preset]; list.Preset != "" && !ok {
+			errs.add(path+".preset", "must be one of %s, got %q", strings.Join(presetNames(), ", "), list.Preset)
+		}
+		for j, country := range list.Countries {
+			if !languagePattern.MatchString(country) {
+				errs.add(fmt.Sprintf("%s.countries[%d]", path, j), "must be a two-letter lowercase country code, got %q", country)
+			}
+		}
+		if len(list.Networks) > 0 && list.Type == "movies" {
+			errs.add(path+".networks", "only applies to lists of shows")
+		}
+	}
+
+	for i, mirror := range c.Sync.Mirrors {
+		path := fmt.Sprintf("sync.mirrors[%d]", i)
+		if mirror.ListID <= 0 {
+			errs.add(path+".list_id", "must be the Trakt ID of a list")
+		}
+		if mirror.Name == "" {
+			errs.add(path+".name", "is required")
+		}
+		if !oneOf(mirror.Type, listTypes) {
+			errs.add(path+".type", "must be movies or shows, got %q", mirror.Type)
+		}
+		if mirror.Limit < 0 {
+			errs.add(path+".limit", "must not be negative")
+		}
+		if mirror.Privacy != "" && !oneOf(mirror.Privacy, listPrivacies) {
+			errs.add(path+".privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), mirror.Privacy)
+		}
+	}
+
+	if top10 := c.Sync.StreamingTop10; len(top10.Services) > 0 {
+		for i, service := range top10.Services {
+			if _, ok := StreamingServices[service]; !ok {
+				errs.add(fmt.Sprintf("sync.streaming_top10.services[%d]", i), "must be one of %s, got %q", strings.Join(streamingServiceSlugs(), ", "), service)
+			}
+		}
+		if !languagePattern.MatchString(top10.Country) {
+			errs.add("sync.streaming_top10.country", "must be a two-letter lowercase country code, got %q", top10.Country)
+		}
+		if !oneOf(top10.Type, listTypes) {
+			errs.add("sync.streaming_top10.type", "must be movies or shows, got %q", top10.Type)
+		}
+		if top10.Privacy != "" && !oneOf(top10.Privacy, listPrivacies) {
+			errs.add("sync.streaming_top10.privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), top10.Privacy)
+		}
+	}
+
+	if anime := c.Sync.Anime; anime.Enabled {
+		if !oneOf(anime.Type, listTypes) {
+			errs.add("sync.anime.type", "must be movies or shows, got %q", anime.Type)
+		}
+		if anime.Limit < 0 {
+			errs.add("sync.anime.limit", "must not be negative")
+		}
+		if anime.Privacy != "" && !oneOf(anime.Privacy, listPrivacies) {
+			errs.add("sync.anime.privacy", "must be one of %s, got %q", strings.Join(listPrivacies, ", "), anime.Privacy)
+		}
+		if anime.MappingURL != "" {
+			u, err := url.Parse(anime.MappingURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs.add("sync.anime.mapping_url", "must be an http(s) URL")
+			}
+		}
+	}
+
+	slugs := make([]string, 0, len(c.Sync.Split))
+	for slug := range c.Sync.Split {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		split := c.Sync.Split[slug]
+		if split.By != "" && !oneOf(split.By, splitKinds) {
+			errs.add("sync.split."+slug+".by", "must be genre, decade or year, got %q", split.By)
+		}
+		if split.NameTemplate != "" {
+			if _, err := template.New(slug).Parse(split.NameTemplate); err != nil {
+				errs.add("sync.split."+slug+".name_template", "is invalid: %v", err)
+			}
+		}
+	}
+
+	if c.Watchlist.PruneAfterDays < 0 {
+		errs.add("watchlist.prune_after_days", "must not be negative")
+	}
+
+	if c.Daemon.Interval < 0 {
+		errs.add("daemon.interval", "must not be negative")
+	} else if c.Daemon.Interval > 0 && c.Daemon.Interval < time.Minute {
+		errs.add("daemon.interval", "must be at least 1m")
+	}
+	if c.Daemon.MaintenanceRetry < 0 {
+		errs.add("daemon.maintenance_retry", "must not be negative")
+	} else if c.Daemon.MaintenanceRetry > 0 && c.Daemon.MaintenanceRetry < time.Minute {
+		errs.add("daemon.maintenance_retry", "must be at least 1m")
+	}
+	if c.Daemon.MaxRuntime < 0 {
+		errs.add("daemon.max_runtime", "must not be negative")
+	} else if c.Daemon.MaxRuntime > 0 && c.Daemon.MaxRuntime < time.Minute {
+		errs.add("daemon.max_runtime", "must be at least 1m")
+	}
+	for i, window := range c.Daemon.QuietHours {
+		if _, err := parseQuietWindow(window); err != nil {
+			errs.add(fmt.Sprintf("daemon.quiet_hours[%d]", i), "%v", err)
+		}
+	}
+	if addr := c.Daemon.MetricsAddress; addr != "" {
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			errs.add("daemon.metrics_address", "must be host:port or :port, e.g. :9464, got %q", addr)
+		}
+	}
+	if ttl := c.Daemon.LeaderElection.LeaseTTL; ttl < 0 {
+		errs.add("daemon.leader_election.lease_ttl", "must not be negative")
+	} else if ttl > 0 && ttl < 3*time.Second {
+		errs.add("daemon.leader_election.lease_ttl", "must be at least 3s")
+	}
+	if c.Daemon.LeaderElection.Enabled && c.State.Backend != "sqlite" && c.State.Backend != "redis" {
+		errs.add("daemon.leader_election.enabled", "needs a shared state backend (state.backend sqlite or redis)")
+	}
+
+	if level := strings.ToLower(c.Logging.Level); level != "" && !oneOf(level, logLevels) {
+		errs.add("logging.level", "must be one of %s, got %q", strings.Join(logLevels, ", "), c.Logging.Level)
+	}
+	if format := strings.ToLower(c.Logging.Format); format != "" && !oneOf(format, logFormats) {
+		errs.add("logging.format", "must be text or json, got %q", c.Logging.Format)
+	}
+
+	if c.Plex.APIURL != "" {
+		u, err := url.Parse(c.Plex.APIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("plex.api_url", "must be an http(s) URL")
+		}
+	}
+
+	if c.Targets.Jellyfin.URL != "" {
+		u, err := url.Parse(c.Targets.Jellyfin.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("targets.jellyfin.url", "must be an http(s) URL")
+		}
+	} else if c.Targets.Jellyfin.Enabled {
+		errs.add("targets.jellyfin.url", "is required when the Jellyfin target is enabled")
+	}
+	if c.Targets.Jellyfin.Enabled && c.Targets.Jellyfin.APIKey == "" {
+		errs.add("targets.jellyfin.api_key", "is required when the Jellyfin target is enabled")
+	}
+
+	if c.Translation.Provider != "" && !oneOf(c.Translation.Provider, translationProviders) {
+		errs.add("translation.provider", "must be deepl or libretranslate, got %q", c.Translation.Provider)
+	}
+	if c.Translation.URL != "" {
+		u, err := url.Parse(c.Translation.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("translation.url", "must be an http(s) URL")
+		}
+	} else if c.Translation.Provider == "libretranslate" {
+		errs.add("translation.url", "is required for LibreTranslate")
+	}
+	if c.Translation.Provider == "deepl" && c.Translation.APIKey == "" {
+		errs.add("translation.api_key", "is required for DeepL")
+	}
+
+	if c.Serve.CacheTTL < 0 {
+		errs.add("serve.cache_ttl", "must not be negative")
+	}
+
+	if c.State.Backend != "" && !oneOf(c.State.Backend, stateBackends) {
+		errs.add("state.backend", "must be file, sqlite or redis, got %q", c.State.Backend)
+	}
+	if c.State.Backend == "redis" && c.State.Redis.Address == "" {
+		errs.add("state.redis.address", "is required for the redis backend")
+	}
+	if c.State.Redis.DB < 0 {
+		errs.add("state.redis.db", "must not be negative")
+	}
+
+	if c.Telemetry.Endpoint != "" {
+		u, err := url.Parse(c.Telemetry.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("telemetry.endpoint", "must be an http(s) URL")
+		}
+	} else if c.Telemetry.Enabled {
+		errs.add("telemetry.endpoint", "is required when telemetry is enabled")
+	}
+
+	if c.Updates.ManifestURL != "" {
+		u, err := url.Parse(c.Updates.ManifestURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("updates.manifest_url", "must be an http(s) URL")
+		}
+	}
+	if c.Updates.Channel != "" && !oneOf(c.Updates.Channel, releaseChannels) {
+		errs.add("updates.channel", "must be stable or beta, got %q", c.Updates.Channel)
+	}
+
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
@@ -179,23 +1298,195 @@ func (c *Config) IsAuthenticated() bool {
 	return c.Trakt.AccessToken != "" && c.Trakt.RefreshToken != ""
 }
 
+// EarliestYear returns the first release year sync.min_year and
+// sync.max_age_years allow at now, or 0 without a limit
+func (s SyncConfig) EarliestYear(now time.Time) int {
+	year := s.MinYear
+	if s.MaxAgeYears > 0 && now.Year()-s.MaxAgeYears > year {
+		year = now.Year() - s.MaxAgeYears
+	}
+	return year
+}
+
+// quietWindow is a daily window of daemon.quiet_hours in minutes after
+// midnight; it crosses midnight if end is before start
+type quietWindow struct {
+	start, end int
+}
+
+// parseQuietWindow parses a window like "23:00-07:00"; its errors read as
+// validation messages
+func parseQuietWindow(window string) (quietWindow, error) {
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return quietWindow{}, fmt.Errorf("must be a window like 23:00-07:00, got %q", window)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return quietWindow{}, fmt.Errorf("must be a window like 23:00-07:00, got %q", window)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return quietWindow{}, fmt.Errorf("must be a window like 23:00-07:00, got %q", window)
+	}
+	if start.Equal(end) {
+		return quietWindow{}, fmt.Errorf("must not start and end at the same time, got %q", window)
+	}
+	return quietWindow{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+	}, nil
+}
+
+// quietWindows returns the valid windows of daemon.quiet_hours
+func (d DaemonConfig) quietWindows() []quietWindow {
+	var windows []quietWindow
+	for _, window := range d.QuietHours {
+		if w, err := parseQuietWindow(window); err == nil {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// atMinute returns the time minute minutes after the midnight of day
+func atMinute(day time.Time, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, day.Location())
+}
+
+// QuietUntil reports whether now falls into daemon.quiet_hours and, if so,
+// when the quiet time ends. Adjoining or overlapping windows count as one.
+func (d DaemonConfig) QuietUntil(now time.Time) (time.Time, bool) {
+	windows := d.quietWindows()
+	until := now
+	// Each pass extends until to the end of a window it falls into; a day
+	// has at most as many window ends as there are windows.
+	for i := 0; i <= len(windows); i++ {
+		extended := false
+		for _, w := range windows {
+			minute := until.Hour()*60 + until.Minute()
+			var end time.Time
+			switch {
+			case w.start < w.end && minute >= w.start && minute < w.end:
+				end = atMinute(until, w.end)
+			case w.start > w.end && minute >= w.start:
+				end = atMinute(until.AddDate(0, 0, 1), w.end)
+			case w.start > w.end && minute < w.end:
+				end = atMinute(until, w.end)
+			default:
+				continue
+			}
+			if end.After(until) {
+				until = end
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	return until, until.After(now)
+}
+
+// NextQuietHours returns when the next window of daemon.quiet_hours begins
+// after now, or the zero time without quiet hours
+func (d DaemonConfig) NextQuietHours(now time.Time) time.Time {
+	var next time.Time
+	for _, w := range d.quietWindows() {
+		start := atMinute(now, w.start)
+		if !start.After(now) {
+			start = atMinute(now.AddDate(0, 0, 1), w.start)
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+// clockSkewTolerance is the clock offset below which the local clock counts
+// as correct
+const clockSkewTolerance = time.Minute
+
+// SetTokens stores new tokens that Trakt issued at issuedAt, by its clock,
+// and that were received at receivedAt, by the local clock
+func (c *Config) SetTokens(accessToken, refreshToken string, issuedAt, receivedAt, expiresAt time.Time) {
+	c.Trakt.AccessToken = accessToken
+	c.Trakt.RefreshToken = refreshToken
+	c.Trakt.TokenExpires = expiresAt
+	c.Trakt.ClockOffset = ClockOffset(issuedAt, receivedAt)
+	c.tokenIssued = issuedAt
+	c.tokenReceived = receivedAt
+}
+
+// ClockOffset returns how far the Trakt time traktTime is ahead of the local
+// time localTime, in whole seconds, or 0 within clockSkewTolerance
+func ClockOffset(traktTime, localTime time.Time) time.Duration {
+	offset := traktTime.Sub(localTime).Round(time.Second)
+	if offset > -clockSkewTolerance && offset < clockSkewTolerance {
+		return 0
+	}
+	return offset
+}
+
+// TraktNow estimates the current time by the Trakt clock. After SetTokens it
+// is the issue time plus the monotonic time elapsed since, which wall clock
+// jumps (e.g. an NTP sync after boot) do not affect; otherwise the local
+// clock corrected by the stored offset.
+func (c *Config) TraktNow() time.Time {
+	if !c.tokenReceived.IsZero() {
+		return c.tokenIssued.Add(time.Since(c.tokenReceived))
+	}
+	return time.Now().Add(c.Trakt.ClockOffset)
+}
+
 // NeedsRefresh checks if the access token needs to be refreshed
 func (c *Config) NeedsRefresh() bool {
 	if c.Trakt.AccessToken == "" {
 		return false
 	}
-	return time.Now().Add(1 * time.Hour).After(c.Trakt.TokenExpires)
+	return c.TraktNow().Add(1 * time.Hour).After(c.Trakt.TokenExpires)
 }
 
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("sync.limit", 30)
 	v.SetDefault("sync.min_rating", 60)
+	v.SetDefault("sync.watched_period", "weekly")
 	v.SetDefault("sync.list_privacy", "private")
 	v.SetDefault("sync.full_refresh_days", 7)
+	v.SetDefault("sync.conflict_policy", ConflictOverwrite)
+	v.SetDefault("sync.anomaly_detection.identical_runs", 12)
+	v.SetDefault("sync.anomaly_detection.full_churn_runs", 3)
 	v.SetDefault("sync.lists.movies", true)
 	v.SetDefault("sync.lists.shows", true)
+	v.SetDefault("sync.ratings_list.enabled", false)
+	v.SetDefault("sync.ratings_list.type", "movies")
+	v.SetDefault("sync.ratings_list.min_rating", 9)
+	v.SetDefault("sync.recently_watched.enabled", false)
+	v.SetDefault("sync.recently_watched.type", "movies")
+	v.SetDefault("sync.recently_watched.limit", 20)
+	v.SetDefault("sync.anime.enabled", false)
+	v.SetDefault("sync.anime.type", "shows")
+	v.SetDefault("sync.anime.limit", 20)
+	v.SetDefault("sync.streaming_top10.type", "shows")
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "text")
+	v.SetDefault("telemetry.enabled", false)
+	v.SetDefault("archive.enabled", false)
+	v.SetDefault("state.backend", "file")
+	v.SetDefault("targets.jellyfin.enabled", false)
+	v.SetDefault("serve.address", ":7979")
+	v.SetDefault("translation.source_language", "de")
+	v.SetDefault("translation.target_language", "en")
+	v.SetDefault("updates.channel", "stable")
+}
+
+// isNotFound reports whether reading the config failed because the file
+// does not exist. Viper only returns ConfigFileNotFoundError when searching
+// config paths; an explicit file yields the underlying os error.
+func isNotFound(err error) bool {
+	var notFound viper.ConfigFileNotFoundError
+	return errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist)
 }
 
 func createDefaultConfig(path string) error {
@@ -203,24 +1494,78 @@ func createDefaultConfig(path string) error {
 	return Save(cfg, path)
 }
 
+// Init writes a new config file with the defaults and the lists of the named
+// templates. An existing file is only replaced with force.
+func Init(path string, force bool, templates ...string) error {
+	if path == "" {
+		path = DefaultConfigPath()
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("config file %s already exists", path)
+	}
+
+	cfg := defaultConfig()
+	if err := cfg.ApplyTemplates(templates...); err != nil {
+		return err
+	}
+	return Save(cfg, path)
+}
+
 func defaultConfig() *Config {
 	return &Config{
 		Trakt: TraktConfig{},
 		Sync: SyncConfig{
 			Limit:           30,
 			MinRating:       60,
+			WatchedPeriod:   "weekly",
 			ListPrivacy:     "private",
 			FullRefreshDays: 7,
+			ConflictPolicy:  ConflictOverwrite,
+			AnomalyDetection: AnomalyDetectionConfig{
+				IdenticalRuns: 12,
+				FullChurnRuns: 3,
+			},
 			Lists: ListSyncConfig{
 				Movies: true,
 				Shows:  true,
 			},
+			RatingsList: RatingsListConfig{
+				Type:      "movies",
+				MinRating: 9,
+			},
+			RecentlyWatched: RecentlyWatchedConfig{
+				Type:  "movies",
+				Limit: 20,
+			},
+			Anime: AnimeListConfig{
+				Type:  "shows",
+				Limit: 20,
+			},
+			StreamingTop10: StreamingTop10Config{
+				Type: "shows",
+			},
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "text",
 		},
+		Updates: UpdatesConfig{
+			Channel: "stable",
+		},
+		State: StateConfig{
+			Backend: "file",
+		},
+		Serve: ServeConfig{
+			Address: ":7979",
+		},
+	}
+}
+
+func formatDurationOrEmpty(value time.Duration) string {
+	if value == 0 {
+		return ""
 	}
+	return value.String()
 }
 
 func formatTimeOrEmpty(value time.Time) string {
@@ -230,6 +1575,150 @@ func formatTimeOrEmpty(value time.Time) string {
 	return value.UTC().Format(time.RFC3339)
 }
 
+func splitSettings(splits map[string]SplitConfig) map[string]interface{} {
+	settings := make(map[string]interface{}, len(splits))
+	for slug, split := range splits {
+		settings[slug] = map[string]interface{}{
+			"by":            split.By,
+			"genres":        split.Genres,
+			"name_template": split.NameTemplate,
+		}
+	}
+	return settings
+}
+
+func listDisplaySettings(displays map[string]ListDisplayConfig) map[string]interface{} {
+	settings := make(map[string]interface{}, len(displays))
+	for slug, display := range displays {
+		values := make(map[string]interface{})
+		if display.DisplayNumbers != nil {
+			values["display_numbers"] = *display.DisplayNumbers
+		}
+		if display.AllowComments != nil {
+			values["allow_comments"] = *display.AllowComments
+		}
+		if display.SortBy != "" {
+			values["sort_by"] = display.SortBy
+		}
+		if display.SortHow != "" {
+			values["sort_how"] = display.SortHow
+		}
+		settings[slug] = values
+	}
+	return settings
+}
+
+func stringMapSettings(values map[string]string) map[string]string {
+	settings := make(map[string]string, len(values))
+	for slug, value := range values {
+		settings[slug] = value
+	}
+	return settings
+}
+
+func customListSettings(lists []CustomListConfig) []map[string]interface{} {
+	settings := make([]map[string]interface{}, 0, len(lists))
+	for _, list := range lists {
+		values := map[string]interface{}{
+			"name":    list.Name,
+			"type":    list.Type,
+			"sources": list.Sources,
+		}
+		if list.Slug != "" {
+			values["slug"] = list.Slug
+		}
+		if len(list.IMDbLists) > 0 {
+			values["imdb_lists"] = list.IMDbLists
+		}
+		if len(list.MDBLists) > 0 {
+			values["mdblists"] = list.MDBLists
+		}
+		if list.Description != "" {
+			values["description"] = list.Description
+		}
+		if list.Enabled != nil {
+			values["enabled"] = *list.Enabled
+		}
+		if list.Limit != 0 {
+			values["limit"] = list.Limit
+		}
+		if list.Privacy != "" {
+			values["privacy"] = list.Privacy
+		}
+		if list.Preset != "" {
+			values["preset"] = list.Preset
+		}
+		if len(list.Certifications) > 0 {
+			values["certifications"] = list.Certifications
+		}
+		if len(list.ExcludeGenres) > 0 {
+			values["exclude_genres"] = list.ExcludeGenres
+		}
+		if len(list.Countries) > 0 {
+			values["countries"] = list.Countries
+		}
+		if len(list.Networks) > 0 {
+			values["networks"] = list.Networks
+		}
+		settings = append(settings, values)
+	}
+	return settings
+}
+
+func mirrorSettings(mirrors []MirrorConfig) []map[string]interface{} {
+	settings := make([]map[string]interface{}, 0, len(mirrors))
+	for _, mirror := range mirrors {
+		values := map[string]interface{}{
+			"list_id": mirror.ListID,
+			"name":    mirror.Name,
+			"type":    mirror.Type,
+		}
+		if mirror.Enabled != nil {
+			values["enabled"] = *mirror.Enabled
+		}
+		if mirror.Limit != 0 {
+			values["limit"] = mirror.Limit
+		}
+		if mirror.Privacy != "" {
+			values["privacy"] = mirror.Privacy
+		}
+		settings = append(settings, values)
+	}
+	return settings
+}
+
+func pinSettings(pins map[string][]string) map[string][]string {
+	settings := make(map[string][]string, len(pins))
+	for slug, ids := range pins {
+		settings[slug] = ids
+	}
+	return settings
+}
+
+func formatTimeMap(values map[string]time.Time) map[string]string {
+	formatted := make(map[string]string, len(values))
+	for key, value := range values {
+		if value.IsZero() {
+			continue
+		}
+		formatted[key] = formatTimeOrEmpty(value)
+	}
+	return formatted
+}
+
+func stringToDurationHook() mapstructure.DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() == reflect.String && to == reflect.TypeOf(time.Duration(0)) {
+			value := strings.TrimSpace(data.(string))
+			if value == "" {
+				return time.Duration(0), nil
+			}
+			return time.ParseDuration(value)
+		}
+		return data, nil
+	}
+}
+
 func stringToTimeHook() mapstructure.DecodeHookFunc {
 	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
 		if from.Kind() == reflect.String && to == reflect.TypeOf(time.Time{}) {
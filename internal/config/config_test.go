@@ -0,0 +1,370 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Trakt: TraktConfig{ClientID: "id", ClientSecret: "secret", Username: "alice"},
+		Sync: SyncConfig{
+			Limit:           30,
+			MinRating:       60,
+			ListPrivacy:     "private",
+			FullRefreshDays: 7,
+		},
+		Logging: LoggingConfig{Level: "info", Format: "text"},
+	}
+}
+
+func TestValidateValidConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+}
+
+func TestValidateCollectsAllErrors(t *testing.T) {
+	cfg := validConfig()
+	cfg.Trakt.ClientID = ""
+	cfg.Sync.Limit = 0
+	cfg.Sync.MinYear = 99
+	cfg.Sync.Languages = []string{"de", "German"}
+	cfg.Sync.ListPrivacy = "secret"
+	cfg.Sync.ConflictPolicies = map[string]string{"trakt-sync-filme": "ignore"}
+	cfg.Sync.Pins = map[string][]string{"trakt-sync-filme": {"tt0111161", "0068646"}}
+	cfg.Sync.ItemNotes = map[string]string{"trakt-sync-filme": "#{{.Rnak}}"}
+	cfg.Sync.DescriptionTemplates = map[string]string{"trakt-sync-filme": "{{.Items}} Filme", "trakt-sync-serien": "{{.Count}} Serien"}
+	cfg.Logging.Level = "verbose"
+	cfg.Sync.Split = map[string]SplitConfig{"trakt-sync-filme": {By: "mood"}}
+	cfg.Sync.CustomLists = []CustomListConfig{{Name: "Kids", Type: "movies", Sources: []string{"trending", "upcoming"}, Preset: "kids"}}
+	cfg.Sync.Mirrors = []MirrorConfig{{Name: "Picks", Type: "movies"}}
+	cfg.Sync.StreamingTop10 = StreamingTop10Config{Services: []string{"netflix", "hulu"}, Country: "DE", Type: "shows"}
+	cfg.Daemon.QuietHours = []string{"23:00-07:00", "7-9"}
+	cfg.Daemon.MetricsAddress = "9464"
+	cfg.State.Backend = "redis"
+	cfg.Telemetry.Enabled = true
+
+	err := cfg.Validate()
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	want := []string{
+		"trakt.client_id",
+		"sync.limit",
+		"sync.min_year",
+		"sync.languages[1]",
+		"sync.list_privacy",
+		"sync.conflict_policies.trakt-sync-filme",
+		"sync.pins.trakt-sync-filme[1]",
+		"sync.item_notes.trakt-sync-filme",
+		"sync.description_templates.trakt-sync-serien",
+		"sync.custom_lists[0].sources[1]",
+		"sync.custom_lists[0].preset",
+		"sync.mirrors[0].list_id",
+		"sync.streaming_top10.services[1]",
+		"sync.streaming_top10.country",
+		"sync.split.trakt-sync-filme.by",
+		"daemon.quiet_hours[1]",
+		"daemon.metrics_address",
+		"logging.level",
+		"state.redis.address",
+		"telemetry.endpoint",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, path := range want {
+		if errs[i].Path != path {
+			t.Errorf("error %d: expected path %s, got %s", i, path, errs[i].Path)
+		}
+	}
+}
+
+func TestEarliestYear(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		minYear, maxAge, want int
+	}{
+		{0, 0, 0},
+		{2015, 0, 2015},
+		{0, 5, 2019},
+		{2015, 5, 2019},
+		{2022, 5, 2022},
+	}
+	for _, tt := range tests {
+		cfg := SyncConfig{MinYear: tt.minYear, MaxAgeYears: tt.maxAge}
+		if got := cfg.EarliestYear(now); got != tt.want {
+			t.Errorf("min_year %d, max_age_years %d: expected %d, got %d", tt.minYear, tt.maxAge, tt.want, got)
+		}
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 1, hour, minute, 0, 0, time.UTC)
+	}
+	cfg := DaemonConfig{QuietHours: []string{"23:00-07:00", "07:00-07:30", "12:00-13:00"}}
+	tests := []struct {
+		now       time.Time
+		quiet     bool
+		until     time.Time
+		nextQuiet time.Time
+	}{
+		{day(22, 59), false, time.Time{}, day(23, 0)},
+		{day(23, 0), true, day(31, 30), day(31, 0)},
+		{day(3, 0), true, day(7, 30), day(7, 0)},
+		{day(7, 15), true, day(7, 30), day(12, 0)},
+		{day(7, 30), false, time.Time{}, day(12, 0)},
+		{day(12, 30), true, day(13, 0), day(23, 0)},
+	}
+	for _, tt := range tests {
+		until, quiet := cfg.QuietUntil(tt.now)
+		if quiet != tt.quiet || (quiet && !until.Equal(tt.until)) {
+			t.Errorf("%s: expected quiet %v until %s, got %v until %s", tt.now.Format("15:04"), tt.quiet, tt.until, quiet, until)
+		}
+		if next := cfg.NextQuietHours(tt.now); !next.Equal(tt.nextQuiet) {
+			t.Errorf("%s: expected next quiet hours at %s, got %s", tt.now.Format("15:04"), tt.nextQuiet, next)
+		}
+	}
+
+	if _, quiet := (DaemonConfig{}).QuietUntil(day(3, 0)); quiet {
+		t.Error("expected no quiet hours without daemon.quiet_hours")
+	}
+}
+
+func TestSchemaAcceptsExampleAndSavedConfig(t *testing.T) {
+	problems, err := CheckSchema(filepath.Join("..", "..", "config.example.yaml"))
+	if err != nil {
+		t.Fatalf("check example: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("config.example.yaml does not match schema: %v", problems)
+	}
+
+	cfg := validConfig()
+	cfg.Sync.Split = map[string]SplitConfig{"trakt-sync-filme": {By: "genre", Genres: []string{"horror"}}}
+	cfg.Sync.LastFullRefresh.Movies = time.Now()
+	cfg.Sync.ConflictPolicies = map[string]string{"trakt-sync-serien": ConflictSkip}
+	cfg.Sync.Pins = map[string][]string{"trakt-sync-filme": {"tt0111161"}}
+	cfg.Sync.ItemNotes = map[string]string{"trakt-sync-filme": "#{{.Rank}} on {{.Source}}"}
+	cfg.Sync.DescriptionTemplates = map[string]string{"trakt-sync-filme": "{{.Items}} Filme"}
+	cfg.Translation = TranslationConfig{Provider: "deepl", APIKey: "key:fx", SourceLanguage: "de", TargetLanguage: "en"}
+	disabled := false
+	cfg.Sync.CustomLists = []CustomListConfig{{Name: "Popular Shows", Type: "shows", Sources: []string{"popular", "watched"}, Enabled: &disabled, Preset: PresetFamily, ExcludeGenres: []string{"horror"}}}
+	numbers := false
+	cfg.Sync.ListDisplay = map[string]ListDisplayConfig{"trakt-sync-filme": {DisplayNumbers: &numbers, SortBy: "title"}}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := Save(cfg, path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	problems, err = CheckSchema(path)
+	if err != nil {
+		t.Fatalf("check saved: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("saved config does not match schema: %v", problems)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load saved: %v", err)
+	}
+	if display := loaded.Sync.ListDisplay["trakt-sync-filme"]; display.DisplayNumbers == nil || *display.DisplayNumbers || display.AllowComments != nil {
+		t.Errorf("list display did not round-trip: %+v", display)
+	}
+	if pins := loaded.Sync.Pins["trakt-sync-filme"]; len(pins) != 1 || pins[0] != "tt0111161" {
+		t.Errorf("pins did not round-trip: %v", pins)
+	}
+	if notes := loaded.Sync.ItemNotes["trakt-sync-filme"]; notes != "#{{.Rank}} on {{.Source}}" {
+		t.Errorf("item notes did not round-trip: %q", notes)
+	}
+	if description := loaded.Sync.DescriptionTemplates["trakt-sync-filme"]; description != "{{.Items}} Filme" || loaded.Translation.Provider != "deepl" {
+		t.Errorf("description templates did not round-trip: %q, %+v", description, loaded.Translation)
+	}
+	if custom := loaded.Sync.CustomLists; len(custom) != 1 || custom[0].Name != "Popular Shows" || len(custom[0].Sources) != 2 || custom[0].Enabled == nil || *custom[0].Enabled || custom[0].Preset != PresetFamily || len(custom[0].ExcludeGenres) != 1 {
+		t.Errorf("custom lists did not round-trip: %+v", custom)
+	}
+}
+
+func TestSchemaReportsUnknownKeysAndTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("sync:\n  limit: many\n  lsts:\n    movies: true\nlogging:\n  level: loud\n")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := CheckSchema(path)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	want := []string{"logging.level", "sync.limit", "sync.lsts"}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, path := range want {
+		if problems[i].Path != path {
+			t.Errorf("problem %d: expected path %s, got %s", i, path, problems[i].Path)
+		}
+	}
+}
+
+func TestSchemaMatchesLoaderCase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("logging:\n  level: INFO\n  format: Text\n")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := CheckSchema(path)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("expected the loader's case-insensitive values to pass, got %v", problems)
+	}
+}
+
+func TestLoadDaemonInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := validConfig()
+	cfg.Daemon.Interval = 90 * time.Minute
+	if err := Save(cfg, path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Daemon.Interval != 90*time.Minute {
+		t.Fatalf("expected 1h30m interval, got %s", loaded.Daemon.Interval)
+	}
+
+	cfg.Daemon.Interval = 0
+	if err := Save(cfg, path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if loaded, err = Load(path); err != nil || loaded.Daemon.Interval != 0 {
+		t.Fatalf("expected empty interval to load as 0, got %v (%v)", loaded, err)
+	}
+}
+
+func TestLoadCreatesDefaultConfigUnlessExistingRequired(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "trakt-sync")
+	path := filepath.Join(dir, "config.yaml")
+
+	if _, err := LoadExisting(path); err == nil {
+		t.Fatal("expected an error for a missing config")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("LoadExisting created the config directory: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Sync.Limit != 30 {
+		t.Errorf("expected default limit 30, got %d", cfg.Sync.Limit)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("default config was not written: %v", err)
+	}
+	if _, err := LoadExisting(path); err != nil {
+		t.Errorf("load existing: %v", err)
+	}
+}
+
+func TestTemplatesAreValid(t *testing.T) {
+	for _, template := range Templates() {
+		cfg := validConfig()
+		if err := cfg.ApplyTemplates(template.Name, template.Name); err != nil {
+			t.Fatalf("apply %s: %v", template.Name, err)
+		}
+		if len(cfg.Sync.CustomLists) != len(template.Lists) {
+			t.Errorf("%s: expected %d lists, got %d", template.Name, len(template.Lists), len(cfg.Sync.CustomLists))
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s: %v", template.Name, err)
+		}
+		for _, list := range template.Lists {
+			if want := Slugify(list.Name); list.Slug != want {
+				t.Errorf("%s: slug %q does not match the name %q, Trakt creates %q", template.Name, list.Slug, list.Name, want)
+			}
+		}
+	}
+
+	if err := validConfig().ApplyTemplates("netflix-top10-us"); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
+
+func TestNeedsRefreshCorrectsClockOffset(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		offset  time.Duration
+		expires time.Time
+		want    bool
+	}{
+		// The local clock is two days behind: by its reading the token
+		// would be valid for two more days and never get refreshed.
+		{"clock behind", 48 * time.Hour, now.Add(48*time.Hour + 30*time.Minute), true},
+		// The local clock is two days ahead: by its reading the token
+		// expired long ago and would be refreshed on every run.
+		{"clock ahead", -48 * time.Hour, now.Add(-48*time.Hour + 10*time.Hour), false},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Trakt: TraktConfig{AccessToken: "a", TokenExpires: tt.expires, ClockOffset: tt.offset}}
+		if got := cfg.NeedsRefresh(); got != tt.want {
+			t.Errorf("%s: NeedsRefresh() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetTokensMeasuresClockOffset(t *testing.T) {
+	local := time.Now()
+	cfg := &Config{}
+
+	cfg.SetTokens("a", "r", local.Add(20*time.Second), local, local.Add(24*time.Hour))
+	if cfg.Trakt.ClockOffset != 0 {
+		t.Errorf("offset within tolerance = %s, want 0", cfg.Trakt.ClockOffset)
+	}
+
+	issued := local.Add(-3 * time.Hour)
+	cfg.SetTokens("a", "r", issued, local, issued.Add(24*time.Hour))
+	if cfg.Trakt.ClockOffset != -3*time.Hour {
+		t.Errorf("offset = %s, want -3h", cfg.Trakt.ClockOffset)
+	}
+	// Expiry is judged by the time elapsed since the tokens arrived, not by
+	// the local clock.
+	if cfg.NeedsRefresh() {
+		t.Error("fresh tokens need a refresh")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Trakt Sync Filme":       "trakt-sync-filme",
+		"  Netflix Top 10 DE ":   "netflix-top-10-de",
+		"Komödie":                "komodie",
+		"Straße der Ärzte":       "strasse-der-arzte",
+		"Sci-Fi / Fantasy (90s)": "sci-fi-fantasy-90s",
+		"Crème brûlée":           "creme-brulee",
+		"アニメ Top":                "top",
+	}
+	for name, want := range tests {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RuntimeFileName is the name of the runtime file in a state directory
+const RuntimeFileName = "runtime.json"
+
+// Runtime holds the values trakt-sync writes back while running: tokens and
+// full refresh timestamps. With a state directory they are kept there
+// instead of in the config file, so the config can stay read-only.
+type Runtime struct {
+	AccessToken     string           `json:"access_token,omitempty"`
+	RefreshToken    string           `json:"refresh_token,omitempty"`
+	TokenExpires    time.Time        `json:"token_expires_at"`
+	ClockOffset     time.Duration    `json:"clock_offset,omitempty"`
+	LastFullRefresh FullRefreshState `json:"last_full_refresh"`
+}
+
+// LoadRuntime applies the runtime file at path to cfg. A missing file leaves
+// cfg unchanged, so tokens from the config file are used until the first
+// refresh.
+func LoadRuntime(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read runtime file: %w", err)
+	}
+
+	var rt Runtime
+	if err := json.Unmarshal(data, &rt); err != nil {
+		return fmt.Errorf("failed to parse runtime file: %w", err)
+	}
+
+	if rt.AccessToken != "" && rt.RefreshToken != "" {
+		cfg.Trakt.AccessToken = rt.AccessToken
+		cfg.Trakt.RefreshToken = rt.RefreshToken
+		cfg.Trakt.TokenExpires = rt.TokenExpires
+		cfg.Trakt.ClockOffset = rt.ClockOffset
+	}
+	cfg.Sync.LastFullRefresh = rt.LastFullRefresh
+	return nil
+}
+
+// CopyRuntime copies the runtime values of src to dst, e.g. into a config
+// file edited while trakt-sync was running
+func CopyRuntime(dst, src *Config) {
+	dst.Trakt.AccessToken = src.Trakt.AccessToken
+	dst.Trakt.RefreshToken = src.Trakt.RefreshToken
+	dst.Trakt.TokenExpires = src.Trakt.TokenExpires
+	dst.Trakt.ClockOffset = src.Trakt.ClockOffset
+	dst.Sync.LastFullRefresh = src.Sync.LastFullRefresh
+	dst.tokenIssued = src.tokenIssued
+	dst.tokenReceived = src.tokenReceived
+}
+
+// SaveRuntime atomically writes the runtime values of cfg to path with 0600
+// permissions
+func SaveRuntime(cfg *Config, path string) error {
+	rt := Runtime{
+		AccessToken:     cfg.Trakt.AccessToken,
+		RefreshToken:    cfg.Trakt.RefreshToken,
+		TokenExpires:    cfg.Trakt.TokenExpires.UTC(),
+		ClockOffset:     cfg.Trakt.ClockOffset,
+		LastFullRefresh: cfg.Sync.LastFullRefresh,
+	}
+
+	data, err := json.MarshalIndent(rt, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode runtime file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".runtime-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary runtime file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write runtime file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write runtime file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace runtime file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Schema is the subset of JSON Schema used to describe the config format
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+
+	// foldCase matches the enum case-insensitively, like the loader does
+	foldCase bool
+}
+
+// schemaEnums lists the allowed values of enum-like string keys. "*" matches
+// any map key. Optional keys allow "" to fall back to their default.
+var schemaEnums = map[string][]string{
+	"trakt.environment":             append([]string{""}, environments...),
+	"sync.list_privacy":             listPrivacies,
+	"sync.watched_period":           append([]string{""}, watchedPeriods...),
+	"sync.conflict_policy":          append([]string{""}, conflictPolicies...),
+	"sync.conflict_policies.*":      conflictPolicies,
+	"sync.list_display.*.sort_by":   append([]string{""}, listSortBy...),
+	"sync.list_display.*.sort_how":  append([]string{""}, listSortHow...),
+	"sync.ratings_list.type":        append([]string{""}, listTypes...),
+	"sync.ratings_list.privacy":     append([]string{""}, listPrivacies...),
+	"sync.recently_watched.type":    append([]string{""}, listTypes...),
+	"sync.recently_watched.privacy": append([]string{""}, listPrivacies...),
+	"sync.custom_lists.type":        listTypes,
+	"sync.custom_lists.sources":     chartSources,
+	"sync.custom_lists.privacy":     append([]string{""}, listPrivacies...),
+	"sync.custom_lists.preset":      {"", PresetFamily},
+	"sync.mirrors.type":             listTypes,
+	"sync.mirrors.privacy":          append([]string{""}, listPrivacies...),
+	"sync.anime.type":               append([]string{""}, listTypes...),
+	"sync.anime.privacy":            append([]string{""}, listPrivacies...),
+	"sync.streaming_top10.services": streamingServiceSlugs(),
+	"sync.streaming_top10.type":     append([]string{""}, listTypes...),
+	"sync.streaming_top10.privacy":  append([]string{""}, listPrivacies...),
+	"sync.split.*.by":               append([]string{""}, splitKinds...),
+	"logging.level":                 append([]string{""}, logLevels...),
+	"logging.format":                append([]string{""}, logFormats...),
+	"updates.channel":               append([]string{""}, releaseChannels...),
+	"state.backend":                 append([]string{""}, stateBackends...),
+	"translation.provider":          append([]string{""}, translationProviders...),
+}
+
+// foldCaseEnums are the enum keys the loader accepts in any case
+var foldCaseEnums = map[string]bool{
+	"logging.level":  true,
+	"logging.format": true,
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// GenerateSchema returns the JSON Schema for the config file, derived from the
+// mapstructure tags of Config
+func GenerateSchema() *Schema {
+	schema := schemaFor(reflect.TypeOf(Config{}), "")
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.Title = "trakt-sync configuration"
+	return schema
+}
+
+func schemaFor(t reflect.Type, path string) *Schema {
+	switch {
+	case t == timeType:
+		// Empty until the first value is written, so no date-time format.
+		return &Schema{Type: "string"}
+	case t == durationType:
+		// Go duration strings such as "6h" or "90m"
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Ptr:
+		// Optional values, unset when missing
+		return schemaFor(t.Elem(), path)
+	case t.Kind() == reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := field.Tag.Get("mapstructure")
+			if key == "" || key == "-" {
+				continue
+			}
+			schema.Properties[key] = schemaFor(field.Type, joinPath(path, key))
+		}
+		return schema
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaFor(t.Elem(), joinPath(path, "*"))}
+	case t.Kind() == reflect.Slice:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem(), path)}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	default:
+		return &Schema{Type: "string", Enum: schemaEnums[path], foldCase: foldCaseEnums[path]}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// CheckSchema validates the raw config file at configPath against the schema,
+// reporting unknown keys and values of the wrong type or outside an enum
+func CheckSchema(configPath string) (ValidationErrors, error) {
+	if configPath == "" {
+		configPath = DefaultConfigPath()
+	}
+
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return checkSettings(v), nil
+}
+
+func checkSettings(v *viper.Viper) ValidationErrors {
+	var errs ValidationErrors
+	GenerateSchema().check("", v.AllSettings(), &errs)
+	return errs
+}
+
+func (s *Schema) check(path string, value interface{}, errs *ValidationErrors) {
+	if value == nil {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			errs.add(path, "must be a mapping")
+			return
+		}
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child, ok := s.Properties[key]
+			if !ok {
+				child, _ = s.AdditionalProperties.(*Schema)
+			}
+			if child == nil {
+				errs.add(joinPath(path, key), "is not a known config key")
+				continue
+			}
+			child.check(joinPath(path, key), fields[key], errs)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			errs.add(path, "must be a list")
+			return
+		}
+		for i, item := range items {
+			s.Items.check(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs.add(path, "must be true or false")
+		}
+	case "integer":
+		switch value.(type) {
+		case int, int64, uint64:
+		default:
+			errs.add(path, "must be a whole number")
+		}
+	case "string":
+		switch str := value.(type) {
+		case string:
+			got := str
+			if s.foldCase {
+				got = strings.ToLower(got)
+			}
+			if len(s.Enum) > 0 && !oneOf(got, s.Enum) {
+				errs.add(path, "must be one of %s, got %q", strings.Join(nonEmpty(s.Enum), ", "), str)
+			}
+		case time.Time:
+			// YAML decodes unquoted timestamps as time values
+		default:
+			errs.add(path, "must be a string")
+		}
+	}
+}
+
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// Flatten returns every config value keyed by its YAML path, e.g.
+// "sync.limit" -> "30". Slices are joined with commas, slices of mappings
+// are indexed, e.g. "sync.custom_lists[0].name".
+func Flatten(cfg *Config) map[string]string {
+	values := make(map[string]string)
+	flattenValue(reflect.ValueOf(*cfg), "", values)
+	return values
+}
+
+func flattenValue(v reflect.Value, path string, values map[string]string) {
+	switch {
+	case v.Type() == timeType:
+		values[path] = formatTimeOrEmpty(v.Interface().(time.Time))
+	case v.Type() == durationType:
+		values[path] = formatDurationOrEmpty(v.Interface().(time.Duration))
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			values[path] = ""
+			return
+		}
+		flattenValue(v.Elem(), path, values)
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			key := v.Type().Field(i).Tag.Get("mapstructure")
+			if key == "" || key == "-" {
+				continue
+			}
+			flattenValue(v.Field(i), joinPath(path, key), values)
+		}
+	case v.Kind() == reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			flattenValue(iter.Value(), joinPath(path, fmt.Sprint(iter.Key().Interface())), values)
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		for i := 0; i < v.Len(); i++ {
+			flattenValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), values)
+		}
+	case v.Kind() == reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, fmt.Sprint(v.Index(i).Interface()))
+		}
+		values[path] = strings.Join(items, ",")
+	default:
+		values[path] = fmt.Sprint(v.Interface())
+	}
+}
+
+// FileKeys returns the keys set in a config file as YAML paths, e.g.
+// sync.limit, without defaults
+func FileKeys(configPath string) ([]string, error) {
+	if configPath == "" {
+		configPath = DefaultConfigPath()
+	}
+
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugLetters spells out the letters that do not decompose into an ASCII
+// letter and accents
+var slugLetters = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+// Slugify derives a list slug from a list name like Trakt does: accents are
+// dropped ("Komödie" becomes "komodie"), and any run of other characters
+// than ASCII letters and digits becomes a single dash
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(strings.TrimSpace(name))) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		letters := string(r)
+		if spelled, ok := slugLetters[r]; ok {
+			letters = spelled
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || letters != string(r) {
+			b.WriteString(letters)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template is a named set of list definitions shipped with trakt-sync that
+// `config init` writes to sync.custom_lists
+type Template struct {
+	Name        string
+	Description string
+	Lists       []CustomListConfig
+}
+
+var templates = []Template{
+	{
+		Name:        "trending-movies",
+		Description: "The movies trending on Trakt right now",
+		Lists: []CustomListConfig{{
+			Name:    "Trending Movies",
+			Slug:    "trending-movies",
+			Type:    "movies",
+			Sources: []string{"trending"},
+		}},
+	},
+	{
+		Name:        "netflix-top10-de",
+		Description: "The 10 German Netflix shows most watched on Trakt this week",
+		Lists: []CustomListConfig{{
+			Name:        "Netflix Top 10 DE",
+			Slug:        "netflix-top-10-de",
+			Description: "German Netflix shows most watched on Trakt this week",
+			Type:        "shows",
+			Sources:     []string{"watched"},
+			Limit:       10,
+			Countries:   []string{"de"},
+			Networks:    []string{"Netflix"},
+		}},
+	},
+	{
+		Name:        "upcoming-premieres",
+		Description: "Movies and shows premiering in the next 30 days",
+		Lists: []CustomListConfig{
+			{
+				Name:        "Upcoming Movie Premieres",
+				Slug:        "upcoming-movie-premieres",
+				Description: "Movies released in the next 30 days",
+				Type:        "movies",
+				Sources:     []string{"premieres"},
+			},
+			{
+				Name:        "Upcoming Show Premieres",
+				Slug:        "upcoming-show-premieres",
+				Description: "Shows premiering in the next 30 days",
+				Type:        "shows",
+				Sources:     []string{"premieres"},
+			},
+		},
+	},
+}
+
+// Templates returns the built-in templates
+func Templates() []Template {
+	return append([]Template(nil), templates...)
+}
+
+// ApplyTemplates adds the lists of the named templates to sync.custom_lists.
+// Lists whose slug is already defined are left as they are.
+func (c *Config) ApplyTemplates(names ...string) error {
+	defined := make(map[string]bool, len(c.Sync.CustomLists))
+	for _, list := range c.Sync.CustomLists {
+		defined[list.Slug] = true
+	}
+
+	for _, name := range names {
+		template, ok := lookupTemplate(name)
+		if !ok {
+			return fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(templateNames(), ", "))
+		}
+		for _, list := range template.Lists {
+			if defined[list.Slug] {
+				continue
+			}
+			defined[list.Slug] = true
+			c.Sync.CustomLists = append(c.Sync.CustomLists, list)
+		}
+	}
+	return nil
+}
+
+func lookupTemplate(name string) (Template, bool) {
+	for _, template := range templates {
+		if template.Name == name {
+			return template, true
+		}
+	}
+	return Template{}, false
+}
+
+func templateNames() []string {
+	names := make([]string, 0, len(templates))
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	sort.Strings(names)
+	return names
+}
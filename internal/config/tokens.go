@@ -0,0 +1,78 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TokenFile is the portable representation of Trakt credentials used by
+// 'auth export' and 'auth import'.
+type TokenFile struct {
+	Username     string    `json:"username,omitempty"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenExpires time.Time `json:"token_expires_at"`
+}
+
+// ExportTokens writes the tokens from cfg to path with 0600 permissions
+func ExportTokens(cfg *Config, path string) error {
+	tokens := TokenFile{
+		Username:     cfg.Trakt.Username,
+		AccessToken:  cfg.Trakt.AccessToken,
+		RefreshToken: cfg.Trakt.RefreshToken,
+		TokenExpires: cfg.Trakt.TokenExpires.UTC(),
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+
+	// WriteFile keeps the mode of an existing file, so tighten it explicitly.
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to set token file permissions: %w", err)
+	}
+
+	return nil
+}
+
+// ImportTokens reads a token file written by ExportTokens
+func ImportTokens(path string) (*TokenFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var tokens TokenFile
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		return nil, fmt.Errorf("token file is missing access_token or refresh_token")
+	}
+
+	return &tokens, nil
+}
+
+// Apply copies the tokens into cfg. The username is only taken over when cfg
+// does not have one yet.
+func (t *TokenFile) Apply(cfg *Config) {
+	cfg.Trakt.AccessToken = t.AccessToken
+	cfg.Trakt.RefreshToken = t.RefreshToken
+	cfg.Trakt.TokenExpires = t.TokenExpires
+	if cfg.Trakt.Username == "" {
+		cfg.Trakt.Username = t.Username
+	}
+}
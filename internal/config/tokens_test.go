@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportImportTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	cfg := &Config{Trakt: TraktConfig{
+		Username:     "alice",
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		TokenExpires: expires,
+	}}
+
+	if err := ExportTokens(cfg, path); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected mode 0600, got %v", perm)
+	}
+
+	tokens, err := ImportTokens(path)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	imported := &Config{}
+	tokens.Apply(imported)
+	if imported.Trakt.AccessToken != "access" || imported.Trakt.RefreshToken != "refresh" {
+		t.Fatalf("unexpected tokens: %+v", imported.Trakt)
+	}
+	if !imported.Trakt.TokenExpires.Equal(expires) {
+		t.Fatalf("expected expiry %v, got %v", expires, imported.Trakt.TokenExpires)
+	}
+	if imported.Trakt.Username != "alice" {
+		t.Fatalf("expected username to be taken over, got %q", imported.Trakt.Username)
+	}
+}
+
+func TestImportTokensRequiresBothTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path, []byte(`{"access_token":"a"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ImportTokens(path); err == nil {
+		t.Fatal("expected error for missing refresh token")
+	}
+}
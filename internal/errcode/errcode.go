@@ -0,0 +1,125 @@
+// Package errcode assigns stable codes to the errors trakt-sync reports, so
+// monitoring and support can key off a code instead of a log message that may
+// be reworded in any release. Codes are never reused or renumbered.
+package errcode
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/rs/zerolog"
+)
+
+// Code is a stable error code like TS-AUTH-001
+type Code string
+
+const (
+	// NotAuthenticated: no tokens are stored yet
+	NotAuthenticated Code = "TS-AUTH-001"
+	// TokensRejected: Trakt answered 401 to the stored tokens
+	TokensRejected Code = "TS-AUTH-002"
+	// Forbidden: Trakt answered 403, usually an invalid client ID
+	Forbidden Code = "TS-AUTH-003"
+	// AccountLocked: Trakt answered 423
+	AccountLocked Code = "TS-AUTH-004"
+
+	// AccountLimit: Trakt answered 420, a list or item limit of the account
+	AccountLimit Code = "TS-RATE-001"
+	// RateLimited: Trakt answered 429
+	RateLimited Code = "TS-RATE-002"
+
+	// NotFound: Trakt answered 404, usually a wrong list slug or username
+	NotFound Code = "TS-API-001"
+	// ServerError: Trakt answered with a 5xx status
+	ServerError Code = "TS-API-002"
+	// RequestRejected: Trakt answered with any other 4xx status
+	RequestRejected Code = "TS-API-003"
+	// Unavailable: Trakt is down for maintenance or behind a Cloudflare
+	// challenge; the run is skipped, not failed
+	Unavailable Code = "TS-API-004"
+
+	// Network: Trakt or another service could not be reached
+	Network Code = "TS-NET-001"
+
+	// InvalidConfig: the config failed validation
+	InvalidConfig Code = "TS-CONFIG-001"
+	// ReadOnly: a write was blocked because --read-only is set
+	ReadOnly Code = "TS-CONFIG-002"
+
+	// AllListsFailed: not a single list of a run could be synced
+	AllListsFailed Code = "TS-SYNC-001"
+	// Canceled: the run was interrupted, e.g. with Ctrl+C
+	Canceled Code = "TS-SYNC-002"
+
+	// Panic: a bug crashed a list sync; a crash report was written
+	Panic Code = "TS-INTERNAL-001"
+
+	// Unknown: the error has no specific code
+	Unknown Code = "TS-UNKNOWN-000"
+)
+
+// Coder is implemented by errors that carry their own code
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Of returns the code of err: the code of the first error in its chain that
+// has one, Canceled for canceled requests, Network for network failures and
+// Unknown otherwise. Of(nil) is "".
+func Of(err error) Code {
+	if err == nil {
+		return ""
+	}
+	// Walk the chain by hand so a Coder without a code (e.g. an unclassified
+	// status) does not hide a coded error further in.
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if c, ok := e.(Coder); ok {
+			if code := c.ErrorCode(); code != "" {
+				return code
+			}
+		}
+	}
+	// A canceled request fails like a network error, so check first.
+	if errors.Is(err, context.Canceled) {
+		return Canceled
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return Network
+	}
+	return Unknown
+}
+
+// Log adds err to a log event like Err, plus its code as a "code" field so
+// monitoring can alert on codes
+func Log(e *zerolog.Event, err error) *zerolog.Event {
+	e = e.Err(err)
+	if code := Of(err); code != "" {
+		e = e.Str("code", string(code))
+	}
+	return e
+}
+
+// ErrReadOnly is returned by API clients for writes blocked by --read-only
+var ErrReadOnly = New(ReadOnly, "write blocked by read-only mode")
+
+// Error is an error with a fixed code
+type Error struct {
+	Code Code
+	Msg  string
+}
+
+// New returns an error with code and message msg
+func New(code Code, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// ErrorCode implements Coder
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
@@ -0,0 +1,54 @@
+package errcode_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errcode.Code
+	}{
+		{"nil", nil, ""},
+		{"unauthorized", &trakt.APIError{Status: 401}, errcode.TokensRejected},
+		{"wrapped rate limit", fmt.Errorf("sync list: %w", &trakt.APIError{Status: 429}), errcode.RateLimited},
+		{"account limit", &trakt.APIError{Status: 420}, errcode.AccountLimit},
+		{"server error", &trakt.APIError{Status: 503}, errcode.ServerError},
+		{"bad request", &trakt.APIError{Status: 422}, errcode.RequestRejected},
+		{"config", fmt.Errorf("load: %w", config.ValidationErrors{{Path: "sync.limit"}}), errcode.InvalidConfig},
+		{"coded", errcode.New(errcode.AllListsFailed, "all lists failed"), errcode.AllListsFailed},
+		{"network", fmt.Errorf("get list: %w", &net.DNSError{Err: "no such host", Name: "api.trakt.tv"}), errcode.Network},
+		{"plain", errors.New("boom"), errcode.Unknown},
+	}
+	for _, tt := range tests {
+		if got := errcode.Of(tt.err); got != tt.want {
+			t.Errorf("%s: Of(%v) = %q, want %q", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	logger := zerolog.New(&out)
+	errcode.Log(logger.Error(), &trakt.APIError{Status: 429}).Msg("Failed to sync list")
+	if !strings.Contains(out.String(), `"code":"TS-RATE-002"`) {
+		t.Errorf("code missing from log line: %s", out.String())
+	}
+
+	out.Reset()
+	errcode.Log(logger.Error(), nil).Msg("no error")
+	if strings.Contains(out.String(), `"code"`) {
+		t.Errorf("code logged without an error: %s", out.String())
+	}
+}
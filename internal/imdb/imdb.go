@@ -0,0 +1,230 @@
+// Package imdb reads the titles of public IMDb lists, from the list's CSV
+// export or, where IMDb does not serve the export, from the list page.
+package imdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseURL is the IMDb website
+const BaseURL = "https://www.imdb.com"
+
+// Kinds of titles
+const (
+	KindMovie = "movie"
+	KindShow  = "show"
+)
+
+// Title is an entry of an IMDb list. Kind, Name and Year are only known for
+// lists read from a CSV export; Kind is KindMovie, KindShow or "".
+type Title struct {
+	IMDB string
+	Kind string
+	Name string
+	Year int
+}
+
+var (
+	listIDPattern  = regexp.MustCompile(`^ls\d+$`)
+	listURLPattern = regexp.MustCompile(`/list/(ls\d+)`)
+	titlePattern   = regexp.MustCompile(`/title/(tt\d{7,})`)
+)
+
+// Client fetches IMDb lists
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient returns a client for lists given by ID on baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		ctx:        context.Background(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// SetContext makes every request of the client use ctx, so it is aborted
+// once ctx is done
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// ValidList reports whether list names an IMDb list: a list ID like
+// ls012345678, the URL of a list or the path of a CSV export
+func ValidList(list string) bool {
+	if listIDPattern.MatchString(list) || strings.HasSuffix(strings.ToLower(list), ".csv") {
+		return true
+	}
+	u, err := url.Parse(list)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && listURLPattern.MatchString(u.Path)
+}
+
+// List returns the titles of list in list order. list is a list ID, the URL
+// of a list or the path of a CSV export saved from IMDb.
+func (c *Client) List(list string) ([]Title, error) {
+	if strings.HasSuffix(strings.ToLower(list), ".csv") {
+		file, err := os.Open(list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open IMDb export: %w", err)
+		}
+		defer file.Close()
+		return ParseCSV(file)
+	}
+
+	baseURL, id := c.baseURL, list
+	if !listIDPattern.MatchString(list) {
+		u, err := url.Parse(list)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IMDb list %q: %w", list, err)
+		}
+		match := listURLPattern.FindStringSubmatch(u.Path)
+		if match == nil {
+			return nil, fmt.Errorf("invalid IMDb list %q: expected a list ID like ls012345678", list)
+		}
+		baseURL, id = u.Scheme+"://"+u.Host, match[1]
+	}
+
+	// The export is complete and typed, but IMDb only serves it for some
+	// lists and sessions. Fall back to the titles linked on the list page.
+	body, err := c.get(baseURL + "/list/" + id + "/export")
+	if err == nil && looksLikeCSV(body) {
+		return ParseCSV(bytes.NewReader(body))
+	}
+	page, pageErr := c.get(baseURL + "/list/" + id + "/")
+	if pageErr != nil {
+		return nil, fmt.Errorf("failed to fetch IMDb list %s: %w", id, pageErr)
+	}
+	titles := ScrapePage(page)
+	if len(titles) == 0 {
+		return nil, fmt.Errorf("IMDb list %s has no titles or is not public", id)
+	}
+	return titles, nil
+}
+
+func (c *Client) get(target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "trakt-sync")
+	req.Header.Set("Accept-Language", "en-US")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IMDb returned %s", resp.Status)
+	}
+	return body, nil
+}
+
+func looksLikeCSV(body []byte) bool {
+	header, _, _ := bytes.Cut(body, []byte("\n"))
+	return bytes.Contains(header, []byte("Const"))
+}
+
+// ParseCSV reads the titles of an IMDb list export. The Const column holds
+// the IMDb ID; Title, Year and Title Type are used when present. Titles that
+// are neither movies nor shows, e.g. episodes, are skipped.
+func ParseCSV(r io.Reader) ([]Title, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IMDb export: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Exports start with a byte order mark.
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+	constCol, ok := columns["Const"]
+	if !ok {
+		return nil, errors.New("IMDb export has no Const column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var titles []Title
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IMDb export: %w", err)
+		}
+		if constCol >= len(record) || !strings.HasPrefix(record[constCol], "tt") {
+			continue
+		}
+		kind, ok := titleKind(field(record, "Title Type"))
+		if !ok {
+			continue
+		}
+		year, _ := strconv.Atoi(field(record, "Year"))
+		titles = append(titles, Title{
+			IMDB: record[constCol],
+			Kind: kind,
+			Name: field(record, "Title"),
+			Year: year,
+		})
+	}
+	return titles, nil
+}
+
+// titleKind maps an IMDb title type like "TV Mini Series" to KindMovie or
+// KindShow. ok is false for types neither list holds, e.g. episodes.
+func titleKind(titleType string) (kind string, ok bool) {
+	switch strings.ToLower(titleType) {
+	case "":
+		return "", true
+	case "movie", "tv movie", "video", "short", "tv short", "tv special":
+		return KindMovie, true
+	case "tv series", "tv mini series":
+		return KindShow, true
+	}
+	return "", false
+}
+
+// ScrapePage returns the titles linked on a list page, in page order. The
+// page only shows the first page of large lists.
+func ScrapePage(page []byte) []Title {
+	var titles []Title
+	seen := make(map[string]bool)
+	for _, match := range titlePattern.FindAllSubmatch(page, -1) {
+		id := string(match[1])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		titles = append(titles, Title{IMDB: id})
+	}
+	return titles
+}
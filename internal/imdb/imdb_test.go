@@ -0,0 +1,58 @@
+package imdb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListFallsBackToListPage(t *testing.T) {
+	page := `<a href="/title/tt0111161/?ref_=ttls_li_tt">The Shawshank Redemption</a>
+<a href="/title/tt0068646/">The Godfather</a>
+<a href="/title/tt0111161/">The Shawshank Redemption</a>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list/ls000000001/export":
+			http.Error(w, "sign in to export", http.StatusForbidden)
+		case "/list/ls000000001/":
+			w.Write([]byte(page))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	titles, err := NewClient(server.URL).List("ls000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Title{{IMDB: "tt0111161"}, {IMDB: "tt0068646"}}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles = %+v, want %+v", titles, want)
+	}
+
+	if _, err := NewClient(server.URL).List("ls000000002"); err == nil {
+		t.Error("expected an error for a missing list")
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	export := "\ufeffPosition,Const,Title,Title Type,Year\n" +
+		"1,tt0111161,The Shawshank Redemption,Movie,1994\n" +
+		"2,tt0903747,Breaking Bad,TV Series,2008\n" +
+		"3,tt0959621,Pilot,TV Episode,2008\n"
+
+	titles, err := ParseCSV(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Title{
+		{IMDB: "tt0111161", Kind: KindMovie, Name: "The Shawshank Redemption", Year: 1994},
+		{IMDB: "tt0903747", Kind: KindShow, Name: "Breaking Bad", Year: 2008},
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles = %+v, want %+v", titles, want)
+	}
+}
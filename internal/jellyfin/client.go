@@ -0,0 +1,185 @@
+// Package jellyfin keeps Jellyfin (and Emby) collections in step with synced
+// Trakt lists through the server's REST API.
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+)
+
+// libraryPageSize is the number of library items requested per page
+const libraryPageSize = 500
+
+// Item types in the Jellyfin API
+const (
+	TypeMovie      = "Movie"
+	TypeSeries     = "Series"
+	TypeCollection = "BoxSet"
+)
+
+// Client talks to a Jellyfin or Emby server with an API key
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	readOnly   bool
+}
+
+// Item is a movie, series or collection in the library
+type Item struct {
+	ID             string `json:"Id"`
+	Name           string `json:"Name"`
+	Type           string `json:"Type"`
+	ProductionYear int    `json:"ProductionYear,omitempty"`
+	// ProviderIds holds external IDs, e.g. "Tmdb" and "Imdb"
+	ProviderIds map[string]string `json:"ProviderIds,omitempty"`
+}
+
+// SetReadOnly makes the client refuse every request that could change data
+// on the server with errcode.ErrReadOnly
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// NewClient returns a client for the server at baseURL
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		ctx:        context.Background(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+	}
+}
+
+// SetContext makes every request of the client use ctx, so it is aborted
+// once ctx is done
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Library returns all movies and series with their provider IDs
+func (c *Client) Library() ([]Item, error) {
+	items, err := c.items(url.Values{
+		"Recursive":        {"true"},
+		"IncludeItemTypes": {TypeMovie + "," + TypeSeries},
+		"Fields":           {"ProviderIds"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library: %w", err)
+	}
+	return items, nil
+}
+
+// Collections returns all collections
+func (c *Client) Collections() ([]Item, error) {
+	items, err := c.items(url.Values{
+		"Recursive":        {"true"},
+		"IncludeItemTypes": {TypeCollection},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	return items, nil
+}
+
+// CollectionItems returns the items of a collection
+func (c *Client) CollectionItems(collectionID string) ([]Item, error) {
+	items, err := c.items(url.Values{"ParentId": {collectionID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items of collection %s: %w", collectionID, err)
+	}
+	return items, nil
+}
+
+// CreateCollection creates a collection holding itemIDs and returns its ID
+func (c *Client) CreateCollection(name string, itemIDs []string) (string, error) {
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	query := url.Values{"Name": {name}, "Ids": {strings.Join(itemIDs, ",")}}
+	if err := c.do("POST", "/Collections", query, &resp); err != nil {
+		return "", fmt.Errorf("failed to create collection %q: %w", name, err)
+	}
+	return resp.ID, nil
+}
+
+// AddToCollection adds items to a collection
+func (c *Client) AddToCollection(collectionID string, itemIDs []string) error {
+	query := url.Values{"Ids": {strings.Join(itemIDs, ",")}}
+	if err := c.do("POST", "/Collections/"+url.PathEscape(collectionID)+"/Items", query, nil); err != nil {
+		return fmt.Errorf("failed to add items to collection %s: %w", collectionID, err)
+	}
+	return nil
+}
+
+// RemoveFromCollection removes items from a collection
+func (c *Client) RemoveFromCollection(collectionID string, itemIDs []string) error {
+	query := url.Values{"Ids": {strings.Join(itemIDs, ",")}}
+	if err := c.do("DELETE", "/Collections/"+url.PathEscape(collectionID)+"/Items", query, nil); err != nil {
+		return fmt.Errorf("failed to remove items from collection %s: %w", collectionID, err)
+	}
+	return nil
+}
+
+// items pages through an /Items query
+func (c *Client) items(query url.Values) ([]Item, error) {
+	var items []Item
+	for start := 0; ; start += libraryPageSize {
+		var resp struct {
+			Items            []Item `json:"Items"`
+			TotalRecordCount int    `json:"TotalRecordCount"`
+		}
+		query.Set("StartIndex", fmt.Sprint(start))
+		query.Set("Limit", fmt.Sprint(libraryPageSize))
+		if err := c.do("GET", "/Items", query, &resp); err != nil {
+			return nil, err
+		}
+		items = append(items, resp.Items...)
+		if len(resp.Items) == 0 || len(items) >= resp.TotalRecordCount {
+			return items, nil
+		}
+	}
+}
+
+func (c *Client) do(method, path string, query url.Values, result interface{}) error {
+	if c.readOnly && method != http.MethodGet {
+		return fmt.Errorf("%s %s: %w", method, path, errcode.ErrReadOnly)
+	}
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	// Jellyfin and Emby both accept the key in the Emby header.
+	req.Header.Set("X-Emby-Token", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("jellyfin API key was rejected (%s)", resp.Status)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("jellyfin API error %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
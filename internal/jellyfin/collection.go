@@ -0,0 +1,169 @@
+package jellyfin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Title is a movie or show of a synced Trakt list
+type Title struct {
+	Name  string
+	TMDB  int
+	IMDB  string
+	Movie bool
+}
+
+// Result summarizes a collection sync
+type Result struct {
+	Created   bool
+	Added     int
+	Removed   int
+	Unmatched int
+}
+
+// Index finds library items by their TMDB or IMDb ID
+type Index struct {
+	ids map[string]string
+}
+
+// NewIndex indexes the provider IDs of library items. TMDB IDs of movies and
+// shows overlap, so keys include the item type.
+func NewIndex(items []Item) *Index {
+	index := &Index{ids: make(map[string]string, 2*len(items))}
+	for _, item := range items {
+		for provider, id := range item.ProviderIds {
+			if id == "" {
+				continue
+			}
+			if provider = strings.ToLower(provider); provider == "tmdb" || provider == "imdb" {
+				index.ids[indexKey(item.Type == TypeMovie, provider, id)] = item.ID
+			}
+		}
+	}
+	return index
+}
+
+// Find returns the library item ID of a title, preferring the TMDB match
+func (x *Index) Find(title Title) (string, bool) {
+	if title.TMDB > 0 {
+		if id, ok := x.ids[indexKey(title.Movie, "tmdb", fmt.Sprint(title.TMDB))]; ok {
+			return id, true
+		}
+	}
+	if title.IMDB != "" {
+		if id, ok := x.ids[indexKey(title.Movie, "imdb", title.IMDB)]; ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func indexKey(movie bool, provider, id string) string {
+	kind := "show"
+	if movie {
+		kind = "movie"
+	}
+	return kind + ":" + provider + ":" + id
+}
+
+// FindCollection returns the ID of the collection trakt-sync used before, or
+// of one with the given name, or "" if there is none
+func FindCollection(collections []Item, knownID, name string) string {
+	for _, collection := range collections {
+		if knownID != "" && collection.ID == knownID {
+			return collection.ID
+		}
+	}
+	for _, collection := range collections {
+		if collection.Name == name {
+			return collection.ID
+		}
+	}
+	return ""
+}
+
+// SyncCollection makes the collection hold exactly the library items of
+// titles, creating it under name when collectionID is "". Titles that are not
+// in the library are skipped. It returns the collection ID. With dryRun set,
+// changes are only logged.
+func SyncCollection(c *Client, index *Index, collectionID, name string, titles []Title, dryRun bool) (string, Result, error) {
+	var result Result
+	wanted := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		id, ok := index.Find(title)
+		if !ok {
+			log.Debug().Str("title", title.Name).Str("collection", name).Msg("Not in Jellyfin library, skipping")
+			result.Unmatched++
+			continue
+		}
+		wanted[id] = true
+	}
+
+	if collectionID == "" {
+		// Jellyfin does not create empty collections from the API.
+		if len(wanted) == 0 {
+			return "", result, nil
+		}
+		result.Created = true
+		result.Added = len(wanted)
+		if dryRun {
+			log.Info().Str("collection", name).Int("items", len(wanted)).Msg("DRY RUN: would create Jellyfin collection")
+			return "", result, nil
+		}
+		id, err := c.CreateCollection(name, sortedKeys(wanted))
+		return id, result, err
+	}
+
+	current, err := c.CollectionItems(collectionID)
+	if err != nil {
+		return collectionID, result, err
+	}
+	present := make(map[string]bool, len(current))
+	var toRemove []string
+	for _, item := range current {
+		present[item.ID] = true
+		if !wanted[item.ID] {
+			toRemove = append(toRemove, item.ID)
+		}
+	}
+	sort.Strings(toRemove)
+	var toAdd []string
+	for _, id := range sortedKeys(wanted) {
+		if !present[id] {
+			toAdd = append(toAdd, id)
+		}
+	}
+
+	if dryRun {
+		if len(toAdd) > 0 || len(toRemove) > 0 {
+			log.Info().Str("collection", name).Int("add", len(toAdd)).Int("remove", len(toRemove)).Msg("DRY RUN: would update Jellyfin collection")
+		}
+		result.Added, result.Removed = len(toAdd), len(toRemove)
+		return collectionID, result, nil
+	}
+	if len(toAdd) > 0 {
+		if err := c.AddToCollection(collectionID, toAdd); err != nil {
+			return collectionID, result, err
+		}
+		result.Added = len(toAdd)
+	}
+	if len(toRemove) > 0 {
+		if err := c.RemoveFromCollection(collectionID, toRemove); err != nil {
+			return collectionID, result, err
+		}
+		result.Removed = len(toRemove)
+	}
+	return collectionID, result, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,209 @@
+// Package jellyfintest provides a fake Jellyfin server for tests: a library of
+// movies and series and the collections built from it.
+package jellyfintest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maximilian/trakt-sync/internal/jellyfin"
+)
+
+// APIKey is the only API key the fake accepts
+const APIKey = "jellyfin-e2e-key"
+
+// Server is a fake Jellyfin API backed by in-memory state
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	library     []jellyfin.Item
+	collections []jellyfin.Item
+	members     map[string][]string
+}
+
+// NewServer starts a fake Jellyfin server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{members: make(map[string][]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Add puts a movie or series into the library and returns its item ID.
+// providerIDs are pairs like "Tmdb", "603".
+func (s *Server) Add(name, itemType string, providerIDs ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := jellyfin.Item{
+		ID:          fmt.Sprintf("item%04d", len(s.library)+1),
+		Name:        name,
+		Type:        itemType,
+		ProviderIds: make(map[string]string),
+	}
+	for i := 0; i+1 < len(providerIDs); i += 2 {
+		item.ProviderIds[providerIDs[i]] = providerIDs[i+1]
+	}
+	s.library = append(s.library, item)
+	return item.ID
+}
+
+// Collection returns the names of the items in the named collection, sorted,
+// and whether it exists
+func (s *Server) Collection(name string) ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, collection := range s.collections {
+		if collection.Name != name {
+			continue
+		}
+		names := []string{}
+		for _, id := range s.members[collection.ID] {
+			if item, ok := s.item(id); ok {
+				names = append(names, item.Name)
+			}
+		}
+		sort.Strings(names)
+		return names, true
+	}
+	return nil, false
+}
+
+// Collections returns the number of collections
+func (s *Server) Collections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.collections)
+}
+
+func (s *Server) item(id string) (jellyfin.Item, bool) {
+	for _, item := range s.library {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return jellyfin.Item{}, false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Emby-Token") != APIKey {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	ids := strings.Split(query.Get("Ids"), ",")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/Items":
+		s.handleItems(w, r)
+	case r.Method == "POST" && r.URL.Path == "/Collections":
+		collection := jellyfin.Item{
+			ID:   fmt.Sprintf("boxset%04d", len(s.collections)+1),
+			Name: query.Get("Name"),
+			Type: jellyfin.TypeCollection,
+		}
+		s.collections = append(s.collections, collection)
+		s.members[collection.ID] = s.addMembers(nil, ids)
+		writeJSON(w, map[string]string{"Id": collection.ID})
+	case strings.HasPrefix(r.URL.Path, "/Collections/") && strings.HasSuffix(r.URL.Path, "/Items"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/Collections/"), "/Items")
+		members, ok := s.members[id]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case "POST":
+			s.members[id] = s.addMembers(members, ids)
+		case "DELETE":
+			s.members[id] = removeMembers(members, ids)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) handleItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var matches []jellyfin.Item
+	if parent := query.Get("ParentId"); parent != "" {
+		for _, id := range s.members[parent] {
+			if item, ok := s.item(id); ok {
+				matches = append(matches, item)
+			}
+		}
+	} else {
+		types := strings.Split(query.Get("IncludeItemTypes"), ",")
+		for _, item := range append(append([]jellyfin.Item{}, s.library...), s.collections...) {
+			for _, itemType := range types {
+				if item.Type == itemType {
+					matches = append(matches, item)
+				}
+			}
+		}
+	}
+
+	start, _ := strconv.Atoi(query.Get("StartIndex"))
+	limit, err := strconv.Atoi(query.Get("Limit"))
+	if err != nil || limit <= 0 {
+		limit = len(matches)
+	}
+	page := []jellyfin.Item{}
+	for i := start; i < len(matches) && len(page) < limit; i++ {
+		page = append(page, matches[i])
+	}
+	writeJSON(w, map[string]interface{}{
+		"Items":            page,
+		"TotalRecordCount": len(matches),
+		"StartIndex":       start,
+	})
+}
+
+func (s *Server) addMembers(members, ids []string) []string {
+	for _, id := range ids {
+		if _, ok := s.item(id); !ok {
+			continue
+		}
+		present := false
+		for _, member := range members {
+			present = present || member == id
+		}
+		if !present {
+			members = append(members, id)
+		}
+	}
+	return members
+}
+
+func removeMembers(members, ids []string) []string {
+	kept := members[:0]
+	for _, member := range members {
+		removed := false
+		for _, id := range ids {
+			removed = removed || member == id
+		}
+		if !removed {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
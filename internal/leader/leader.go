@@ -0,0 +1,111 @@
+// Package leader elects one of several daemon replicas to sync, using a
+// lease in the shared state backend. The leader renews its lease well before
+// it expires; a standby takes over once the leader stops renewing.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+)
+
+// Leaser is a store that can hold a time-limited lease
+type Leaser interface {
+	// AcquireLease takes the lease for holder, or extends it if holder
+	// already has it, and reports whether holder holds it now
+	AcquireLease(holder string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives up the lease if holder holds it
+	ReleaseLease(holder string) error
+}
+
+// Elector campaigns for the lease on behalf of one replica
+type Elector struct {
+	leaser Leaser
+	id     string
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	leader bool
+	// term counts the times this replica acquired the lease
+	term int
+}
+
+// New returns an elector for the replica id with leases of ttl
+func New(leaser Leaser, id string, ttl time.Duration) *Elector {
+	return &Elector{leaser: leaser, id: id, ttl: ttl}
+}
+
+// DefaultID identifies this replica by host name and process ID
+func DefaultID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Campaign tries once to take or renew the lease and reports whether this
+// replica leads. A replica that cannot reach the store steps down.
+func (e *Elector) Campaign() bool {
+	held, err := e.leaser.AcquireLease(e.id, e.ttl)
+	if err != nil {
+		errcode.Log(log.Warn(), err).Str("id", e.id).Msg("Failed to renew leader lease")
+		held = false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case held && !e.leader:
+		log.Info().Str("id", e.id).Dur("lease", e.ttl).Msg("Acquired leadership, this replica syncs")
+		e.term++
+	case !held && e.leader:
+		log.Warn().Str("id", e.id).Msg("Lost leadership, standing by")
+	}
+	e.leader = held
+	return held
+}
+
+// IsLeader reports whether this replica held the lease at the last campaign
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Term counts the times this replica acquired the lease. A change tells the
+// replica it took over from another one since it last looked.
+func (e *Elector) Term() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.term
+}
+
+// Run campaigns every third of the lease until ctx is done, then releases
+// the lease so a standby can take over right away
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				if err := e.leaser.ReleaseLease(e.id); err != nil {
+					errcode.Log(log.Warn(), err).Msg("Failed to release leader lease")
+				}
+				e.mu.Lock()
+				e.leader = false
+				e.mu.Unlock()
+			}
+			return
+		case <-ticker.C:
+			e.Campaign()
+		}
+	}
+}
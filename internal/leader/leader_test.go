@@ -0,0 +1,74 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+)
+
+func openStore(t *testing.T) *state.SQLiteStore {
+	t.Helper()
+	store, err := state.OpenSQLite(filepath.Join(t.TempDir(), state.SQLiteFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStandbyTakesOverExpiredLease(t *testing.T) {
+	store := openStore(t)
+	const ttl = 100 * time.Millisecond
+	primary := New(store, "primary", ttl)
+	standby := New(store, "standby", ttl)
+
+	if !primary.Campaign() {
+		t.Fatal("first replica did not get the free lease")
+	}
+	if standby.Campaign() {
+		t.Fatal("standby got a lease that is held")
+	}
+	if !primary.Campaign() {
+		t.Fatal("leader could not renew its lease")
+	}
+
+	// The primary stops renewing, e.g. because it crashed.
+	time.Sleep(ttl + 20*time.Millisecond)
+	if !standby.Campaign() {
+		t.Fatal("standby did not take over the expired lease")
+	}
+	if primary.Campaign() || primary.IsLeader() {
+		t.Error("old leader still leads after the standby took over")
+	}
+	if primary.Term() != 1 || standby.Term() != 1 {
+		t.Errorf("terms = %d, %d, want one term each", primary.Term(), standby.Term())
+	}
+}
+
+func TestRunReleasesLeaseOnShutdown(t *testing.T) {
+	store := openStore(t)
+	primary := New(store, "primary", time.Hour)
+	standby := New(store, "standby", time.Hour)
+
+	if !primary.Campaign() {
+		t.Fatal("first replica did not get the free lease")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		primary.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	if primary.IsLeader() {
+		t.Error("replica still leads after shutdown")
+	}
+	if !standby.Campaign() {
+		t.Error("standby could not take over the released lease")
+	}
+}
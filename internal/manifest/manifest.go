@@ -0,0 +1,168 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Manifest announces releases, upcoming Trakt API changes and deprecated
+// config keys. It is a small JSON document published alongside releases.
+type Manifest struct {
+	// Channels maps a release channel (stable, beta) to its latest release
+	Channels       map[string]Release `json:"channels"`
+	APIChanges     []APIChange        `json:"api_changes"`
+	DeprecatedKeys []DeprecatedKey    `json:"deprecated_keys"`
+}
+
+// Release is the latest version on a channel
+type Release struct {
+	Version string `json:"version"`
+	URL     string `json:"url,omitempty"`
+}
+
+// APIChange is a breaking Trakt API change and what users should do about it
+type APIChange struct {
+	Message string `json:"message"`
+	Action  string `json:"action,omitempty"`
+	// FixedIn is the first release that handles the change
+	FixedIn string `json:"fixed_in,omitempty"`
+}
+
+// DeprecatedKey is a config key that is going away
+type DeprecatedKey struct {
+	Key         string `json:"key"`
+	Replacement string `json:"replacement,omitempty"`
+	RemovedIn   string `json:"removed_in,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Fetch downloads and parses the manifest at url
+func Fetch(url string) (*Manifest, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch manifest: %s", resp.Status)
+	}
+
+	var m Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Warnings returns actionable messages for the running version on channel
+// and the keys set in its config file. Notices that only concern older
+// versions are left out; development builds see all of them.
+func (m *Manifest) Warnings(channel, version string, configKeys []string) []string {
+	var warnings []string
+
+	if release, ok := m.Channels[channel]; ok && release.Version != "" && olderThan(version, release.Version) {
+		msg := fmt.Sprintf("trakt-sync %s is available on the %s channel (running %s)", release.Version, channel, version)
+		if release.URL != "" {
+			msg += ": " + release.URL
+		}
+		warnings = append(warnings, msg)
+	}
+
+	for _, change := range m.APIChanges {
+		if change.FixedIn != "" && !olderThan(version, change.FixedIn) && isRelease(version) {
+			continue
+		}
+		msg := "Trakt API change: " + change.Message
+		if change.Action != "" {
+			msg += " " + change.Action
+		}
+		warnings = append(warnings, msg)
+	}
+
+	for _, deprecated := range m.DeprecatedKeys {
+		if !hasKey(configKeys, deprecated.Key) {
+			continue
+		}
+		msg := fmt.Sprintf("Config key %s is deprecated", deprecated.Key)
+		if deprecated.RemovedIn != "" {
+			msg += fmt.Sprintf(" and will be removed in %s", deprecated.RemovedIn)
+		}
+		if deprecated.Replacement != "" {
+			msg += fmt.Sprintf("; use %s instead", deprecated.Replacement)
+		}
+		if deprecated.Message != "" {
+			msg += ". " + deprecated.Message
+		}
+		warnings = append(warnings, msg)
+	}
+
+	return warnings
+}
+
+// hasKey reports whether key or one of its children is set
+func hasKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isRelease(version string) bool {
+	_, _, ok := parseVersion(version)
+	return ok
+}
+
+// olderThan reports whether version a precedes b. Versions that do not parse,
+// e.g. "dev", are never older.
+func olderThan(a, b string) bool {
+	coreA, preA, okA := parseVersion(a)
+	coreB, preB, okB := parseVersion(b)
+	if !okA || !okB {
+		return false
+	}
+
+	for i := range coreA {
+		if coreA[i] != coreB[i] {
+			return coreA[i] < coreB[i]
+		}
+	}
+	// A pre-release precedes its release.
+	switch {
+	case preA == preB:
+		return false
+	case preA == "":
+		return false
+	case preB == "":
+		return true
+	}
+	return preA < preB
+}
+
+// parseVersion splits "v1.2.3-beta.1" into its numeric core and pre-release
+func parseVersion(version string) ([3]int, string, bool) {
+	var core [3]int
+	version = strings.TrimPrefix(version, "v")
+	version, pre, _ := strings.Cut(version, "-")
+
+	parts := strings.Split(version, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return core, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return core, "", false
+		}
+		core[i] = n
+	}
+	return core, pre, true
+}
@@ -0,0 +1,63 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOlderThan(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.3", "1.3.0", true},
+		{"v1.10.0", "1.9.9", false},
+		{"1.2.3", "1.2.3", false},
+		{"1.3.0-beta.1", "1.3.0", true},
+		{"1.3.0", "1.3.0-beta.1", false},
+		{"1.3.0-beta.1", "1.3.0-beta.2", true},
+		{"1.2", "1.2.1", true},
+		{"dev", "1.0.0", false},
+	}
+	for _, c := range cases {
+		if got := olderThan(c.a, c.b); got != c.want {
+			t.Errorf("olderThan(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	m := &Manifest{
+		Channels: map[string]Release{
+			"stable": {Version: "1.4.0"},
+			"beta":   {Version: "1.5.0-beta.1"},
+		},
+		APIChanges: []APIChange{
+			{Message: "Lists move to /v3.", Action: "Upgrade before March.", FixedIn: "1.4.0"},
+		},
+		DeprecatedKeys: []DeprecatedKey{
+			{Key: "sync.lists", Replacement: "sync.sources", RemovedIn: "2.0.0"},
+			{Key: "sync.min_rating"},
+		},
+	}
+
+	got := m.Warnings("stable", "1.3.0", []string{"sync.limit", "sync.lists.movies"})
+	want := []string{
+		"trakt-sync 1.4.0 is available on the stable channel (running 1.3.0)",
+		"Trakt API change: Lists move to /v3. Upgrade before March.",
+		"Config key sync.lists is deprecated and will be removed in 2.0.0; use sync.sources instead",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	got = m.Warnings("stable", "1.4.0", nil)
+	if len(got) != 0 {
+		t.Errorf("up-to-date release got warnings %q", got)
+	}
+
+	got = m.Warnings("beta", "1.4.0", nil)
+	if len(got) != 1 || got[0] != "trakt-sync 1.5.0-beta.1 is available on the beta channel (running 1.4.0)" {
+		t.Errorf("beta warnings = %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+// Package mdblist reads public MDBList lists through their JSON export
+package mdblist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BaseURL is the MDBList website, which serves each public list as JSON
+const BaseURL = "https://mdblist.com"
+
+// Media types of list items
+const (
+	TypeMovie = "movie"
+	TypeShow  = "show"
+)
+
+// Item is an entry of an MDBList list
+type Item struct {
+	Rank      int    `json:"rank"`
+	Title     string `json:"title"`
+	IMDB      string `json:"imdb_id"`
+	TVDB      int    `json:"tvdb_id"`
+	MediaType string `json:"mediatype"`
+	Year      int    `json:"release_year"`
+}
+
+// listPattern matches a list as user/slug
+var listPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// Client fetches MDBList lists
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient returns a client for lists on baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		ctx:        context.Background(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// SetContext makes every request of the client use ctx, so it is aborted
+// once ctx is done
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// ValidList reports whether list names an MDBList list: user/slug or the URL
+// of a list
+func ValidList(list string) bool {
+	_, _, err := splitList(list)
+	return err == nil
+}
+
+// splitList returns the base URL and user/slug path of list. The base URL is
+// "" unless list is a URL.
+func splitList(list string) (baseURL, path string, err error) {
+	if listPattern.MatchString(list) {
+		return "", list, nil
+	}
+	u, err := url.Parse(list)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", "", fmt.Errorf("invalid MDBList list %q: expected user/slug or a list URL", list)
+	}
+	path = strings.TrimSuffix(strings.TrimPrefix(strings.Trim(u.Path, "/"), "lists/"), "/json")
+	if !listPattern.MatchString(path) {
+		return "", "", fmt.Errorf("invalid MDBList list %q: expected user/slug or a list URL", list)
+	}
+	return u.Scheme + "://" + u.Host, path, nil
+}
+
+// List returns the items of list in list order. list is user/slug, as in
+// https://mdblist.com/lists/user/slug, or the URL of the list.
+func (c *Client) List(list string) ([]Item, error) {
+	baseURL, path, err := splitList(list)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		baseURL = c.baseURL
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, baseURL+"/lists/"+path+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "trakt-sync")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch MDBList list %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("MDBList list %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var items []Item
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("failed to decode MDBList list %s: %w", path, err)
+	}
+	return items, nil
+}
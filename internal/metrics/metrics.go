@@ -0,0 +1,227 @@
+// Package metrics collects what the daemon did for monitoring: it serves the
+// outcome of the last sync at /healthz and counters in the Prometheus text
+// format at /metrics.
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Run is the outcome of a sync
+type Run struct {
+	Finished   time.Time
+	Duration   time.Duration
+	Successful int
+	Failed     int
+	// Err is the error that ended the run, if any
+	Err error
+	// Stopped is set for a run that ended early, by a signal,
+	// daemon.max_runtime or quiet hours; the lists it skipped are synced
+	// by a later run
+	Stopped bool
+}
+
+// Metrics counts syncs, list writes and Trakt API errors. A nil *Metrics
+// records nothing, so callers need not check whether metrics are enabled.
+type Metrics struct {
+	mu sync.Mutex
+
+	started time.Time
+	last    *Run
+
+	syncs         map[string]int
+	syncSeconds   float64
+	itemsAdded    int
+	itemsRemoved  int
+	apiErrors     map[string]int
+	rateLimitWait int
+	rateLimitSecs float64
+}
+
+// New returns empty metrics
+func New() *Metrics {
+	return &Metrics{
+		started:   time.Now(),
+		syncs:     make(map[string]int),
+		apiErrors: make(map[string]int),
+	}
+}
+
+// status is how a run ended: "success", "partial" with failed lists,
+// "failed" or "stopped" early
+func (r Run) status() string {
+	switch {
+	case r.Stopped:
+		return "stopped"
+	case r.Err != nil || (r.Failed > 0 && r.Successful == 0):
+		return "failed"
+	case r.Failed > 0:
+		return "partial"
+	}
+	return "success"
+}
+
+// RecordSync records a finished sync
+func (m *Metrics) RecordSync(run Run) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = &run
+	m.syncs[run.status()]++
+	m.syncSeconds += run.Duration.Seconds()
+}
+
+// ItemsAdded counts items added to a list
+func (m *Metrics) ItemsAdded(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.itemsAdded += n
+}
+
+// ItemsRemoved counts items removed from a list
+func (m *Metrics) ItemsRemoved(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.itemsRemoved += n
+}
+
+// APIError counts a failed Trakt request by its error code, e.g. TS-API-002
+func (m *Metrics) APIError(code string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiErrors[code]++
+}
+
+// RateLimitWait counts a wait for the Trakt rate limit
+func (m *Metrics) RateLimitWait(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimitWait++
+	m.rateLimitSecs += d.Seconds()
+}
+
+// Health is the /healthz response
+type Health struct {
+	Status string `json:"status"`
+	// LastSync is when the last sync finished; nil before the first one
+	LastSync   *time.Time `json:"last_sync"`
+	LastResult string     `json:"last_result,omitempty"`
+	Successful int        `json:"successful"`
+	Failed     int        `json:"failed"`
+	Duration   string     `json:"duration,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Health describes the last sync. Only a failed sync makes the daemon
+// unhealthy, not one that was stopped early; there is nothing to report
+// before the first one.
+func (m *Metrics) Health() Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.last == nil {
+		return Health{Status: "ok"}
+	}
+	h := Health{
+		Status:     "ok",
+		LastSync:   &m.last.Finished,
+		LastResult: m.last.status(),
+		Successful: m.last.Successful,
+		Failed:     m.last.Failed,
+		Duration:   m.last.Duration.Round(time.Millisecond).String(),
+	}
+	if m.last.Err != nil {
+		h.Error = m.last.Err.Error()
+	}
+	if h.LastResult == "failed" {
+		h.Status = "failing"
+	}
+	return h
+}
+
+// Handler serves /healthz and /metrics
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		h := m.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if h.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(h)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.write(w)
+	})
+	return mux
+}
+
+// write writes the metrics in the Prometheus text format
+func (m *Metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("trakt_sync_up_since_seconds", "gauge", "Unix time the daemon started.")
+	fmt.Fprintf(w, "trakt_sync_up_since_seconds %d\n", m.started.Unix())
+
+	metric("trakt_sync_runs_total", "counter", "Syncs by result: success, partial, failed or stopped.")
+	for _, status := range []string{"success", "partial", "failed", "stopped"} {
+		fmt.Fprintf(w, "trakt_sync_runs_total{result=%q} %d\n", status, m.syncs[status])
+	}
+	metric("trakt_sync_run_duration_seconds_total", "counter", "Time spent syncing.")
+	fmt.Fprintf(w, "trakt_sync_run_duration_seconds_total %g\n", m.syncSeconds)
+
+	if m.last != nil {
+		metric("trakt_sync_last_run_timestamp_seconds", "gauge", "Unix time the last sync finished.")
+		fmt.Fprintf(w, "trakt_sync_last_run_timestamp_seconds %d\n", m.last.Finished.Unix())
+		metric("trakt_sync_last_run_duration_seconds", "gauge", "Duration of the last sync.")
+		fmt.Fprintf(w, "trakt_sync_last_run_duration_seconds %g\n", m.last.Duration.Seconds())
+		metric("trakt_sync_last_run_lists", "gauge", "Lists of the last sync by result.")
+		fmt.Fprintf(w, "trakt_sync_last_run_lists{result=\"synced\"} %d\n", m.last.Successful)
+		fmt.Fprintf(w, "trakt_sync_last_run_lists{result=\"failed\"} %d\n", m.last.Failed)
+	}
+
+	metric("trakt_sync_items_added_total", "counter", "Items added to Trakt lists.")
+	fmt.Fprintf(w, "trakt_sync_items_added_total %d\n", m.itemsAdded)
+	metric("trakt_sync_items_removed_total", "counter", "Items removed from Trakt lists.")
+	fmt.Fprintf(w, "trakt_sync_items_removed_total %d\n", m.itemsRemoved)
+
+	metric("trakt_sync_api_errors_total", "counter", "Failed Trakt API requests by error code.")
+	codes := make([]string, 0, len(m.apiErrors))
+	for code := range m.apiErrors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "trakt_sync_api_errors_total{code=%q} %d\n", code, m.apiErrors[code])
+	}
+
+	metric("trakt_sync_rate_limit_waits_total", "counter", "Waits for the Trakt rate limit.")
+	fmt.Fprintf(w, "trakt_sync_rate_limit_waits_total %d\n", m.rateLimitWait)
+	metric("trakt_sync_rate_limit_wait_seconds_total", "counter", "Time spent waiting for the Trakt rate limit.")
+	fmt.Fprintf(w, "trakt_sync_rate_limit_wait_seconds_total %g\n", m.rateLimitSecs)
+}
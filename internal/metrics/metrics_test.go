@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	m := New()
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("/healthz"); status != http.StatusOK {
+		t.Errorf("expected 200 before the first sync, got %d", status)
+	}
+
+	finished := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	m.RecordSync(Run{Finished: finished, Duration: 2 * time.Second, Successful: 2, Failed: 1})
+	m.ItemsAdded(3)
+	m.ItemsRemoved(1)
+	m.APIError("TS-API-002")
+	m.APIError("TS-API-002")
+	m.RateLimitWait(1500 * time.Millisecond)
+
+	status, body := get("/healthz")
+	var health Health
+	if err := json.Unmarshal([]byte(body), &health); err != nil {
+		t.Fatalf("decode /healthz: %v", err)
+	}
+	if status != http.StatusOK || health.LastResult != "partial" || health.LastSync == nil || !health.LastSync.Equal(finished) {
+		t.Errorf("unexpected /healthz after a partial sync: %d %s", status, body)
+	}
+
+	_, body = get("/metrics")
+	for _, line := range []string{
+		`trakt_sync_runs_total{result="partial"} 1`,
+		`trakt_sync_last_run_duration_seconds 2`,
+		`trakt_sync_items_added_total 3`,
+		`trakt_sync_items_removed_total 1`,
+		`trakt_sync_api_errors_total{code="TS-API-002"} 2`,
+		`trakt_sync_rate_limit_waits_total 1`,
+		`trakt_sync_rate_limit_wait_seconds_total 1.5`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("/metrics lacks %q:\n%s", line, body)
+		}
+	}
+
+	m.RecordSync(Run{Finished: finished.Add(time.Hour), Err: errors.New("tokens rejected")})
+	if status, body := get("/healthz"); status != http.StatusServiceUnavailable || !strings.Contains(body, "tokens rejected") {
+		t.Errorf("expected 503 with the error after a failed sync, got %d %s", status, body)
+	}
+
+	// A run stopped by daemon.max_runtime replaces the failed one.
+	m.RecordSync(Run{Finished: finished.Add(2 * time.Hour), Successful: 1, Stopped: true})
+	if status, body := get("/healthz"); status != http.StatusOK || !strings.Contains(body, `"last_result":"stopped"`) {
+		t.Errorf("expected 200 after a stopped sync, got %d %s", status, body)
+	}
+	if _, body := get("/metrics"); !strings.Contains(body, `trakt_sync_runs_total{result="stopped"} 1`+"\n") {
+		t.Errorf("/metrics lacks the stopped run:\n%s", body)
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	m.RecordSync(Run{})
+	m.ItemsAdded(1)
+	m.APIError("TS-NET-001")
+	m.RateLimitWait(time.Second)
+}
@@ -0,0 +1,66 @@
+// Package notify shows native desktop notifications: notify-send on Linux,
+// osascript on macOS and a PowerShell toast on Windows
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// appName names trakt-sync as the sender of notifications
+const appName = "trakt-sync"
+
+// powershellAppID is the app ID toasts are shown under on Windows; toasts of
+// an unregistered app ID are dropped, so borrow the one of PowerShell
+const powershellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// toastScript shows a toast with the title and message passed in the
+// environment, which keeps them out of the script itself
+var toastScript = strings.Join([]string{
+	`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null`,
+	`$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)`,
+	`$text = $template.GetElementsByTagName('text')`,
+	`$text.Item(0).AppendChild($template.CreateTextNode($env:TRAKT_SYNC_TITLE)) | Out-Null`,
+	`$text.Item(1).AppendChild($template.CreateTextNode($env:TRAKT_SYNC_MESSAGE)) | Out-Null`,
+	`$toast = [Windows.UI.Notifications.ToastNotification]::new($template)`,
+	`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('` + powershellAppID + `').Show($toast)`,
+}, "; ")
+
+// command returns the command that shows a notification on goos. Title and
+// message are passed as arguments or in the environment, never as code.
+func command(goos, title, message string) (*exec.Cmd, error) {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.Command("notify-send", "--app-name="+appName, "--", title, message), nil
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message), nil
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+		cmd.Env = append(os.Environ(), "TRAKT_SYNC_TITLE="+title, "TRAKT_SYNC_MESSAGE="+message)
+		return cmd, nil
+	}
+	return nil, fmt.Errorf("desktop notifications are not supported on %s", goos)
+}
+
+// Desktop shows a desktop notification and waits until it was handed to the
+// desktop
+func Desktop(title, message string) error {
+	cmd, err := command(runtime.GOOS, title, message)
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if text := strings.TrimSpace(string(out)); text != "" {
+			return fmt.Errorf("failed to show notification: %w: %s", err, text)
+		}
+		return fmt.Errorf("failed to show notification: %w", err)
+	}
+	return nil
+}
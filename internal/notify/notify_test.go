@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandKeepsTextOutOfCode(t *testing.T) {
+	title, message := `"; rm -rf ~ #`, "-2 lists failed"
+
+	cmd, err := command("linux", title, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"notify-send", "--app-name=trakt-sync", "--", title, message}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("linux: expected %q, got %q", want, cmd.Args)
+	}
+
+	cmd, err = command("darwin", title, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := cmd.Args; args[len(args)-2] != title || args[len(args)-1] != message {
+		t.Errorf("darwin: expected title and message as the last arguments, got %q", args)
+	}
+
+	cmd, err = command("windows", title, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(cmd.Args, " "), title) {
+		t.Errorf("windows: title ended up in the script: %q", cmd.Args)
+	}
+	if !contains(cmd.Env, "TRAKT_SYNC_TITLE="+title) || !contains(cmd.Env, "TRAKT_SYNC_MESSAGE="+message) {
+		t.Error("windows: expected title and message in the environment")
+	}
+
+	if _, err := command("plan9", title, message); err == nil {
+		t.Error("expected an error for an unsupported OS")
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
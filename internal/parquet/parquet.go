@@ -0,0 +1,294 @@
+// Package parquet writes small, flat Apache Parquet files: required columns
+// of strings, integers and timestamps in a single uncompressed row group.
+// That is all the chart export needs and keeps the dependency out. Read
+// decodes such files again.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Column is a named column. Exactly one of its value slices is set.
+type Column struct {
+	Name    string
+	Strings []string
+	Ints    []int64
+	// Times are stored as UTC milliseconds (TIMESTAMP_MILLIS)
+	Times []time.Time
+}
+
+// String returns a string column
+func String(name string, values []string) Column {
+	return Column{Name: name, Strings: values}
+}
+
+// Int returns an integer column
+func Int(name string, values []int64) Column {
+	return Column{Name: name, Ints: values}
+}
+
+// Time returns a timestamp column
+func Time(name string, values []time.Time) Column {
+	return Column{Name: name, Times: values}
+}
+
+func (c Column) len() int {
+	switch {
+	case c.Strings != nil:
+		return len(c.Strings)
+	case c.Times != nil:
+		return len(c.Times)
+	}
+	return len(c.Ints)
+}
+
+// Parquet physical, converted and encoding enum values
+const (
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	repetitionRequired = 0
+	pageTypeData       = 0
+	codecUncompressed  = 0
+)
+
+var magic = []byte("PAR1")
+
+// Write writes the columns as one Parquet file. All columns must have the
+// same number of values.
+func Write(w io.Writer, columns []Column) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("parquet: no columns")
+	}
+	rows := columns[0].len()
+	for _, col := range columns {
+		if col.len() != rows {
+			return fmt.Errorf("parquet: column %s has %d values, expected %d", col.Name, col.len(), rows)
+		}
+	}
+
+	var file bytes.Buffer
+	file.Write(magic)
+
+	chunks := make([]chunkMeta, 0, len(columns))
+	var totalSize int64
+	for _, col := range columns {
+		data := col.plain()
+
+		var header compactWriter
+		header.structBegin()
+		header.i32Field(1, pageTypeData)
+		header.i32Field(2, int32(len(data)))
+		header.i32Field(3, int32(len(data)))
+		header.structField(5)
+		header.i32Field(1, int32(rows))
+		header.i32Field(2, encodingPlain)
+		header.i32Field(3, encodingRLE)
+		header.i32Field(4, encodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		offset := int64(file.Len())
+		file.Write(header.Bytes())
+		file.Write(data)
+
+		size := int64(header.Len() + len(data))
+		totalSize += size
+		chunks = append(chunks, chunkMeta{column: col, offset: offset, size: size})
+	}
+
+	footer := fileMetadata(columns, chunks, int64(rows), totalSize)
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.Write(magic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// chunkMeta is where a column's single data page was written
+type chunkMeta struct {
+	column Column
+	offset int64
+	size   int64
+}
+
+// plain encodes the column values with the PLAIN encoding. Required columns
+// have no definition or repetition levels.
+func (c Column) plain() []byte {
+	var buf bytes.Buffer
+	switch {
+	case c.Strings != nil:
+		for _, s := range c.Strings {
+			binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+			buf.WriteString(s)
+		}
+	case c.Times != nil:
+		for _, t := range c.Times {
+			binary.Write(&buf, binary.LittleEndian, t.UnixMilli())
+		}
+	default:
+		for _, n := range c.Ints {
+			binary.Write(&buf, binary.LittleEndian, n)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (c Column) physicalType() int32 {
+	if c.Strings != nil {
+		return typeByteArray
+	}
+	return typeInt64
+}
+
+// fileMetadata encodes the FileMetaData footer
+func fileMetadata(columns []Column, chunks []chunkMeta, rows, totalSize int64) []byte {
+	var m compactWriter
+	m.structBegin()
+	m.i32Field(1, 1)
+
+	// The schema is a root element followed by one element per column.
+	m.listField(2, typeStruct, len(columns)+1)
+	m.structBegin()
+	m.binaryField(4, "schema")
+	m.i32Field(5, int32(len(columns)))
+	m.structEnd()
+	for _, col := range columns {
+		m.structBegin()
+		m.i32Field(1, col.physicalType())
+		m.i32Field(3, repetitionRequired)
+		m.binaryField(4, col.Name)
+		switch {
+		case col.Strings != nil:
+			m.i32Field(6, convertedUTF8)
+		case col.Times != nil:
+			m.i32Field(6, convertedTimestampMillis)
+		}
+		m.structEnd()
+	}
+
+	m.i64Field(3, rows)
+
+	m.listField(4, typeStruct, 1)
+	m.structBegin()
+	m.listField(1, typeStruct, len(chunks))
+	for _, chunk := range chunks {
+		m.structBegin()
+		m.i64Field(2, chunk.offset)
+		m.structField(3)
+		m.i32Field(1, chunk.column.physicalType())
+		m.listField(2, typeI32, 2)
+		m.writeVarint(zigzag(encodingPlain))
+		m.writeVarint(zigzag(encodingRLE))
+		m.listField(3, typeBinary, 1)
+		m.writeBinary(chunk.column.Name)
+		m.i32Field(4, codecUncompressed)
+		m.i64Field(5, rows)
+		m.i64Field(6, chunk.size)
+		m.i64Field(7, chunk.size)
+		m.i64Field(9, chunk.offset)
+		m.structEnd()
+		m.structEnd()
+	}
+	m.i64Field(2, totalSize)
+	m.i64Field(3, rows)
+	m.structEnd()
+
+	m.binaryField(6, "trakt-sync")
+	m.structEnd()
+	return m.Bytes()
+}
+
+// Thrift compact protocol type IDs
+const (
+	typeI32    = 5
+	typeI64    = 6
+	typeBinary = 8
+	typeList   = 9
+	typeStruct = 12
+)
+
+// compactWriter encodes the Thrift compact protocol Parquet metadata uses
+type compactWriter struct {
+	bytes.Buffer
+	// lastField holds the last field ID of each open struct
+	lastField []int16
+}
+
+func (w *compactWriter) structBegin() {
+	w.lastField = append(w.lastField, 0)
+}
+
+func (w *compactWriter) structEnd() {
+	w.WriteByte(0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+func (w *compactWriter) fieldHeader(id int16, typ byte) {
+	last := &w.lastField[len(w.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.WriteByte(typ)
+		w.writeVarint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *compactWriter) i32Field(id int16, v int32) {
+	w.fieldHeader(id, typeI32)
+	w.writeVarint(zigzag(int64(v)))
+}
+
+func (w *compactWriter) i64Field(id int16, v int64) {
+	w.fieldHeader(id, typeI64)
+	w.writeVarint(zigzag(v))
+}
+
+func (w *compactWriter) binaryField(id int16, v string) {
+	w.fieldHeader(id, typeBinary)
+	w.writeBinary(v)
+}
+
+// structField starts a nested struct field; close it with structEnd
+func (w *compactWriter) structField(id int16) {
+	w.fieldHeader(id, typeStruct)
+	w.structBegin()
+}
+
+// listField starts a list field; the caller writes its size elements
+func (w *compactWriter) listField(id int16, elemType byte, size int) {
+	w.fieldHeader(id, typeList)
+	if size < 15 {
+		w.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.WriteByte(0xf0 | elemType)
+	w.writeVarint(uint64(size))
+}
+
+func (w *compactWriter) writeBinary(v string) {
+	w.writeVarint(uint64(len(v)))
+	w.WriteString(v)
+}
+
+func (w *compactWriter) writeVarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	w.Write(buf[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,99 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteLayout(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, []Column{
+		Time("date", []time.Time{time.UnixMilli(1000), time.UnixMilli(2000)}),
+		String("title", []string{"Dune", "Heat"}),
+		Int("rank", []int64{1, 2}),
+	})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data := buf.Bytes()
+
+	if !bytes.HasPrefix(data, magic) || !bytes.HasSuffix(data, magic) {
+		t.Fatal("file is not framed by PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		t.Fatalf("footer length %d out of range", footerLen)
+	}
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	for _, name := range []string{"date", "title", "rank", "trakt-sync"} {
+		if !bytes.Contains(footer, []byte(name)) {
+			t.Errorf("footer does not mention %s", name)
+		}
+	}
+
+	// The string column is PLAIN encoded: a length prefix per value.
+	plain := String("title", []string{"Dune", "Heat"}).plain()
+	if !bytes.Contains(data, plain) || len(plain) != 16 {
+		t.Errorf("string values not found PLAIN encoded")
+	}
+}
+
+func TestReadRoundTrip(t *testing.T) {
+	titles := make([]string, 20)
+	ranks := make([]int64, 20)
+	for i := range titles {
+		titles[i] = fmt.Sprintf("Title %d", i+1)
+		ranks[i] = int64(-i)
+	}
+	date := time.UnixMilli(1700000000123).UTC()
+	dates := make([]time.Time, 20)
+	for i := range dates {
+		dates[i] = date
+	}
+	want := []Column{Time("date", dates), String("title", titles), Int("rank", ranks)}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, want); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+
+	if _, err := Read(buf.Bytes()[:buf.Len()-20]); err == nil {
+		t.Error("expected an error for a truncated file")
+	}
+}
+
+func TestWriteRejectsUnevenColumns(t *testing.T) {
+	err := Write(&bytes.Buffer{}, []Column{
+		Int("rank", []int64{1, 2}),
+		String("title", []string{"Dune"}),
+	})
+	if err == nil {
+		t.Fatal("expected an error for columns of different length")
+	}
+}
+
+func TestCompactFieldHeaders(t *testing.T) {
+	var w compactWriter
+	w.structBegin()
+	w.i32Field(1, 1)
+	w.i64Field(20, -1)
+	w.structEnd()
+
+	// Field 1 uses the short form (delta 1, type i32, zigzag 2), field 20 the
+	// long form since the delta exceeds 15.
+	want := []byte{0x15, 0x02, 0x06, 0x28, 0x01, 0x00}
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("encoded % x, want % x", w.Bytes(), want)
+	}
+}
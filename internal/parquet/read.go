@@ -0,0 +1,246 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Read decodes a file written by Write back into its columns. Other Parquet
+// files are only supported as far as they use the same subset: one row group
+// of required, PLAIN encoded, uncompressed columns.
+func Read(data []byte) ([]Column, error) {
+	if len(data) < 12 || !bytes.HasPrefix(data, magic) || !bytes.HasSuffix(data, magic) {
+		return nil, fmt.Errorf("parquet: not a Parquet file")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		return nil, fmt.Errorf("parquet: footer length %d out of range", footerLen)
+	}
+	meta, err := decodeStruct(data[len(data)-8-footerLen : len(data)-8])
+	if err != nil {
+		return nil, fmt.Errorf("parquet: footer: %w", err)
+	}
+
+	// The first schema element is the root, the others are the columns.
+	schema := meta.list(2)
+	if len(schema) < 2 {
+		return nil, fmt.Errorf("parquet: no columns")
+	}
+	rows := int(meta.i64(3))
+	if rows < 0 {
+		return nil, fmt.Errorf("parquet: %d rows", rows)
+	}
+	groups := meta.list(4)
+	if len(groups) != 1 {
+		return nil, fmt.Errorf("parquet: %d row groups, expected 1", len(groups))
+	}
+	group, _ := groups[0].(thriftStruct)
+	chunks := group.list(1)
+	if len(chunks) != len(schema)-1 {
+		return nil, fmt.Errorf("parquet: %d column chunks for %d columns", len(chunks), len(schema)-1)
+	}
+
+	columns := make([]Column, 0, len(chunks))
+	for i, chunk := range chunks {
+		element, _ := schema[i+1].(thriftStruct)
+		chunkMeta, _ := chunk.(thriftStruct)
+		values, err := pageValues(data, int(chunkMeta.field(3).i64(9)))
+		if err != nil {
+			return nil, fmt.Errorf("parquet: column %s: %w", element.str(4), err)
+		}
+		col, err := decodePlain(element, values, rows)
+		if err != nil {
+			return nil, fmt.Errorf("parquet: column %s: %w", element.str(4), err)
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// pageValues returns the values of the data page at offset
+func pageValues(data []byte, offset int) ([]byte, error) {
+	if offset < len(magic) || offset >= len(data) {
+		return nil, fmt.Errorf("page offset %d out of range", offset)
+	}
+	r := compactReader{data: data[offset:]}
+	header, err := r.readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("page header: %w", err)
+	}
+	if pageType := header.i64(1); pageType != pageTypeData {
+		return nil, fmt.Errorf("page type %d, expected a data page", pageType)
+	}
+	size := int(header.i64(3))
+	if size < 0 || size > len(r.data)-r.pos {
+		return nil, fmt.Errorf("page size %d out of range", size)
+	}
+	return r.data[r.pos : r.pos+size], nil
+}
+
+// decodePlain decodes rows PLAIN encoded values of the schema element
+func decodePlain(element thriftStruct, values []byte, rows int) (Column, error) {
+	col := Column{Name: element.str(4)}
+	switch element.i64(1) {
+	case typeByteArray:
+		col.Strings = make([]string, 0, rows)
+		for len(col.Strings) < rows {
+			if len(values) < 4 {
+				return col, fmt.Errorf("truncated values")
+			}
+			n := int(binary.LittleEndian.Uint32(values))
+			if n > len(values)-4 {
+				return col, fmt.Errorf("truncated values")
+			}
+			col.Strings = append(col.Strings, string(values[4:4+n]))
+			values = values[4+n:]
+		}
+	case typeInt64:
+		if len(values) < rows*8 {
+			return col, fmt.Errorf("truncated values")
+		}
+		ints := make([]int64, rows)
+		for i := range ints {
+			ints[i] = int64(binary.LittleEndian.Uint64(values[i*8:]))
+		}
+		if converted, ok := element[6].(int64); ok && converted == convertedTimestampMillis {
+			col.Times = make([]time.Time, rows)
+			for i, ms := range ints {
+				col.Times[i] = time.UnixMilli(ms).UTC()
+			}
+		} else {
+			col.Ints = ints
+		}
+	default:
+		return col, fmt.Errorf("unsupported physical type %d", element.i64(1))
+	}
+	return col, nil
+}
+
+// thriftStruct is a decoded Thrift struct keyed by field ID. Values are
+// int64, string, []interface{} or thriftStruct.
+type thriftStruct map[int16]interface{}
+
+func (s thriftStruct) i64(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) str(id int16) string {
+	v, _ := s[id].(string)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+func (s thriftStruct) field(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func decodeStruct(data []byte) (thriftStruct, error) {
+	r := compactReader{data: data}
+	return r.readStruct()
+}
+
+// compactReader decodes the Thrift compact protocol types compactWriter
+// writes
+type compactReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *compactReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *compactReader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint at %d", r.pos)
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *compactReader) readStruct() (thriftStruct, error) {
+	s := make(thriftStruct)
+	var last int16
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return s, nil
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.readVarint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(unzigzag(v))
+		}
+		last = id
+		if s[id], err = r.readValue(b & 0x0f); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *compactReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case typeI32, typeI64:
+		v, err := r.readVarint()
+		return unzigzag(v), err
+	case typeBinary:
+		n, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("binary of %d bytes out of range", n)
+		}
+		v := string(r.data[r.pos : r.pos+int(n)])
+		r.pos += int(n)
+		return v, nil
+	case typeList:
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(b >> 4)
+		if size == 15 {
+			if size, err = r.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("list of %d elements out of range", size)
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			if items[i], err = r.readValue(b & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case typeStruct:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("unsupported Thrift type %d", typ)
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,187 @@
+package plex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+)
+
+// DiscoverURL is the Plex Discover API, which serves the account watchlist
+const DiscoverURL = "https://discover.provider.plex.tv"
+
+// product identifies trakt-sync to Plex
+const product = "trakt-sync"
+
+// watchlistPageSize is the number of watchlist items requested per page
+const watchlistPageSize = 100
+
+// Media types in the Discover API
+const (
+	TypeMovie = "movie"
+	TypeShow  = "show"
+)
+
+// Client talks to the Plex Discover API on behalf of one Plex account
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	readOnly   bool
+}
+
+// Item is a movie or show in the Plex metadata catalog
+type Item struct {
+	RatingKey string `json:"ratingKey"`
+	GUID      string `json:"guid"`
+	Title     string `json:"title"`
+	Type      string `json:"type"`
+	Year      int    `json:"year"`
+}
+
+// SetReadOnly makes the client refuse every request that could change data
+// on Plex with errcode.ErrReadOnly
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// NewClient returns a client authenticated with a Plex account token
+func NewClient(token string) *Client {
+	return &Client{
+		ctx:        context.Background(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    DiscoverURL,
+		token:      token,
+	}
+}
+
+// SetBaseURL points the client at a different API host, e.g. a test server
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetContext makes every request of the client use ctx, so it is aborted
+// once ctx is done
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Watchlist returns all movies and shows on the account watchlist
+func (c *Client) Watchlist() ([]Item, error) {
+	var items []Item
+	for start := 0; ; start += watchlistPageSize {
+		var resp struct {
+			MediaContainer struct {
+				TotalSize int    `json:"totalSize"`
+				Metadata  []Item `json:"Metadata"`
+			} `json:"MediaContainer"`
+		}
+		query := url.Values{
+			"X-Plex-Container-Start": {fmt.Sprint(start)},
+			"X-Plex-Container-Size":  {fmt.Sprint(watchlistPageSize)},
+		}
+		if err := c.do("GET", "/library/sections/watchlist/all", query, &resp); err != nil {
+			return nil, fmt.Errorf("failed to get watchlist: %w", err)
+		}
+		items = append(items, resp.MediaContainer.Metadata...)
+		if len(resp.MediaContainer.Metadata) == 0 || len(items) >= resp.MediaContainer.TotalSize {
+			return items, nil
+		}
+	}
+}
+
+// Search looks up a movie or show by title and returns the result with the
+// same year, or nil if there is none
+func (c *Client) Search(title string, year int, mediaType string) (*Item, error) {
+	searchType := "movies"
+	if mediaType == TypeShow {
+		searchType = "tv"
+	}
+	var resp struct {
+		MediaContainer struct {
+			SearchResults []struct {
+				SearchResult []struct {
+					Metadata Item `json:"Metadata"`
+				} `json:"SearchResult"`
+			} `json:"SearchResults"`
+		} `json:"MediaContainer"`
+	}
+	query := url.Values{
+		"query":           {title},
+		"searchTypes":     {searchType},
+		"searchProviders": {"discover"},
+		"includeMetadata": {"1"},
+		"limit":           {"10"},
+	}
+	if err := c.do("GET", "/library/search", query, &resp); err != nil {
+		return nil, fmt.Errorf("failed to search %q: %w", title, err)
+	}
+
+	for _, results := range resp.MediaContainer.SearchResults {
+		for _, result := range results.SearchResult {
+			item := result.Metadata
+			if item.Type == mediaType && item.Year == year && strings.EqualFold(item.Title, title) {
+				return &item, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// AddToWatchlist puts an item on the account watchlist
+func (c *Client) AddToWatchlist(ratingKey string) error {
+	if err := c.do("PUT", "/actions/addToWatchlist", url.Values{"ratingKey": {ratingKey}}, nil); err != nil {
+		return fmt.Errorf("failed to add %s to watchlist: %w", ratingKey, err)
+	}
+	return nil
+}
+
+// RemoveFromWatchlist takes an item off the account watchlist
+func (c *Client) RemoveFromWatchlist(ratingKey string) error {
+	if err := c.do("PUT", "/actions/removeFromWatchlist", url.Values{"ratingKey": {ratingKey}}, nil); err != nil {
+		return fmt.Errorf("failed to remove %s from watchlist: %w", ratingKey, err)
+	}
+	return nil
+}
+
+func (c *Client) do(method, path string, query url.Values, result interface{}) error {
+	if c.readOnly && method != http.MethodGet {
+		return fmt.Errorf("%s %s: %w", method, path, errcode.ErrReadOnly)
+	}
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Token", c.token)
+	req.Header.Set("X-Plex-Product", product)
+	req.Header.Set("X-Plex-Client-Identifier", product)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("plex token was rejected (%s)", resp.Status)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("plex API error %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
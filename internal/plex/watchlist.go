@@ -0,0 +1,117 @@
+package plex
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/rs/zerolog/log"
+)
+
+// Title is a movie or show that belongs on the watchlist
+type Title struct {
+	TraktID int
+	Title   string
+	Year    int
+	// Type is TypeMovie or TypeShow
+	Type string
+}
+
+func (t Title) key() string {
+	return fmt.Sprintf("%s:%d", t.Type, t.TraktID)
+}
+
+// Result summarizes a watchlist sync
+type Result struct {
+	Added     int
+	Removed   int
+	Unmatched int
+}
+
+// SyncWatchlist makes the watchlist contain titles. Titles trakt-sync added
+// in earlier runs that are no longer wanted are removed; everything else on
+// the watchlist is left alone. Titles are matched to Plex by title and year,
+// and matches are cached in st.
+func SyncWatchlist(c *Client, titles []Title, st *state.PlexState, dryRun bool) (Result, error) {
+	var result Result
+	if st.Matches == nil {
+		st.Matches = make(map[string]string)
+	}
+
+	wanted := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		ratingKey, ok := st.Matches[title.key()]
+		if !ok {
+			item, err := c.Search(title.Title, title.Year, title.Type)
+			if err != nil {
+				return result, err
+			}
+			if item == nil {
+				log.Warn().Str("title", title.Title).Int("year", title.Year).Msg("No Plex match, skipping")
+				result.Unmatched++
+				continue
+			}
+			ratingKey = item.RatingKey
+			st.Matches[title.key()] = ratingKey
+		}
+		wanted[ratingKey] = true
+	}
+
+	watchlist, err := c.Watchlist()
+	if err != nil {
+		return result, err
+	}
+	present := make(map[string]bool, len(watchlist))
+	for _, item := range watchlist {
+		present[item.RatingKey] = true
+	}
+	// managed starts out as what earlier runs added and is kept up to date
+	// as items are added and removed, so a failed run is recorded as far as
+	// it got.
+	managed := make(map[string]bool, len(st.Managed))
+	for _, ratingKey := range st.Managed {
+		managed[ratingKey] = true
+	}
+	if !dryRun {
+		defer func() { st.Managed = sortedKeys(managed) }()
+	}
+
+	for _, ratingKey := range sortedKeys(wanted) {
+		// Titles already on the watchlist are left as they are; those the
+		// user put there stay theirs.
+		if present[ratingKey] {
+			continue
+		}
+		if dryRun {
+			log.Info().Str("rating_key", ratingKey).Msg("DRY RUN: would add to Plex watchlist")
+		} else if err := c.AddToWatchlist(ratingKey); err != nil {
+			return result, err
+		}
+		managed[ratingKey] = true
+		result.Added++
+	}
+	for _, ratingKey := range sortedKeys(managed) {
+		if wanted[ratingKey] {
+			continue
+		}
+		if present[ratingKey] {
+			if dryRun {
+				log.Info().Str("rating_key", ratingKey).Msg("DRY RUN: would remove from Plex watchlist")
+			} else if err := c.RemoveFromWatchlist(ratingKey); err != nil {
+				return result, err
+			}
+			result.Removed++
+		}
+		delete(managed, ratingKey)
+	}
+	return result, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
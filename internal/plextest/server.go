@@ -0,0 +1,188 @@
+// Package plextest provides a fake Plex Discover API for tests: a searchable
+// catalog and the watchlist of one account.
+package plextest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maximilian/trakt-sync/internal/plex"
+)
+
+// Token is the only account token the fake accepts
+const Token = "plex-e2e-token"
+
+// Server is a fake Plex Discover API backed by in-memory state
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	catalog   []plex.Item
+	watchlist []string
+	searches  int
+}
+
+// NewServer starts a fake Plex Discover API. Call Close when done.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Add puts a movie or show into the searchable catalog and returns its
+// rating key
+func (s *Server) Add(title string, year int, mediaType string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ratingKey := fmt.Sprintf("%x", int64(0x5d7760000)+int64(len(s.catalog)))
+	s.catalog = append(s.catalog, plex.Item{
+		RatingKey: ratingKey,
+		GUID:      fmt.Sprintf("plex://%s/%s", mediaType, ratingKey),
+		Title:     title,
+		Type:      mediaType,
+		Year:      year,
+	})
+	return ratingKey
+}
+
+// Watch puts an item on the watchlist as if the user added it in Plex
+func (s *Server) Watch(ratingKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchlist = append(s.watchlist, ratingKey)
+}
+
+// Watchlist returns the titles on the watchlist, sorted
+func (s *Server) Watchlist() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var titles []string
+	for _, ratingKey := range s.watchlist {
+		if item, ok := s.item(ratingKey); ok {
+			titles = append(titles, item.Title)
+		}
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+// Searches returns the number of search requests served
+func (s *Server) Searches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.searches
+}
+
+func (s *Server) item(ratingKey string) (plex.Item, bool) {
+	for _, item := range s.catalog {
+		if item.RatingKey == ratingKey {
+			return item, true
+		}
+	}
+	return plex.Item{}, false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Plex-Token") != Token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/library/sections/watchlist/all":
+		s.handleWatchlist(w, r)
+	case r.Method == "GET" && r.URL.Path == "/library/search":
+		s.handleSearch(w, r)
+	case r.Method == "PUT" && r.URL.Path == "/actions/addToWatchlist":
+		ratingKey := r.URL.Query().Get("ratingKey")
+		if _, ok := s.item(ratingKey); !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		for _, key := range s.watchlist {
+			if key == ratingKey {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		s.watchlist = append(s.watchlist, ratingKey)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PUT" && r.URL.Path == "/actions/removeFromWatchlist":
+		ratingKey := r.URL.Query().Get("ratingKey")
+		for i, key := range s.watchlist {
+			if key == ratingKey {
+				s.watchlist = append(s.watchlist[:i], s.watchlist[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) handleWatchlist(w http.ResponseWriter, r *http.Request) {
+	start, _ := strconv.Atoi(r.URL.Query().Get("X-Plex-Container-Start"))
+	size, err := strconv.Atoi(r.URL.Query().Get("X-Plex-Container-Size"))
+	if err != nil || size <= 0 {
+		size = 20
+	}
+
+	page := make([]plex.Item, 0, size)
+	for i := start; i < len(s.watchlist) && len(page) < size; i++ {
+		if item, ok := s.item(s.watchlist[i]); ok {
+			page = append(page, item)
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"MediaContainer": map[string]interface{}{
+			"offset":    start,
+			"size":      len(page),
+			"totalSize": len(s.watchlist),
+			"Metadata":  page,
+		},
+	})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	s.searches++
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	mediaType := plex.TypeMovie
+	if r.URL.Query().Get("searchTypes") == "tv" {
+		mediaType = plex.TypeShow
+	}
+
+	type result struct {
+		Score    float64   `json:"score"`
+		Metadata plex.Item `json:"Metadata"`
+	}
+	results := []result{}
+	for _, item := range s.catalog {
+		if item.Type == mediaType && strings.Contains(strings.ToLower(item.Title), query) {
+			results = append(results, result{Score: 1, Metadata: item})
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"MediaContainer": map[string]interface{}{
+			"SearchResults": []map[string]interface{}{
+				{"id": "external", "SearchResult": results},
+			},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,111 @@
+// Package power tells whether a laptop runs on battery or a metered
+// connection. On Linux it asks UPower and NetworkManager over D-Bus through
+// busctl, and reads the battery from sysfs without UPower; elsewhere both
+// checks report false.
+package power
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// busctl runs busctl and returns its output; tests replace it
+var busctl = func(args ...string) (string, error) {
+	out, err := exec.Command("busctl", args...).Output()
+	return string(out), err
+}
+
+// powerSupplyDir lists the power supplies in sysfs; tests replace it
+var powerSupplyDir = "/sys/class/power_supply"
+
+// OnBattery reports whether the machine runs on battery
+func OnBattery() (bool, error) {
+	if runtime.GOOS != "linux" {
+		return false, nil
+	}
+	out, err := busctl("--system", "get-property",
+		"org.freedesktop.UPower", "/org/freedesktop/UPower", "org.freedesktop.UPower", "OnBattery")
+	if err == nil {
+		return parseBool(out)
+	}
+	return onBatterySysfs()
+}
+
+// onBatterySysfs reports whether sysfs has a battery but no mains or USB
+// supply that is online
+func onBatterySysfs() (bool, error) {
+	entries, err := os.ReadDir(powerSupplyDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to read power supplies: %w", err)
+	}
+	battery := false
+	for _, entry := range entries {
+		dir := filepath.Join(powerSupplyDir, entry.Name())
+		switch readAttr(dir, "type") {
+		case "Battery":
+			if readAttr(dir, "scope") != "Device" {
+				battery = true
+			}
+		case "Mains", "USB":
+			if readAttr(dir, "online") == "1" {
+				return false, nil
+			}
+		}
+	}
+	return battery, nil
+}
+
+func readAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// NetworkManager's NMMetered values for a metered connection
+const (
+	meteredYes      = 1
+	meteredGuessYes = 3
+)
+
+// Metered reports whether NetworkManager considers the primary connection
+// metered, e.g. a phone hotspot. Without NetworkManager it reports an error.
+func Metered() (bool, error) {
+	if runtime.GOOS != "linux" {
+		return false, nil
+	}
+	out, err := busctl("--system", "get-property",
+		"org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered")
+	if err != nil {
+		return false, fmt.Errorf("failed to ask NetworkManager: %w", err)
+	}
+	value, err := parseUint(out)
+	if err != nil {
+		return false, err
+	}
+	return value == meteredYes || value == meteredGuessYes, nil
+}
+
+// parseBool parses a busctl boolean like "b true"
+func parseBool(out string) (bool, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 || fields[0] != "b" {
+		return false, fmt.Errorf("unexpected busctl output %q", strings.TrimSpace(out))
+	}
+	return fields[1] == "true", nil
+}
+
+// parseUint parses a busctl unsigned integer like "u 4"
+func parseUint(out string) (uint64, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 || fields[0] != "u" {
+		return 0, fmt.Errorf("unexpected busctl output %q", strings.TrimSpace(out))
+	}
+	return strconv.ParseUint(fields[1], 10, 32)
+}
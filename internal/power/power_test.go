@@ -0,0 +1,66 @@
+package power
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMetered(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("NetworkManager is only asked on Linux")
+	}
+	defer func(orig func(...string) (string, error)) { busctl = orig }(busctl)
+
+	for out, want := range map[string]bool{"u 1\n": true, "u 3\n": true, "u 2\n": false, "u 0\n": false} {
+		busctl = func(args ...string) (string, error) { return out, nil }
+		got, err := Metered()
+		if err != nil {
+			t.Fatalf("%q: %v", out, err)
+		}
+		if got != want {
+			t.Errorf("%q: expected metered %v, got %v", out, want, got)
+		}
+	}
+}
+
+func TestOnBatteryFallsBackToSysfs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the battery is only checked on Linux")
+	}
+	defer func(orig func(...string) (string, error)) { busctl = orig }(busctl)
+	defer func(orig string) { powerSupplyDir = orig }(powerSupplyDir)
+	busctl = func(args ...string) (string, error) { return "", errors.New("no UPower") }
+
+	writeSupply := func(name string, attrs map[string]string) {
+		dir := filepath.Join(powerSupplyDir, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for attr, value := range attrs {
+			if err := os.WriteFile(filepath.Join(dir, attr), []byte(value+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	powerSupplyDir = t.TempDir()
+	writeSupply("BAT0", map[string]string{"type": "Battery"})
+	writeSupply("hidpp_battery_0", map[string]string{"type": "Battery", "scope": "Device"})
+	writeSupply("AC", map[string]string{"type": "Mains", "online": "0"})
+	if onBattery, err := OnBattery(); err != nil || !onBattery {
+		t.Errorf("unplugged: expected on battery, got %v, %v", onBattery, err)
+	}
+
+	writeSupply("AC", map[string]string{"online": "1"})
+	if onBattery, err := OnBattery(); err != nil || onBattery {
+		t.Errorf("plugged in: expected mains power, got %v, %v", onBattery, err)
+	}
+
+	busctl = func(args ...string) (string, error) { return "b true\n", nil }
+	if onBattery, err := OnBattery(); err != nil || !onBattery {
+		t.Errorf("UPower: expected on battery, got %v, %v", onBattery, err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package serve exposes the resolved lists over HTTP as import list feeds for
+// Radarr and Sonarr, so they can pick up the synced titles without talking to
+// Trakt.
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/rs/zerolog/log"
+)
+
+// Resolver returns the items of the enabled lists
+type Resolver func() ([]syncpkg.ResolvedList, error)
+
+// RadarrMovie is an entry of a StevenLu-style custom list, which Radarr
+// imports by IMDb ID
+type RadarrMovie struct {
+	Title  string `json:"title"`
+	ImdbID string `json:"imdb_id"`
+	TmdbID int    `json:"tmdb_id,omitempty"`
+	Year   int    `json:"year,omitempty"`
+}
+
+// SonarrSeries is an entry of a Sonarr custom import list, which Sonarr
+// imports by TVDB ID
+type SonarrSeries struct {
+	Title  string `json:"title"`
+	TvdbID int    `json:"tvdbId"`
+}
+
+// Handler serves the feeds. Resolved lists are cached for the TTL so list
+// refreshes of Radarr and Sonarr do not each go through to Trakt.
+type Handler struct {
+	resolve Resolver
+	ttl     time.Duration
+	mux     *http.ServeMux
+
+	mu       sync.Mutex
+	lists    []syncpkg.ResolvedList
+	resolved time.Time
+}
+
+// NewHandler returns a handler that resolves lists at most once per ttl
+func NewHandler(resolve Resolver, ttl time.Duration) *Handler {
+	h := &Handler{resolve: resolve, ttl: ttl, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/radarr", h.handleRadarr)
+	h.mux.HandleFunc("/sonarr", h.handleSonarr)
+	h.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// cachedLists returns the resolved lists, resolving them again once the
+// cache expired. A failed refresh falls back to the previous result.
+func (h *Handler) cachedLists() ([]syncpkg.ResolvedList, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.lists != nil && time.Since(h.resolved) < h.ttl {
+		return h.lists, nil
+	}
+	lists, err := h.resolve()
+	if err != nil {
+		if h.lists != nil {
+			errcode.Log(log.Warn(), err).Msg("Failed to resolve lists, serving the previous result")
+			return h.lists, nil
+		}
+		return nil, err
+	}
+	if lists == nil {
+		lists = []syncpkg.ResolvedList{}
+	}
+	h.lists, h.resolved = lists, time.Now()
+	return lists, nil
+}
+
+// selectLists returns the lists of one kind, or only the one named by the
+// list query parameter. ok is false if that list is unknown.
+func (h *Handler) selectLists(r *http.Request, movies bool) (selected []syncpkg.ResolvedList, ok bool, err error) {
+	lists, err := h.cachedLists()
+	if err != nil {
+		return nil, false, err
+	}
+	slug := r.URL.Query().Get("list")
+	for _, list := range lists {
+		if list.IsMovie == movies && (slug == "" || list.Slug == slug) {
+			selected = append(selected, list)
+		}
+	}
+	return selected, slug == "" || len(selected) > 0, nil
+}
+
+func (h *Handler) handleRadarr(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lists, ok, err := h.selectLists(r, true)
+	if err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to resolve lists for the Radarr feed")
+		http.Error(w, "failed to resolve lists", http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "unknown movie list", http.StatusNotFound)
+		return
+	}
+
+	movies := []RadarrMovie{}
+	seen := make(map[int]bool)
+	for _, list := range lists {
+		for _, item := range list.Items {
+			// Radarr matches StevenLu entries by IMDb ID only.
+			if item.IDs.IMDB == "" || seen[item.IDs.Trakt] {
+				continue
+			}
+			seen[item.IDs.Trakt] = true
+			movies = append(movies, RadarrMovie{Title: item.Title, ImdbID: item.IDs.IMDB, TmdbID: item.IDs.TMDB, Year: item.Year})
+		}
+	}
+	writeJSON(w, movies)
+}
+
+func (h *Handler) handleSonarr(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lists, ok, err := h.selectLists(r, false)
+	if err != nil {
+		errcode.Log(log.Error(), err).Msg("Failed to resolve lists for the Sonarr feed")
+		http.Error(w, "failed to resolve lists", http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "unknown show list", http.StatusNotFound)
+		return
+	}
+
+	series := []SonarrSeries{}
+	seen := make(map[int]bool)
+	for _, list := range lists {
+		for _, item := range list.Items {
+			// Sonarr only imports by TVDB ID.
+			if item.IDs.TVDB == 0 || seen[item.IDs.Trakt] {
+				continue
+			}
+			seen[item.IDs.Trakt] = true
+			series = append(series, SonarrSeries{Title: item.Title, TvdbID: item.IDs.TVDB})
+		}
+	}
+	writeJSON(w, series)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		errcode.Log(log.Warn(), err).Msg("Failed to write feed")
+	}
+}
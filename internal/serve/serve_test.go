@@ -0,0 +1,111 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	syncpkg "github.com/maximilian/trakt-sync/internal/sync"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+func movie(id int, imdb string) syncpkg.Item {
+	return syncpkg.Item{IDs: trakt.MediaIDs{Trakt: id, IMDB: imdb, TMDB: id + 500}, Title: "Movie", Year: 2020}
+}
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func TestRadarrFeed(t *testing.T) {
+	calls := 0
+	resolve := func() ([]syncpkg.ResolvedList, error) {
+		calls++
+		return []syncpkg.ResolvedList{
+			{Slug: "trending", IsMovie: true, Items: []syncpkg.Item{movie(1, "tt0000001"), movie(2, "")}},
+			{Slug: "popular", IsMovie: true, Items: []syncpkg.Item{movie(1, "tt0000001"), movie(3, "tt0000003")}},
+			{Slug: "shows", IsMovie: false, Items: []syncpkg.Item{movie(4, "tt0000004")}},
+		}, nil
+	}
+	h := NewHandler(resolve, time.Hour)
+
+	rec := get(t, h, "/radarr")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var movies []RadarrMovie
+	if err := json.Unmarshal(rec.Body.Bytes(), &movies); err != nil {
+		t.Fatal(err)
+	}
+	// Movies without IMDb ID and shows are left out, duplicates listed once.
+	var ids []string
+	for _, m := range movies {
+		ids = append(ids, m.ImdbID)
+	}
+	if want := []string{"tt0000001", "tt0000003"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("feed IDs = %v, want %v", ids, want)
+	}
+	if movies[0].TmdbID != 501 || movies[0].Title != "Movie" {
+		t.Errorf("unexpected entry %+v", movies[0])
+	}
+
+	if rec := get(t, h, "/radarr?list=popular"); rec.Code != http.StatusOK {
+		t.Errorf("list filter: status %d", rec.Code)
+	}
+	if rec := get(t, h, "/radarr?list=shows"); rec.Code != http.StatusNotFound {
+		t.Errorf("show list in the movie feed: status %d, want 404", rec.Code)
+	}
+	if calls != 1 {
+		t.Errorf("lists resolved %d times, want 1 (cached)", calls)
+	}
+}
+
+func TestSonarrFeed(t *testing.T) {
+	show := func(id, tvdb int) syncpkg.Item {
+		return syncpkg.Item{IDs: trakt.MediaIDs{Trakt: id, TVDB: tvdb}, Title: "Show"}
+	}
+	resolve := func() ([]syncpkg.ResolvedList, error) {
+		return []syncpkg.ResolvedList{
+			{Slug: "trending", IsMovie: true, Items: []syncpkg.Item{movie(1, "tt0000001")}},
+			{Slug: "shows", IsMovie: false, Items: []syncpkg.Item{show(2, 81189), show(3, 0), show(2, 81189)}},
+		}, nil
+	}
+	rec := get(t, NewHandler(resolve, time.Hour), "/sonarr")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	// Shows without TVDB ID cannot be imported and are left out.
+	if got, want := rec.Body.String(), `[{"title":"Show","tvdbId":81189}]`+"\n"; got != want {
+		t.Errorf("feed = %s, want %s", got, want)
+	}
+}
+
+func TestFailedRefreshServesPreviousResult(t *testing.T) {
+	fail := false
+	resolve := func() ([]syncpkg.ResolvedList, error) {
+		if fail {
+			return nil, errors.New("trakt is down")
+		}
+		return []syncpkg.ResolvedList{{Slug: "trending", IsMovie: true, Items: []syncpkg.Item{movie(1, "tt0000001")}}}, nil
+	}
+	h := NewHandler(resolve, 0)
+
+	if rec := get(t, h, "/radarr"); rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	fail = true
+	if rec := get(t, h, "/radarr"); rec.Code != http.StatusOK {
+		t.Errorf("expected the previous result after a failed refresh, got status %d", rec.Code)
+	}
+
+	if rec := get(t, NewHandler(resolve, 0), "/radarr"); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 without a previous result, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisKey is the key of the state document in Redis
+const DefaultRedisKey = "trakt-sync:state"
+
+// redisTimeout bounds a single Redis command
+const redisTimeout = 10 * time.Second
+
+// acquireScript takes a free lease or extends the caller's own
+var acquireScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if holder == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease if the caller holds it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisOptions locate the state in Redis
+type RedisOptions struct {
+	// Address is host:port of the server
+	Address  string
+	Password string
+	DB       int
+	// Key holds the state document (default: DefaultRedisKey)
+	Key string
+}
+
+// RedisStore keeps the state as a JSON document under one Redis key, so
+// instances on different hosts can share it
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// OpenRedis connects to Redis and checks the server is reachable
+func OpenRedis(opts RedisOptions) (*RedisStore, error) {
+	key := opts.Key
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Address,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", opts.Address, err)
+	}
+	return &RedisStore{client: client, key: key}, nil
+}
+
+// Load reads the state document
+func (s *RedisStore) Load() (*State, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := s.client.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return decode(data)
+}
+
+// Save replaces the state document
+func (s *RedisStore) Save(st *State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := s.client.Set(ctx, s.key, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// LoadTokens reads the shared tokens, or nil if no replica shared tokens yet
+func (s *RedisStore) LoadTokens() (*Tokens, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := s.client.Get(ctx, s.tokensKey()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared tokens: %w", err)
+	}
+	return decodeTokens(data)
+}
+
+// SaveTokens replaces the shared tokens
+func (s *RedisStore) SaveTokens(tokens *Tokens) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to encode shared tokens: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := s.client.Set(ctx, s.tokensKey(), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to write shared tokens: %w", err)
+	}
+	return nil
+}
+
+// AcquireLease takes the leader lease if it is free, or extends it for its
+// holder. Redis expires the lease, so the clocks of the replicas do not
+// matter.
+func (s *RedisStore) AcquireLease(holder string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	held, err := acquireScript.Run(ctx, s.client, []string{s.leaseKey()}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return held == 1, nil
+}
+
+// ReleaseLease deletes the leader lease if holder holds it
+func (s *RedisStore) ReleaseLease(holder string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := releaseScript.Run(ctx, s.client, []string{s.leaseKey()}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// leaseKey is the key of the leader lease next to the state
+func (s *RedisStore) leaseKey() string {
+	return s.key + ":leader"
+}
+
+// tokensKey is the key of the shared tokens next to the lease
+func (s *RedisStore) tokensKey() string {
+	return s.key + ":tokens"
+}
+
+// Close closes the connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// String describes the store for log messages
+func (s *RedisStore) String() string {
+	return "redis:" + s.client.Options().Addr + "/" + s.key
+}
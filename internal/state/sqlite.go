@@ -0,0 +1,153 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Registers the pure-Go sqlite driver, so builds without cgo can use it
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteFileName is the name of the SQLite database in the data directory
+const SQLiteFileName = "state.db"
+
+// stateKey is the row holding the state document
+const stateKey = "state"
+
+// tokensKey is the row holding the tokens shared by daemon replicas
+const tokensKey = "tokens"
+
+// leaderLease is the row of the daemon leader lease
+const leaderLease = "leader"
+
+// SQLiteStore keeps the state as a JSON document in a SQLite database, which
+// instances on one host or a shared volume can use together
+type SQLiteStore struct {
+	path string
+	db   *sql.DB
+}
+
+// OpenSQLite opens or creates the SQLite database at path
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS state (
+		key TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS lease (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+	return &SQLiteStore{path: path, db: db}, nil
+}
+
+// Load reads the state row
+func (s *SQLiteStore) Load() (*State, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM state WHERE key = ?`, stateKey).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return decode([]byte(data))
+}
+
+// Save replaces the state row
+func (s *SQLiteStore) Save(st *State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO state (key, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		stateKey, string(data), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// LoadTokens reads the shared tokens row, or nil if no replica shared tokens
+// yet
+func (s *SQLiteStore) LoadTokens() (*Tokens, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM state WHERE key = ?`, tokensKey).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared tokens: %w", err)
+	}
+	return decodeTokens([]byte(data))
+}
+
+// SaveTokens replaces the shared tokens row
+func (s *SQLiteStore) SaveTokens(tokens *Tokens) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to encode shared tokens: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO state (key, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		tokensKey, string(data), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to write shared tokens: %w", err)
+	}
+	return nil
+}
+
+// AcquireLease takes the leader lease if it is free or expired, or extends
+// it for its holder. Expiry uses the clocks of the replicas, which should be
+// kept in sync.
+func (s *SQLiteStore) AcquireLease(holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := s.db.Exec(`INSERT INTO lease (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE lease.holder = excluded.holder OR lease.expires_at <= ?`,
+		leaderLease, holder, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ReleaseLease deletes the leader lease if holder holds it
+func (s *SQLiteStore) ReleaseLease(holder string) error {
+	if _, err := s.db.Exec(`DELETE FROM lease WHERE name = ? AND holder = ?`, leaderLease, holder); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// String describes the store for log messages
+func (s *SQLiteStore) String() string {
+	return "sqlite:" + s.path
+}
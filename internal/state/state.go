@@ -0,0 +1,168 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State holds data the syncer persists between runs that does not belong in
+// the user-edited config file
+type State struct {
+	// SplitLists maps a parent list slug to the child lists created by fan-out
+	SplitLists map[string][]string `json:"split_lists,omitempty"`
+	// Sources tracks recent results per chart source for anomaly detection
+	Sources map[string]SourceState `json:"sources,omitempty"`
+	// Renames maps a managed list slug to the list it was renamed to or the
+	// existing list that was adopted in its place
+	Renames map[string]ListRename `json:"renames,omitempty"`
+	// Lists records the content of each managed list as of the last write
+	Lists map[string]ListState `json:"lists,omitempty"`
+	// Pins caches the Trakt IDs of IMDb IDs looked up for sync.pins and IMDb
+	// list imports, keyed by type and IMDb ID, e.g. "movie:tt0111161"
+	Pins map[string]int `json:"pins,omitempty"`
+	// Plex tracks the Plex watchlist of sync --target plex
+	Plex *PlexState `json:"plex,omitempty"`
+	// JellyfinCollections maps a list slug to the ID of the Jellyfin
+	// collection that mirrors it
+	JellyfinCollections map[string]string `json:"jellyfin_collections,omitempty"`
+	// Pending are list writes a run computed but could not send because the
+	// network was down, keyed by Trakt list slug; the next run sends them
+	Pending map[string]PendingWrite `json:"pending,omitempty"`
+	// ListStats tracks the likes and comments of public managed lists, one
+	// sample per day, keyed by managed list slug
+	ListStats map[string][]ListStatsSample `json:"list_stats,omitempty"`
+	// DescriptionTranslations caches the translation of each rendered
+	// description template, keyed by managed list slug
+	DescriptionTranslations map[string]DescriptionTranslation `json:"description_translations,omitempty"`
+}
+
+// DescriptionTranslation is the translation of a rendered description
+type DescriptionTranslation struct {
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+// ListStatsSample is the engagement of a list on one day
+type ListStatsSample struct {
+	Date     string `json:"date"`
+	Likes    int    `json:"likes"`
+	Comments int    `json:"comments"`
+}
+
+// PendingWrite is a queued change to a list, by Trakt ID
+type PendingWrite struct {
+	IsMovie bool  `json:"is_movie"`
+	Add     []int `json:"add,omitempty"`
+	Remove  []int `json:"remove,omitempty"`
+	// Items are the list's content once the change is applied
+	Items    []int     `json:"items"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// PlexState is what trakt-sync knows about the Plex watchlist
+type PlexState struct {
+	// Managed are the rating keys trakt-sync put on the watchlist; items
+	// the user added themselves are never removed
+	Managed []string `json:"managed,omitempty"`
+	// Matches caches the Plex rating key of a title, keyed by type and Trakt
+	// ID, e.g. "movie:1234"
+	Matches map[string]string `json:"matches,omitempty"`
+}
+
+// ListState is what trakt-sync last wrote to a managed list
+type ListState struct {
+	// Items are the Trakt IDs of the movies or shows in the list
+	Items     []int     `json:"items"`
+	WrittenAt time.Time `json:"written_at"`
+	// Adopted marks lists that existed before trakt-sync took them over
+	Adopted bool `json:"adopted,omitempty"`
+	// Manual are items others added that sync.conflict_policy preserves
+	Manual []int `json:"manual,omitempty"`
+	// Excluded are items others removed that are not added back
+	Excluded []int `json:"excluded,omitempty"`
+	// Tombstones are items someone removed that sync.respect_manual_removals
+	// keeps out of the list
+	Tombstones []int `json:"tombstones,omitempty"`
+	// RemovedAt records when trakt-sync removed an item, keyed by Trakt ID,
+	// for sync.readd_cooldown_days
+	RemovedAt map[int]time.Time `json:"removed_at,omitempty"`
+	// EmptyRuns counts consecutive syncs that left the list empty
+	EmptyRuns int `json:"empty_runs,omitempty"`
+}
+
+// ListRename is the current name and Trakt slug of a renamed managed list
+type ListRename struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// SourceState records the last result of a source and how it evolved
+type SourceState struct {
+	Hash string `json:"hash"`
+	IDs  []int  `json:"ids,omitempty"`
+	// IdenticalRuns counts consecutive runs with an unchanged result
+	IdenticalRuns int `json:"identical_runs"`
+	// ChurnRuns counts consecutive runs where every item was replaced
+	ChurnRuns int       `json:"churn_runs"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FileName is the name of the state file in the data directory
+const FileName = "state.json"
+
+// DefaultPath returns the state file location next to the given config file
+func DefaultPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+// Load reads the state file. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &State{}, nil
+		}
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return &st, nil
+}
+
+// Save atomically writes the state file
+func Save(st *State, path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Store is where the state lives between runs. The file store suits a single
+// instance; the SQLite and Redis stores let several instances, e.g. an HA
+// daemon pair, share one state.
+type Store interface {
+	// Load reads the state. A store without state yields an empty state.
+	Load() (*State, error)
+	// Save replaces the stored state
+	Save(st *State) error
+	// Close releases the connection of the store
+	Close() error
+}
+
+// Storage backends
+const (
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+	BackendRedis  = "redis"
+)
+
+// FileStore keeps the state in a JSON file
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore returns a store for the state file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Load reads the state file
+func (s *FileStore) Load() (*State, error) {
+	return Load(s.Path)
+}
+
+// Save atomically writes the state file
+func (s *FileStore) Save(st *State) error {
+	return Save(st, s.Path)
+}
+
+// Close is a no-op for the file store
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// String describes the store for log messages
+func (s *FileStore) String() string {
+	return s.Path
+}
+
+// Tokens are the OAuth tokens daemon replicas share through the state
+// backend. Trakt invalidates a refresh token once it was used, so a standby
+// taking over needs the tokens the last leader refreshed.
+type Tokens struct {
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	ClockOffset  time.Duration `json:"clock_offset,omitempty"`
+}
+
+// decodeTokens parses stored tokens
+func decodeTokens(data []byte) (*Tokens, error) {
+	tokens := &Tokens{}
+	if err := json.Unmarshal(data, tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse shared tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// decode parses a stored state document
+func decode(data []byte) (*State, error) {
+	st := &State{}
+	if len(data) == 0 {
+		return st, nil
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return st, nil
+}
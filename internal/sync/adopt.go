@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+)
+
+// AdoptList takes over an existing list, e.g. one maintained by another
+// list-sync tool, as the list of the managed definition source. The list
+// keeps its slug and name; like a rename, the mapping is stored in the state
+// together with the list's current items. It returns the adopted definition.
+func (s *Syncer) AdoptList(slug, source string) (ListDefinition, error) {
+	var listDef *ListDefinition
+	for _, def := range s.allListDefinitions() {
+		if def.Slug == source {
+			def := def
+			listDef = &def
+			break
+		}
+	}
+	if listDef == nil {
+		return ListDefinition{}, fmt.Errorf("%s is not a list managed by trakt-sync", source)
+	}
+	if _, ok := s.config.Sync.Split[source]; ok {
+		return ListDefinition{}, fmt.Errorf("%s is split into child lists by sync.split and cannot adopt a list", source)
+	}
+
+	for _, def := range s.GetListDefinitions() {
+		if def.Slug == slug && s.managedSlug(def.Slug) != source {
+			return ListDefinition{}, fmt.Errorf("%s is already managed as %s", slug, s.managedSlug(def.Slug))
+		}
+	}
+
+	list, err := s.client.GetList(s.config.Trakt.Username, slug)
+	if err != nil {
+		return ListDefinition{}, err
+	}
+	if list == nil {
+		return ListDefinition{}, fmt.Errorf("list %s not found", slug)
+	}
+
+	items, err := s.client.GetListItems(s.config.Trakt.Username, slug)
+	if err != nil {
+		return ListDefinition{}, err
+	}
+
+	// Only items matching the source's media type are managed entries; the
+	// next sync replaces everything else.
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		switch {
+		case listDef.IsMovie && item.Movie != nil:
+			ids = append(ids, item.Movie.IDs.Trakt)
+		case !listDef.IsMovie && item.Show != nil:
+			ids = append(ids, item.Show.IDs.Trakt)
+		}
+	}
+	sort.Ints(ids)
+
+	if s.state.Renames == nil {
+		s.state.Renames = make(map[string]state.ListRename)
+	}
+	if slug == source {
+		delete(s.state.Renames, source)
+	} else {
+		s.state.Renames[source] = state.ListRename{Slug: slug, Name: list.Name}
+	}
+
+	if s.state.Lists == nil {
+		s.state.Lists = make(map[string]state.ListState)
+	}
+	s.state.Lists[source] = state.ListState{Items: ids, WrittenAt: list.UpdatedAt, Adopted: true}
+	s.stateDirty = true
+
+	listDef.Slug = slug
+	listDef.Name = list.Name
+	return *listDef, nil
+}
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// AnimeGenre is the Trakt genre slug of anime
+const AnimeGenre = "anime"
+
+// animeMappingEntry is one title of an anime ID mapping such as the
+// anime-lists project's anime-list-full.json
+type animeMappingEntry struct {
+	TMDB    int    `json:"themoviedb_id"`
+	IMDB    string `json:"imdb_id"`
+	AniList int    `json:"anilist_id"`
+	MAL     int    `json:"mal_id"`
+}
+
+// animeMapping indexes a mapping by the IDs Trakt shares with it
+type animeMapping struct {
+	tmdb map[int]animeMappingEntry
+	imdb map[string]animeMappingEntry
+}
+
+var mappingClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchAnime merges the trending and most watched charts restricted to the
+// anime genre, optionally keeping only titles known to sync.anime.mapping_url
+func (s *Syncer) fetchAnime(client *trakt.Client, limit int) ([]Item, error) {
+	anime := s.config.Sync.Anime
+	opts := s.chartOptions(limit)
+	opts.Genres = []string{AnimeGenre}
+
+	var trending, watched []Item
+	var err error
+	if anime.Type == "movies" {
+		trending, err = s.fetchChart("movies/trending/anime", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+			movies, err := client.GetTrendingMovies(opts)
+			if err != nil {
+				return nil, err
+			}
+			items, _ := trendingMovieItems(movies, 0)
+			return items, nil
+		})
+		if err == nil {
+			watched, err = s.fetchChart("movies/watched/anime", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+				movies, err := client.GetMostWatchedMovies(opts)
+				if err != nil {
+					return nil, err
+				}
+				items, _ := watchedMovieItems(movies, config.WatchedSourceConfig{})
+				return items, nil
+			})
+		}
+	} else {
+		trending, err = s.fetchChart("shows/trending/anime", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+			shows, err := client.GetTrendingShows(opts)
+			if err != nil {
+				return nil, err
+			}
+			items, _ := trendingShowItems(shows, 0)
+			return items, nil
+		})
+		if err == nil {
+			watched, err = s.fetchChart("shows/watched/anime", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+				shows, err := client.GetMostWatchedShows(opts)
+				if err != nil {
+					return nil, err
+				}
+				items, _ := watchedShowItems(shows, config.WatchedSourceConfig{})
+				return items, nil
+			})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := uniqueItems(append(trending, watched...))
+
+	if anime.MappingURL == "" {
+		return items, nil
+	}
+	if s.animeMapping == nil {
+		mapping, err := fetchAnimeMapping(s.ctx, anime.MappingURL)
+		if err != nil {
+			return nil, err
+		}
+		s.animeMapping = mapping
+	}
+	return s.animeMapping.filter(items), nil
+}
+
+// fetchAnimeMapping downloads and indexes an anime ID mapping
+func fetchAnimeMapping(ctx context.Context, url string) (*animeMapping, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anime mapping: %w", err)
+	}
+	resp, err := mappingClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anime mapping: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch anime mapping: %s", resp.Status)
+	}
+
+	var entries []animeMappingEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse anime mapping: %w", err)
+	}
+	return newAnimeMapping(entries), nil
+}
+
+func newAnimeMapping(entries []animeMappingEntry) *animeMapping {
+	mapping := &animeMapping{
+		tmdb: make(map[int]animeMappingEntry),
+		imdb: make(map[string]animeMappingEntry),
+	}
+	for _, entry := range entries {
+		if entry.AniList == 0 && entry.MAL == 0 {
+			continue
+		}
+		if entry.TMDB > 0 {
+			mapping.tmdb[entry.TMDB] = entry
+		}
+		if entry.IMDB != "" {
+			mapping.imdb[entry.IMDB] = entry
+		}
+	}
+	return mapping
+}
+
+// lookup finds the mapping entry of a title by TMDB or IMDb ID
+func (m *animeMapping) lookup(ids trakt.MediaIDs) (animeMappingEntry, bool) {
+	if entry, ok := m.tmdb[ids.TMDB]; ok && ids.TMDB > 0 {
+		return entry, true
+	}
+	if entry, ok := m.imdb[ids.IMDB]; ok && ids.IMDB != "" {
+		return entry, true
+	}
+	return animeMappingEntry{}, false
+}
+
+// filter keeps the items that have an AniList or MyAnimeList entry
+func (m *animeMapping) filter(items []Item) []Item {
+	kept := make([]Item, 0, len(items))
+	for _, item := range items {
+		entry, ok := m.lookup(item.IDs)
+		if !ok {
+			log.Debug().Str("title", item.Title).Msg("Skipping anime without AniList or MyAnimeList entry")
+			continue
+		}
+		log.Debug().Str("title", item.Title).Int("anilist", entry.AniList).Int("mal", entry.MAL).Msg("Matched anime mapping")
+		kept = append(kept, item)
+	}
+	return kept
+}
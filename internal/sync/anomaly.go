@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/rs/zerolog/log"
+)
+
+// observeSource records a chart source result and warns when it looks stale
+// (identical for many runs) or broken (fully replaced on every run). Each
+// source is only observed once per run.
+func (s *Syncer) observeSource(key string, items []Item) {
+	if s.observed[key] {
+		return
+	}
+	if s.observed == nil {
+		s.observed = make(map[string]bool)
+	}
+	s.observed[key] = true
+
+	if s.state.Sources == nil {
+		s.state.Sources = make(map[string]state.SourceState)
+	}
+
+	next := nextSourceState(s.state.Sources[key], items, time.Now().UTC())
+	s.state.Sources[key] = next
+	s.stateDirty = true
+
+	detection := s.config.Sync.AnomalyDetection
+	if detection.IdenticalRuns > 0 && next.IdenticalRuns >= detection.IdenticalRuns {
+		log.Warn().
+			Str("source", key).
+			Int("identical_runs", next.IdenticalRuns).
+			Msg("Source returned identical results for many consecutive runs (possible caching issue)")
+	}
+	if detection.FullChurnRuns > 0 && next.ChurnRuns >= detection.FullChurnRuns {
+		log.Warn().
+			Str("source", key).
+			Int("churn_runs", next.ChurnRuns).
+			Msg("Source results changed completely for several consecutive runs (possible parsing issue)")
+	}
+}
+
+// nextSourceState derives the new tracking state from the previous one
+func nextSourceState(prev state.SourceState, items []Item, now time.Time) state.SourceState {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.IDs.Trakt)
+	}
+	sort.Ints(ids)
+
+	next := state.SourceState{
+		Hash:      hashIDs(ids),
+		IDs:       ids,
+		UpdatedAt: now,
+	}
+
+	if prev.Hash == "" || len(ids) == 0 {
+		return next
+	}
+
+	if prev.Hash == next.Hash {
+		next.IdenticalRuns = prev.IdenticalRuns + 1
+		return next
+	}
+
+	if !sharesAnyID(prev.IDs, ids) {
+		next.ChurnRuns = prev.ChurnRuns + 1
+	}
+	return next
+}
+
+func hashIDs(sortedIDs []int) string {
+	h := sha256.New()
+	for _, id := range sortedIDs {
+		h.Write([]byte(strconv.Itoa(id)))
+		h.Write([]byte{','})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func sharesAnyID(a, b []int) bool {
+	seen := make(map[int]struct{}, len(a))
+	for _, id := range a {
+		seen[id] = struct{}{}
+	}
+	for _, id := range b {
+		if _, ok := seen[id]; ok {
+			return true
+		}
+	}
+	return false
+}
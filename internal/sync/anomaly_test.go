@@ -0,0 +1,40 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+func TestNextSourceState(t *testing.T) {
+	items := func(ids ...int) []Item {
+		result := make([]Item, 0, len(ids))
+		for _, id := range ids {
+			result = append(result, Item{IDs: trakt.MediaIDs{Trakt: id}})
+		}
+		return result
+	}
+	now := time.Now()
+
+	st := nextSourceState(state.SourceState{}, items(1, 2, 3), now)
+	if st.IdenticalRuns != 0 || st.ChurnRuns != 0 {
+		t.Fatalf("unexpected counters for first run: %+v", st)
+	}
+
+	st = nextSourceState(st, items(3, 2, 1), now)
+	if st.IdenticalRuns != 1 {
+		t.Fatalf("expected identical run to be counted, got %+v", st)
+	}
+
+	st = nextSourceState(st, items(4, 5, 6), now)
+	if st.IdenticalRuns != 0 || st.ChurnRuns != 1 {
+		t.Fatalf("expected full churn to be counted, got %+v", st)
+	}
+
+	st = nextSourceState(st, items(6, 7, 8), now)
+	if st.ChurnRuns != 0 {
+		t.Fatalf("expected partial change to reset churn, got %+v", st)
+	}
+}
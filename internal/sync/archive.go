@@ -0,0 +1,44 @@
+package sync
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/archive"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// archiveChart records a chart response for the archive when archive.enabled
+// is set. Each source is archived once per run; responses narrowed down by a
+// list's own filters (genres, certifications, ...) are left out.
+func (s *Syncer) archiveChart(source string, opts trakt.ChartOptions, response interface{}) {
+	if !s.config.Archive.Enabled || s.listFiltered(opts) {
+		return
+	}
+	for _, snapshot := range s.snapshots {
+		if snapshot.Source == source {
+			return
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		errcode.Log(log.Warn(), err).Str("source", source).Msg("Failed to archive chart")
+		return
+	}
+	s.snapshots = append(s.snapshots, archive.Snapshot{Time: time.Now().UTC(), Source: source, Response: data})
+}
+
+// listFiltered reports whether opts carry filters beyond the global ones
+func (s *Syncer) listFiltered(opts trakt.ChartOptions) bool {
+	return len(opts.Genres) > 0 || len(opts.Certifications) > 0 || len(opts.Networks) > 0 || len(opts.WatchNow) > 0 ||
+		strings.Join(opts.Countries, ",") != strings.Join(s.config.Sync.Countries, ",")
+}
+
+// Snapshots returns the chart responses archived since the syncer was created
+func (s *Syncer) Snapshots() []archive.Snapshot {
+	return s.snapshots
+}
@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"path"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// sourceResult is a chart result fetched earlier in the current run
+type sourceResult struct {
+	opts  trakt.ChartOptions
+	items []Item
+}
+
+// fetchChart fetches a chart source once per run. A later request for the
+// same source reuses the earlier result when it is compatible: the same
+// rating filter, extended metadata if needed and at least as many items.
+// Titles released before opts.MinYear are dropped in case the source ignored
+// the years filter.
+func (s *Syncer) fetchChart(source string, opts trakt.ChartOptions, fetch func(trakt.ChartOptions) ([]Item, error)) ([]Item, error) {
+	for _, cached := range s.sourceCache[source] {
+		if !chartCovers(cached.opts, opts) {
+			continue
+		}
+		items := cached.items
+		if len(items) > opts.Limit {
+			items = items[:opts.Limit]
+		}
+		log.Debug().Str("source", source).Int("limit", opts.Limit).Msg("Using source result cached earlier in this run")
+		return append([]Item(nil), items...), nil
+	}
+
+	items, err := fetch(opts)
+	if err != nil {
+		return nil, err
+	}
+	items = releasedSince(source, items, opts.MinYear)
+	for i := range items {
+		if items[i].Source == "" {
+			items[i].Source = path.Base(source)
+		}
+	}
+	if s.sourceCache != nil {
+		s.sourceCache[source] = append(s.sourceCache[source], sourceResult{opts: opts, items: items})
+	}
+	return items, nil
+}
+
+// chartCovers reports whether a result fetched with cached options can serve
+// a request with the wanted options
+func chartCovers(cached, wanted trakt.ChartOptions) bool {
+	return cached.Period == wanted.Period &&
+		cached.MinRating == wanted.MinRating &&
+		cached.MinYear == wanted.MinYear &&
+		strings.Join(cached.Genres, ",") == strings.Join(wanted.Genres, ",") &&
+		strings.Join(cached.Certifications, ",") == strings.Join(wanted.Certifications, ",") &&
+		strings.Join(cached.Languages, ",") == strings.Join(wanted.Languages, ",") &&
+		strings.Join(cached.Countries, ",") == strings.Join(wanted.Countries, ",") &&
+		strings.Join(cached.Networks, ",") == strings.Join(wanted.Networks, ",") &&
+		strings.Join(cached.WatchNow, ",") == strings.Join(wanted.WatchNow, ",") &&
+		(cached.Extended || !wanted.Extended) &&
+		cached.Limit >= wanted.Limit
+}
+
+// releasedSince drops items released before minYear. Items without a year,
+// usually unreleased ones, are kept.
+func releasedSince(source string, items []Item, minYear int) []Item {
+	if minYear == 0 {
+		return items
+	}
+	kept := items[:0:0]
+	for _, item := range items {
+		if item.Year == 0 || item.Year >= minYear {
+			kept = append(kept, item)
+		}
+	}
+	if dropped := len(items) - len(kept); dropped > 0 {
+		log.Debug().Str("source", source).Int("min_year", minYear).Int("dropped", dropped).Msg("Dropped items released before the minimum year")
+	}
+	return kept
+}
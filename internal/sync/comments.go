@@ -0,0 +1,51 @@
+package sync
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// linkPattern matches links, the usual payload of list comment spam
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.|\b[a-z0-9-]+\.(com|net|org|io|xyz|ru|top|shop|link|site)\b|t\.me/)`)
+
+// spamWords are phrases that rarely appear in comments about movies or shows
+var spamWords = []string{"telegram", "whatsapp", "crypto", "bitcoin", "casino", "onlyfans", "free download", "watch free", "promo code"}
+
+// SpamComments flags likely spam among the comments of a list and returns
+// the reason per comment ID: a link, a spam phrase, or text posted more
+// than once.
+func SpamComments(comments []trakt.Comment) map[int64]string {
+	posts := make(map[string]int, len(comments))
+	for _, comment := range comments {
+		posts[normalizeComment(comment.Comment)]++
+	}
+
+	reasons := make(map[int64]string)
+	for _, comment := range comments {
+		text := strings.ToLower(comment.Comment)
+		switch {
+		case linkPattern.MatchString(text):
+			reasons[comment.ID] = "link"
+		case containsAny(text, spamWords):
+			reasons[comment.ID] = "spam phrase"
+		case posts[normalizeComment(comment.Comment)] > 1:
+			reasons[comment.ID] = "duplicate"
+		}
+	}
+	return reasons
+}
+
+func normalizeComment(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func containsAny(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+)
+
+// ConfigChange is one operational difference between two configs
+type ConfigChange struct {
+	// Area groups changes: lists, settings or schedule
+	Area string
+	// Target is the list slug or config path affected
+	Target string
+	Detail string
+}
+
+// diffIgnoredPaths are config values that do not change what a sync does:
+// credentials and bookkeeping written by trakt-sync itself
+var diffIgnoredPaths = []string{
+	"trakt.access_token",
+	"trakt.refresh_token",
+	"trakt.token_expires_at",
+	"trakt.client_secret",
+	"sync.last_full_refresh",
+}
+
+// diffListPaths are covered by the list section of the diff
+var diffListPaths = []string{
+	"sync.lists",
+	"sync.split",
+	"sync.ratings_list.enabled",
+	"sync.recently_watched.enabled",
+	"sync.anime.enabled",
+	"sync.streaming_top10.services",
+}
+
+// DiffConfigs describes what would change operationally when switching from
+// the old to the new config: managed lists, settings and the daemon schedule
+func DiffConfigs(oldCfg, newCfg *config.Config) []ConfigChange {
+	changes := diffLists(oldCfg, newCfg)
+	changes = append(changes, diffSplits(oldCfg.Sync.Split, newCfg.Sync.Split)...)
+	changes = append(changes, diffSettings(oldCfg, newCfg)...)
+	return changes
+}
+
+func diffLists(oldCfg, newCfg *config.Config) []ConfigChange {
+	oldLists := enabledLists(oldCfg)
+	newLists := enabledLists(newCfg)
+
+	var changes []ConfigChange
+	for _, newDef := range NewSyncer(nil, newCfg).allListDefinitions() {
+		oldDef, wasEnabled := oldLists[newDef.Slug]
+		_, isEnabled := newLists[newDef.Slug]
+
+		switch {
+		case isEnabled && !wasEnabled:
+			changes = append(changes, ConfigChange{"lists", newDef.Slug, fmt.Sprintf("will be synced (created as %q if missing)", newDef.Name)})
+		case wasEnabled && !isEnabled:
+			changes = append(changes, ConfigChange{"lists", newDef.Slug, "will no longer be synced (the list stays on Trakt)"})
+		case isEnabled:
+			if oldDef.Name != newDef.Name {
+				changes = append(changes, ConfigChange{"lists", newDef.Slug, fmt.Sprintf("name %q -> %q", oldDef.Name, newDef.Name)})
+			}
+			if oldDef.Description != newDef.Description {
+				changes = append(changes, ConfigChange{"lists", newDef.Slug, fmt.Sprintf("description %q -> %q", oldDef.Description, newDef.Description)})
+			}
+			if oldDef.IsMovie != newDef.IsMovie {
+				changes = append(changes, ConfigChange{"lists", newDef.Slug, fmt.Sprintf("content switches from %s to %s (all items are replaced)", mediaKind(oldDef.IsMovie), mediaKind(newDef.IsMovie))})
+			}
+		}
+	}
+	return changes
+}
+
+func enabledLists(cfg *config.Config) map[string]ListDefinition {
+	lists := make(map[string]ListDefinition)
+	for _, listDef := range NewSyncer(nil, cfg).allListDefinitions() {
+		if listDef.Enabled {
+			lists[listDef.Slug] = listDef
+		}
+	}
+	return lists
+}
+
+func mediaKind(isMovie bool) string {
+	if isMovie {
+		return "movies"
+	}
+	return "shows"
+}
+
+func diffSplits(oldSplits, newSplits map[string]config.SplitConfig) []ConfigChange {
+	slugs := make(map[string]bool)
+	for slug := range oldSplits {
+		slugs[slug] = true
+	}
+	for slug := range newSplits {
+		slugs[slug] = true
+	}
+
+	var changes []ConfigChange
+	for _, slug := range sortedKeys(slugs) {
+		oldSplit, hadSplit := oldSplits[slug]
+		newSplit, hasSplit := newSplits[slug]
+
+		switch {
+		case hasSplit && !hadSplit:
+			changes = append(changes, ConfigChange{"lists", slug, fmt.Sprintf("will be split by %s into separate lists", splitBy(newSplit))})
+		case hadSplit && !hasSplit:
+			changes = append(changes, ConfigChange{"lists", slug, "will no longer be split; previously created child lists are left as they are"})
+		default:
+			if splitBy(oldSplit) != splitBy(newSplit) {
+				changes = append(changes, ConfigChange{"lists", slug, fmt.Sprintf("split by %s -> %s (child lists are replaced)", splitBy(oldSplit), splitBy(newSplit))})
+			}
+			if strings.Join(oldSplit.Genres, ",") != strings.Join(newSplit.Genres, ",") {
+				changes = append(changes, ConfigChange{"lists", slug, fmt.Sprintf("split genres [%s] -> [%s]", strings.Join(oldSplit.Genres, ", "), strings.Join(newSplit.Genres, ", "))})
+			}
+			if oldSplit.NameTemplate != newSplit.NameTemplate {
+				changes = append(changes, ConfigChange{"lists", slug, "split name template changed (child lists are recreated under the new names)"})
+			}
+		}
+	}
+	return changes
+}
+
+func splitBy(split config.SplitConfig) string {
+	if split.By == "" {
+		return "genre"
+	}
+	return split.By
+}
+
+func diffSettings(oldCfg, newCfg *config.Config) []ConfigChange {
+	oldValues := config.Flatten(oldCfg)
+	newValues := config.Flatten(newCfg)
+
+	paths := make(map[string]bool)
+	for path := range oldValues {
+		paths[path] = true
+	}
+	for path := range newValues {
+		paths[path] = true
+	}
+
+	var changes []ConfigChange
+	for _, path := range sortedKeys(paths) {
+		if hasPathPrefix(path, diffIgnoredPaths) || hasPathPrefix(path, diffListPaths) {
+			continue
+		}
+		if oldValues[path] == newValues[path] {
+			continue
+		}
+
+		area := "settings"
+		if strings.HasPrefix(path, "daemon.") {
+			area = "schedule"
+		}
+		changes = append(changes, ConfigChange{area, path, fmt.Sprintf("%s -> %s", displayValue(oldValues[path]), displayValue(newValues[path]))})
+	}
+	return changes
+}
+
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func displayValue(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+	return value
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,48 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	oldCfg := &config.Config{}
+	oldCfg.Sync.Limit = 30
+	oldCfg.Sync.Lists = config.ListSyncConfig{Movies: true, Shows: true}
+	oldCfg.Trakt.AccessToken = "old"
+
+	newCfg := &config.Config{}
+	newCfg.Sync.Limit = 50
+	newCfg.Sync.Lists = config.ListSyncConfig{Movies: true}
+	newCfg.Sync.Split = map[string]config.SplitConfig{MoviesListSlug: {By: "decade"}}
+	newCfg.Daemon.Interval = 3 * time.Hour
+	newCfg.Trakt.AccessToken = "new"
+
+	changes := DiffConfigs(oldCfg, newCfg)
+
+	want := []ConfigChange{
+		{"lists", ShowsListSlug, "will no longer be synced (the list stays on Trakt)"},
+		{"lists", MoviesListSlug, "will be split by decade into separate lists"},
+		{"schedule", "daemon.interval", "(unset) -> 3h0m0s"},
+		{"settings", "sync.limit", "30 -> 50"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(want), len(changes), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d: expected %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestDiffConfigsUnchanged(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sync.Lists.Movies = true
+
+	if changes := DiffConfigs(cfg, cfg); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
@@ -0,0 +1,248 @@
+package sync
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// externalEdits are the changes others made to a managed list since
+// trakt-sync last wrote to it
+type externalEdits struct {
+	added   []trakt.ListItem
+	removed []int
+}
+
+func (e externalEdits) empty() bool {
+	return len(e.added) == 0 && len(e.removed) == 0
+}
+
+// detectExternalEdits compares a list with what trakt-sync last wrote to it.
+// The list's updated_at tells whether anyone touched it since; the item diff
+// then tells what changed.
+func (s *Syncer) detectExternalEdits(slug string, list *trakt.List, current []trakt.ListItem) externalEdits {
+	written, ok := s.state.Lists[s.managedSlug(slug)]
+	if !ok || list == nil || !list.UpdatedAt.After(written.WrittenAt) {
+		return externalEdits{}
+	}
+
+	wrote := make(map[int]bool, len(written.Items))
+	for _, id := range written.Items {
+		wrote[id] = true
+	}
+
+	var edits externalEdits
+	present := make(map[int]bool, len(current))
+	for _, item := range current {
+		if item.Movie == nil && item.Show == nil {
+			continue
+		}
+		ids := itemMediaIDs(item)
+		present[ids.Trakt] = true
+		if !wrote[ids.Trakt] {
+			edits.added = append(edits.added, item)
+		}
+	}
+	for _, id := range written.Items {
+		if !present[id] {
+			edits.removed = append(edits.removed, id)
+		}
+	}
+
+	if !edits.empty() {
+		localized := append([]trakt.ListItem(nil), edits.added...)
+		s.localizer.Localize(localized)
+		titles := make([]string, 0, len(localized))
+		for _, item := range localized {
+			titles = append(titles, itemTitle(item))
+		}
+		log.Warn().
+			Str("list", slug).
+			Strs("added", titles).
+			Ints("removed", edits.removed).
+			Str("conflict_policy", s.conflictPolicy(slug)).
+			Msg("List was edited outside trakt-sync since the last sync")
+	}
+	return edits
+}
+
+// applyConflictPolicy adjusts the items a list should contain for changes
+// others made to it. With preserve_manual, items others added stay in the
+// list and items they removed are not added back, also in later runs.
+func (s *Syncer) applyConflictPolicy(slug string, edits externalEdits, current []trakt.ListItem, newItems []trakt.MediaIDs) []trakt.MediaIDs {
+	key := s.managedSlug(slug)
+	entry, ok := s.state.Lists[key]
+	if !ok {
+		return newItems
+	}
+
+	if s.conflictPolicy(slug) != config.ConflictPreserveManual {
+		if len(entry.Manual) > 0 || len(entry.Excluded) > 0 {
+			entry.Manual, entry.Excluded = nil, nil
+			s.state.Lists[key] = entry
+			s.stateDirty = true
+		}
+		return newItems
+	}
+
+	currentIDs := make(map[int]trakt.MediaIDs, len(current))
+	for _, item := range current {
+		ids := itemMediaIDs(item)
+		currentIDs[ids.Trakt] = ids
+	}
+
+	// Manual items the user removed again are no longer protected, excluded
+	// items someone added back are manual ones now.
+	manual := make(map[int]bool)
+	for _, id := range entry.Manual {
+		manual[id] = true
+	}
+	for _, item := range edits.added {
+		manual[itemMediaIDs(item).Trakt] = true
+	}
+	excluded := make(map[int]bool)
+	for _, id := range append(entry.Excluded, edits.removed...) {
+		excluded[id] = true
+	}
+	for id := range manual {
+		if _, ok := currentIDs[id]; !ok {
+			delete(manual, id)
+		}
+	}
+	for id := range excluded {
+		if _, ok := currentIDs[id]; ok {
+			delete(excluded, id)
+		}
+	}
+
+	entry.Manual = sortedIDs(manual)
+	entry.Excluded = sortedIDs(excluded)
+	s.state.Lists[key] = entry
+	if !edits.empty() {
+		s.stateDirty = true
+	}
+
+	result := make([]trakt.MediaIDs, 0, len(newItems)+len(manual))
+	seen := make(map[int]bool)
+	for _, ids := range newItems {
+		if !excluded[ids.Trakt] {
+			result = append(result, ids)
+			seen[ids.Trakt] = true
+		}
+	}
+	for _, id := range entry.Manual {
+		if !seen[id] {
+			result = append(result, currentIDs[id])
+		}
+	}
+	return result
+}
+
+// applyManualRemovals keeps items someone removed from a list out of it for
+// good when sync.respect_manual_removals is set. Removed items become
+// tombstones in the state; adding one back on Trakt lifts it.
+func (s *Syncer) applyManualRemovals(slug string, edits externalEdits, newItems []trakt.MediaIDs) []trakt.MediaIDs {
+	key := s.managedSlug(slug)
+	entry, ok := s.state.Lists[key]
+	if !ok {
+		return newItems
+	}
+
+	if !s.config.Sync.RespectManualRemovals {
+		if len(entry.Tombstones) > 0 {
+			entry.Tombstones = nil
+			s.state.Lists[key] = entry
+			s.stateDirty = true
+		}
+		return newItems
+	}
+
+	tombstones := make(map[int]bool, len(entry.Tombstones)+len(edits.removed))
+	for _, id := range append(entry.Tombstones, edits.removed...) {
+		tombstones[id] = true
+	}
+	for _, item := range edits.added {
+		delete(tombstones, itemMediaIDs(item).Trakt)
+	}
+	if !edits.empty() {
+		entry.Tombstones = sortedIDs(tombstones)
+		s.state.Lists[key] = entry
+		s.stateDirty = true
+	}
+	if len(tombstones) == 0 {
+		return newItems
+	}
+
+	result := make([]trakt.MediaIDs, 0, len(newItems))
+	for _, ids := range newItems {
+		if !tombstones[ids.Trakt] {
+			result = append(result, ids)
+		}
+	}
+	if skipped := len(newItems) - len(result); skipped > 0 {
+		log.Info().Str("list", slug).Int("skipped", skipped).Msg("Not re-adding items removed on Trakt")
+	}
+	return result
+}
+
+// conflictPolicy returns the conflict policy that applies to a list:
+// sync.conflict_policies for the list (or the list a split list was fanned
+// out from), falling back to sync.conflict_policy
+func (s *Syncer) conflictPolicy(slug string) string {
+	for _, key := range s.configKeys(slug) {
+		if policy, ok := s.config.Sync.ConflictPolicies[key]; ok {
+			return policy
+		}
+	}
+	if policy := s.config.Sync.ConflictPolicy; policy != "" {
+		return policy
+	}
+	return config.ConflictOverwrite
+}
+
+// configKeys returns the slugs per-list config maps may use for a list, most
+// specific first: its built-in slug and, for split lists, the parent's
+func (s *Syncer) configKeys(slug string) []string {
+	slug = s.managedSlug(strings.TrimSuffix(slug, s.suffix))
+	keys := []string{slug}
+	for parent, children := range s.state.SplitLists {
+		for _, child := range children {
+			if child == slug {
+				keys = append(keys, parent)
+			}
+		}
+	}
+	return keys
+}
+
+func itemMediaIDs(item trakt.ListItem) trakt.MediaIDs {
+	switch {
+	case item.Movie != nil:
+		return item.Movie.IDs
+	case item.Show != nil:
+		return item.Show.IDs
+	}
+	return trakt.MediaIDs{}
+}
+
+func itemTitle(item trakt.ListItem) string {
+	switch {
+	case item.Movie != nil:
+		return item.Movie.Title
+	case item.Show != nil:
+		return item.Show.Title
+	}
+	return item.Type
+}
+
+func sortedIDs(set map[int]bool) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// applyCooldown keeps items trakt-sync removed from a list within the last
+// sync.readd_cooldown_days out of it, so titles hovering at the edge of a
+// chart are not removed and added back on every run. Items still in the list
+// are unaffected. Expired removals are forgotten.
+func (s *Syncer) applyCooldown(slug string, current []trakt.ListItem, newItems []trakt.MediaIDs) []trakt.MediaIDs {
+	key := s.managedSlug(slug)
+	entry, ok := s.state.Lists[key]
+	if !ok || len(entry.RemovedAt) == 0 {
+		return newItems
+	}
+
+	cooldown := time.Duration(s.config.Sync.ReaddCooldownDays) * 24 * time.Hour
+	cooling := make(map[int]bool)
+	for id, removedAt := range entry.RemovedAt {
+		if time.Since(removedAt) < cooldown {
+			cooling[id] = true
+			continue
+		}
+		delete(entry.RemovedAt, id)
+		s.stateDirty = true
+	}
+	if len(entry.RemovedAt) == 0 {
+		entry.RemovedAt = nil
+	}
+	s.state.Lists[key] = entry
+	if len(cooling) == 0 {
+		return newItems
+	}
+
+	for _, item := range current {
+		delete(cooling, itemMediaIDs(item).Trakt)
+	}
+	result := make([]trakt.MediaIDs, 0, len(newItems))
+	for _, ids := range newItems {
+		if !cooling[ids.Trakt] {
+			result = append(result, ids)
+		}
+	}
+	if skipped := len(newItems) - len(result); skipped > 0 {
+		log.Info().
+			Str("list", slug).
+			Int("skipped", skipped).
+			Int("cooldown_days", s.config.Sync.ReaddCooldownDays).
+			Msg("Not re-adding recently removed items")
+	}
+	return result
+}
+
+// recordRemovals remembers when items were removed from a list while
+// sync.readd_cooldown_days is set
+func (s *Syncer) recordRemovals(slug string, removed []trakt.MediaIDs) {
+	if s.config.Sync.ReaddCooldownDays <= 0 || len(removed) == 0 {
+		return
+	}
+
+	if s.state.Lists == nil {
+		s.state.Lists = make(map[string]state.ListState)
+	}
+	key := s.managedSlug(slug)
+	entry := s.state.Lists[key]
+	if entry.RemovedAt == nil {
+		entry.RemovedAt = make(map[int]time.Time, len(removed))
+	}
+	now := time.Now().UTC()
+	for _, ids := range removed {
+		entry.RemovedAt[ids.Trakt] = now
+	}
+	s.state.Lists[key] = entry
+	s.stateDirty = true
+}
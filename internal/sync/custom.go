@@ -0,0 +1,163 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// customListDefinitions builds the lists of sync.custom_lists. Lists whose
+// slug is already taken by an earlier list are skipped.
+func (s *Syncer) customListDefinitions(taken map[string]bool) []ListDefinition {
+	var lists []ListDefinition
+	for _, custom := range s.config.Sync.CustomLists {
+		listDef := s.customListDefinition(custom)
+		if taken[listDef.Slug] {
+			log.Warn().Str("list", listDef.Slug).Msg("Skipping custom list whose slug is already in use")
+			continue
+		}
+		taken[listDef.Slug] = true
+		lists = append(lists, listDef)
+	}
+	return lists
+}
+
+func (s *Syncer) customListDefinition(custom config.CustomListConfig) ListDefinition {
+	custom = custom.Expanded()
+	slug := custom.Slug
+	if slug == "" {
+		slug = config.Slugify(custom.Name)
+	}
+	name := custom.Name
+	if name == "" {
+		name = slug
+	}
+	description := custom.Description
+	if description == "" {
+		sources := custom.Sources
+		if len(custom.IMDbLists) > 0 {
+			sources = append(sources[:len(sources):len(sources)], "IMDb lists")
+		}
+		if len(custom.MDBLists) > 0 {
+			sources = append(sources[:len(sources):len(sources)], "MDBList lists")
+		}
+		description = fmt.Sprintf("%s %s", strings.Join(sources, ", "), custom.Type)
+		description = strings.ToUpper(description[:1]) + description[1:]
+	}
+
+	return ListDefinition{
+		Slug:        slug,
+		Name:        name,
+		Description: description,
+		Enabled:     custom.Enabled == nil || *custom.Enabled,
+		FetchFunc:   s.fetchCustom(custom),
+		IsMovie:     custom.Type == "movies",
+		Limit:       custom.Limit,
+		Privacy:     custom.Privacy,
+	}
+}
+
+// fetchCustom merges the chart sources, the IMDb lists and then the MDBList
+// lists of a custom list in order and applies its certification and genre
+// filters
+func (s *Syncer) fetchCustom(custom config.CustomListConfig) func(*trakt.Client, int) ([]Item, error) {
+	isMovie := custom.Type == "movies"
+	return func(client *trakt.Client, limit int) ([]Item, error) {
+		opts := s.chartOptions(limit)
+		opts.Certifications = custom.Certifications
+		if len(custom.Countries) > 0 {
+			opts.Countries = custom.Countries
+		}
+		opts.Networks = custom.Networks
+		if len(custom.ExcludeGenres) > 0 {
+			opts.Extended = true
+		}
+
+		var items []Item
+		for _, source := range custom.Sources {
+			fetch, err := s.chartSource(source, isMovie)
+			if err != nil {
+				return nil, err
+			}
+			result, err := fetch(client, opts)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, result...)
+		}
+		for _, list := range custom.IMDbLists {
+			result, err := s.imdbListItems(list, isMovie, limit)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, result...)
+		}
+		for _, list := range custom.MDBLists {
+			result, err := s.mdbListItems(list, isMovie, limit)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, result...)
+		}
+		return withoutGenres(uniqueItems(items), custom.ExcludeGenres), nil
+	}
+}
+
+// chartSource returns the fetch function of a named chart source
+func (s *Syncer) chartSource(source string, isMovie bool) (func(*trakt.Client, trakt.ChartOptions) ([]Item, error), error) {
+	switch {
+	case source == "trending" && isMovie:
+		return s.trendingMovies, nil
+	case source == "trending":
+		return s.trendingShows, nil
+	case source == "popular" && isMovie:
+		return s.popularMovies, nil
+	case source == "popular":
+		return s.popularShows, nil
+	case source == "watched" && isMovie:
+		return s.streamingMovies, nil
+	case source == "watched":
+		return s.streamingShows, nil
+	case source == "anticipated" && isMovie:
+		return s.anticipatedMovies, nil
+	case source == "anticipated":
+		return s.anticipatedShows, nil
+	case source == "premieres" && isMovie:
+		return s.premiereMovies, nil
+	case source == "premieres":
+		return s.premiereShows, nil
+	case source == "recommendations" && isMovie:
+		return s.recommendedMovies, nil
+	case source == "recommendations":
+		return s.recommendedShows, nil
+	}
+	return nil, fmt.Errorf("unknown source %q", source)
+}
+
+// withoutGenres drops items that have any of the excluded genres
+func withoutGenres(items []Item, excluded []string) []Item {
+	if len(excluded) == 0 {
+		return items
+	}
+	kept := make([]Item, 0, len(items))
+	for _, item := range items {
+		if !hasAnyGenre(item.Genres, excluded) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func hasAnyGenre(genres, wanted []string) bool {
+	for _, genre := range genres {
+		for _, w := range wanted {
+			if genre == w {
+				return true
+			}
+		}
+	}
+	return false
+}
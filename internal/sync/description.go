@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/maximilian/trakt-sync/internal/translate"
+	"github.com/rs/zerolog/log"
+)
+
+// descriptionTopItems is the number of titles in .Top
+const descriptionTopItems = 3
+
+// updateDescription renders the sync.description_templates template of a
+// list, appends its translation if a translation provider is configured and
+// updates the description on Trakt when it changed. Translations are cached
+// in the state, so the provider is only asked when the rendered text changes.
+// Template and translation failures leave the description as it is.
+func (s *Syncer) updateDescription(listDef ListDefinition, list *trakt.List, fetched []Item, content []trakt.MediaIDs) error {
+	var text string
+	for _, key := range s.configKeys(listDef.Slug) {
+		if text = s.config.Sync.DescriptionTemplates[key]; text != "" {
+			break
+		}
+	}
+	if strings.TrimSpace(text) == "" || list == nil {
+		return nil
+	}
+	tmpl, err := template.New(listDef.Slug).Option("missingkey=error").Parse(text)
+	if err != nil {
+		errcode.Log(log.Warn(), err).Str("list", listDef.Slug).Msg("Invalid description template, keeping the description")
+		return nil
+	}
+
+	titles := make(map[int]string, len(fetched))
+	for _, item := range fetched {
+		titles[item.IDs.Trakt] = item.Title
+	}
+	data := config.DescriptionData{List: list.Name, Items: len(content), Updated: time.Now().Format("2006-01-02")}
+	for _, id := range content {
+		if len(data.Top) == descriptionTopItems {
+			break
+		}
+		if title := titles[id.Trakt]; title != "" {
+			data.Top = append(data.Top, title)
+		}
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		errcode.Log(log.Warn(), err).Str("list", listDef.Slug).Msg("Failed to render description, keeping the description")
+		return nil
+	}
+	description := strings.TrimSpace(rendered.String())
+	if translation, ok := s.translateDescription(listDef.Slug, description); ok {
+		description += "\n\n" + translation
+	} else if s.config.Translation.Provider != "" {
+		return nil
+	}
+	if description == list.Description {
+		return nil
+	}
+
+	log.Info().Str("list", listDef.Slug).Msg("Updating list description")
+	if _, err := s.client.UpdateList(s.config.Trakt.Username, listDef.Slug, trakt.UpdateListRequest{Description: description}); err != nil {
+		return fmt.Errorf("failed to update list description: %w", err)
+	}
+	return nil
+}
+
+// translateDescription returns the translation of a rendered description,
+// from the state if it was translated before. It reports false if no
+// translation provider is configured or the translation failed.
+func (s *Syncer) translateDescription(slug, source string) (string, bool) {
+	settings := s.config.Translation
+	if settings.Provider == "" {
+		return "", false
+	}
+	key := s.managedSlug(slug)
+	if cached, ok := s.state.DescriptionTranslations[key]; ok && cached.Source == source {
+		return cached.Text, true
+	}
+
+	client, err := translate.NewClient(settings.Provider, settings.URL, settings.APIKey)
+	if err == nil {
+		client.SetContext(s.ctx)
+		var text string
+		if text, err = client.Translate(source, settings.SourceLanguage, settings.TargetLanguage); err == nil {
+			if s.state.DescriptionTranslations == nil {
+				s.state.DescriptionTranslations = make(map[string]state.DescriptionTranslation)
+			}
+			s.state.DescriptionTranslations[key] = state.DescriptionTranslation{Source: source, Text: text}
+			s.stateDirty = true
+			return text, true
+		}
+	}
+	errcode.Log(log.Warn(), err).Str("list", slug).Msg("Failed to translate description, keeping the description")
+	return "", false
+}
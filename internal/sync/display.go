@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// listDisplay returns the sync.list_display options that apply to a list
+func (s *Syncer) listDisplay(slug string) (config.ListDisplayConfig, bool) {
+	for _, key := range s.configKeys(slug) {
+		if display, ok := s.config.Sync.ListDisplay[key]; ok {
+			return display, true
+		}
+	}
+	return config.ListDisplayConfig{}, false
+}
+
+// reconcileDisplay updates the privacy and display options of a list on
+// Trakt where they differ from the list definition and sync.list_display.
+// Display options that are not configured are left as they are, so changes
+// made on the website stick.
+func (s *Syncer) reconcileDisplay(listDef ListDefinition, list *trakt.List) error {
+	if list == nil {
+		return nil
+	}
+	slug := listDef.Slug
+	display, _ := s.listDisplay(slug)
+
+	var req trakt.UpdateListRequest
+	changed := false
+	if privacy := s.listPrivacy(listDef); privacy != list.Privacy {
+		req.Privacy = privacy
+		changed = true
+	}
+	if display.DisplayNumbers != nil && *display.DisplayNumbers != list.DisplayNumbers {
+		req.DisplayNumbers = display.DisplayNumbers
+		changed = true
+	}
+	if display.AllowComments != nil && *display.AllowComments != list.AllowComments {
+		req.AllowComments = display.AllowComments
+		changed = true
+	}
+	if display.SortBy != "" && display.SortBy != list.SortBy {
+		req.SortBy = display.SortBy
+		changed = true
+	}
+	if display.SortHow != "" && display.SortHow != list.SortHow {
+		req.SortHow = display.SortHow
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	log.Info().Str("list", slug).Msg("Updating list display options")
+	_, err := s.client.UpdateList(s.config.Trakt.Username, slug, req)
+	return err
+}
+
+// applyDisplay sets the configured display options on a list to be created
+func (s *Syncer) applyDisplay(slug string, req *trakt.CreateListRequest) {
+	display, ok := s.listDisplay(slug)
+	if !ok {
+		return
+	}
+	if display.DisplayNumbers != nil {
+		req.DisplayNumbers = *display.DisplayNumbers
+	}
+	if display.AllowComments != nil {
+		req.AllowComments = *display.AllowComments
+	}
+	req.SortBy = display.SortBy
+	req.SortHow = display.SortHow
+}
@@ -0,0 +1,108 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// titleSet holds the Trakt IDs of movies and shows, which are numbered
+// independently
+type titleSet struct {
+	movies map[int]bool
+	shows  map[int]bool
+}
+
+func newTitleSet() *titleSet {
+	return &titleSet{movies: make(map[int]bool), shows: make(map[int]bool)}
+}
+
+// add adds movie or show, whichever is set
+func (t *titleSet) add(movie *trakt.Movie, show *trakt.Show) {
+	switch {
+	case movie != nil:
+		t.movies[movie.IDs.Trakt] = true
+	case show != nil:
+		t.shows[show.IDs.Trakt] = true
+	}
+}
+
+// has reports whether the movie or show with ids is in the set
+func (t *titleSet) has(ids trakt.MediaIDs, isMovie bool) bool {
+	if isMovie {
+		return t.movies[ids.Trakt]
+	}
+	return t.shows[ids.Trakt]
+}
+
+// watchlistItems returns the watchlist items in the set
+func (t *titleSet) watchlistItems(items []trakt.WatchlistItem) []trakt.WatchlistItem {
+	var found []trakt.WatchlistItem
+	for _, item := range items {
+		switch {
+		case item.Movie != nil && t.has(item.Movie.IDs, true),
+			item.Show != nil && t.has(item.Show.IDs, false):
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+// excludeOwnTitles drops the titles the user watched or collected from the
+// items of listDef, per watchlist.exclude_watched and sync.exclude_collected.
+// Lists of the user's own titles are left alone.
+func (s *Syncer) excludeOwnTitles(listDef ListDefinition, ids []trakt.MediaIDs) ([]trakt.MediaIDs, error) {
+	if listDef.personal {
+		return ids, nil
+	}
+	if s.config.Watchlist.ExcludeWatched {
+		watched, err := s.watchedTitles()
+		if err != nil {
+			return nil, err
+		}
+		ids = withoutTitles(listDef, ids, watched, "watched")
+	}
+	if s.config.Sync.ExcludeCollected {
+		collected, err := s.collectedTitles()
+		if err != nil {
+			return nil, err
+		}
+		ids = withoutTitles(listDef, ids, collected, "collected")
+	}
+	return ids, nil
+}
+
+// withoutTitles drops the titles in set from the items of listDef; reason
+// names the set in the log
+func withoutTitles(listDef ListDefinition, ids []trakt.MediaIDs, set *titleSet, reason string) []trakt.MediaIDs {
+	kept := make([]trakt.MediaIDs, 0, len(ids))
+	for _, id := range ids {
+		if !set.has(id, listDef.IsMovie) {
+			kept = append(kept, id)
+		}
+	}
+	if dropped := len(ids) - len(kept); dropped > 0 {
+		log.Info().Str("list", listDef.Slug).Int(reason, dropped).Msgf("Excluded %s titles", reason)
+	}
+	return kept
+}
+
+// collectedTitles fetches the movie and show collection once per run
+func (s *Syncer) collectedTitles() (*titleSet, error) {
+	if s.collected != nil {
+		return s.collected, nil
+	}
+	collected := newTitleSet()
+	for _, mediaType := range []string{"movies", "shows"} {
+		items, err := s.client.GetCollection(mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get collection: %w", err)
+		}
+		for _, item := range items {
+			collected.add(item.Movie, item.Show)
+		}
+	}
+	s.collected = collected
+	return collected, nil
+}
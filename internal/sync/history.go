@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// DuplicatePlay is a history entry that repeats an earlier play of the same
+// item within the dedupe window
+type DuplicatePlay struct {
+	Entry    trakt.HistoryItem
+	Original trakt.HistoryItem
+}
+
+// FindDuplicatePlays returns plays that follow an earlier play of the same
+// movie or episode within window. The earliest play of each cluster is kept.
+func FindDuplicatePlays(items []trakt.HistoryItem, window time.Duration) []DuplicatePlay {
+	sorted := make([]trakt.HistoryItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].WatchedAt.Equal(sorted[j].WatchedAt) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].WatchedAt.Before(sorted[j].WatchedAt)
+	})
+
+	lastKept := make(map[string]trakt.HistoryItem)
+	var duplicates []DuplicatePlay
+
+	for _, item := range sorted {
+		key := historyItemKey(item)
+		if key == "" {
+			continue
+		}
+
+		if original, ok := lastKept[key]; ok && item.WatchedAt.Sub(original.WatchedAt) <= window {
+			duplicates = append(duplicates, DuplicatePlay{Entry: item, Original: original})
+			continue
+		}
+
+		lastKept[key] = item
+	}
+
+	return duplicates
+}
+
+// HistoryItemTitle returns a human readable title for a history entry
+func HistoryItemTitle(item trakt.HistoryItem) string {
+	switch {
+	case item.Episode != nil && item.Show != nil:
+		return fmt.Sprintf("%s S%02dE%02d", item.Show.Title, item.Episode.Season, item.Episode.Number)
+	case item.Movie != nil:
+		return fmt.Sprintf("%s (%d)", item.Movie.Title, item.Movie.Year)
+	default:
+		return fmt.Sprintf("history entry %d", item.ID)
+	}
+}
+
+func historyItemKey(item trakt.HistoryItem) string {
+	switch {
+	case item.Episode != nil:
+		return fmt.Sprintf("episode:%d", item.Episode.IDs.Trakt)
+	case item.Movie != nil:
+		return fmt.Sprintf("movie:%d", item.Movie.IDs.Trakt)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,54 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+func TestFindDuplicatePlays(t *testing.T) {
+	base := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	movie := &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 1}}
+	episode := &trakt.Episode{IDs: trakt.MediaIDs{Trakt: 7}}
+
+	items := []trakt.HistoryItem{
+		{ID: 3, WatchedAt: base.Add(5 * time.Minute), Movie: movie},
+		{ID: 1, WatchedAt: base, Movie: movie},
+		{ID: 2, WatchedAt: base.Add(3 * time.Hour), Movie: movie},
+		{ID: 4, WatchedAt: base, Episode: episode},
+		{ID: 5, WatchedAt: base.Add(time.Minute), Episode: episode},
+	}
+
+	duplicates := FindDuplicatePlays(items, 10*time.Minute)
+
+	got := make(map[int64]int64)
+	for _, dup := range duplicates {
+		got[dup.Entry.ID] = dup.Original.ID
+	}
+
+	want := map[int64]int64{3: 1, 5: 4}
+	if len(got) != len(want) {
+		t.Fatalf("expected duplicates %v, got %v", want, got)
+	}
+	for id, original := range want {
+		if got[id] != original {
+			t.Fatalf("expected entry %d to duplicate %d, got %v", id, original, got)
+		}
+	}
+}
+
+func TestRecentlyWatchedIDs(t *testing.T) {
+	show := &trakt.Show{IDs: trakt.MediaIDs{Trakt: 100}}
+	items := []trakt.HistoryItem{
+		{ID: 1, Show: show, Episode: &trakt.Episode{IDs: trakt.MediaIDs{Trakt: 1}}},
+		{ID: 2, Show: show, Episode: &trakt.Episode{IDs: trakt.MediaIDs{Trakt: 2}}},
+		{ID: 3, Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: 200}}},
+		{ID: 4, Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: 300}}},
+	}
+
+	ids := extractIDs(itemIDs(recentlyWatchedItems(items, 2)))
+	if len(ids) != 2 || ids[0] != 100 || ids[1] != 200 {
+		t.Fatalf("expected [100 200], got %v", ids)
+	}
+}
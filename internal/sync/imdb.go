@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"github.com/maximilian/trakt-sync/internal/imdb"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// imdbListItems returns the titles of an IMDb list that Trakt knows, in list
+// order and capped at limit. Titles of the other media type are skipped.
+func (s *Syncer) imdbListItems(list string, isMovie bool, limit int) ([]Item, error) {
+	client := imdb.NewClient(imdb.BaseURL)
+	client.SetContext(s.ctx)
+	titles, err := client.List(list)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := imdb.KindShow
+	if isMovie {
+		wanted = imdb.KindMovie
+	}
+	var items []Item
+	unresolved := 0
+	for _, title := range titles {
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+		if title.Kind != "" && title.Kind != wanted {
+			continue
+		}
+		ids, ok, err := s.resolveIMDB(title.IMDB, isMovie)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unresolved++
+			continue
+		}
+		items = append(items, Item{IDs: ids, Title: title.Name, Year: title.Year, Source: "IMDb " + list})
+	}
+	if unresolved > 0 {
+		log.Debug().Str("imdb_list", list).Int("unresolved", unresolved).Msg("Skipped IMDb titles Trakt does not know")
+	}
+	return uniqueItems(items), nil
+}
+
+// resolveIMDB returns the Trakt IDs of the movie or show with an IMDb ID. The
+// Trakt ID is cached in the state by type and IMDb ID, so each ID is only
+// looked up once per type; ok is false if Trakt does not know the ID.
+func (s *Syncer) resolveIMDB(imdbID string, isMovie bool) (ids trakt.MediaIDs, ok bool, err error) {
+	mediaType := "show"
+	if isMovie {
+		mediaType = "movie"
+	}
+	key := mediaType + ":" + imdbID
+	if id, ok := s.state.Pins[key]; ok {
+		return trakt.MediaIDs{Trakt: id, IMDB: imdbID}, true, nil
+	}
+
+	results, err := s.client.LookupIMDB(imdbID, mediaType)
+	if err != nil {
+		return trakt.MediaIDs{}, false, err
+	}
+	for _, result := range results {
+		switch {
+		case isMovie && result.Movie != nil:
+			ids = result.Movie.IDs
+		case !isMovie && result.Show != nil:
+			ids = result.Show.IDs
+		default:
+			continue
+		}
+
+		if s.state.Pins == nil {
+			s.state.Pins = make(map[string]int)
+		}
+		s.state.Pins[key] = ids.Trakt
+		// Drop the entry of an older version, which was not keyed by type.
+		delete(s.state.Pins, imdbID)
+		s.stateDirty = true
+		return ids, true, nil
+	}
+	return trakt.MediaIDs{}, false, nil
+}
@@ -0,0 +1,251 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// CloneList copies the movies and shows of any accessible list into a new
+// list of the configured user. It returns the slug of the new list and the
+// number of copied items.
+func (s *Syncer) CloneList(sourceUser, sourceSlug, newSlug string) (string, int, error) {
+	source, err := s.client.GetList(sourceUser, sourceSlug)
+	if err != nil {
+		return "", 0, err
+	}
+	if source == nil {
+		return "", 0, fmt.Errorf("list %s/%s not found or not accessible", sourceUser, sourceSlug)
+	}
+
+	existing, err := s.client.GetList(s.config.Trakt.Username, newSlug)
+	if err != nil {
+		return "", 0, err
+	}
+	if existing != nil {
+		return "", 0, fmt.Errorf("list %s already exists", newSlug)
+	}
+
+	items, err := s.client.GetListItems(sourceUser, sourceSlug)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Trakt derives the slug from the name, so the new slug doubles as name.
+	created, err := s.client.CreateList(s.config.Trakt.Username, trakt.CreateListRequest{
+		Name:           newSlug,
+		Description:    fmt.Sprintf("Copy of %q by %s", source.Name, sourceUser),
+		Privacy:        s.config.Sync.ListPrivacy,
+		DisplayNumbers: true,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	slug := created.IDs.Slug
+	if slug == "" {
+		slug = newSlug
+	}
+
+	req := addRequestForItems(items)
+	count := len(req.Movies) + len(req.Shows)
+	if skipped := len(items) - count; skipped > 0 {
+		log.Warn().Int("skipped", skipped).Msg("Only movies and shows are copied; skipping other list items")
+	}
+	if count > 0 {
+		if err := s.client.AddItemsToList(s.config.Trakt.Username, slug, req); err != nil {
+			return slug, 0, err
+		}
+	}
+
+	return slug, count, nil
+}
+
+// MergeLists adds the union of two of the user's lists to a target list,
+// creating the target if it does not exist. Items are deduplicated by type
+// and Trakt ID, including against items already in the target. It returns
+// the number of added items.
+func (s *Syncer) MergeLists(slugA, slugB, target string) (int, error) {
+	username := s.config.Trakt.Username
+
+	var merged []trakt.ListItem
+	for _, slug := range []string{slugA, slugB} {
+		items, err := s.ownListItems(slug)
+		if err != nil {
+			return 0, err
+		}
+		merged = append(merged, items...)
+	}
+
+	existing, err := s.client.GetList(username, target)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]bool)
+	if existing == nil {
+		created, err := s.client.CreateList(username, trakt.CreateListRequest{
+			Name:           target,
+			Description:    fmt.Sprintf("Merged from %s and %s", slugA, slugB),
+			Privacy:        s.config.Sync.ListPrivacy,
+			DisplayNumbers: true,
+		})
+		if err != nil {
+			return 0, err
+		}
+		if created.IDs.Slug != "" {
+			target = created.IDs.Slug
+		}
+	} else {
+		targetItems, err := s.client.GetListItems(username, target)
+		if err != nil {
+			return 0, err
+		}
+		for _, item := range targetItems {
+			seen[listItemKey(item)] = true
+		}
+	}
+
+	var missing []trakt.ListItem
+	for _, item := range merged {
+		key := listItemKey(item)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		missing = append(missing, item)
+	}
+
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	if err := s.client.AddItemsToList(username, target, addRequestForItems(missing)); err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// WatchlistSlug refers to the user's watchlist in list commands
+const WatchlistSlug = "watchlist"
+
+// ListComparison holds the overlap of two lists, in the order of the list
+// the items were taken from
+type ListComparison struct {
+	Both  []trakt.ListItem
+	OnlyA []trakt.ListItem
+	OnlyB []trakt.ListItem
+}
+
+// CompareLists compares two of the user's lists by type and Trakt ID.
+// WatchlistSlug can be used for either side. Items other than movies and
+// shows are ignored.
+func (s *Syncer) CompareLists(slugA, slugB string) (*ListComparison, error) {
+	itemsA, err := s.ownListItems(slugA)
+	if err != nil {
+		return nil, err
+	}
+	itemsB, err := s.ownListItems(slugB)
+	if err != nil {
+		return nil, err
+	}
+	return compareListItems(itemsA, itemsB), nil
+}
+
+func compareListItems(itemsA, itemsB []trakt.ListItem) *ListComparison {
+	inA := make(map[string]bool)
+	for _, item := range itemsA {
+		inA[listItemKey(item)] = true
+	}
+	inB := make(map[string]bool)
+	for _, item := range itemsB {
+		inB[listItemKey(item)] = true
+	}
+
+	result := &ListComparison{}
+	for _, item := range itemsA {
+		if listItemKey(item) == "" {
+			continue
+		}
+		if inB[listItemKey(item)] {
+			result.Both = append(result.Both, item)
+		} else {
+			result.OnlyA = append(result.OnlyA, item)
+		}
+	}
+	for _, item := range itemsB {
+		if key := listItemKey(item); key != "" && !inA[key] {
+			result.OnlyB = append(result.OnlyB, item)
+		}
+	}
+	return result
+}
+
+// ManagedSlugs returns the current slugs of all lists trakt-sync maintains:
+// enabled lists under their renamed slugs and child lists of sync.split
+func (s *Syncer) ManagedSlugs() map[string]bool {
+	slugs := make(map[string]bool)
+	for _, listDef := range s.GetListDefinitions() {
+		if listDef.Enabled {
+			slugs[listDef.Slug] = true
+		}
+	}
+	for _, children := range s.state.SplitLists {
+		for _, child := range children {
+			slugs[child] = true
+		}
+	}
+	return slugs
+}
+
+// ownListItems fetches the items of one of the configured user's lists or,
+// for WatchlistSlug, of the watchlist
+func (s *Syncer) ownListItems(slug string) ([]trakt.ListItem, error) {
+	if slug == WatchlistSlug {
+		watchlist, err := s.client.GetWatchlist("")
+		if err != nil {
+			return nil, err
+		}
+		items := make([]trakt.ListItem, 0, len(watchlist))
+		for _, entry := range watchlist {
+			items = append(items, trakt.ListItem{Rank: entry.Rank, ListedAt: entry.ListedAt, Type: entry.Type, Movie: entry.Movie, Show: entry.Show})
+		}
+		return items, nil
+	}
+
+	list, err := s.client.GetList(s.config.Trakt.Username, slug)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, fmt.Errorf("list %s not found", slug)
+	}
+	return s.client.GetListItems(s.config.Trakt.Username, slug)
+}
+
+// listItemKey identifies a movie or show by type and Trakt ID, since movie
+// and show IDs are separate namespaces. Other item types yield "".
+func listItemKey(item trakt.ListItem) string {
+	switch {
+	case item.Movie != nil:
+		return fmt.Sprintf("movie:%d", item.Movie.IDs.Trakt)
+	case item.Show != nil:
+		return fmt.Sprintf("show:%d", item.Show.IDs.Trakt)
+	}
+	return ""
+}
+
+// addRequestForItems builds one add request for the movies and shows of a
+// list, skipping items of other types (seasons, episodes, people)
+func addRequestForItems(items []trakt.ListItem) trakt.AddToListRequest {
+	req := trakt.AddToListRequest{}
+	for _, item := range items {
+		switch {
+		case item.Movie != nil:
+			req.Movies = append(req.Movies, trakt.AddMovie{IDs: item.Movie.IDs})
+		case item.Show != nil:
+			req.Shows = append(req.Shows, trakt.AddShow{IDs: item.Show.IDs})
+		}
+	}
+	return req
+}
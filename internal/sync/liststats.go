@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// maxListStatsSamples caps the daily samples kept per list to about a year
+const maxListStatsSamples = 400
+
+// recordListStats samples the likes and comments of a public list. Later
+// syncs on the same day update the day's sample.
+func (s *Syncer) recordListStats(slug string, list *trakt.List) {
+	if list == nil || list.Privacy != "public" {
+		return
+	}
+
+	sample := state.ListStatsSample{
+		Date:     time.Now().UTC().Format("2006-01-02"),
+		Likes:    list.Likes,
+		Comments: list.CommentCount,
+	}
+	key := s.managedSlug(slug)
+	samples := s.state.ListStats[key]
+	if n := len(samples); n > 0 && samples[n-1].Date == sample.Date {
+		if samples[n-1] == sample {
+			return
+		}
+		samples = samples[:n-1]
+	}
+	samples = append(samples, sample)
+	if len(samples) > maxListStatsSamples {
+		samples = samples[len(samples)-maxListStatsSamples:]
+	}
+
+	if s.state.ListStats == nil {
+		s.state.ListStats = make(map[string][]state.ListStatsSample)
+	}
+	s.state.ListStats[key] = samples
+	s.stateDirty = true
+}
@@ -0,0 +1,53 @@
+package sync
+
+import (
+	"github.com/maximilian/trakt-sync/internal/mdblist"
+	"github.com/rs/zerolog/log"
+)
+
+// mdbListItems returns the items of an MDBList list that Trakt knows, in list
+// order and capped at limit. MDBList items are resolved by IMDb ID, so they
+// dedupe against the chart sources by Trakt ID.
+func (s *Syncer) mdbListItems(list string, isMovie bool, limit int) ([]Item, error) {
+	client := mdblist.NewClient(mdblist.BaseURL)
+	client.SetContext(s.ctx)
+	entries, err := client.List(list)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := mdblist.TypeShow
+	if isMovie {
+		wanted = mdblist.TypeMovie
+	}
+	var items []Item
+	unresolved := 0
+	for _, entry := range entries {
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+		if entry.MediaType != "" && entry.MediaType != wanted {
+			continue
+		}
+		if entry.IMDB == "" {
+			unresolved++
+			continue
+		}
+		ids, ok, err := s.resolveIMDB(entry.IMDB, isMovie)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unresolved++
+			continue
+		}
+		if ids.TVDB == 0 {
+			ids.TVDB = entry.TVDB
+		}
+		items = append(items, Item{IDs: ids, Title: entry.Title, Year: entry.Year, Source: "MDBList " + list})
+	}
+	if unresolved > 0 {
+		log.Debug().Str("mdblist", list).Int("unresolved", unresolved).Msg("Skipped MDBList items Trakt does not know")
+	}
+	return uniqueItems(items), nil
+}
@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// mirrorListDefinitions builds the lists of sync.mirrors. Lists whose slug is
+// already taken by an earlier list are skipped.
+func (s *Syncer) mirrorListDefinitions(taken map[string]bool) []ListDefinition {
+	var lists []ListDefinition
+	for _, mirror := range s.config.Sync.Mirrors {
+		listDef := ListDefinition{
+			Slug:        config.Slugify(mirror.Name),
+			Name:        mirror.Name,
+			Description: fmt.Sprintf("Mirror of Trakt list %d", mirror.ListID),
+			Enabled:     mirror.Enabled == nil || *mirror.Enabled,
+			FetchFunc:   s.fetchMirror(mirror),
+			IsMovie:     mirror.Type == "movies",
+			Limit:       mirror.Limit,
+			Privacy:     mirror.Privacy,
+		}
+		if taken[listDef.Slug] {
+			log.Warn().Str("list", listDef.Slug).Msg("Skipping mirror whose slug is already in use")
+			continue
+		}
+		taken[listDef.Slug] = true
+		lists = append(lists, listDef)
+	}
+	return lists
+}
+
+// fetchMirror returns the movies or shows of a public list in list order.
+// The whole list is mirrored unless the mirror sets a limit.
+func (s *Syncer) fetchMirror(mirror config.MirrorConfig) func(*trakt.Client, int) ([]Item, error) {
+	return func(client *trakt.Client, limit int) ([]Item, error) {
+		listItems, err := client.GetPublicListItems(mirror.ListID)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, item := range listItems {
+			switch {
+			case mirror.Type == "movies" && item.Movie != nil:
+				items = append(items, movieItem(*item.Movie))
+			case mirror.Type == "shows" && item.Show != nil:
+				items = append(items, showItem(*item.Show))
+			}
+		}
+		items = uniqueItems(items)
+		if mirror.Limit > 0 && len(items) > mirror.Limit {
+			items = items[:mirror.Limit]
+		}
+		return items, nil
+	}
+}
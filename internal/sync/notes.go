@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// maxNoteLength is the longest note Trakt accepts for a list item
+const maxNoteLength = 500
+
+// itemNotes renders the sync.item_notes template of a list for the items
+// that are going to be added, keyed by Trakt ID. It returns nil if the list
+// has no template or the template fails.
+func (s *Syncer) itemNotes(listDef ListDefinition, fetched []Item, ids []trakt.MediaIDs, pins []trakt.MediaIDs) map[int]string {
+	var text string
+	for _, key := range s.configKeys(listDef.Slug) {
+		if text = s.config.Sync.ItemNotes[key]; text != "" {
+			break
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	tmpl, err := template.New(listDef.Slug).Option("missingkey=error").Parse(text)
+	if err != nil {
+		errcode.Log(log.Warn(), err).Str("list", listDef.Slug).Msg("Invalid item notes template, adding items without notes")
+		return nil
+	}
+
+	items := make(map[int]Item, len(fetched)+len(pins))
+	for _, pin := range pins {
+		items[pin.Trakt] = Item{IDs: pin, Source: "pin"}
+	}
+	for _, item := range fetched {
+		if _, ok := items[item.IDs.Trakt]; !ok {
+			items[item.IDs.Trakt] = item
+		}
+	}
+
+	added := time.Now().Format("2006-01-02")
+	notes := make(map[int]string, len(ids))
+	for i, id := range ids {
+		item := items[id.Trakt]
+		var note strings.Builder
+		data := config.NoteData{Source: item.Source, Rank: i + 1, Added: added, Title: item.Title, Year: item.Year, List: listDef.Name}
+		if err := tmpl.Execute(&note, data); err != nil {
+			errcode.Log(log.Warn(), err).Str("list", listDef.Slug).Msg("Failed to render item notes, adding items without notes")
+			return nil
+		}
+		notes[id.Trakt] = truncateNote(strings.TrimSpace(note.String()))
+	}
+	return notes
+}
+
+// truncateNote cuts a note to the length Trakt accepts
+func truncateNote(note string) string {
+	if utf8.RuneCountInString(note) <= maxNoteLength {
+		return note
+	}
+	runes := []rune(note)
+	return string(runes[:maxNoteLength-1]) + "…"
+}
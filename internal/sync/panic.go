@@ -0,0 +1,70 @@
+package sync
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+)
+
+// PanicError is a panic recovered while syncing a list. The list is marked as
+// failed and the run goes on with the next one.
+type PanicError struct {
+	// List is the list being synced, or "" outside of a list
+	List  string
+	Value interface{}
+	Stack []byte
+}
+
+// Recovered wraps the value of recover() with the current stack. Call it
+// from the deferred function that recovered.
+func Recovered(list string, value interface{}) *PanicError {
+	return &PanicError{List: list, Value: value, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// ErrorCode implements errcode.Coder
+func (e *PanicError) ErrorCode() errcode.Code {
+	return errcode.Panic
+}
+
+// recoverList turns a panic while syncing list into a *PanicError in *err.
+// It must be deferred directly.
+func recoverList(list string, err *error) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	panicErr := Recovered(list, value)
+	errcode.Log(log.Error(), panicErr).
+		Str("list", list).
+		Str("stack", string(panicErr.Stack)).
+		Msg("Recovered from panic while syncing list")
+	*err = panicErr
+}
+
+// syncListRecovered is SyncList with panics turned into errors
+func (s *Syncer) syncListRecovered(listDef ListDefinition) (err error) {
+	defer recoverList(listDef.Slug, &err)
+	return s.SyncList(listDef)
+}
+
+// syncSplitListRecovered is syncSplitList with a panic counted as a failure
+// of the parent list
+func (s *Syncer) syncSplitListRecovered(parent ListDefinition, split config.SplitConfig, result *SyncResult) (runErr error) {
+	var err error
+	func() {
+		defer recoverList(parent.Slug, &err)
+		runErr = s.syncSplitList(parent, split, result)
+	}()
+	if err != nil {
+		result.Total++
+		result.fail(parent.Slug, err)
+	}
+	return runErr
+}
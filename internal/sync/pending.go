@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// offline reports whether err is a network failure, after which a list's
+// writes are queued instead of dropped
+func offline(err error) bool {
+	return errcode.Of(err) == errcode.Network
+}
+
+// queueWrites keeps the writes a list sync could not send because the
+// network went down, so the next run sends them. content is the list once
+// they are applied. err is returned, noting the queue if the writes were
+// queued.
+func (s *Syncer) queueWrites(listDef ListDefinition, add, remove, content []trakt.MediaIDs, err error) error {
+	if !offline(err) {
+		return err
+	}
+
+	if s.state.Pending == nil {
+		s.state.Pending = make(map[string]state.PendingWrite)
+	}
+	s.state.Pending[listDef.Slug] = state.PendingWrite{
+		IsMovie:  listDef.IsMovie,
+		Add:      traktIDs(add),
+		Remove:   traktIDs(remove),
+		Items:    traktIDs(content),
+		QueuedAt: time.Now().UTC(),
+	}
+	s.stateDirty = true
+
+	log.Warn().
+		Str("list", listDef.Slug).
+		Int("add", len(add)).
+		Int("remove", len(remove)).
+		Msg("Network is down, queued list changes for the next run")
+	return fmt.Errorf("%w (changes queued for the next run)", err)
+}
+
+// SendPendingWrites sends the list writes queued by earlier runs. Writes
+// that still cannot be sent for lack of a network stay queued; writes Trakt
+// rejects, e.g. for a deleted list, are dropped.
+func (s *Syncer) SendPendingWrites() {
+	slugs := make([]string, 0, len(s.state.Pending))
+	for slug := range s.state.Pending {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	for _, slug := range slugs {
+		pending := s.state.Pending[slug]
+		if len(pending.Remove) > 0 {
+			if err := s.removeItems(slug, mediaIDs(pending.Remove), pending.IsMovie); err != nil {
+				if s.keepPending(slug, pending, err) {
+					return
+				}
+				continue
+			}
+			pending.Remove = nil
+		}
+		if len(pending.Add) > 0 {
+			if err := s.addItems(slug, mediaIDs(pending.Add), pending.IsMovie, nil); err != nil {
+				if s.keepPending(slug, pending, err) {
+					return
+				}
+				continue
+			}
+		}
+
+		delete(s.state.Pending, slug)
+		s.recordListWrite(slug, mediaIDs(pending.Items))
+		log.Info().
+			Str("list", slug).
+			Time("queued_at", pending.QueuedAt).
+			Msg("Sent list changes queued while offline")
+	}
+}
+
+// keepPending handles a failed queued write: while offline or canceled it
+// keeps what is left of pending and reports true, so sending stops;
+// otherwise the write is dropped.
+func (s *Syncer) keepPending(slug string, pending state.PendingWrite, err error) bool {
+	s.stateDirty = true
+	if s.aborted() {
+		s.state.Pending[slug] = pending
+		return true
+	}
+	if offline(err) {
+		s.state.Pending[slug] = pending
+		errcode.Log(log.Info(), err).Int("lists", len(s.state.Pending)).Msg("Still offline, keeping queued list changes")
+		return true
+	}
+	delete(s.state.Pending, slug)
+	errcode.Log(log.Warn(), err).Str("list", slug).Msg("Dropped queued list changes")
+	return false
+}
+
+// dropPending forgets the queued writes of a list
+func (s *Syncer) dropPending(slug string) {
+	if _, ok := s.state.Pending[slug]; ok {
+		delete(s.state.Pending, slug)
+		s.stateDirty = true
+	}
+}
+
+// PendingWrites returns the number of lists with queued writes
+func (s *Syncer) PendingWrites() int {
+	return len(s.state.Pending)
+}
+
+func traktIDs(ids []trakt.MediaIDs) []int {
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.Trakt)
+	}
+	return out
+}
+
+func mediaIDs(ids []int) []trakt.MediaIDs {
+	out := make([]trakt.MediaIDs, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, trakt.MediaIDs{Trakt: id})
+	}
+	return out
+}
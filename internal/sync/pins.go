@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// pinnedIDs returns the items sync.pins keeps in a list. IMDb IDs are resolved
+// with resolveIMDB; IDs that cannot be resolved are skipped with a warning and
+// looked up again next run.
+func (s *Syncer) pinnedIDs(listDef ListDefinition) []trakt.MediaIDs {
+	imdbIDs := s.config.Sync.Pins[s.configKeys(listDef.Slug)[0]]
+	if len(imdbIDs) == 0 {
+		return nil
+	}
+
+	mediaType := "show"
+	if listDef.IsMovie {
+		mediaType = "movie"
+	}
+
+	pins := make([]trakt.MediaIDs, 0, len(imdbIDs))
+	for _, imdbID := range imdbIDs {
+		ids, ok, err := s.resolveIMDB(imdbID, listDef.IsMovie)
+		if err != nil {
+			errcode.Log(log.Warn(), err).Str("list", listDef.Slug).Str("imdb", imdbID).Msg("Failed to resolve pinned item")
+			continue
+		}
+		if !ok {
+			log.Warn().Str("list", listDef.Slug).Str("imdb", imdbID).Str("type", mediaType).Msg("Pinned item not found on Trakt")
+			continue
+		}
+		pins = append(pins, ids)
+	}
+	return uniqueIDs(pins)
+}
+
+// applyPins puts pinned items at the top of the items a list should contain
+func applyPins(newItems, pins []trakt.MediaIDs) []trakt.MediaIDs {
+	if len(pins) == 0 {
+		return newItems
+	}
+	return uniqueIDs(append(append([]trakt.MediaIDs(nil), pins...), newItems...))
+}
+
+// withoutPins drops pinned items from items to remove
+func withoutPins(toRemove, pins []trakt.MediaIDs) []trakt.MediaIDs {
+	if len(pins) == 0 {
+		return toRemove
+	}
+	pinned := make(map[int]bool, len(pins))
+	for _, ids := range pins {
+		pinned[ids.Trakt] = true
+	}
+	kept := toRemove[:0]
+	for _, ids := range toRemove {
+		if !pinned[ids.Trakt] {
+			kept = append(kept, ids)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/state"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// RenameList renames a managed list on Trakt and records its new name and
+// slug in the state, so later syncs keep updating the same list instead of
+// creating a new one under the old name. It returns the new slug.
+func (s *Syncer) RenameList(slug, newName string) (string, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return "", fmt.Errorf("new list name must not be empty")
+	}
+
+	managed := s.managedSlug(slug)
+	var listDef *ListDefinition
+	for _, def := range s.allListDefinitions() {
+		if def.Slug == managed {
+			def := def
+			listDef = &def
+			break
+		}
+	}
+	if listDef == nil {
+		for parent, children := range s.state.SplitLists {
+			for _, child := range children {
+				if child == slug {
+					return "", fmt.Errorf("%s was created by sync.split; change sync.split.%s.name_template instead", slug, parent)
+				}
+			}
+		}
+		return "", fmt.Errorf("%s is not a list managed by trakt-sync", slug)
+	}
+
+	currentSlug := managed
+	if rename, ok := s.state.Renames[managed]; ok {
+		currentSlug = rename.Slug
+	}
+
+	wantSlug := config.Slugify(newName)
+	if wantSlug == "" {
+		return "", fmt.Errorf("list name %q yields an empty slug", newName)
+	}
+	for _, def := range s.GetListDefinitions() {
+		if def.Slug == wantSlug && s.managedSlug(def.Slug) != managed {
+			return "", fmt.Errorf("another managed list already uses the slug %s", wantSlug)
+		}
+	}
+
+	list, err := s.client.UpdateList(s.config.Trakt.Username, currentSlug, trakt.UpdateListRequest{Name: newName})
+	if err != nil {
+		return "", err
+	}
+
+	newSlug := list.IDs.Slug
+	if newSlug == "" {
+		newSlug = wantSlug
+	}
+
+	if s.state.Renames == nil {
+		s.state.Renames = make(map[string]state.ListRename)
+	}
+	if newSlug == managed && newName == listDef.Name {
+		// Back to the built-in name, no mapping needed
+		delete(s.state.Renames, managed)
+	} else {
+		s.state.Renames[managed] = state.ListRename{Slug: newSlug, Name: newName}
+	}
+	s.stateDirty = true
+
+	return newSlug, nil
+}
@@ -0,0 +1,64 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// reorderItems ranks the items of a list in the order of wanted, the list
+// content in source order, for sync.reorder. Items that are not wanted, such
+// as ones kept by sync.min_items, follow in their current order. current is
+// the list before this run's writes; wrote reports whether items were added
+// or removed since, in which case the list is fetched again for the IDs of
+// the new items. It reports whether the list was reordered.
+func (s *Syncer) reorderItems(slug string, wanted []trakt.MediaIDs, current []trakt.ListItem, wrote bool) (bool, error) {
+	if !s.config.Sync.Reorder {
+		return false, nil
+	}
+
+	items := current
+	if wrote {
+		var err error
+		if items, err = s.client.GetListItems(s.config.Trakt.Username, slug); err != nil {
+			return false, fmt.Errorf("failed to get list items: %w", err)
+		}
+	}
+	items = append([]trakt.ListItem(nil), items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Rank < items[j].Rank })
+
+	position := make(map[int]int, len(wanted))
+	for i, ids := range wanted {
+		if _, ok := position[ids.Trakt]; !ok {
+			position[ids.Trakt] = i
+		}
+	}
+	rank := func(item trakt.ListItem) int {
+		if i, ok := position[itemMediaIDs(item).Trakt]; ok {
+			return i
+		}
+		return len(wanted)
+	}
+	ordered := append([]trakt.ListItem(nil), items...)
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+
+	changed := false
+	ids := make([]int64, 0, len(ordered))
+	for i, item := range ordered {
+		if item.ID != items[i].ID {
+			changed = true
+		}
+		ids = append(ids, item.ID)
+	}
+	if !changed {
+		return false, nil
+	}
+
+	log.Info().Str("list", slug).Int("items", len(ids)).Msg("Reordering list items")
+	if err := s.client.ReorderListItems(s.config.Trakt.Username, slug, ids); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ResolvedList is the item set a list resolves to before it is written
+type ResolvedList struct {
+	Slug    string
+	Name    string
+	IsMovie bool
+	Items   []Item
+}
+
+// ResolveLists fetches the items of every enabled list without touching the
+// Trakt lists themselves, for targets that mirror what a sync would write
+func (s *Syncer) ResolveLists() ([]ResolvedList, error) {
+	startTime := time.Now()
+	s.observed = make(map[string]bool)
+	s.sourceCache = make(map[string][]sourceResult)
+	defer func() { s.sourceCache = nil }()
+
+	var resolved []ResolvedList
+	for _, listDef := range s.GetListDefinitions() {
+		if !listDef.Enabled {
+			continue
+		}
+
+		limit := s.config.Sync.Limit
+		if listDef.Limit > 0 {
+			limit = listDef.Limit
+		}
+		items, err := listDef.FetchFunc(s.client, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch items of %s: %w", listDef.Slug, err)
+		}
+		log.Info().Str("list", listDef.Slug).Int("count", len(items)).Msg("Resolved list items")
+		resolved = append(resolved, ResolvedList{Slug: listDef.Slug, Name: listDef.Name, IsMovie: listDef.IsMovie, Items: uniqueItems(items)})
+	}
+
+	log.Debug().Int("lists", len(resolved)).Dur("duration", time.Since(startTime)).Msg("Resolved lists")
+	return resolved, nil
+}
+
+// PreviewLists returns the content a sync would write to every enabled list:
+// the source items without the excluded own titles, with the pins on top.
+// It only reads from Trakt. Edits made on Trakt and the re-add cooldown
+// depend on the list as it is and are left out.
+func (s *Syncer) PreviewLists() ([]SyncedList, error) {
+	s.observed = make(map[string]bool)
+	s.sourceCache = make(map[string][]sourceResult)
+	defer func() { s.sourceCache = nil }()
+
+	var previews []SyncedList
+	for _, listDef := range s.GetListDefinitions() {
+		if !listDef.Enabled {
+			continue
+		}
+		_, ids, err := s.fetchContent(listDef)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", listDef.Slug, err)
+		}
+		ids = applyPins(ids, s.pinnedIDs(listDef))
+		log.Info().Str("list", listDef.Slug).Int("count", len(ids)).Msg("Previewed list items")
+		previews = append(previews, SyncedList{Slug: listDef.Slug, Name: listDef.Name, IsMovie: listDef.IsMovie, IDs: ids})
+	}
+	return previews, nil
+}
@@ -0,0 +1,518 @@
+package sync
+
+import (
+	"sort"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// Item is a list candidate returned by a source, carrying the metadata used
+// for filtering and fan-out
+type Item struct {
+	IDs           trakt.MediaIDs
+	Title         string
+	Year          int
+	Genres        []string
+	Certification string
+	// Source names where the item came from, e.g. "trending", for item notes
+	Source string
+}
+
+func movieItem(m trakt.Movie) Item {
+	return Item{IDs: m.IDs, Title: m.Title, Year: m.Year, Genres: m.Genres, Certification: m.Certification}
+}
+
+func showItem(sh trakt.Show) Item {
+	return Item{IDs: sh.IDs, Title: sh.Title, Year: sh.Year, Genres: sh.Genres, Certification: sh.Certification}
+}
+
+func itemIDs(items []Item) []trakt.MediaIDs {
+	ids := make([]trakt.MediaIDs, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.IDs)
+	}
+	return ids
+}
+
+func uniqueItems(items []Item) []Item {
+	seen := make(map[int]struct{}, len(items))
+	unique := make([]Item, 0, len(items))
+	for _, item := range items {
+		if _, ok := seen[item.IDs.Trakt]; ok {
+			continue
+		}
+		seen[item.IDs.Trakt] = struct{}{}
+		unique = append(unique, item)
+	}
+	return unique
+}
+
+// trendingMovieItems returns all items of the trending chart and those with
+// at least minWatchers people watching
+func trendingMovieItems(movies []trakt.TrendingMovie, minWatchers int) (items, qualified []Item) {
+	for _, m := range movies {
+		items = append(items, movieItem(m.Movie))
+		if m.Watchers >= minWatchers {
+			qualified = append(qualified, movieItem(m.Movie))
+		}
+	}
+	return items, qualified
+}
+
+// trendingShowItems is trendingMovieItems for shows
+func trendingShowItems(shows []trakt.TrendingShow, minWatchers int) (items, qualified []Item) {
+	for _, sh := range shows {
+		items = append(items, showItem(sh.Show))
+		if sh.Watchers >= minWatchers {
+			qualified = append(qualified, showItem(sh.Show))
+		}
+	}
+	return items, qualified
+}
+
+// watchedMovieItems returns all items of the most watched chart and those
+// meeting the play and watcher thresholds
+func watchedMovieItems(movies []trakt.WatchedMovie, threshold config.WatchedSourceConfig) (items, qualified []Item) {
+	for _, m := range movies {
+		items = append(items, movieItem(m.Movie))
+		if m.PlayCount >= threshold.MinPlays && m.WatcherCount >= threshold.MinWatcherCount {
+			qualified = append(qualified, movieItem(m.Movie))
+		}
+	}
+	return items, qualified
+}
+
+// watchedShowItems is watchedMovieItems for shows
+func watchedShowItems(shows []trakt.WatchedShow, threshold config.WatchedSourceConfig) (items, qualified []Item) {
+	for _, sh := range shows {
+		items = append(items, showItem(sh.Show))
+		if sh.PlayCount >= threshold.MinPlays && sh.WatcherCount >= threshold.MinWatcherCount {
+			qualified = append(qualified, showItem(sh.Show))
+		}
+	}
+	return items, qualified
+}
+
+// logThreshold reports items a source threshold dropped
+func logThreshold(source, threshold string, value, dropped int) {
+	if dropped == 0 {
+		return
+	}
+	log.Debug().
+		Str("source", source).
+		Int(threshold, value).
+		Int("dropped", dropped).
+		Msg("Dropped items below source threshold")
+}
+
+func logWatchedThreshold(source string, threshold config.WatchedSourceConfig, dropped int) {
+	if dropped == 0 {
+		return
+	}
+	log.Debug().
+		Str("source", source).
+		Int("min_plays", threshold.MinPlays).
+		Int("min_watcher_count", threshold.MinWatcherCount).
+		Int("dropped", dropped).
+		Msg("Dropped items below source threshold")
+}
+
+// chartOptions returns the chart query for the configured filters
+func (s *Syncer) chartOptions(limit int) trakt.ChartOptions {
+	return trakt.ChartOptions{
+		Limit:     limit,
+		Period:    s.config.Sync.WatchedPeriod,
+		MinRating: s.config.Sync.MinRating,
+		MinYear:   s.config.Sync.EarliestYear(time.Now()),
+		Languages: s.config.Sync.Languages,
+		Countries: s.config.Sync.Countries,
+		Extended:  s.needsExtendedInfo(),
+	}
+}
+
+// Fetch functions for different list types
+func (s *Syncer) fetchCombinedMovies(client *trakt.Client, limit int) ([]Item, error) {
+	trending, err := s.fetchTrendingMovies(client, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	streaming, err := s.fetchStreamingMovies(client, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := append(trending, streaming...)
+
+	if s.config.Sync.BoxOffice {
+		boxOffice, err := s.boxOfficeMovies(client, s.chartOptions(boxOfficeSize))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, boxOffice...)
+	}
+
+	return uniqueItems(items), nil
+}
+
+func (s *Syncer) fetchCombinedShows(client *trakt.Client, limit int) ([]Item, error) {
+	trending, err := s.fetchTrendingShows(client, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	streaming, err := s.fetchStreamingShows(client, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return uniqueItems(append(trending, streaming...)), nil
+}
+
+func (s *Syncer) fetchTrendingMovies(client *trakt.Client, limit int) ([]Item, error) {
+	return s.trendingMovies(client, s.chartOptions(limit))
+}
+
+func (s *Syncer) trendingMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/trending", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		movies, err := client.GetTrendingMovies(opts)
+		if err != nil {
+			return nil, err
+		}
+		s.archiveChart("movies/trending", opts, movies)
+
+		minWatchers := s.config.Sync.Sources.Movies.Trending.MinWatchers
+		items, qualified := trendingMovieItems(movies, minWatchers)
+		s.observeSource("movies/trending", items)
+		logThreshold("movies/trending", "min_watchers", minWatchers, len(items)-len(qualified))
+		return qualified, nil
+	})
+}
+
+func (s *Syncer) fetchTrendingShows(client *trakt.Client, limit int) ([]Item, error) {
+	return s.trendingShows(client, s.chartOptions(limit))
+}
+
+func (s *Syncer) trendingShows(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("shows/trending", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		shows, err := client.GetTrendingShows(opts)
+		if err != nil {
+			return nil, err
+		}
+		s.archiveChart("shows/trending", opts, shows)
+
+		minWatchers := s.config.Sync.Sources.Shows.Trending.MinWatchers
+		items, qualified := trendingShowItems(shows, minWatchers)
+		s.observeSource("shows/trending", items)
+		logThreshold("shows/trending", "min_watchers", minWatchers, len(items)-len(qualified))
+		return qualified, nil
+	})
+}
+
+func (s *Syncer) fetchPopularMovies(client *trakt.Client, limit int) ([]Item, error) {
+	return s.popularMovies(client, s.chartOptions(limit))
+}
+
+func (s *Syncer) popularMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/popular", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		movies, err := client.GetPopularMovies(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, m := range movies {
+			items = append(items, movieItem(m))
+		}
+		s.observeSource("movies/popular", items)
+		return items, nil
+	})
+}
+
+func (s *Syncer) fetchPopularShows(client *trakt.Client, limit int) ([]Item, error) {
+	return s.popularShows(client, s.chartOptions(limit))
+}
+
+func (s *Syncer) popularShows(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("shows/popular", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		shows, err := client.GetPopularShows(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, sh := range shows {
+			items = append(items, showItem(sh))
+		}
+		s.observeSource("shows/popular", items)
+		return items, nil
+	})
+}
+
+func (s *Syncer) fetchStreamingMovies(client *trakt.Client, limit int) ([]Item, error) {
+	return s.streamingMovies(client, s.chartOptions(limit))
+}
+
+func (s *Syncer) streamingMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/watched", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		movies, err := client.GetMostWatchedMovies(opts)
+		if err != nil {
+			return nil, err
+		}
+		s.archiveChart("movies/watched", opts, movies)
+
+		threshold := s.config.Sync.Sources.Movies.Watched
+		items, qualified := watchedMovieItems(movies, threshold)
+		s.observeSource("movies/watched", items)
+		logWatchedThreshold("movies/watched", threshold, len(items)-len(qualified))
+		return qualified, nil
+	})
+}
+
+func (s *Syncer) fetchStreamingShows(client *trakt.Client, limit int) ([]Item, error) {
+	return s.streamingShows(client, s.chartOptions(limit))
+}
+
+func (s *Syncer) streamingShows(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("shows/watched", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		shows, err := client.GetMostWatchedShows(opts)
+		if err != nil {
+			return nil, err
+		}
+		s.archiveChart("shows/watched", opts, shows)
+
+		threshold := s.config.Sync.Sources.Shows.Watched
+		items, qualified := watchedShowItems(shows, threshold)
+		s.observeSource("shows/watched", items)
+		logWatchedThreshold("shows/watched", threshold, len(items)-len(qualified))
+		return qualified, nil
+	})
+}
+
+// boxOfficeSize is the number of movies in the box office chart
+const boxOfficeSize = 10
+
+// boxOfficeMovies returns last weekend's top 10 box office movies. The chart
+// takes no filters, so sync.min_rating is applied here.
+func (s *Syncer) boxOfficeMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/boxoffice", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		opts.Extended = true
+		movies, err := client.GetBoxOfficeMovies(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, m := range movies {
+			if m.Movie.Rating*10 < float64(opts.MinRating) {
+				continue
+			}
+			items = append(items, movieItem(m.Movie))
+		}
+		return items, nil
+	})
+}
+
+func (s *Syncer) anticipatedMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/anticipated", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		movies, err := client.GetAnticipatedMovies(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, m := range movies {
+			items = append(items, movieItem(m.Movie))
+		}
+		s.observeSource("movies/anticipated", items)
+		return items, nil
+	})
+}
+
+func (s *Syncer) anticipatedShows(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("shows/anticipated", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		shows, err := client.GetAnticipatedShows(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, sh := range shows {
+			items = append(items, showItem(sh.Show))
+		}
+		s.observeSource("shows/anticipated", items)
+		return items, nil
+	})
+}
+
+// recommendedMovies returns the movies Trakt recommends to the user. The
+// recommendations take no chart filters; only the earliest year applies.
+func (s *Syncer) recommendedMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/recommended", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		movies, err := client.GetRecommendedMovies(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, m := range movies {
+			items = append(items, movieItem(m))
+		}
+		return items, nil
+	})
+}
+
+// recommendedShows is recommendedMovies for shows
+func (s *Syncer) recommendedShows(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("shows/recommended", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		shows, err := client.GetRecommendedShows(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, sh := range shows {
+			items = append(items, showItem(sh))
+		}
+		return items, nil
+	})
+}
+
+// premiereDays is how far ahead the premieres source looks
+const premiereDays = 30
+
+// premiereMovies returns movies released in the next premiereDays days,
+// soonest first
+func (s *Syncer) premiereMovies(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("movies/premieres", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		movies, err := client.GetMovieReleases(time.Now(), premiereDays, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, m := range movies {
+			items = append(items, movieItem(m.Movie))
+		}
+		items = uniqueItems(items)
+		if len(items) > opts.Limit {
+			items = items[:opts.Limit]
+		}
+		return items, nil
+	})
+}
+
+// premiereShows returns shows premiering in the next premiereDays days,
+// soonest first
+func (s *Syncer) premiereShows(client *trakt.Client, opts trakt.ChartOptions) ([]Item, error) {
+	return s.fetchChart("shows/premieres", opts, func(opts trakt.ChartOptions) ([]Item, error) {
+		shows, err := client.GetShowPremieres(time.Now(), premiereDays, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, sh := range shows {
+			items = append(items, showItem(sh.Show))
+		}
+		items = uniqueItems(items)
+		if len(items) > opts.Limit {
+			items = items[:opts.Limit]
+		}
+		return items, nil
+	})
+}
+
+func (s *Syncer) fetchRatings(client *trakt.Client, limit int) ([]Item, error) {
+	ratings := s.config.Sync.RatingsList
+
+	rated, err := client.GetUserRatings(s.config.Trakt.Username, ratings.Type, ratings.MinRating)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterRatedItems(rated, ratings.MinRating, ratings.Year, limit), nil
+}
+
+// filterRatedItems keeps items rated at least minRating (and in year, if set),
+// highest rated and most recently rated first
+func filterRatedItems(rated []trakt.RatedItem, minRating, year, limit int) []Item {
+	filtered := make([]trakt.RatedItem, 0, len(rated))
+	for _, item := range rated {
+		if item.Rating < minRating {
+			continue
+		}
+		if year > 0 && item.RatedAt.Year() != year {
+			continue
+		}
+		if item.Movie == nil && item.Show == nil {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].Rating != filtered[j].Rating {
+			return filtered[i].Rating > filtered[j].Rating
+		}
+		return filtered[i].RatedAt.After(filtered[j].RatedAt)
+	})
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+
+	items := make([]Item, 0, len(filtered))
+	for _, item := range filtered {
+		if item.Movie != nil {
+			items = append(items, movieItem(*item.Movie))
+		} else {
+			items = append(items, showItem(*item.Show))
+		}
+	}
+	return items
+}
+
+func (s *Syncer) fetchRecentlyWatched(client *trakt.Client, limit int) ([]Item, error) {
+	recent := s.config.Sync.RecentlyWatched
+
+	var since time.Time
+	if recent.Days > 0 {
+		since = time.Now().AddDate(0, 0, -recent.Days)
+	}
+
+	// Shows appear once per watched episode, so fetch more plays than needed.
+	maxPlays := limit
+	if recent.Type == "shows" {
+		maxPlays = limit * 10
+	}
+
+	history, err := client.GetRecentHistory(recent.Type, since, maxPlays)
+	if err != nil {
+		return nil, err
+	}
+
+	return recentlyWatchedItems(history, limit), nil
+}
+
+// recentlyWatchedItems returns distinct movies/shows in history order (most
+// recent first), capped at limit
+func recentlyWatchedItems(history []trakt.HistoryItem, limit int) []Item {
+	var items []Item
+	for _, entry := range history {
+		switch {
+		case entry.Movie != nil:
+			items = append(items, movieItem(*entry.Movie))
+		case entry.Show != nil:
+			items = append(items, showItem(*entry.Show))
+		}
+	}
+
+	items = uniqueItems(items)
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,237 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// splitGroup is the set of items for one child list of a fan-out
+type splitGroup struct {
+	Key   string
+	Title string
+	Items []Item
+}
+
+const defaultSplitNameTemplate = "{{.Name}} {{.Group}}"
+
+// splitNameData is passed to split name templates
+type splitNameData struct {
+	Name  string
+	Group string
+	Key   string
+}
+
+// needsExtendedInfo reports whether sources must request extended metadata
+func (s *Syncer) needsExtendedInfo() bool {
+	for _, split := range s.config.Sync.Split {
+		if split.By == "genre" {
+			return true
+		}
+	}
+	return false
+}
+
+// syncSplitList fetches the parent's items once, syncs one child list per
+// group and deletes child lists from earlier runs that no longer have items.
+// It returns ErrCanceled if the run stopped before all groups were synced and
+// ErrUnavailable if Trakt is down, like SyncAll does for other lists.
+func (s *Syncer) syncSplitList(parent ListDefinition, split config.SplitConfig, result *SyncResult) error {
+	limit := s.config.Sync.Limit
+	if parent.Limit > 0 {
+		limit = parent.Limit
+	}
+
+	items, err := parent.FetchFunc(s.client, limit)
+	if err != nil {
+		if traktUnavailable(err) {
+			errcode.Log(log.Info(), err).Str("list", parent.Slug).Msg("Trakt is down for maintenance, skipping the rest of the run")
+			return ErrUnavailable
+		}
+		errcode.Log(log.Error(), err).Str("list", parent.Slug).Msg("Failed to fetch items for split list")
+		result.Total++
+		result.fail(parent.Slug, fmt.Errorf("failed to fetch items: %w", err))
+		return nil
+	}
+
+	skipRemovals := s.belowMinItems(parent.Slug, len(items))
+	groups := groupItems(split, items)
+	nameTemplate, err := parseSplitNameTemplate(split.NameTemplate)
+	if err != nil {
+		errcode.Log(log.Error(), err).Str("list", parent.Slug).Msg("Invalid split name template")
+		result.Total++
+		result.fail(parent.Slug, err)
+		return nil
+	}
+
+	log.Info().
+		Str("list", parent.Slug).
+		Str("split_by", split.By).
+		Int("items", len(items)).
+		Int("groups", len(groups)).
+		Msg("Split list into groups")
+
+	current := make(map[string]bool, len(groups))
+	var slugs []string
+	for _, group := range groups {
+		child, err := splitChildDefinition(parent, group, nameTemplate)
+		if err != nil {
+			errcode.Log(log.Error(), err).Str("list", parent.Slug).Str("group", group.Key).Msg("Failed to name split list")
+			result.Total++
+			result.fail(parent.Slug, err)
+			continue
+		}
+		child.skipRemovals = skipRemovals
+		current[child.Slug] = true
+		slugs = append(slugs, child.Slug)
+
+		if err := s.syncListRecovered(child); err != nil {
+			// The list was interrupted, not broken, and the lists of the
+			// groups not synced yet must not be deleted as stale.
+			if errors.Is(err, ErrCanceled) || s.aborted() {
+				log.Info().Str("list", child.Slug).Msg("Sync canceled")
+				return ErrCanceled
+			}
+			// Every other group would fail the same way.
+			if traktUnavailable(err) {
+				errcode.Log(log.Info(), err).Str("list", child.Slug).Msg("Trakt is down for maintenance, skipping the rest of the run")
+				return ErrUnavailable
+			}
+			result.Total++
+			errcode.Log(log.Error(), err).Str("list", child.Slug).Msg("Failed to sync list")
+			result.fail(child.Slug, err)
+			continue
+		}
+		result.Total++
+		result.Successful++
+	}
+
+	stateKey := s.managedSlug(parent.Slug)
+	for _, slug := range s.state.SplitLists[stateKey] {
+		if current[slug] {
+			continue
+		}
+		if skipRemovals {
+			slugs = append(slugs, slug)
+			continue
+		}
+		if err := s.client.DeleteList(s.config.Trakt.Username, slug); err != nil {
+			errcode.Log(log.Warn(), err).Str("list", slug).Msg("Failed to delete empty split list")
+			slugs = append(slugs, slug)
+			continue
+		}
+		if s.config.Sync.LastFullRefresh.Lists != nil {
+			delete(s.config.Sync.LastFullRefresh.Lists, slug)
+			s.configDirty = true
+		}
+	}
+
+	sort.Strings(slugs)
+	if s.state.SplitLists == nil {
+		s.state.SplitLists = make(map[string][]string)
+	}
+	s.state.SplitLists[stateKey] = slugs
+	s.stateDirty = true
+	return nil
+}
+
+func parseSplitNameTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = defaultSplitNameTemplate
+	}
+	return template.New("split").Option("missingkey=error").Parse(text)
+}
+
+// splitChildDefinition builds the list for one group. Trakt derives list slugs
+// from names, so the child slug is the slugified rendered name.
+func splitChildDefinition(parent ListDefinition, group splitGroup, nameTemplate *template.Template) (ListDefinition, error) {
+	var name strings.Builder
+	if err := nameTemplate.Execute(&name, splitNameData{Name: parent.Name, Group: group.Title, Key: group.Key}); err != nil {
+		return ListDefinition{}, err
+	}
+
+	slug := config.Slugify(name.String())
+	if slug == "" {
+		return ListDefinition{}, fmt.Errorf("split list name %q yields an empty slug", name.String())
+	}
+
+	items := group.Items
+	return ListDefinition{
+		Slug:        slug,
+		Name:        strings.TrimSpace(name.String()),
+		Description: fmt.Sprintf("%s (%s)", parent.Description, group.Title),
+		Enabled:     true,
+		IsMovie:     parent.IsMovie,
+		Privacy:     parent.Privacy,
+		splitParent: parent.Slug,
+		personal:    parent.personal,
+		FetchFunc: func(_ *trakt.Client, _ int) ([]Item, error) {
+			return items, nil
+		},
+	}, nil
+}
+
+// groupItems groups items according to split, ordered by group key
+func groupItems(split config.SplitConfig, items []Item) []splitGroup {
+	allowed := make(map[string]bool, len(split.Genres))
+	for _, genre := range split.Genres {
+		allowed[strings.ToLower(strings.TrimSpace(genre))] = true
+	}
+
+	byKey := make(map[string][]Item)
+	titles := make(map[string]string)
+	for _, item := range items {
+		switch split.By {
+		case "decade", "year":
+			if item.Year <= 0 {
+				continue
+			}
+			year := item.Year
+			if split.By == "decade" {
+				year -= year % 10
+			}
+			key := strconv.Itoa(year)
+			if split.By == "decade" {
+				key += "s"
+			}
+			byKey[key] = append(byKey[key], item)
+			titles[key] = key
+		default:
+			for _, genre := range item.Genres {
+				key := strings.ToLower(genre)
+				if len(allowed) > 0 && !allowed[key] {
+					continue
+				}
+				byKey[key] = append(byKey[key], item)
+				titles[key] = splitKeyTitle(key)
+			}
+		}
+	}
+
+	groups := make([]splitGroup, 0, len(byKey))
+	for key, groupItems := range byKey {
+		groups = append(groups, splitGroup{Key: key, Title: titles[key], Items: groupItems})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// splitKeyTitle turns a slug like "science-fiction" into "Science Fiction"
+func splitKeyTitle(key string) string {
+	words := strings.Split(key, "-")
+	for i, word := range words {
+		if word == "" {
+			continue
+		}
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+func TestGroupItemsByGenre(t *testing.T) {
+	items := []Item{
+		{IDs: trakt.MediaIDs{Trakt: 1}, Genres: []string{"horror", "comedy"}},
+		{IDs: trakt.MediaIDs{Trakt: 2}, Genres: []string{"comedy"}},
+		{IDs: trakt.MediaIDs{Trakt: 3}, Genres: []string{"science-fiction"}},
+		{IDs: trakt.MediaIDs{Trakt: 4}},
+	}
+
+	groups := groupItems(config.SplitConfig{By: "genre"}, items)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+
+	if groups[0].Key != "comedy" || groups[1].Key != "horror" || groups[2].Key != "science-fiction" {
+		t.Fatalf("unexpected group order: %+v", groups)
+	}
+	assertIDs(t, itemIDs(groups[0].Items), []int{1, 2})
+	if groups[2].Title != "Science Fiction" {
+		t.Fatalf("expected title 'Science Fiction', got %q", groups[2].Title)
+	}
+
+	groups = groupItems(config.SplitConfig{By: "genre", Genres: []string{"Horror"}}, items)
+	if len(groups) != 1 || groups[0].Key != "horror" {
+		t.Fatalf("expected only horror group, got %+v", groups)
+	}
+}
+
+func TestGroupItemsByDecade(t *testing.T) {
+	items := []Item{
+		{IDs: trakt.MediaIDs{Trakt: 1}, Year: 1994},
+		{IDs: trakt.MediaIDs{Trakt: 2}, Year: 1999},
+		{IDs: trakt.MediaIDs{Trakt: 3}, Year: 2001},
+		{IDs: trakt.MediaIDs{Trakt: 4}},
+	}
+
+	groups := groupItems(config.SplitConfig{By: "decade"}, items)
+	if len(groups) != 2 || groups[0].Key != "1990s" || groups[1].Key != "2000s" {
+		t.Fatalf("unexpected decade groups: %+v", groups)
+	}
+	assertIDs(t, itemIDs(groups[0].Items), []int{1, 2})
+
+	groups = groupItems(config.SplitConfig{By: "year"}, items)
+	if len(groups) != 3 || groups[0].Key != "1994" {
+		t.Fatalf("unexpected year groups: %+v", groups)
+	}
+}
+
+func TestSplitChildDefinitionTemplate(t *testing.T) {
+	parent := ListDefinition{Slug: MoviesListSlug, Name: "Trakt Sync Filme", IsMovie: true}
+	group := splitGroup{Key: "1990s", Title: "1990s"}
+
+	tmpl, err := parseSplitNameTemplate("Most watched {{.Group}} movies")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	child, err := splitChildDefinition(parent, group, tmpl)
+	if err != nil {
+		t.Fatalf("definition failed: %v", err)
+	}
+	if child.Name != "Most watched 1990s movies" || child.Slug != "most-watched-1990s-movies" {
+		t.Fatalf("unexpected child list %q (%s)", child.Name, child.Slug)
+	}
+
+	tmpl, _ = parseSplitNameTemplate("")
+	child, _ = splitChildDefinition(parent, splitGroup{Key: "science-fiction", Title: "Science Fiction"}, tmpl)
+	if child.Slug != "trakt-sync-filme-science-fiction" {
+		t.Fatalf("expected default slug, got %s", child.Slug)
+	}
+
+	// Trakt slugs are ASCII, with accents dropped.
+	child, _ = splitChildDefinition(parent, splitGroup{Key: "comedy", Title: "Komödie & Spaß"}, tmpl)
+	if child.Slug != "trakt-sync-filme-komodie-spass" {
+		t.Fatalf("expected an ASCII slug, got %s", child.Slug)
+	}
+}
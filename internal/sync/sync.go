@@ -1,16 +1,38 @@
 package sync
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/maximilian/trakt-sync/internal/archive"
 	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/state"
 	"github.com/maximilian/trakt-sync/internal/trakt"
 	"github.com/rs/zerolog/log"
 )
 
-var ErrAllFailed = errors.New("all lists failed to sync")
+var ErrAllFailed error = errcode.New(errcode.AllListsFailed, "all lists failed to sync")
+
+// ErrUnavailable ends a run early while Trakt is down for maintenance or
+// behind a Cloudflare challenge
+var ErrUnavailable error = errcode.New(errcode.Unavailable, "Trakt is unavailable, run skipped")
+
+// ErrCanceled ends a run whose context was canceled, e.g. by Ctrl+C
+var ErrCanceled error = errcode.New(errcode.Canceled, "sync canceled")
+
+// Slugs of the built-in lists
+const (
+	MoviesListSlug  = "trakt-sync-filme"
+	ShowsListSlug   = "trakt-sync-serien"
+	RatingsListSlug = "trakt-sync-bewertungen"
+	RecentListSlug  = "trakt-sync-zuletzt-gesehen"
+	AnimeListSlug   = "trakt-sync-anime"
+)
 
 // ListDefinition defines a list to sync
 type ListDefinition struct {
@@ -18,8 +40,20 @@ type ListDefinition struct {
 	Name        string
 	Description string
 	Enabled     bool
-	FetchFunc   func(*trakt.Client, int) ([]trakt.MediaIDs, error)
+	FetchFunc   func(*trakt.Client, int) ([]Item, error)
 	IsMovie     bool
+	// Limit overrides sync.limit for this list when greater than 0
+	Limit int
+	// Privacy overrides sync.list_privacy for this list when set
+	Privacy string
+
+	// splitParent is the slug of the list this one was fanned out from
+	splitParent string
+	// skipRemovals keeps existing items, e.g. when the source looked incomplete
+	skipRemovals bool
+	// personal lists hold the user's own titles, e.g. ratings, and are exempt
+	// from watchlist.exclude_watched and sync.exclude_collected
+	personal bool
 }
 
 // SyncResult captures the summary of a sync run
@@ -28,33 +62,213 @@ type SyncResult struct {
 	Failed     int
 	Total      int
 	Duration   time.Duration
+	// Failures are the errors of the failed lists, in sync order
+	Failures []Failure
+	// Pending counts the lists with writes queued for the next run because
+	// the network was down
+	Pending int
+}
+
+// Failure is why a list failed to sync
+type Failure struct {
+	List string
+	Err  error
+}
+
+// fail counts a failed list and records its error
+func (r *SyncResult) fail(list string, err error) {
+	r.Failed++
+	r.Failures = append(r.Failures, Failure{List: list, Err: err})
 }
 
 // Syncer handles syncing lists
 type Syncer struct {
+	// ctx aborts the request in flight, stop ends a run after the list at
+	// hand
+	ctx         context.Context
+	stop        context.Context
 	client      *trakt.Client
 	config      *config.Config
 	configDirty bool
+	state       *state.State
+	stateDirty  bool
+	listFilter  map[string]bool
+	observed    map[string]bool
+	// sourceCache shares chart results between lists during SyncAll
+	sourceCache map[string][]sourceResult
+	// animeMapping is the sync.anime.mapping_url index, fetched once
+	animeMapping *animeMapping
+	// suffix redirects all lists to sandbox copies, e.g. "-test"
+	suffix string
+	// localizer translates titles in log output into trakt.language
+	localizer *trakt.Localizer
+	// snapshots are the chart responses archived in this run
+	snapshots []archive.Snapshot
+	// synced are the lists written in this run, for sync targets
+	synced []SyncedList
+	// watched and collected are the user's titles, fetched once per run when
+	// needed
+	watched   *titleSet
+	collected *titleSet
+}
+
+// SyncedList is the content of a Trakt list after a sync wrote it
+type SyncedList struct {
+	Slug    string
+	Name    string
+	IsMovie bool
+	IDs     []trakt.MediaIDs
 }
 
 // NewSyncer creates a new syncer
 func NewSyncer(client *trakt.Client, cfg *config.Config) *Syncer {
 	return &Syncer{
-		client: client,
-		config: cfg,
+		ctx:       context.Background(),
+		stop:      context.Background(),
+		client:    client,
+		config:    cfg,
+		state:     &state.State{},
+		localizer: trakt.NewLocalizer(client, cfg.Trakt.Language),
 	}
 }
 
+// SetContext makes the syncer stop between lists once ctx is done. ctx is
+// passed on to the Trakt client and the IMDb, MDBList, translation and anime
+// mapping requests, which abort the request in flight.
+func (s *Syncer) SetContext(ctx context.Context) {
+	s.ctx = ctx
+	if s.client != nil {
+		s.client.SetContext(ctx)
+	}
+}
+
+// SetStopContext makes the syncer finish the list it is writing and skip the
+// rest once ctx is done, so no list is left half-written. A list that is
+// still being fetched is skipped as well.
+func (s *Syncer) SetStopContext(ctx context.Context) {
+	s.stop = ctx
+}
+
+// aborted reports whether the context of the syncer is done
+func (s *Syncer) aborted() bool {
+	return s.ctx.Err() != nil
+}
+
+// stopping reports whether the run should end before the next list
+func (s *Syncer) stopping() bool {
+	return s.stop.Err() != nil || s.aborted()
+}
+
 // ConfigDirty reports whether sync updated persisted config values.
 func (s *Syncer) ConfigDirty() bool {
 	return s.configDirty
 }
 
+// SetState sets the persisted state used between runs
+func (s *Syncer) SetState(st *state.State) {
+	if st == nil {
+		st = &state.State{}
+	}
+	s.state = st
+}
+
+// StateDirty reports whether sync updated persisted state values.
+func (s *Syncer) StateDirty() bool {
+	return s.stateDirty
+}
+
+// SetListFilter restricts syncing to the given list slugs, regardless of
+// whether they are enabled in the config. It returns the slugs that do not
+// match any known list.
+func (s *Syncer) SetListFilter(slugs []string) []string {
+	s.listFilter = make(map[string]bool, len(slugs))
+	for _, slug := range slugs {
+		s.listFilter[s.managedSlug(slug)] = true
+	}
+
+	known := make(map[string]bool)
+	for _, listDef := range s.allListDefinitions() {
+		known[listDef.Slug] = true
+	}
+
+	var unknown []string
+	for _, slug := range slugs {
+		if !known[s.managedSlug(slug)] {
+			unknown = append(unknown, slug)
+		}
+	}
+	return unknown
+}
+
+// SetSuffix syncs every list into a sandbox copy whose slug carries suffix,
+// e.g. "-test" turns trakt-sync-filme into trakt-sync-filme-test. A missing
+// leading dash is added.
+func (s *Syncer) SetSuffix(suffix string) error {
+	if suffix == "" {
+		s.suffix = ""
+		return nil
+	}
+	if !strings.HasPrefix(suffix, "-") {
+		suffix = "-" + suffix
+	}
+	if config.Slugify(suffix) != strings.TrimPrefix(suffix, "-") {
+		return fmt.Errorf("invalid list suffix %q (use lowercase letters, digits and dashes)", suffix)
+	}
+	s.suffix = suffix
+	return nil
+}
+
 // GetListDefinitions returns all list definitions based on config
 func (s *Syncer) GetListDefinitions() []ListDefinition {
-	return []ListDefinition{
+	lists := s.allListDefinitions()
+	if s.listFilter != nil {
+		for i := range lists {
+			lists[i].Enabled = s.listFilter[lists[i].Slug]
+		}
+	}
+	for i := range lists {
+		if rename, ok := s.state.Renames[lists[i].Slug]; ok {
+			lists[i].Slug = rename.Slug
+			lists[i].Name = rename.Name
+		}
+	}
+	if s.suffix != "" {
+		for i := range lists {
+			lists[i] = s.sandboxDefinition(lists[i])
+		}
+	}
+	return lists
+}
+
+// managedSlug maps the slug of a renamed list back to the built-in slug that
+// config keys such as sync.split and full refresh timestamps refer to
+func (s *Syncer) managedSlug(slug string) string {
+	for managed, rename := range s.state.Renames {
+		if rename.Slug == slug {
+			return managed
+		}
+	}
+	return slug
+}
+
+// sandboxDefinition moves a list to its suffixed copy. Trakt derives slugs
+// from names, so the name gets the suffix as an extra word.
+func (s *Syncer) sandboxDefinition(listDef ListDefinition) ListDefinition {
+	word := strings.ReplaceAll(strings.TrimPrefix(s.suffix, "-"), "-", " ")
+	listDef.Slug += s.suffix
+	listDef.Name += " " + word
+	listDef.Description += " (sandbox)"
+	return listDef
+}
+
+func (s *Syncer) allListDefinitions() []ListDefinition {
+	ratings := s.config.Sync.RatingsList
+	recent := s.config.Sync.RecentlyWatched
+	anime := s.config.Sync.Anime
+
+	lists := []ListDefinition{
 		{
-			Slug:        "trakt-sync-filme",
+			Slug:        MoviesListSlug,
 			Name:        "Trakt Sync Filme",
 			Description: "Top 20 trending and top 20 streaming charts movies",
 			Enabled:     s.config.Sync.Lists.Movies,
@@ -62,14 +276,62 @@ func (s *Syncer) GetListDefinitions() []ListDefinition {
 			IsMovie:     true,
 		},
 		{
-			Slug:        "trakt-sync-serien",
+			Slug:        ShowsListSlug,
 			Name:        "Trakt Sync Serien",
 			Description: "Top 20 trending and top 20 streaming charts shows",
 			Enabled:     s.config.Sync.Lists.Shows,
 			FetchFunc:   s.fetchCombinedShows,
 			IsMovie:     false,
 		},
+		{
+			Slug:        RatingsListSlug,
+			Name:        "Trakt Sync Bewertungen",
+			Description: ratingsListDescription(ratings),
+			Enabled:     ratings.Enabled,
+			FetchFunc:   s.fetchRatings,
+			IsMovie:     ratings.Type != "shows",
+			Limit:       ratings.Limit,
+			Privacy:     ratings.Privacy,
+			personal:    true,
+		},
+		{
+			Slug:        RecentListSlug,
+			Name:        "Trakt Sync Zuletzt gesehen",
+			Description: fmt.Sprintf("My recently watched %s", recent.Type),
+			Enabled:     recent.Enabled,
+			FetchFunc:   s.fetchRecentlyWatched,
+			IsMovie:     recent.Type != "shows",
+			Limit:       recent.Limit,
+			Privacy:     recent.Privacy,
+			personal:    true,
+		},
+		{
+			Slug:        AnimeListSlug,
+			Name:        "Trakt Sync Anime",
+			Description: fmt.Sprintf("Trending and most watched anime %s", anime.Type),
+			Enabled:     anime.Enabled,
+			FetchFunc:   s.fetchAnime,
+			IsMovie:     anime.Type == "movies",
+			Limit:       anime.Limit,
+			Privacy:     anime.Privacy,
+		},
+	}
+	lists = append(lists, s.top10ListDefinitions()...)
+
+	taken := make(map[string]bool, len(lists))
+	for _, listDef := range lists {
+		taken[listDef.Slug] = true
 	}
+	lists = append(lists, s.customListDefinitions(taken)...)
+	return append(lists, s.mirrorListDefinitions(taken)...)
+}
+
+func ratingsListDescription(ratings config.RatingsListConfig) string {
+	description := fmt.Sprintf("My %d+ rated %s", ratings.MinRating, ratings.Type)
+	if ratings.Year > 0 {
+		description += fmt.Sprintf(" from %d", ratings.Year)
+	}
+	return description
 }
 
 // SyncAll syncs all enabled lists
@@ -78,20 +340,53 @@ func (s *Syncer) SyncAll() (SyncResult, error) {
 	lists := s.GetListDefinitions()
 
 	result := SyncResult{}
+	s.observed = make(map[string]bool)
+	s.sourceCache = make(map[string][]sourceResult)
+	defer func() { s.sourceCache = nil }()
 
 	log.Info().Msg("Starting sync...")
 
+	if len(s.state.Pending) > 0 {
+		s.SendPendingWrites()
+	}
+
 	for _, listDef := range lists {
+		if s.stopping() {
+			log.Info().Msg("Sync canceled, skipping the remaining lists")
+			result.Duration = time.Since(startTime)
+			return result, ErrCanceled
+		}
 		if !listDef.Enabled {
 			log.Debug().Str("list", listDef.Slug).Msg("List disabled, skipping")
 			continue
 		}
 
+		if split, ok := s.config.Sync.Split[s.managedSlug(strings.TrimSuffix(listDef.Slug, s.suffix))]; ok && split.By != "" {
+			if err := s.syncSplitListRecovered(listDef, split, &result); err != nil {
+				result.Duration = time.Since(startTime)
+				return result, err
+			}
+			continue
+		}
+
 		result.Total++
 
-		if err := s.SyncList(listDef); err != nil {
-			log.Error().Err(err).Str("list", listDef.Slug).Msg("Failed to sync list")
-			result.Failed++
+		if err := s.syncListRecovered(listDef); err != nil {
+			// Every other list would fail the same way, and an outage is
+			// no reason for error-level alerts.
+			if traktUnavailable(err) {
+				errcode.Log(log.Info(), err).Str("list", listDef.Slug).Msg("Trakt is down for maintenance, skipping the rest of the run")
+				result.Duration = time.Since(startTime)
+				return result, ErrUnavailable
+			}
+			// The list was interrupted, not broken.
+			if errors.Is(err, ErrCanceled) || s.aborted() {
+				log.Info().Str("list", listDef.Slug).Msg("Sync canceled")
+				result.Duration = time.Since(startTime)
+				return result, ErrCanceled
+			}
+			errcode.Log(log.Error(), err).Str("list", listDef.Slug).Msg("Failed to sync list")
+			result.fail(listDef.Slug, err)
 			continue
 		}
 
@@ -99,6 +394,7 @@ func (s *Syncer) SyncAll() (SyncResult, error) {
 	}
 
 	result.Duration = time.Since(startTime)
+	result.Pending = len(s.state.Pending)
 
 	if result.Total == 0 {
 		log.Warn().Msg("No lists enabled for sync")
@@ -119,50 +415,118 @@ func (s *Syncer) SyncAll() (SyncResult, error) {
 	return result, nil
 }
 
+// traktUnavailable reports whether err is a maintenance or Cloudflare
+// response of Trakt
+func traktUnavailable(err error) bool {
+	var apiErr *trakt.APIError
+	return errors.As(err, &apiErr) && apiErr.Unavailable
+}
+
 // SyncList syncs a single list
 func (s *Syncer) SyncList(listDef ListDefinition) error {
 	startTime := time.Now()
 
 	log.Info().Str("list", listDef.Slug).Msg("Starting list sync")
 
-	if err := s.client.EnsureListExists(
-		s.config.Trakt.Username,
-		listDef.Slug,
-		listDef.Name,
-		listDef.Description,
-		s.config.Sync.ListPrivacy,
-	); err != nil {
-		return fmt.Errorf("failed to ensure list exists: %w", err)
-	}
-
-	newItems, err := listDef.FetchFunc(s.client, s.config.Sync.Limit)
+	fetched, newItems, err := s.fetchContent(listDef)
 	if err != nil {
-		return fmt.Errorf("failed to fetch items: %w", err)
+		return err
 	}
-	newItems = uniqueIDs(newItems)
 
 	log.Info().Str("list", listDef.Slug).Int("count", len(newItems)).Msg("Fetched items from API")
 
-	currentItems, err := s.client.GetListItems(s.config.Trakt.Username, listDef.Slug)
+	skipRemovals := listDef.skipRemovals
+	if listDef.splitParent == "" && s.belowMinItems(listDef.Slug, len(newItems)) {
+		skipRemovals = true
+	}
+
+	list, err := s.client.GetList(s.config.Trakt.Username, listDef.Slug)
 	if err != nil {
-		return fmt.Errorf("failed to get current list items: %w", err)
+		return fmt.Errorf("failed to get list: %w", err)
 	}
 
-	if s.shouldFullRefresh(listDef.IsMovie) {
-		toRemove := listItemIDs(currentItems)
+	// Once the run is stopping, e.g. at the start of quiet hours, a list
+	// is only finished if its writes have begun.
+	if s.stopping() {
+		return ErrCanceled
+	}
+
+	var currentItems []trakt.ListItem
+	if list == nil {
+		// Lists are only created once there is something to put in them.
+		if len(newItems) == 0 {
+			log.Info().Str("list", listDef.Slug).Msg("Source returned no items, not creating list")
+			return nil
+		}
+		if list, err = s.createList(listDef); err != nil {
+			return fmt.Errorf("failed to create list: %w", err)
+		}
+		// Whatever was recorded belongs to a list that no longer exists.
+		if _, ok := s.state.Lists[s.managedSlug(listDef.Slug)]; ok {
+			delete(s.state.Lists, s.managedSlug(listDef.Slug))
+			s.stateDirty = true
+		}
+	} else {
+		currentItems, err = s.client.GetListItems(s.config.Trakt.Username, listDef.Slug)
+		if err != nil {
+			return fmt.Errorf("failed to get current list items: %w", err)
+		}
+		s.recordListStats(listDef.Slug, list)
+	}
+
+	edits := s.detectExternalEdits(listDef.Slug, list, currentItems)
+	if !edits.empty() && s.conflictPolicy(listDef.Slug) == config.ConflictSkip {
+		// The list stays edited, so this repeats every run until someone
+		// restores it or picks another policy.
+		log.Warn().
+			Str("list", listDef.Slug).
+			Msg("Skipping list edited outside trakt-sync (conflict policy skip); it is not synced again until its items match the last sync or conflict_policy is changed")
+		return nil
+	}
+	newItems = s.applyConflictPolicy(listDef.Slug, edits, currentItems, newItems)
+	newItems = s.applyManualRemovals(listDef.Slug, edits, newItems)
+	newItems = s.applyCooldown(listDef.Slug, currentItems, newItems)
+	pins := s.pinnedIDs(listDef)
+	newItems = applyPins(newItems, pins)
+	notes := s.itemNotes(listDef, fetched, newItems, pins)
+
+	if err := s.reconcileDisplay(listDef, list); err != nil {
+		return fmt.Errorf("failed to update list display options: %w", err)
+	}
+	// The diff is computed against the list as it is now, so it supersedes
+	// anything queued for it.
+	s.dropPending(listDef.Slug)
+
+	if !skipRemovals && s.shouldFullRefresh(s.managedSlug(listDef.Slug)) {
+		_, toRemove := s.calculateDiff(currentItems, nil)
+		toRemove = withoutPins(toRemove, pins)
 		if len(toRemove) > 0 {
 			if err := s.removeItems(listDef.Slug, toRemove, listDef.IsMovie); err != nil {
-				return fmt.Errorf("failed to remove items: %w", err)
+				return s.queueWrites(listDef, newItems, toRemove, newItems, fmt.Errorf("failed to remove items: %w", err))
 			}
 		}
 
 		if len(newItems) > 0 {
-			if err := s.addItems(listDef.Slug, newItems, listDef.IsMovie); err != nil {
-				return fmt.Errorf("failed to add items: %w", err)
+			if err := s.addItems(listDef.Slug, newItems, listDef.IsMovie, notes); err != nil {
+				return s.queueWrites(listDef, newItems, nil, newItems, fmt.Errorf("failed to add items: %w", err))
 			}
 		}
 
-		s.markFullRefresh(listDef.IsMovie)
+		if _, err := s.reorderItems(listDef.Slug, newItems, nil, true); err != nil {
+			return err
+		}
+
+		_, dropped := s.calculateDiff(currentItems, newItems)
+		s.recordRemovals(listDef.Slug, dropped)
+		s.markFullRefresh(s.managedSlug(listDef.Slug))
+		s.recordListWrite(listDef.Slug, newItems)
+		s.recordSynced(listDef, list, newItems)
+		if err := s.updateDescription(listDef, list, fetched, newItems); err != nil {
+			return err
+		}
+		if err := s.trackEmptyList(listDef, len(newItems)); err != nil {
+			return err
+		}
 
 		duration := time.Since(startTime)
 		log.Info().
@@ -177,20 +541,42 @@ func (s *Syncer) SyncList(listDef ListDefinition) error {
 	}
 
 	toAdd, toRemove := s.calculateDiff(currentItems, newItems)
+	if skipRemovals {
+		toRemove = nil
+	}
+
+	content := listContentAfter(currentItems, toAdd, toRemove)
 
 	if len(toRemove) > 0 {
 		if err := s.removeItems(listDef.Slug, toRemove, listDef.IsMovie); err != nil {
-			return fmt.Errorf("failed to remove items: %w", err)
+			return s.queueWrites(listDef, toAdd, toRemove, content, fmt.Errorf("failed to remove items: %w", err))
 		}
+		s.recordRemovals(listDef.Slug, toRemove)
 	}
 
 	if len(toAdd) > 0 {
-		if err := s.addItems(listDef.Slug, toAdd, listDef.IsMovie); err != nil {
-			return fmt.Errorf("failed to add items: %w", err)
+		if err := s.addItems(listDef.Slug, toAdd, listDef.IsMovie, notes); err != nil {
+			return s.queueWrites(listDef, toAdd, nil, content, fmt.Errorf("failed to add items: %w", err))
 		}
 	}
 
+	reordered, err := s.reorderItems(listDef.Slug, newItems, currentItems, len(toAdd) > 0 || len(toRemove) > 0)
+	if err != nil {
+		return err
+	}
+
+	if len(toAdd) > 0 || len(toRemove) > 0 || reordered || !edits.empty() {
+		s.recordListWrite(listDef.Slug, content)
+	}
+	s.recordSynced(listDef, list, content)
+	if err := s.updateDescription(listDef, list, fetched, content); err != nil {
+		return err
+	}
+
 	unchanged := len(currentItems) - len(toRemove)
+	if err := s.trackEmptyList(listDef, unchanged+len(toAdd)); err != nil {
+		return err
+	}
 	duration := time.Since(startTime)
 
 	log.Info().
@@ -204,13 +590,94 @@ func (s *Syncer) SyncList(listDef ListDefinition) error {
 	return nil
 }
 
-func (s *Syncer) shouldFullRefresh(isMovie bool) bool {
+// listPrivacy returns the privacy of a list: its own, else sync.list_privacy
+func (s *Syncer) listPrivacy(listDef ListDefinition) string {
+	privacy := listDef.Privacy
+	if privacy == "" {
+		privacy = s.config.Sync.ListPrivacy
+	}
+	if privacy == "" {
+		privacy = "private"
+	}
+	return privacy
+}
+
+// createList creates the Trakt list for a list definition
+func (s *Syncer) createList(listDef ListDefinition) (*trakt.List, error) {
+	req := trakt.CreateListRequest{
+		Name:           listDef.Name,
+		Description:    listDef.Description,
+		Privacy:        s.listPrivacy(listDef),
+		DisplayNumbers: true,
+		AllowComments:  false,
+	}
+	s.applyDisplay(listDef.Slug, &req)
+
+	log.Info().Str("list", listDef.Slug).Msg("Creating list")
+	return s.client.CreateList(s.config.Trakt.Username, req)
+}
+
+// trackEmptyList counts consecutive runs that leave a list empty and deletes
+// the list once sync.delete_empty_after_runs is reached. Lists created by
+// sync.split are deleted as soon as they are empty instead.
+func (s *Syncer) trackEmptyList(listDef ListDefinition, remaining int) error {
+	runs := s.config.Sync.DeleteEmptyAfterRuns
+	if runs <= 0 || listDef.splitParent != "" {
+		return nil
+	}
+
+	key := s.managedSlug(listDef.Slug)
+	entry := s.state.Lists[key]
+	if remaining > 0 {
+		if entry.EmptyRuns > 0 {
+			entry.EmptyRuns = 0
+			s.state.Lists[key] = entry
+			s.stateDirty = true
+		}
+		return nil
+	}
+
+	entry.EmptyRuns++
+	if s.state.Lists == nil {
+		s.state.Lists = make(map[string]state.ListState)
+	}
+	s.state.Lists[key] = entry
+	s.stateDirty = true
+	if entry.EmptyRuns < runs {
+		return nil
+	}
+
+	log.Info().Str("list", listDef.Slug).Int("empty_runs", entry.EmptyRuns).Msg("Deleting list that stayed empty")
+	if err := s.client.DeleteList(s.config.Trakt.Username, listDef.Slug); err != nil {
+		return fmt.Errorf("failed to delete empty list: %w", err)
+	}
+	delete(s.state.Lists, key)
+	return nil
+}
+
+// belowMinItems reports whether a source returned fewer items than
+// sync.min_items, in which case removals are skipped to protect the list
+func (s *Syncer) belowMinItems(slug string, count int) bool {
+	minItems := s.config.Sync.MinItems
+	if minItems <= 0 || count >= minItems {
+		return false
+	}
+
+	log.Warn().
+		Str("list", slug).
+		Int("count", count).
+		Int("min_items", minItems).
+		Msg("Source returned fewer items than sync.min_items, skipping removals for this run")
+	return true
+}
+
+func (s *Syncer) shouldFullRefresh(slug string) bool {
 	days := s.config.Sync.FullRefreshDays
 	if days <= 0 {
 		days = 7
 	}
 
-	last := s.lastFullRefresh(isMovie)
+	last := s.lastFullRefresh(slug)
 	if last.IsZero() {
 		return true
 	}
@@ -218,76 +685,186 @@ func (s *Syncer) shouldFullRefresh(isMovie bool) bool {
 	return time.Since(last) >= time.Duration(days)*24*time.Hour
 }
 
-func (s *Syncer) lastFullRefresh(isMovie bool) time.Time {
-	if isMovie {
+func (s *Syncer) lastFullRefresh(slug string) time.Time {
+	switch slug {
+	case MoviesListSlug:
 		return s.config.Sync.LastFullRefresh.Movies
+	case ShowsListSlug:
+		return s.config.Sync.LastFullRefresh.Shows
+	default:
+		return s.config.Sync.LastFullRefresh.Lists[slug]
 	}
-	return s.config.Sync.LastFullRefresh.Shows
 }
 
-func (s *Syncer) markFullRefresh(isMovie bool) {
+func (s *Syncer) markFullRefresh(slug string) {
 	now := time.Now().UTC()
-	if isMovie {
+	switch slug {
+	case MoviesListSlug:
 		s.config.Sync.LastFullRefresh.Movies = now
-	} else {
+	case ShowsListSlug:
 		s.config.Sync.LastFullRefresh.Shows = now
+	default:
+		if s.config.Sync.LastFullRefresh.Lists == nil {
+			s.config.Sync.LastFullRefresh.Lists = make(map[string]time.Time)
+		}
+		s.config.Sync.LastFullRefresh.Lists[slug] = now
 	}
 	s.configDirty = true
 }
 
-// calculateDiff calculates which items to add and remove
-func (s *Syncer) calculateDiff(current []trakt.ListItem, new []trakt.MediaIDs) (toAdd, toRemove []trakt.MediaIDs) {
-	currentMap := make(map[int]bool)
-	for _, item := range current {
-		if item.Movie != nil {
-			currentMap[item.Movie.IDs.Trakt] = true
-		} else if item.Show != nil {
-			currentMap[item.Show.IDs.Trakt] = true
-		}
+// recordListWrite stores what a list contains after trakt-sync wrote to it
+func (s *Syncer) recordListWrite(slug string, items []trakt.MediaIDs) {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Trakt)
 	}
+	sort.Ints(ids)
 
-	newMap := make(map[int]trakt.MediaIDs)
-	for _, ids := range new {
-		newMap[ids.Trakt] = ids
+	if s.state.Lists == nil {
+		s.state.Lists = make(map[string]state.ListState)
 	}
+	key := s.managedSlug(slug)
+	entry := s.state.Lists[key]
+	entry.Items = ids
+	entry.WrittenAt = time.Now().UTC()
+	s.state.Lists[key] = entry
+	s.stateDirty = true
+}
 
-	for _, ids := range new {
-		if !currentMap[ids.Trakt] {
-			toAdd = append(toAdd, ids)
-		}
+// fetchContent fetches the items of listDef and returns them along with
+// their IDs, without duplicates and the own titles excludeOwnTitles drops
+func (s *Syncer) fetchContent(listDef ListDefinition) ([]Item, []trakt.MediaIDs, error) {
+	limit := s.config.Sync.Limit
+	if listDef.Limit > 0 {
+		limit = listDef.Limit
 	}
 
-	for _, item := range current {
-		var traktID int
-		var ids trakt.MediaIDs
+	fetched, err := listDef.FetchFunc(s.client, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch items: %w", err)
+	}
+	ids, err := s.excludeOwnTitles(listDef, uniqueIDs(itemIDs(fetched)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return fetched, ids, nil
+}
 
-		if item.Movie != nil {
-			traktID = item.Movie.IDs.Trakt
-			ids = item.Movie.IDs
-		} else if item.Show != nil {
-			traktID = item.Show.IDs.Trakt
-			ids = item.Show.IDs
+// recordSynced remembers the content of a list written in this run
+func (s *Syncer) recordSynced(listDef ListDefinition, list *trakt.List, items []trakt.MediaIDs) {
+	s.synced = append(s.synced, SyncedList{Slug: listDef.Slug, Name: list.Name, IsMovie: listDef.IsMovie, IDs: items})
+}
+
+// Synced returns the lists synced since the syncer was created with their
+// content, in sync order
+func (s *Syncer) Synced() []SyncedList {
+	return s.synced
+}
+
+// listContentAfter returns the IDs a list holds after applying a diff
+func listContentAfter(current []trakt.ListItem, toAdd, toRemove []trakt.MediaIDs) []trakt.MediaIDs {
+	removed := make([]int, 0, len(toRemove))
+	for _, ids := range toRemove {
+		removed = append(removed, ids.Trakt)
+	}
+	sort.Ints(removed)
+
+	var content []trakt.MediaIDs
+	for _, ids := range listItemIDs(current) {
+		if i := sort.SearchInts(removed, ids.Trakt); i < len(removed) && removed[i] == ids.Trakt {
+			continue
 		}
+		content = append(content, ids)
+	}
+	return append(content, toAdd...)
+}
 
-		if _, exists := newMap[traktID]; !exists {
-			toRemove = append(toRemove, ids)
+// calculateDiff calculates which items to add and remove. Both sides are
+// walked in Trakt ID order instead of being loaded into maps, which keeps
+// memory flat for large lists on small devices. Items to add keep the order
+// of new, i.e. their rank in the source; items to remove are ordered by their
+// rank in the list, then Trakt ID, so identical inputs give identical logs
+// and API payloads.
+func (s *Syncer) calculateDiff(current []trakt.ListItem, new []trakt.MediaIDs) (toAdd, toRemove []trakt.MediaIDs) {
+	currentID := func(i int) int { return itemMediaIDs(current[i]).Trakt }
+	newID := func(i int) int { return new[i].Trakt }
+	currentOrder := orderByID(len(current), currentID)
+	newOrder := orderByID(len(new), newID)
+
+	var added, removed []int
+	a, b := 0, 0
+	for a < len(currentOrder) || b < len(newOrder) {
+		switch {
+		case b == len(newOrder) || (a < len(currentOrder) && currentID(currentOrder[a]) < newID(newOrder[b])):
+			if current[currentOrder[a]].Movie != nil || current[currentOrder[a]].Show != nil {
+				removed = append(removed, currentOrder[a])
+			}
+			a++
+		case a == len(currentOrder) || newID(newOrder[b]) < currentID(currentOrder[a]):
+			added = append(added, newOrder[b])
+			b++
+		default:
+			// Present on both sides, including any duplicates
+			id := newID(newOrder[b])
+			for a < len(currentOrder) && currentID(currentOrder[a]) == id {
+				a++
+			}
+			for b < len(newOrder) && newID(newOrder[b]) == id {
+				b++
+			}
 		}
 	}
 
+	sort.Ints(added)
+	toAdd = make([]trakt.MediaIDs, 0, len(added))
+	for _, i := range added {
+		toAdd = append(toAdd, new[i])
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		a, b := current[removed[i]], current[removed[j]]
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		if idA, idB := itemMediaIDs(a).Trakt, itemMediaIDs(b).Trakt; idA != idB {
+			return idA < idB
+		}
+		return removed[i] < removed[j]
+	})
+	toRemove = make([]trakt.MediaIDs, 0, len(removed))
+	for _, i := range removed {
+		toRemove = append(toRemove, itemMediaIDs(current[i]))
+	}
 	return toAdd, toRemove
 }
 
-// addItems adds items to a list
-func (s *Syncer) addItems(listSlug string, items []trakt.MediaIDs, isMovie bool) error {
+// orderByID returns the indexes 0..n-1 sorted by the ID at each index, ties
+// in index order
+func orderByID(n int, id func(int) int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := id(order[i]), id(order[j])
+		if a != b {
+			return a < b
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
+// addItems adds items to a list, with the notes keyed by Trakt ID
+func (s *Syncer) addItems(listSlug string, items []trakt.MediaIDs, isMovie bool, notes map[int]string) error {
 	req := trakt.AddToListRequest{}
 
 	if isMovie {
 		for _, ids := range items {
-			req.Movies = append(req.Movies, trakt.AddMovie{IDs: ids})
+			req.Movies = append(req.Movies, trakt.AddMovie{IDs: ids, Notes: notes[ids.Trakt]})
 		}
 	} else {
 		for _, ids := range items {
-			req.Shows = append(req.Shows, trakt.AddShow{IDs: ids})
+			req.Shows = append(req.Shows, trakt.AddShow{IDs: ids, Notes: notes[ids.Trakt]})
 		}
 	}
 
@@ -335,84 +912,3 @@ func uniqueIDs(items []trakt.MediaIDs) []trakt.MediaIDs {
 	}
 	return unique
 }
-
-// Fetch functions for different list types
-func (s *Syncer) fetchCombinedMovies(client *trakt.Client, limit int) ([]trakt.MediaIDs, error) {
-	trending, err := s.fetchTrendingMovies(client, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	streaming, err := s.fetchStreamingMovies(client, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	return uniqueIDs(append(trending, streaming...)), nil
-}
-
-func (s *Syncer) fetchCombinedShows(client *trakt.Client, limit int) ([]trakt.MediaIDs, error) {
-	trending, err := s.fetchTrendingShows(client, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	streaming, err := s.fetchStreamingShows(client, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	return uniqueIDs(append(trending, streaming...)), nil
-}
-
-func (s *Syncer) fetchTrendingMovies(client *trakt.Client, limit int) ([]trakt.MediaIDs, error) {
-	movies, err := client.GetTrendingMovies(limit, s.config.Sync.MinRating)
-	if err != nil {
-		return nil, err
-	}
-
-	var ids []trakt.MediaIDs
-	for _, m := range movies {
-		ids = append(ids, m.Movie.IDs)
-	}
-	return ids, nil
-}
-
-func (s *Syncer) fetchTrendingShows(client *trakt.Client, limit int) ([]trakt.MediaIDs, error) {
-	shows, err := client.GetTrendingShows(limit, s.config.Sync.MinRating)
-	if err != nil {
-		return nil, err
-	}
-
-	var ids []trakt.MediaIDs
-	for _, sh := range shows {
-		ids = append(ids, sh.Show.IDs)
-	}
-	return ids, nil
-}
-
-func (s *Syncer) fetchStreamingMovies(client *trakt.Client, limit int) ([]trakt.MediaIDs, error) {
-	movies, err := client.GetMostWatchedMovies(limit, s.config.Sync.MinRating)
-	if err != nil {
-		return nil, err
-	}
-
-	var ids []trakt.MediaIDs
-	for _, m := range movies {
-		ids = append(ids, m.Movie.IDs)
-	}
-	return ids, nil
-}
-
-func (s *Syncer) fetchStreamingShows(client *trakt.Client, limit int) ([]trakt.MediaIDs, error) {
-	shows, err := client.GetMostWatchedShows(limit, s.config.Sync.MinRating)
-	if err != nil {
-		return nil, err
-	}
-
-	var ids []trakt.MediaIDs
-	for _, sh := range shows {
-		ids = append(ids, sh.Show.IDs)
-	}
-	return ids, nil
-}
@@ -3,10 +3,12 @@ package sync
 import (
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/state"
 	"github.com/maximilian/trakt-sync/internal/trakt"
 )
 
@@ -37,6 +39,52 @@ func TestCalculateDiffShows(t *testing.T) {
 	assertIDs(t, toRemove, []int{10})
 }
 
+func TestCalculateDiffOrder(t *testing.T) {
+	syncer := &Syncer{}
+	current := []trakt.ListItem{
+		{Rank: 3, Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 9}}},
+		{Rank: 1, Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 4}}},
+		{Rank: 2, Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 7}}},
+		{Rank: 4, Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 4}}},
+		{Rank: 2, Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 6}}},
+	}
+	newItems := []trakt.MediaIDs{{Trakt: 8}, {Trakt: 4}, {Trakt: 2}, {Trakt: 5}}
+
+	// Additions keep source rank, removals follow list rank then Trakt ID
+	for run := 0; run < 3; run++ {
+		toAdd, toRemove := syncer.calculateDiff(current, newItems)
+		if got := extractIDs(toAdd); !reflect.DeepEqual(got, []int{8, 2, 5}) {
+			t.Fatalf("run %d: expected additions [8 2 5], got %v", run, got)
+		}
+		if got := extractIDs(toRemove); !reflect.DeepEqual(got, []int{6, 7, 9}) {
+			t.Fatalf("run %d: expected removals [6 7 9], got %v", run, got)
+		}
+
+		// The API may return the list in any order
+		current[0], current[4] = current[4], current[0]
+		current[1], current[2] = current[2], current[1]
+	}
+}
+
+// BenchmarkCalculateDiff measures a diff of two large lists that share half
+// their items, the shape of a list after a big chart update
+func BenchmarkCalculateDiff(b *testing.B) {
+	const size = 20000
+	current := make([]trakt.ListItem, size)
+	newItems := make([]trakt.MediaIDs, size)
+	for i := 0; i < size; i++ {
+		current[i] = trakt.ListItem{Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: (i * 7919) % (2 * size)}}}
+		newItems[i] = trakt.MediaIDs{Trakt: (i*7919)%(2*size) + size}
+	}
+
+	syncer := &Syncer{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		syncer.calculateDiff(current, newItems)
+	}
+}
+
 func TestUniqueIDs(t *testing.T) {
 	items := []trakt.MediaIDs{{Trakt: 1}, {Trakt: 2}, {Trakt: 1}}
 	unique := uniqueIDs(items)
@@ -57,15 +105,84 @@ func TestShouldFullRefresh(t *testing.T) {
 
 	syncer := &Syncer{config: cfg}
 
-	if !syncer.shouldFullRefresh(true) {
+	if !syncer.shouldFullRefresh(MoviesListSlug) {
 		t.Fatal("expected movies to require full refresh")
 	}
 
-	if syncer.shouldFullRefresh(false) {
+	if syncer.shouldFullRefresh(ShowsListSlug) {
 		t.Fatal("did not expect shows to require full refresh")
 	}
 }
 
+func TestShouldFullRefreshOtherLists(t *testing.T) {
+	cfg := &config.Config{
+		Sync: config.SyncConfig{
+			FullRefreshDays: 7,
+			LastFullRefresh: config.FullRefreshState{
+				Movies: time.Now(),
+			},
+		},
+	}
+
+	syncer := &Syncer{config: cfg}
+
+	if !syncer.shouldFullRefresh(RatingsListSlug) {
+		t.Fatal("expected ratings list without timestamp to require full refresh")
+	}
+
+	syncer.markFullRefresh(RatingsListSlug)
+
+	if syncer.shouldFullRefresh(RatingsListSlug) {
+		t.Fatal("did not expect ratings list to require full refresh after marking")
+	}
+	if !syncer.ConfigDirty() {
+		t.Fatal("expected config to be dirty after marking full refresh")
+	}
+}
+
+func TestBelowMinItems(t *testing.T) {
+	syncer := &Syncer{config: &config.Config{Sync: config.SyncConfig{MinItems: 10}}}
+
+	if !syncer.belowMinItems(MoviesListSlug, 3) {
+		t.Fatal("expected 3 items to be below min_items")
+	}
+	if syncer.belowMinItems(MoviesListSlug, 10) {
+		t.Fatal("did not expect 10 items to be below min_items")
+	}
+
+	syncer.config.Sync.MinItems = 0
+	if syncer.belowMinItems(MoviesListSlug, 0) {
+		t.Fatal("did not expect guard when min_items is disabled")
+	}
+}
+
+func TestFilterRatedItems(t *testing.T) {
+	rated := func(id, rating, year int) trakt.RatedItem {
+		return trakt.RatedItem{
+			Rating:  rating,
+			RatedAt: time.Date(year, 6, id, 0, 0, 0, 0, time.UTC),
+			Movie:   &trakt.Movie{IDs: trakt.MediaIDs{Trakt: id}},
+		}
+	}
+
+	items := []trakt.RatedItem{
+		rated(1, 10, 2024),
+		rated(2, 8, 2024),
+		rated(3, 9, 2023),
+		rated(4, 9, 2024),
+	}
+
+	got := filterRatedItems(items, 9, 2024, 0)
+	if ids := extractIDs(itemIDs(got)); !reflect.DeepEqual(ids, []int{1, 4}) {
+		t.Fatalf("expected [1 4], got %v", ids)
+	}
+
+	got = filterRatedItems(items, 9, 0, 2)
+	if ids := extractIDs(itemIDs(got)); !reflect.DeepEqual(ids, []int{1, 4}) {
+		t.Fatalf("expected [1 4] with limit, got %v", ids)
+	}
+}
+
 func assertIDs(t *testing.T, got []trakt.MediaIDs, want []int) {
 	t.Helper()
 	if want == nil {
@@ -86,3 +203,228 @@ func extractIDs(items []trakt.MediaIDs) []int {
 	}
 	return ids
 }
+
+func TestSetSuffix(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sync.Lists.Movies = true
+	syncer := NewSyncer(nil, cfg)
+
+	if err := syncer.SetSuffix("test"); err != nil {
+		t.Fatalf("SetSuffix: %v", err)
+	}
+
+	for _, listDef := range syncer.GetListDefinitions() {
+		if !strings.HasSuffix(listDef.Slug, "-test") {
+			t.Errorf("expected %s to carry the suffix", listDef.Slug)
+		}
+		if config.Slugify(listDef.Name) != listDef.Slug {
+			t.Errorf("name %q does not slugify to %s", listDef.Name, listDef.Slug)
+		}
+	}
+
+	for _, suffix := range []string{"-Test", "-a b", "--x"} {
+		if err := syncer.SetSuffix(suffix); err == nil {
+			t.Errorf("expected suffix %q to be rejected", suffix)
+		}
+	}
+}
+
+func TestRenamedListDefinitions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sync.Lists.Movies = true
+	syncer := NewSyncer(nil, cfg)
+	syncer.SetState(&state.State{Renames: map[string]state.ListRename{
+		MoviesListSlug: {Slug: "kino-charts", Name: "Kino Charts"},
+	}})
+
+	var found bool
+	for _, listDef := range syncer.GetListDefinitions() {
+		if listDef.Slug == "kino-charts" {
+			found = listDef.Name == "Kino Charts"
+		}
+		if listDef.Slug == MoviesListSlug {
+			t.Error("renamed list still uses its built-in slug")
+		}
+	}
+	if !found {
+		t.Fatal("renamed list definition not found")
+	}
+
+	if got := syncer.managedSlug("kino-charts"); got != MoviesListSlug {
+		t.Errorf("managedSlug = %s, want %s", got, MoviesListSlug)
+	}
+	if unknown := syncer.SetListFilter([]string{"kino-charts"}); len(unknown) != 0 {
+		t.Errorf("renamed slug rejected by list filter: %v", unknown)
+	}
+}
+
+func TestListItemKeySeparatesMoviesAndShows(t *testing.T) {
+	movie := trakt.ListItem{Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 7}}}
+	show := trakt.ListItem{Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: 7}}}
+
+	if listItemKey(movie) == listItemKey(show) {
+		t.Error("movie and show with the same Trakt ID share a key")
+	}
+	if got := listItemKey(trakt.ListItem{Type: "person"}); got != "" {
+		t.Errorf("listItemKey(person) = %q, want empty", got)
+	}
+}
+
+func TestCompareListItems(t *testing.T) {
+	movie := func(id int) trakt.ListItem {
+		return trakt.ListItem{Type: "movie", Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: id}}}
+	}
+	show := func(id int) trakt.ListItem {
+		return trakt.ListItem{Type: "show", Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: id}}}
+	}
+	person := trakt.ListItem{Type: "person"}
+
+	result := compareListItems(
+		[]trakt.ListItem{movie(1), movie(2), show(3), person},
+		[]trakt.ListItem{movie(2), movie(3), show(3), person},
+	)
+
+	keys := func(items []trakt.ListItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, listItemKey(item))
+		}
+		return out
+	}
+	if got, want := keys(result.Both), []string{"movie:2", "show:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Both = %v, want %v", got, want)
+	}
+	if got, want := keys(result.OnlyA), []string{"movie:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OnlyA = %v, want %v", got, want)
+	}
+	if got, want := keys(result.OnlyB), []string{"movie:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OnlyB = %v, want %v", got, want)
+	}
+}
+
+func TestFetchChartReusesCompatibleResults(t *testing.T) {
+	s := NewSyncer(nil, &config.Config{})
+	s.sourceCache = make(map[string][]sourceResult)
+
+	fetches := 0
+	fetch := func(opts trakt.ChartOptions) ([]Item, error) {
+		fetches++
+		items := make([]Item, opts.Limit)
+		for i := range items {
+			items[i] = Item{IDs: trakt.MediaIDs{Trakt: i + 1}}
+		}
+		return items, nil
+	}
+
+	steps := []struct {
+		opts        trakt.ChartOptions
+		wantFetches int
+		wantItems   int
+	}{
+		{trakt.ChartOptions{Limit: 20, MinRating: 60}, 1, 20},
+		{trakt.ChartOptions{Limit: 10, MinRating: 60}, 1, 10},
+		{trakt.ChartOptions{Limit: 30, MinRating: 60}, 2, 30},
+		{trakt.ChartOptions{Limit: 10, MinRating: 70}, 3, 10},
+		{trakt.ChartOptions{Limit: 10, MinRating: 60, Extended: true}, 4, 10},
+		{trakt.ChartOptions{Limit: 5, MinRating: 60}, 4, 5},
+	}
+	for i, step := range steps {
+		items, err := s.fetchChart("movies/trending", step.opts, fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fetches != step.wantFetches || len(items) != step.wantItems {
+			t.Errorf("step %d: fetches = %d, items = %d; want %d, %d", i, fetches, len(items), step.wantFetches, step.wantItems)
+		}
+	}
+
+	if _, err := s.fetchChart("shows/trending", steps[0].opts, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 5 {
+		t.Errorf("another source reused a cached result")
+	}
+}
+
+func TestTrendingMinWatchers(t *testing.T) {
+	movies := []trakt.TrendingMovie{
+		{Watchers: 900, Movie: trakt.Movie{IDs: trakt.MediaIDs{Trakt: 1}}},
+		{Watchers: 120, Movie: trakt.Movie{IDs: trakt.MediaIDs{Trakt: 2}}},
+		{Watchers: 500, Movie: trakt.Movie{IDs: trakt.MediaIDs{Trakt: 3}}},
+	}
+
+	items, qualified := trendingMovieItems(movies, 500)
+	if got := extractIDs(itemIDs(items)); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("all items = %v, want [1 2 3]", got)
+	}
+	if got := extractIDs(itemIDs(qualified)); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("qualified = %v, want [1 3]", got)
+	}
+
+	shows := []trakt.TrendingShow{{Watchers: 3, Show: trakt.Show{IDs: trakt.MediaIDs{Trakt: 10}}}}
+	if _, qualified := trendingShowItems(shows, 0); len(qualified) != 1 {
+		t.Errorf("min_watchers 0 dropped items: %v", qualified)
+	}
+}
+
+func TestWatchedThresholds(t *testing.T) {
+	movies := []trakt.WatchedMovie{
+		{PlayCount: 900, WatcherCount: 300, Movie: trakt.Movie{IDs: trakt.MediaIDs{Trakt: 1}}},
+		{PlayCount: 900, WatcherCount: 50, Movie: trakt.Movie{IDs: trakt.MediaIDs{Trakt: 2}}},
+		{PlayCount: 100, WatcherCount: 300, Movie: trakt.Movie{IDs: trakt.MediaIDs{Trakt: 3}}},
+	}
+
+	items, qualified := watchedMovieItems(movies, config.WatchedSourceConfig{MinPlays: 500, MinWatcherCount: 100})
+	if len(items) != 3 {
+		t.Errorf("expected all 3 chart items, got %d", len(items))
+	}
+	if got := extractIDs(itemIDs(qualified)); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("qualified = %v, want [1]", got)
+	}
+
+	shows := []trakt.WatchedShow{
+		{PlayCount: 40, WatcherCount: 10, Show: trakt.Show{IDs: trakt.MediaIDs{Trakt: 10}}},
+		{PlayCount: 80, WatcherCount: 10, Show: trakt.Show{IDs: trakt.MediaIDs{Trakt: 11}}},
+	}
+	if _, qualified := watchedShowItems(shows, config.WatchedSourceConfig{MinPlays: 50}); len(qualified) != 1 || qualified[0].IDs.Trakt != 11 {
+		t.Errorf("min_plays 50 kept %v, want show 11", qualified)
+	}
+}
+
+func TestAnimeMappingFilter(t *testing.T) {
+	mapping := newAnimeMapping([]animeMappingEntry{
+		{TMDB: 100, AniList: 1},
+		{IMDB: "tt0000200", MAL: 2},
+		{TMDB: 300},
+	})
+	items := []Item{
+		{IDs: trakt.MediaIDs{Trakt: 1, TMDB: 100}},
+		{IDs: trakt.MediaIDs{Trakt: 2, IMDB: "tt0000200"}},
+		{IDs: trakt.MediaIDs{Trakt: 3, TMDB: 300}},
+		{IDs: trakt.MediaIDs{Trakt: 4}},
+	}
+
+	// Entries without AniList or MyAnimeList IDs do not count as anime.
+	if got := extractIDs(itemIDs(mapping.filter(items))); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("filtered = %v, want [1 2]", got)
+	}
+}
+
+func TestSyncListRecoversPanic(t *testing.T) {
+	syncer := &Syncer{config: &config.Config{}, state: &state.State{}}
+	listDef := ListDefinition{
+		Slug: "broken",
+		FetchFunc: func(*trakt.Client, int) ([]Item, error) {
+			panic("boom")
+		},
+	}
+
+	err := syncer.syncListRecovered(listDef)
+	panicErr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("expected a *PanicError, got %v", err)
+	}
+	if panicErr.List != "broken" || !strings.Contains(string(panicErr.Stack), "TestSyncListRecoversPanic") {
+		t.Errorf("unexpected panic error %+v", panicErr)
+	}
+}
@@ -0,0 +1,53 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// top10Size is the number of titles in a streaming top 10 list
+const top10Size = 10
+
+// top10ListDefinitions builds one list per sync.streaming_top10 service
+func (s *Syncer) top10ListDefinitions() []ListDefinition {
+	top10 := s.config.Sync.StreamingTop10
+	lists := make([]ListDefinition, 0, len(top10.Services))
+	for _, service := range top10.Services {
+		name, ok := config.StreamingServices[service]
+		if !ok {
+			name = service
+		}
+		// Trakt derives the slug from the name, e.g. trakt-sync-top-10-netflix.
+		listName := "Trakt Sync Top 10 " + name
+		lists = append(lists, ListDefinition{
+			Slug:        config.Slugify(listName),
+			Name:        listName,
+			Description: fmt.Sprintf("Most watched %s on %s in %s this week", top10.Type, name, strings.ToUpper(top10.Country)),
+			Enabled:     true,
+			FetchFunc:   s.fetchTop10(service),
+			IsMovie:     top10.Type == "movies",
+			Limit:       top10Size,
+			Privacy:     top10.Privacy,
+		})
+	}
+	return lists
+}
+
+// fetchTop10 returns the weekly most watched chart restricted to titles
+// streaming on service in the configured country
+func (s *Syncer) fetchTop10(service string) func(*trakt.Client, int) ([]Item, error) {
+	return func(client *trakt.Client, limit int) ([]Item, error) {
+		top10 := s.config.Sync.StreamingTop10
+		opts := s.chartOptions(limit)
+		opts.Period = trakt.PeriodWeekly
+		opts.WatchNow = []string{service}
+		opts.Countries = []string{top10.Country}
+		if top10.Type == "movies" {
+			return s.streamingMovies(client, opts)
+		}
+		return s.streamingShows(client, opts)
+	}
+}
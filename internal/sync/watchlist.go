@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/rs/zerolog/log"
+)
+
+// PruneWatchlist removes watchlist entries older than
+// watchlist.prune_after_days. It returns the number of pruned items. With
+// dryRun set, items are only logged.
+func (s *Syncer) PruneWatchlist(dryRun bool) (int, error) {
+	days := s.config.Watchlist.PruneAfterDays
+	if days <= 0 {
+		return 0, nil
+	}
+
+	items, err := s.client.GetWatchlist("")
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	stale := staleWatchlistItems(items, cutoff)
+
+	log.Info().
+		Int("watchlist", len(items)).
+		Int("stale", len(stale)).
+		Int("prune_after_days", days).
+		Msg("Checked watchlist for stale items")
+
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	req := watchlistRemoval(stale, "Stale watchlist")
+
+	if dryRun {
+		log.Info().Int("count", len(stale)).Msg("DRY RUN: would remove stale watchlist items")
+		return 0, nil
+	}
+
+	if err := s.client.RemoveFromWatchlist(req); err != nil {
+		return 0, fmt.Errorf("failed to prune watchlist: %w", err)
+	}
+
+	log.Info().Int("removed", len(stale)).Msg("Pruned watchlist")
+	return len(stale), nil
+}
+
+// staleWatchlistItems returns movies and shows added to the watchlist before cutoff
+func staleWatchlistItems(items []trakt.WatchlistItem, cutoff time.Time) []trakt.WatchlistItem {
+	var stale []trakt.WatchlistItem
+	for _, item := range items {
+		if item.Movie == nil && item.Show == nil {
+			continue
+		}
+		if item.ListedAt.Before(cutoff) {
+			stale = append(stale, item)
+		}
+	}
+	return stale
+}
+
+// RemoveWatchedFromWatchlist removes watchlist entries that are in the watch
+// history: movies that were played and shows with a played episode. It
+// returns the number of removed items. With dryRun set, items are only logged.
+func (s *Syncer) RemoveWatchedFromWatchlist(dryRun bool) (int, error) {
+	items, err := s.client.GetWatchlist("")
+	if err != nil {
+		return 0, err
+	}
+	watched, err := s.watchedTitles()
+	if err != nil {
+		return 0, err
+	}
+
+	done := watched.watchlistItems(items)
+
+	log.Info().
+		Int("watchlist", len(items)).
+		Int("watched", len(done)).
+		Msg("Checked watchlist for watched items")
+
+	if len(done) == 0 {
+		return 0, nil
+	}
+
+	req := watchlistRemoval(done, "Watched watchlist")
+
+	if dryRun {
+		log.Info().Int("count", len(done)).Msg("DRY RUN: would remove watched watchlist items")
+		return 0, nil
+	}
+
+	if err := s.client.RemoveFromWatchlist(req); err != nil {
+		return 0, fmt.Errorf("failed to remove watched items from watchlist: %w", err)
+	}
+
+	log.Info().Int("removed", len(done)).Msg("Removed watched items from watchlist")
+	return len(done), nil
+}
+
+// watchlistRemoval logs items with msg, e.g. "Stale watchlist" for "Stale
+// watchlist movie", and returns the request removing them
+func watchlistRemoval(items []trakt.WatchlistItem, msg string) trakt.RemoveFromListRequest {
+	req := trakt.RemoveFromListRequest{}
+	for _, item := range items {
+		switch {
+		case item.Movie != nil:
+			log.Info().Str("title", item.Movie.Title).Time("listed_at", item.ListedAt).Msg(msg + " movie")
+			req.Movies = append(req.Movies, trakt.RemoveMovie{IDs: item.Movie.IDs})
+		case item.Show != nil:
+			log.Info().Str("title", item.Show.Title).Time("listed_at", item.ListedAt).Msg(msg + " show")
+			req.Shows = append(req.Shows, trakt.RemoveShow{IDs: item.Show.IDs})
+		}
+	}
+	return req
+}
+
+// watchedTitles fetches the watch history once per run. A show counts as
+// watched once any of its episodes was played.
+func (s *Syncer) watchedTitles() (*titleSet, error) {
+	if s.watched != nil {
+		return s.watched, nil
+	}
+	history, err := s.client.GetHistory("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch history: %w", err)
+	}
+	s.watched = newTitleSet()
+	for _, entry := range history {
+		s.watched.add(entry.Movie, entry.Show)
+	}
+	return s.watched, nil
+}
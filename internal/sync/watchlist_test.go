@@ -0,0 +1,47 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+func TestStaleWatchlistItems(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []trakt.WatchlistItem{
+		{ListedAt: cutoff.AddDate(0, -1, 0), Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 1}}},
+		{ListedAt: cutoff.AddDate(0, 1, 0), Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 2}}},
+		{ListedAt: cutoff.AddDate(-1, 0, 0), Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: 3}}},
+		{ListedAt: cutoff.AddDate(-1, 0, 0), Type: "episode"},
+	}
+
+	stale := staleWatchlistItems(items, cutoff)
+	if len(stale) != 2 {
+		t.Fatalf("expected 2 stale items, got %d", len(stale))
+	}
+	if stale[0].Movie.IDs.Trakt != 1 || stale[1].Show.IDs.Trakt != 3 {
+		t.Fatalf("unexpected stale items: %+v", stale)
+	}
+}
+
+func TestTitleSetWatchlistItems(t *testing.T) {
+	watched := newTitleSet()
+	watched.add(&trakt.Movie{IDs: trakt.MediaIDs{Trakt: 1}}, nil)
+	watched.add(nil, &trakt.Show{IDs: trakt.MediaIDs{Trakt: 7}})
+
+	items := []trakt.WatchlistItem{
+		{Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 1}}},
+		{Movie: &trakt.Movie{IDs: trakt.MediaIDs{Trakt: 7}}},
+		{Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: 1}}},
+		{Show: &trakt.Show{IDs: trakt.MediaIDs{Trakt: 7}}},
+	}
+
+	done := watched.watchlistItems(items)
+	if len(done) != 2 {
+		t.Fatalf("expected 2 watched items, got %d", len(done))
+	}
+	if done[0].Movie.IDs.Trakt != 1 || done[1].Show.IDs.Trakt != 7 {
+		t.Fatalf("unexpected watched items: %+v", done)
+	}
+}
@@ -0,0 +1,90 @@
+package tokenstore
+
+import (
+	"sync"
+	"time"
+)
+
+// Tokens are the OAuth tokens of an authorized client
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	// IssuedAt and ExpiresAt are Trakt time, ReceivedAt local time
+	IssuedAt   time.Time
+	ReceivedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// Store persists refreshed tokens. Updates from concurrent list syncs are
+// serialized and debounced: a burst of refreshes within the delay results in
+// a single write of the latest tokens.
+type Store struct {
+	save  func(Tokens) error
+	delay time.Duration
+
+	mu      sync.Mutex
+	latest  Tokens
+	version int
+	timer   *time.Timer
+
+	// writeMu serializes writes; written is the version last written
+	writeMu sync.Mutex
+	written int
+	onError func(error)
+}
+
+// New returns a store that writes tokens with save, delay after the first
+// update of a burst. A delay of 0 writes every update right away.
+func New(delay time.Duration, save func(Tokens) error) *Store {
+	return &Store{save: save, delay: delay}
+}
+
+// OnError sets a function called when a debounced write fails. Errors of
+// writes triggered by Update without a delay or by Flush are returned.
+func (s *Store) OnError(fn func(error)) {
+	s.onError = fn
+}
+
+// Update records refreshed tokens and schedules writing them
+func (s *Store) Update(tokens Tokens) error {
+	s.mu.Lock()
+	s.latest = tokens
+	s.version++
+	if s.delay <= 0 {
+		s.mu.Unlock()
+		return s.Flush()
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.delay, func() {
+			if err := s.Flush(); err != nil && s.onError != nil {
+				s.onError(err)
+			}
+		})
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Flush writes pending tokens now. It is a no-op when the latest tokens were
+// already written.
+func (s *Store) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	tokens, version := s.latest, s.version
+	s.mu.Unlock()
+
+	if version == s.written {
+		return nil
+	}
+	if err := s.save(tokens); err != nil {
+		return err
+	}
+	s.written = version
+	return nil
+}
@@ -0,0 +1,85 @@
+package tokenstore
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a save function that records what it wrote
+type recorder struct {
+	mu     sync.Mutex
+	writes []Tokens
+}
+
+func (r *recorder) save(t Tokens) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes = append(r.writes, t)
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.writes)
+}
+
+func TestConcurrentUpdatesAreCoalesced(t *testing.T) {
+	var rec recorder
+	store := New(time.Hour, rec.save)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store.Update(Tokens{AccessToken: fmt.Sprintf("access-%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	store.Update(Tokens{AccessToken: "latest"})
+
+	if n := rec.count(); n != 0 {
+		t.Fatalf("expected no write before the delay, got %d", n)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.count(); n != 1 || rec.writes[0].AccessToken != "latest" {
+		t.Errorf("expected a single write of the latest tokens, got %+v", rec.writes)
+	}
+}
+
+func TestDebouncedWrite(t *testing.T) {
+	var rec recorder
+	store := New(10*time.Millisecond, rec.save)
+
+	store.Update(Tokens{AccessToken: "first"})
+	store.Update(Tokens{AccessToken: "second"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for rec.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := rec.count(); n != 1 || rec.writes[0].AccessToken != "second" {
+		t.Errorf("expected one debounced write of the second tokens, got %+v", rec.writes)
+	}
+}
+
+func TestUpdateWithoutDelayWritesRightAway(t *testing.T) {
+	var rec recorder
+	store := New(0, rec.save)
+
+	if err := store.Update(Tokens{AccessToken: "now"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.count(); n != 1 {
+		t.Errorf("expected an immediate write, got %d", n)
+	}
+}
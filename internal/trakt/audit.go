@@ -0,0 +1,135 @@
+package trakt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/audit"
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+)
+
+// SetAuditLog records every write operation of the client in the audit log
+// at path. An empty path disables auditing.
+func (c *Client) SetAuditLog(path string) {
+	c.auditLog = path
+}
+
+// audit appends a write operation and its outcome to the audit log. Failing
+// to write the log does not fail the operation itself and is only reported
+// once, e.g. on a read-only filesystem.
+func (c *Client) audit(operation, target, summary string, err error) {
+	if c.auditLog == "" {
+		return
+	}
+
+	entry := audit.Entry{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Target:    target,
+		Summary:   summary,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := audit.Append(c.auditLog, entry); err != nil {
+		if !c.auditFailed {
+			errcode.Log(log.Warn(), err).Str("operation", operation).Msg("Failed to write audit log")
+		}
+		c.auditFailed = true
+	}
+}
+
+// itemsSummary describes the movies and shows of a request by their Trakt IDs
+func itemsSummary(movies, shows []MediaIDs) string {
+	var parts []string
+	if len(movies) > 0 {
+		parts = append(parts, countedIDs(movies, "movie", "movies"))
+	}
+	if len(shows) > 0 {
+		parts = append(parts, countedIDs(shows, "show", "shows"))
+	}
+	if len(parts) == 0 {
+		return "no items"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func countedIDs(ids []MediaIDs, singular, plural string) string {
+	noun := plural
+	if len(ids) == 1 {
+		noun = singular
+	}
+	traktIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		traktIDs = append(traktIDs, fmt.Sprint(id.Trakt))
+	}
+	return fmt.Sprintf("%d %s (%s)", len(ids), noun, strings.Join(traktIDs, " "))
+}
+
+func (r AddToListRequest) summary() string {
+	movies := make([]MediaIDs, 0, len(r.Movies))
+	for _, m := range r.Movies {
+		movies = append(movies, m.IDs)
+	}
+	shows := make([]MediaIDs, 0, len(r.Shows))
+	for _, sh := range r.Shows {
+		shows = append(shows, sh.IDs)
+	}
+	return itemsSummary(movies, shows)
+}
+
+func (r RemoveFromListRequest) summary() string {
+	movies := make([]MediaIDs, 0, len(r.Movies))
+	for _, m := range r.Movies {
+		movies = append(movies, m.IDs)
+	}
+	shows := make([]MediaIDs, 0, len(r.Shows))
+	for _, sh := range r.Shows {
+		shows = append(shows, sh.IDs)
+	}
+	return itemsSummary(movies, shows)
+}
+
+func (r CreateListRequest) summary() string {
+	return fmt.Sprintf("name %q, privacy %s", r.Name, r.Privacy)
+}
+
+func (r UpdateListRequest) summary() string {
+	var parts []string
+	if r.Name != "" {
+		parts = append(parts, fmt.Sprintf("name %q", r.Name))
+	}
+	if r.Description != "" {
+		parts = append(parts, "description")
+	}
+	if r.Privacy != "" {
+		parts = append(parts, "privacy "+r.Privacy)
+	}
+	if r.DisplayNumbers != nil {
+		parts = append(parts, fmt.Sprintf("display_numbers %t", *r.DisplayNumbers))
+	}
+	if r.AllowComments != nil {
+		parts = append(parts, fmt.Sprintf("allow_comments %t", *r.AllowComments))
+	}
+	if r.SortBy != "" {
+		parts = append(parts, "sort_by "+r.SortBy)
+	}
+	if r.SortHow != "" {
+		parts = append(parts, "sort_how "+r.SortHow)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func historyIDsSummary(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprint(id))
+	}
+	noun := "plays"
+	if len(ids) == 1 {
+		noun = "play"
+	}
+	return fmt.Sprintf("%d %s (%s)", len(ids), noun, strings.Join(parts, " "))
+}
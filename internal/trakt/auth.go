@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/maximilian/trakt-sync/internal/audit"
+	"github.com/maximilian/trakt-sync/internal/errcode"
 	"github.com/rs/zerolog/log"
 )
 
@@ -37,6 +39,8 @@ func (c *Client) PollForToken(deviceCode string, interval int, expiresIn int) (*
 
 	for {
 		select {
+		case <-c.ctx.Done():
+			return nil, c.ctx.Err()
 		case <-timeout:
 			return nil, fmt.Errorf("authorization timeout")
 		case <-ticker.C:
@@ -89,6 +93,9 @@ func (c *Client) requestToken(deviceCode string) (*TokenResponse, error) {
 	return &resp, nil
 }
 
+// errRefreshRejected is returned when Trakt refuses the refresh token
+var errRefreshRejected = errcode.New(errcode.TokensRejected, "refresh token rejected")
+
 // RefreshAccessToken refreshes the access token using the refresh token
 func (c *Client) RefreshAccessToken() (*TokenResponse, error) {
 	if c.refreshToken == "" {
@@ -102,7 +109,14 @@ func (c *Client) RefreshAccessToken() (*TokenResponse, error) {
 		"client_secret": c.clientSecret,
 		"grant_type":    "refresh_token",
 	}, &resp)
+	c.audit(audit.TokenRefresh, "", "", err)
 	if err != nil {
+		// Trakt answers a revoked or spent refresh token with 400
+		// invalid_grant; only a new device authorization helps then.
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+			return nil, fmt.Errorf("failed to refresh token: %w (%v)", errRefreshRejected, err)
+		}
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 
@@ -110,8 +124,7 @@ func (c *Client) RefreshAccessToken() (*TokenResponse, error) {
 	c.refreshToken = resp.RefreshToken
 
 	if c.onTokenRefresh != nil {
-		expiresAt := time.Unix(resp.CreatedAt, 0).Add(time.Duration(resp.ExpiresIn) * time.Second)
-		c.onTokenRefresh(resp.AccessToken, resp.RefreshToken, expiresAt)
+		c.onTokenRefresh(resp.AccessToken, resp.RefreshToken, resp.IssuedAt(), resp.ExpiresAt())
 	}
 
 	log.Info().Msg("Access token refreshed successfully")
@@ -0,0 +1,33 @@
+package trakt
+
+import (
+	"fmt"
+	"time"
+)
+
+// calendarDateFormat is the start date format of the calendar endpoints
+const calendarDateFormat = "2006-01-02"
+
+// GetShowPremieres returns shows whose first episode airs within days of
+// start. The chart filters of opts apply; the limit does not.
+func (c *Client) GetShowPremieres(start time.Time, days int, opts ChartOptions) ([]CalendarShow, error) {
+	var shows []CalendarShow
+	path := fmt.Sprintf("/calendars/all/shows/premieres/%s/%d?%s", start.UTC().Format(calendarDateFormat), days, opts.query())
+	_, err := c.doRequest("GET", path, nil, &shows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show premieres: %w", err)
+	}
+	return shows, nil
+}
+
+// GetMovieReleases returns movies released within days of start. The chart
+// filters of opts apply; the limit does not.
+func (c *Client) GetMovieReleases(start time.Time, days int, opts ChartOptions) ([]CalendarMovie, error) {
+	var movies []CalendarMovie
+	path := fmt.Sprintf("/calendars/all/movies/%s/%d?%s", start.UTC().Format(calendarDateFormat), days, opts.query())
+	_, err := c.doRequest("GET", path, nil, &movies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie releases: %w", err)
+	}
+	return movies, nil
+}
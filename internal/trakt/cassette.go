@@ -0,0 +1,247 @@
+package trakt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cassette is a sanitized recording of API interactions, used to replay a
+// session offline for bug reports and tests
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	Method       string            `json:"method"`
+	Path         string            `json:"path"`
+	RequestBody  json.RawMessage   `json:"request_body,omitempty"`
+	Status       int               `json:"status"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	ResponseBody json.RawMessage   `json:"response_body,omitempty"`
+}
+
+// redactedFields are JSON keys whose values never end up in a cassette
+var redactedFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"client_id":     true,
+	"client_secret": true,
+	"code":          true,
+	"device_code":   true,
+	"user_code":     true,
+}
+
+// recordedHeaders are the response headers the client relies on
+var recordedHeaders = []string{
+	"Content-Type",
+	"Retry-After",
+	"X-Pagination-Page",
+	"X-Pagination-Limit",
+	"X-Pagination-Page-Count",
+	"X-Pagination-Item-Count",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Reset",
+	"X-Sort-By",
+	"X-Sort-How",
+	"X-Applied-Sort-By",
+	"X-Applied-Sort-How",
+}
+
+// Recorder is an http.RoundTripper that forwards requests and appends each
+// interaction to a cassette file. The file is rewritten after every request
+// so a crashed run still leaves a usable recording.
+type Recorder struct {
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder writing to path
+func NewRecorder(path string) *Recorder {
+	return &Recorder{path: path, next: http.DefaultTransport}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Method:       req.Method,
+		Path:         req.URL.RequestURI(),
+		RequestBody:  sanitizeJSON(reqBody),
+		Status:       resp.StatusCode,
+		Headers:      make(map[string]string),
+		ResponseBody: sanitizeJSON(respBody),
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			interaction.Headers[name] = value
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := saveCassette(r.path, &r.cassette); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette.
+// Repeated requests for the same method and path are answered in recorded
+// order.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewReplayer loads the cassette at path
+func NewReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+
+	return &Replayer{cassette: cassette, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := req.URL.RequestURI()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Method != req.Method || interaction.Path != path {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header)
+		for name, value := range interaction.Headers {
+			header.Set(name, value)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(interaction.ResponseBody)),
+			ContentLength: int64(len(interaction.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("cassette has no recorded interaction for %s %s", req.Method, path)
+}
+
+// SetTransport replaces the HTTP transport, e.g. with a Recorder or Replayer
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// sanitizeJSON blanks credential fields anywhere in a JSON document. Non-JSON
+// bodies are dropped entirely rather than risk recording a secret.
+func sanitizeJSON(data []byte) json.RawMessage {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+
+	sanitized, err := json.Marshal(redact(value))
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[key] {
+				v[key] = "REDACTED"
+			} else {
+				v[key] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+func saveCassette(path string, cassette *Cassette) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
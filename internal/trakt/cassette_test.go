@@ -0,0 +1,62 @@
+package trakt_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/maximilian/trakt-sync/internal/trakttest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := trakttest.NewServer()
+	server.SeedList("user", "list", 1, 2, 3)
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recording := trakt.NewClient("secret-client-id", "secret-client-secret", "secret-access", "secret-refresh")
+	recording.SetBaseURL(server.URL)
+	recording.SetTransport(trakt.NewRecorder(path))
+
+	recorded, err := recording.GetListItems("user", "list")
+	if err != nil {
+		t.Fatalf("GetListItems: %v", err)
+	}
+	if _, err := recording.RefreshAccessToken(); err == nil {
+		t.Fatal("expected refresh with an unknown token to fail")
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-client-id", "secret-client-secret", "secret-access", "secret-refresh"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	replayer, err := trakt.NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer: %v", err)
+	}
+
+	replaying := trakt.NewClient("other", "other", "other", "other")
+	replaying.SetBaseURL(server.URL)
+	replaying.SetTransport(replayer)
+
+	replayed, err := replaying.GetListItems("user", "list")
+	if err != nil {
+		t.Fatalf("replayed GetListItems: %v", err)
+	}
+	if len(replayed) != len(recorded) {
+		t.Fatalf("replayed %d items, recorded %d", len(replayed), len(recorded))
+	}
+
+	if _, err := replaying.GetListItems("user", "list"); err == nil {
+		t.Fatal("expected an error once the recorded interactions are used up")
+	}
+}
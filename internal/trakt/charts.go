@@ -0,0 +1,83 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Periods of the most watched charts
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+	PeriodYearly  = "yearly"
+	PeriodAll     = "all"
+)
+
+// ChartOptions holds the query parameters shared by the chart endpoints
+type ChartOptions struct {
+	// Period is the time window of the most watched charts (default: weekly)
+	Period    string
+	Limit     int
+	MinRating int
+	// MinYear restricts the chart to titles released in or after this year
+	MinYear int
+	// Extended requests full metadata (genres, rating, ...) for each item
+	Extended bool
+	// Genres restricts the chart to these genre slugs, e.g. "anime"
+	Genres []string
+	// Certifications restricts the chart to these US certifications, e.g.
+	// "pg" for movies or "tv-pg" for shows
+	Certifications []string
+	// Languages restricts the chart to these two-letter original languages
+	Languages []string
+	// Countries restricts the chart to these two-letter country codes
+	Countries []string
+	// Networks restricts show charts to these networks, e.g. "Netflix"
+	Networks []string
+	// WatchNow restricts the chart to titles streaming on these services in
+	// Countries, e.g. "netflix"
+	WatchNow []string
+}
+
+func (o ChartOptions) period() string {
+	if o.Period == "" {
+		return PeriodWeekly
+	}
+	return o.Period
+}
+
+func (o ChartOptions) query() string {
+	query := fmt.Sprintf("limit=%d", o.Limit)
+	if o.MinRating > 0 {
+		query += fmt.Sprintf("&ratings=%d-100", o.MinRating)
+	}
+	if o.MinYear > 0 {
+		// Next year's titles are included for charts of upcoming releases.
+		query += fmt.Sprintf("&years=%d-%d", o.MinYear, time.Now().Year()+1)
+	}
+	if len(o.Genres) > 0 {
+		query += "&genres=" + url.QueryEscape(strings.Join(o.Genres, ","))
+	}
+	if len(o.Certifications) > 0 {
+		query += "&certifications=" + url.QueryEscape(strings.Join(o.Certifications, ","))
+	}
+	if len(o.Languages) > 0 {
+		query += "&languages=" + url.QueryEscape(strings.Join(o.Languages, ","))
+	}
+	if len(o.Countries) > 0 {
+		query += "&countries=" + url.QueryEscape(strings.Join(o.Countries, ","))
+	}
+	if len(o.Networks) > 0 {
+		query += "&networks=" + url.QueryEscape(strings.Join(o.Networks, ","))
+	}
+	if len(o.WatchNow) > 0 {
+		query += "&watchnow=" + url.QueryEscape(strings.Join(o.WatchNow, ","))
+	}
+	if o.Extended {
+		query += "&extended=full"
+	}
+	return query
+}
@@ -2,6 +2,7 @@ package trakt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,15 +10,19 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/metrics"
 	"github.com/rs/zerolog/log"
 )
 
 const (
-	BaseURL    = "https://api.trakt.tv"
-	APIVersion = "2"
+	BaseURL        = "https://api.trakt.tv"
+	StagingBaseURL = "https://api-staging.trakt.tv"
+	APIVersion     = "2"
 
 	maxRetries  = 3
 	baseBackoff = 500 * time.Millisecond
@@ -26,12 +31,19 @@ const (
 
 // Client is a Trakt API client
 type Client struct {
+	ctx            context.Context
 	httpClient     *http.Client
+	baseURL        string
 	clientID       string
 	clientSecret   string
 	accessToken    string
 	refreshToken   string
-	onTokenRefresh func(accessToken, refreshToken string, expiresAt time.Time)
+	onTokenRefresh func(accessToken, refreshToken string, issuedAt, expiresAt time.Time)
+	auditLog       string
+	auditFailed    bool
+	staging        bool
+	readOnly       bool
+	metrics        *metrics.Metrics
 
 	rateLimitRemaining int
 	rateLimitReset     time.Time
@@ -41,7 +53,9 @@ type Client struct {
 // NewClient creates a new Trakt API client
 func NewClient(clientID, clientSecret, accessToken, refreshToken string) *Client {
 	return &Client{
+		ctx:          context.Background(),
 		httpClient:   &http.Client{Timeout: 60 * time.Second},
+		baseURL:      BaseURL,
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		accessToken:  accessToken,
@@ -49,13 +63,74 @@ func NewClient(clientID, clientSecret, accessToken, refreshToken string) *Client
 	}
 }
 
+// SetBaseURL points the client at a different API host, e.g. a test server
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetContext makes every request of the client use ctx: once ctx is done,
+// in-flight requests are aborted and retry and rate limit waits end with the
+// context's error
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// SetMetrics counts the failed requests, rate limit waits and list writes
+// of the client in m
+func (c *Client) SetMetrics(m *metrics.Metrics) {
+	c.metrics = m
+}
+
+// SetReadOnly makes the client refuse every request that could change data
+// on Trakt with errcode.ErrReadOnly. Token requests are still sent.
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// UseStaging points the client at the Trakt staging API. Lists it creates
+// are tagged as staging lists in their description.
+func (c *Client) UseStaging() {
+	c.baseURL = StagingBaseURL
+	c.staging = true
+}
+
 // SetTokenRefreshCallback sets the callback function called when tokens are refreshed
-func (c *Client) SetTokenRefreshCallback(callback func(accessToken, refreshToken string, expiresAt time.Time)) {
+func (c *Client) SetTokenRefreshCallback(callback func(accessToken, refreshToken string, issuedAt, expiresAt time.Time)) {
 	c.onTokenRefresh = callback
 }
 
+// ServerTime returns the time of the Trakt API from the Date header of a
+// request, and the local time halfway through the request. The Date header
+// has a resolution of one second.
+func (c *Client) ServerTime() (server, local time.Time, err error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.baseURL+"/", nil)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("trakt-api-version", APIVersion)
+	req.Header.Set("trakt-api-key", c.clientID)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	local = start.Add(time.Since(start) / 2)
+
+	server, err = http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("response has no valid Date header: %w", err)
+	}
+	return server, local, nil
+}
+
 // doRequest performs an HTTP request with proper headers and retries
 func (c *Client) doRequest(method, path string, body interface{}, result interface{}) (*http.Response, error) {
+	if c.readOnly && method != http.MethodGet && !strings.HasPrefix(path, "/oauth/") {
+		return nil, fmt.Errorf("%s %s: %w", method, path, errcode.ErrReadOnly)
+	}
+
 	var bodyBytes []byte
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -68,6 +143,8 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 	var resp *http.Response
 	var err error
 	var retryAfter time.Duration
+	// rateLimited is set when Trakt answered 429 to the last attempt
+	var rateLimited bool
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
@@ -77,23 +154,37 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 			}
 			if delay > 0 {
 				log.Warn().Int("attempt", attempt+1).Dur("delay", delay).Msg("Retrying request")
-				time.Sleep(delay)
+				if rateLimited {
+					c.metrics.RateLimitWait(delay)
+				}
+				if err := sleepContext(c.ctx, delay); err != nil {
+					return resp, fmt.Errorf("%s %s: %w", method, path, err)
+				}
 			}
 		}
 
-		retryAfter = 0
-		c.waitForRateLimit()
+		retryAfter, rateLimited = 0, false
+		if err := c.waitForRateLimit(); err != nil {
+			return resp, fmt.Errorf("%s %s: %w", method, path, err)
+		}
 
 		resp, err = c.doRequestOnce(method, path, bodyBytes, result)
 		if err == nil {
 			return resp, nil
 		}
+		// An aborted request looks like a network error, but retrying it
+		// would only fail again.
+		if c.ctx.Err() != nil {
+			return resp, err
+		}
+		c.metrics.APIError(string(errcode.Of(err)))
 
 		var apiErr *APIError
 		if errors.As(err, &apiErr) {
 			if apiErr.RetryAfter > 0 {
 				retryAfter = apiErr.RetryAfter
 			}
+			rateLimited = apiErr.Status == http.StatusTooManyRequests
 			if apiErr.Status == http.StatusTooManyRequests || apiErr.Status >= 500 {
 				continue
 			}
@@ -116,7 +207,7 @@ func (c *Client) doRequestOnce(method, path string, body []byte, result interfac
 		reqBody = bytes.NewReader(body)
 	}
 
-	req, err := http.NewRequest(method, BaseURL+path, reqBody)
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -150,12 +241,14 @@ func (c *Client) doRequestOnce(method, path string, body []byte, result interfac
 				Code:        errResp.Error,
 				Description: errResp.ErrorDescription,
 				RetryAfter:  retryAfterDuration(resp.Header),
+				Unavailable: resp.StatusCode == http.StatusServiceUnavailable,
 			}
 		}
 		return resp, &APIError{
 			Status:      resp.StatusCode,
 			Description: string(respBody),
 			RetryAfter:  retryAfterDuration(resp.Header),
+			Unavailable: isUnavailable(resp, respBody),
 		}
 	}
 
@@ -168,7 +261,35 @@ func (c *Client) doRequestOnce(method, path string, body []byte, result interfac
 	return resp, nil
 }
 
-func (c *Client) waitForRateLimit() {
+// cloudflareMarkers appear in Cloudflare challenge and error pages
+var cloudflareMarkers = [][]byte{
+	[]byte("challenge-platform"),
+	[]byte("cf-chl-"),
+	[]byte("Attention Required! | Cloudflare"),
+}
+
+// isUnavailable reports whether a non-JSON error response means Trakt is
+// down for maintenance or Cloudflare answered in its place: 503, the
+// Cloudflare origin errors 520-530 or a challenge page
+func isUnavailable(resp *http.Response, body []byte) bool {
+	status := resp.StatusCode
+	if status == http.StatusServiceUnavailable || (status >= 520 && status <= 530) {
+		return true
+	}
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	for _, marker := range cloudflareMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForRateLimit waits for the rate limit to reset once it is exhausted.
+// It returns the context's error if the context ends first.
+func (c *Client) waitForRateLimit() error {
 	c.rateLimitMu.Lock()
 	remaining := c.rateLimitRemaining
 	reset := c.rateLimitReset
@@ -178,7 +299,21 @@ func (c *Client) waitForRateLimit() {
 	if remaining == 0 && !reset.IsZero() && time.Now().Before(reset) {
 		sleep := time.Until(reset)
 		log.Warn().Dur("delay", sleep).Msg("Rate limit reached, waiting for reset")
-		time.Sleep(sleep)
+		c.metrics.RateLimitWait(sleep)
+		return sleepContext(c.ctx, sleep)
+	}
+	return nil
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
@@ -0,0 +1,99 @@
+package trakt_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/maximilian/trakt-sync/internal/trakttest"
+)
+
+func TestUnavailableResponses(t *testing.T) {
+	challenge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<html><head><title>Just a moment...</title></head></html>`))
+	}))
+	defer challenge.Close()
+
+	maintenance := trakttest.NewServer()
+	defer maintenance.Close()
+	maintenance.Maintenance = true
+
+	for name, url := range map[string]string{"challenge": challenge.URL, "maintenance": maintenance.URL} {
+		client := trakt.NewClient("id", "secret", "", "")
+		client.SetBaseURL(url)
+
+		_, err := client.GetTrendingMovies(trakt.ChartOptions{Limit: 1})
+		var apiErr *trakt.APIError
+		if !errors.As(err, &apiErr) || !apiErr.Unavailable {
+			t.Errorf("%s: expected an unavailable API error, got %v", name, err)
+		}
+		if code := errcode.Of(err); code != errcode.Unavailable {
+			t.Errorf("%s: code = %s, want %s", name, code, errcode.Unavailable)
+		}
+	}
+}
+
+func TestReadOnlyBlocksWrites(t *testing.T) {
+	server := trakttest.NewServer()
+	defer server.Close()
+	server.SeedCatalog(3, 0)
+	server.SeedList("reader", "picks", 1)
+
+	client := trakt.NewClient("id", "secret", "", "")
+	client.SetBaseURL(server.URL)
+	client.SetReadOnly(true)
+
+	if _, err := client.GetListItems("reader", "picks"); err != nil {
+		t.Fatalf("read in read-only mode: %v", err)
+	}
+
+	requests := server.Requests()
+	err := client.AddItemsToList("reader", "picks", trakt.AddToListRequest{Movies: []trakt.AddMovie{{IDs: trakt.MediaIDs{Trakt: 2}}}})
+	if code := errcode.Of(err); code != errcode.ReadOnly {
+		t.Errorf("code = %s, want %s (err: %v)", code, errcode.ReadOnly, err)
+	}
+	if server.Requests() != requests {
+		t.Error("the blocked write reached the server")
+	}
+	if got := len(server.ListItems("reader", "picks")); got != 1 {
+		t.Errorf("list has %d items, want 1", got)
+	}
+}
+
+func TestContextEndsRateLimitWait(t *testing.T) {
+	server := trakttest.NewServer()
+	defer server.Close()
+	server.SeedList("reader", "picks", 1)
+	server.RateLimit = 1
+	server.RateLimitWindow = time.Hour
+
+	client := trakt.NewClient("id", "secret", "", "")
+	client.SetBaseURL(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	client.SetContext(ctx)
+
+	if _, err := client.GetListItems("reader", "picks"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	// The rate limit is exhausted for an hour; canceling ends the wait.
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := client.GetListItems("reader", "picks")
+	if !errors.Is(err, context.Canceled) || errcode.Of(err) != errcode.Canceled {
+		t.Errorf("err = %v, want a canceled error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request returned after %s", elapsed)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("server got %d requests, want 1", server.Requests())
+	}
+}
@@ -0,0 +1,17 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// GetCollection retrieves the authenticated user's collection. mediaType is
+// "movies" or "shows".
+func (c *Client) GetCollection(mediaType string) ([]CollectionItem, error) {
+	var items []CollectionItem
+	_, err := c.doRequest("GET", "/sync/collection/"+url.PathEscape(mediaType), nil, &items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return items, nil
+}
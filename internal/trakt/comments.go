@@ -0,0 +1,65 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/audit"
+)
+
+// commentsPageLimit is the page size used when fetching comments
+const commentsPageLimit = 100
+
+// Comment is a comment on a list, movie or show
+type Comment struct {
+	ID        int64       `json:"id"`
+	ParentID  int64       `json:"parent_id"`
+	Comment   string      `json:"comment"`
+	Spoiler   bool        `json:"spoiler"`
+	Review    bool        `json:"review"`
+	CreatedAt time.Time   `json:"created_at"`
+	Replies   int         `json:"replies"`
+	Likes     int         `json:"likes"`
+	User      CommentUser `json:"user"`
+}
+
+// CommentUser is the author of a comment
+type CommentUser struct {
+	Username string `json:"username"`
+	Private  bool   `json:"private"`
+	VIP      bool   `json:"vip"`
+}
+
+// GetListComments returns the comments on a list, newest first
+func (c *Client) GetListComments(username, listSlug string) ([]Comment, error) {
+	user := url.PathEscape(username)
+	slug := url.PathEscape(listSlug)
+
+	var all []Comment
+	for page := 1; ; page++ {
+		var comments []Comment
+		path := fmt.Sprintf("/users/%s/lists/%s/comments/newest?page=%d&limit=%d", user, slug, page, commentsPageLimit)
+		resp, err := c.doRequest("GET", path, nil, &comments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list comments: %w", err)
+		}
+		all = append(all, comments...)
+
+		pageCount := parsePaginationPageCount(resp.Header)
+		if pageCount == 0 || page >= pageCount {
+			return all, nil
+		}
+	}
+}
+
+// DeleteComment deletes a comment. Trakt refuses comments with replies and
+// may refuse comments the account did not write.
+func (c *Client) DeleteComment(id int64) error {
+	_, err := c.doRequest("DELETE", fmt.Sprintf("/comments/%d", id), nil, nil)
+	c.audit(audit.DeleteComment, "comments", fmt.Sprintf("comment %d", id), err)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/audit"
+)
+
+const historyPageLimit = 100
+
+// GetHistory retrieves the authenticated user's watch history. mediaType can
+// be "movies", "shows", "episodes" or empty for everything.
+func (c *Client) GetHistory(mediaType string) ([]HistoryItem, error) {
+	return c.GetRecentHistory(mediaType, time.Time{}, 0)
+}
+
+// GetRecentHistory retrieves the most recent plays first, optionally limited
+// to plays after since and to at most maxItems entries (0 = no limit)
+func (c *Client) GetRecentHistory(mediaType string, since time.Time, maxItems int) ([]HistoryItem, error) {
+	base := "/sync/history"
+	if mediaType != "" {
+		base += "/" + url.PathEscape(mediaType)
+	}
+
+	query := ""
+	if !since.IsZero() {
+		query = "&start_at=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+
+	var allItems []HistoryItem
+	page := 1
+
+	for {
+		var items []HistoryItem
+		path := fmt.Sprintf("%s?page=%d&limit=%d%s", base, page, historyPageLimit, query)
+		resp, err := c.doRequest("GET", path, nil, &items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get history: %w", err)
+		}
+
+		allItems = append(allItems, items...)
+
+		if maxItems > 0 && len(allItems) >= maxItems {
+			allItems = allItems[:maxItems]
+			break
+		}
+
+		pageCount := parsePaginationPageCount(resp.Header)
+		if pageCount == 0 || page >= pageCount {
+			break
+		}
+
+		page++
+	}
+
+	return allItems, nil
+}
+
+// RemoveHistoryEntries removes individual plays by their history IDs
+func (c *Client) RemoveHistoryEntries(historyIDs []int64) (*RemoveHistoryResponse, error) {
+	var resp RemoveHistoryResponse
+	_, err := c.doRequest("POST", "/sync/history/remove", RemoveHistoryRequest{IDs: historyIDs}, &resp)
+	c.audit(audit.RemoveHistory, "history", historyIDsSummary(historyIDs), err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove history entries: %w", err)
+	}
+	return &resp, nil
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/maximilian/trakt-sync/internal/audit"
 	"github.com/rs/zerolog/log"
 )
 
@@ -27,20 +30,96 @@ func (c *Client) GetList(username, listSlug string) (*List, error) {
 	return &list, nil
 }
 
+// GetPublicList retrieves a public list by its Trakt ID, without knowing its
+// owner, e.g. one of Trakt's official lists. A missing list yields nil.
+func (c *Client) GetPublicList(listID int) (*List, error) {
+	var list List
+	resp, err := c.doRequest("GET", fmt.Sprintf("/lists/%d", listID), nil, &list)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+	return &list, nil
+}
+
+// GetPublicListItems retrieves all items of a public list by its Trakt ID
+func (c *Client) GetPublicListItems(listID int) ([]ListItem, error) {
+	items, _, err := c.fetchListItems(fmt.Sprintf("/lists/%d/items", listID), false)
+	return items, err
+}
+
+// GetUserLists retrieves all lists of a user
+func (c *Client) GetUserLists(username string) ([]List, error) {
+	var lists []List
+	path := fmt.Sprintf("/users/%s/lists", url.PathEscape(username))
+	if _, err := c.doRequest("GET", path, nil, &lists); err != nil {
+		return nil, fmt.Errorf("failed to get user lists: %w", err)
+	}
+	return lists, nil
+}
+
 // GetListItems retrieves all items in a list
 func (c *Client) GetListItems(username, listSlug string) ([]ListItem, error) {
+	items, _, err := c.getListItems(username, listSlug, false)
+	return items, err
+}
+
+// GetListItemsExtended retrieves all items in a list with full metadata
+// (genres, rating, ...) for each movie and show
+func (c *Client) GetListItemsExtended(username, listSlug string) ([]ListItem, error) {
+	items, _, err := c.getListItems(username, listSlug, true)
+	return items, err
+}
+
+// GetListItemsInListOrder retrieves all items in a list in the order users
+// see on Trakt. Trakt reports the list's sort as X-Sort-By/How and the order
+// of the response as X-Applied-Sort-By/How; when they differ, the items are
+// sorted to match the list's sort where the item data allows it.
+func (c *Client) GetListItemsInListOrder(username, listSlug string, extended bool) ([]ListItem, ListSort, error) {
+	items, headers, err := c.getListItems(username, listSlug, extended)
+	if err != nil {
+		return nil, ListSort{}, err
+	}
+
+	listSort := ListSort{By: headers.Get("X-Sort-By"), How: headers.Get("X-Sort-How")}
+	applied := ListSort{By: headers.Get("X-Applied-Sort-By"), How: headers.Get("X-Applied-Sort-How")}
+	if listSort.By == "" {
+		return items, applied, nil
+	}
+	if listSort != applied {
+		SortListItems(items, listSort)
+	}
+	return items, listSort, nil
+}
+
+// getListItems fetches all pages of a list and returns the headers of the
+// first page, which carry the sort order
+func (c *Client) getListItems(username, listSlug string, extended bool) ([]ListItem, http.Header, error) {
 	user := url.PathEscape(username)
 	slug := url.PathEscape(listSlug)
+	return c.fetchListItems(fmt.Sprintf("/users/%s/lists/%s/items", user, slug), extended)
+}
 
+// fetchListItems fetches all pages of the list items at path
+func (c *Client) fetchListItems(path string, extended bool) ([]ListItem, http.Header, error) {
 	var allItems []ListItem
+	var headers http.Header
 	page := 1
 
 	for {
 		var items []ListItem
-		path := fmt.Sprintf("/users/%s/lists/%s/items?page=%d&limit=%d", user, slug, page, listItemsPageLimit)
-		resp, err := c.doRequest("GET", path, nil, &items)
+		pagePath := fmt.Sprintf("%s?page=%d&limit=%d", path, page, listItemsPageLimit)
+		if extended {
+			pagePath += "&extended=full"
+		}
+		resp, err := c.doRequest("GET", pagePath, nil, &items)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get list items: %w", err)
+			return nil, nil, fmt.Errorf("failed to get list items: %w", err)
+		}
+		if headers == nil {
+			headers = resp.Header
 		}
 
 		allItems = append(allItems, items...)
@@ -53,15 +132,51 @@ func (c *Client) GetListItems(username, listSlug string) ([]ListItem, error) {
 		page++
 	}
 
-	return allItems, nil
+	return allItems, headers, nil
 }
 
+// SortListItems sorts items by a Trakt list sort. Sorts that need data list
+// items don't carry (runtime, popularity, votes, ...) keep the given order.
+func SortListItems(items []ListItem, by ListSort) {
+	var less func(a, b ListItem) bool
+	switch by.By {
+	case "rank":
+		less = func(a, b ListItem) bool { return a.Rank < b.Rank }
+	case "added":
+		less = func(a, b ListItem) bool { return a.ListedAt.Before(b.ListedAt) }
+	case "title":
+		less = func(a, b ListItem) bool { return strings.ToLower(a.title()) < strings.ToLower(b.title()) }
+	case "released":
+		less = func(a, b ListItem) bool { return a.year() < b.year() }
+	default:
+		return
+	}
+
+	if by.How == "desc" {
+		sort.SliceStable(items, func(i, j int) bool { return less(items[j], items[i]) })
+	} else {
+		sort.SliceStable(items, func(i, j int) bool { return less(items[i], items[j]) })
+	}
+}
+
+// StagingListTag prefixes the description of lists created on staging
+const StagingListTag = "[staging]"
+
 // CreateList creates a new list
 func (c *Client) CreateList(username string, req CreateListRequest) (*List, error) {
+	if c.staging {
+		req.Description = strings.TrimSpace(StagingListTag + " " + req.Description)
+	}
+
 	var list List
 	user := url.PathEscape(username)
 	path := fmt.Sprintf("/users/%s/lists", user)
 	_, err := c.doRequest("POST", path, req, &list)
+	target := list.IDs.Slug
+	if target == "" {
+		target = req.Name
+	}
+	c.audit(audit.CreateList, target, req.summary(), err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create list: %w", err)
 	}
@@ -69,15 +184,33 @@ func (c *Client) CreateList(username string, req CreateListRequest) (*List, erro
 	return &list, nil
 }
 
+// UpdateList changes a list's settings. Trakt regenerates the slug when the
+// name changes, so callers should use the slug of the returned list.
+func (c *Client) UpdateList(username, listSlug string, req UpdateListRequest) (*List, error) {
+	var list List
+	user := url.PathEscape(username)
+	slug := url.PathEscape(listSlug)
+	path := fmt.Sprintf("/users/%s/lists/%s", user, slug)
+	_, err := c.doRequest("PUT", path, req, &list)
+	c.audit(audit.UpdateList, listSlug, req.summary(), err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update list: %w", err)
+	}
+	log.Info().Str("list", listSlug).Str("new_slug", list.IDs.Slug).Msg("Updated list")
+	return &list, nil
+}
+
 // AddItemsToList adds items to a list
 func (c *Client) AddItemsToList(username, listSlug string, req AddToListRequest) error {
 	user := url.PathEscape(username)
 	slug := url.PathEscape(listSlug)
 	path := fmt.Sprintf("/users/%s/lists/%s/items", user, slug)
 	_, err := c.doRequest("POST", path, req, nil)
+	c.audit(audit.AddItems, listSlug, req.summary(), err)
 	if err != nil {
 		return fmt.Errorf("failed to add items to list: %w", err)
 	}
+	c.metrics.ItemsAdded(len(req.Movies) + len(req.Shows))
 	return nil
 }
 
@@ -87,35 +220,39 @@ func (c *Client) RemoveItemsFromList(username, listSlug string, req RemoveFromLi
 	slug := url.PathEscape(listSlug)
 	path := fmt.Sprintf("/users/%s/lists/%s/items/remove", user, slug)
 	_, err := c.doRequest("POST", path, req, nil)
+	c.audit(audit.RemoveItems, listSlug, req.summary(), err)
 	if err != nil {
 		return fmt.Errorf("failed to remove items from list: %w", err)
 	}
+	c.metrics.ItemsRemoved(len(req.Movies) + len(req.Shows))
 	return nil
 }
 
-// EnsureListExists checks if a list exists and creates it if it doesn't
-func (c *Client) EnsureListExists(username, listSlug, listName, description, privacy string) error {
-	list, err := c.GetList(username, listSlug)
+// ReorderListItems sets the ranks of the items of a list. ids are list item
+// IDs in the new order; items that are left out keep their place after them.
+func (c *Client) ReorderListItems(username, listSlug string, ids []int64) error {
+	user := url.PathEscape(username)
+	slug := url.PathEscape(listSlug)
+	path := fmt.Sprintf("/users/%s/lists/%s/items/reorder", user, slug)
+	_, err := c.doRequest("POST", path, map[string][]int64{"rank": ids}, nil)
+	c.audit(audit.ReorderItems, listSlug, fmt.Sprintf("%d items", len(ids)), err)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to reorder list items: %w", err)
 	}
+	return nil
+}
 
-	if list == nil {
-		if privacy == "" {
-			privacy = "private"
-		}
-		_, err := c.CreateList(username, CreateListRequest{
-			Name:           listName,
-			Description:    description,
-			Privacy:        privacy,
-			DisplayNumbers: true,
-			AllowComments:  false,
-		})
-		if err != nil {
-			return err
-		}
+// DeleteList deletes a list including all of its items
+func (c *Client) DeleteList(username, listSlug string) error {
+	user := url.PathEscape(username)
+	slug := url.PathEscape(listSlug)
+	path := fmt.Sprintf("/users/%s/lists/%s", user, slug)
+	_, err := c.doRequest("DELETE", path, nil, nil)
+	c.audit(audit.DeleteList, listSlug, "", err)
+	if err != nil {
+		return fmt.Errorf("failed to delete list: %w", err)
 	}
-
+	log.Info().Str("list", listSlug).Msg("Deleted list")
 	return nil
 }
 
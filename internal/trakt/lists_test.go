@@ -0,0 +1,47 @@
+package trakt_test
+
+import (
+	"testing"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+	"github.com/maximilian/trakt-sync/internal/trakttest"
+)
+
+func TestGetListItemsInListOrder(t *testing.T) {
+	server := trakttest.NewServer()
+	defer server.Close()
+	server.SeedList("user", "list", 1, 2, 3)
+
+	client := trakt.NewClient("id", "secret", "", "")
+	client.SetBaseURL(server.URL)
+
+	items, sort, err := client.GetListItemsInListOrder("user", "list", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sort != (trakt.ListSort{By: "rank", How: "asc"}) || titles(items) != "Movie 1,Movie 2,Movie 3" {
+		t.Errorf("rank order: sort %+v, items %s", sort, titles(items))
+	}
+
+	if _, err := client.UpdateList("user", "list", trakt.UpdateListRequest{SortBy: "title", SortHow: "desc"}); err != nil {
+		t.Fatal(err)
+	}
+	items, sort, err = client.GetListItemsInListOrder("user", "list", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sort != (trakt.ListSort{By: "title", How: "desc"}) || titles(items) != "Movie 3,Movie 2,Movie 1" {
+		t.Errorf("title desc order: sort %+v, items %s", sort, titles(items))
+	}
+}
+
+func titles(items []trakt.ListItem) string {
+	result := ""
+	for i, item := range items {
+		if i > 0 {
+			result += ","
+		}
+		result += item.Movie.Title
+	}
+	return result
+}
@@ -3,12 +3,9 @@ package trakt
 import "fmt"
 
 // GetTrendingMovies returns trending movies filtered by minimum rating
-func (c *Client) GetTrendingMovies(limit int, minRating int) ([]TrendingMovie, error) {
+func (c *Client) GetTrendingMovies(opts ChartOptions) ([]TrendingMovie, error) {
 	var movies []TrendingMovie
-	path := fmt.Sprintf("/movies/trending?limit=%d", limit)
-	if minRating > 0 {
-		path += fmt.Sprintf("&ratings=%d-100", minRating)
-	}
+	path := "/movies/trending?" + opts.query()
 	_, err := c.doRequest("GET", path, nil, &movies)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trending movies: %w", err)
@@ -17,12 +14,9 @@ func (c *Client) GetTrendingMovies(limit int, minRating int) ([]TrendingMovie, e
 }
 
 // GetPopularMovies returns popular movies filtered by minimum rating
-func (c *Client) GetPopularMovies(limit int, minRating int) ([]Movie, error) {
+func (c *Client) GetPopularMovies(opts ChartOptions) ([]Movie, error) {
 	var movies []Movie
-	path := fmt.Sprintf("/movies/popular?limit=%d", limit)
-	if minRating > 0 {
-		path += fmt.Sprintf("&ratings=%d-100", minRating)
-	}
+	path := "/movies/popular?" + opts.query()
 	_, err := c.doRequest("GET", path, nil, &movies)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get popular movies: %w", err)
@@ -30,16 +24,41 @@ func (c *Client) GetPopularMovies(limit int, minRating int) ([]Movie, error) {
 	return movies, nil
 }
 
-// GetMostWatchedMovies returns most watched movies weekly filtered by minimum rating
-func (c *Client) GetMostWatchedMovies(limit int, minRating int) ([]WatchedMovie, error) {
+// GetMostWatchedMovies returns the most watched movies in opts.Period filtered by
+// minimum rating
+func (c *Client) GetMostWatchedMovies(opts ChartOptions) ([]WatchedMovie, error) {
 	var movies []WatchedMovie
-	path := fmt.Sprintf("/movies/watched/weekly?limit=%d", limit)
-	if minRating > 0 {
-		path += fmt.Sprintf("&ratings=%d-100", minRating)
-	}
+	path := "/movies/watched/" + opts.period() + "?" + opts.query()
 	_, err := c.doRequest("GET", path, nil, &movies)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get most watched movies: %w", err)
 	}
 	return movies, nil
 }
+
+// GetAnticipatedMovies returns the movies most added to lists ahead of their
+// release, filtered by minimum rating
+func (c *Client) GetAnticipatedMovies(opts ChartOptions) ([]AnticipatedMovie, error) {
+	var movies []AnticipatedMovie
+	path := "/movies/anticipated?" + opts.query()
+	_, err := c.doRequest("GET", path, nil, &movies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anticipated movies: %w", err)
+	}
+	return movies, nil
+}
+
+// GetBoxOfficeMovies returns the top 10 grossing movies in the US box office
+// last weekend. The chart takes no filters; only opts.Extended applies.
+func (c *Client) GetBoxOfficeMovies(opts ChartOptions) ([]BoxOfficeMovie, error) {
+	var movies []BoxOfficeMovie
+	path := "/movies/boxoffice"
+	if opts.Extended {
+		path += "?extended=full"
+	}
+	_, err := c.doRequest("GET", path, nil, &movies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get box office movies: %w", err)
+	}
+	return movies, nil
+}
@@ -0,0 +1,43 @@
+package trakt
+
+import "fmt"
+
+// maxRecommendations is the most recommendations Trakt returns per request
+const maxRecommendations = 100
+
+// recommendationsPath returns the path of the recommendations of mediaType.
+// The endpoints take no chart filters; only opts.Limit and opts.Extended
+// apply.
+func recommendationsPath(mediaType string, opts ChartOptions) string {
+	limit := opts.Limit
+	if limit <= 0 || limit > maxRecommendations {
+		limit = maxRecommendations
+	}
+	path := fmt.Sprintf("/recommendations/%s?limit=%d", mediaType, limit)
+	if opts.Extended {
+		path += "&extended=full"
+	}
+	return path
+}
+
+// GetRecommendedMovies returns the movies Trakt recommends to the
+// authenticated user, best match first
+func (c *Client) GetRecommendedMovies(opts ChartOptions) ([]Movie, error) {
+	var movies []Movie
+	_, err := c.doRequest("GET", recommendationsPath("movies", opts), nil, &movies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommended movies: %w", err)
+	}
+	return movies, nil
+}
+
+// GetRecommendedShows returns the shows Trakt recommends to the authenticated
+// user, best match first
+func (c *Client) GetRecommendedShows(opts ChartOptions) ([]Show, error) {
+	var shows []Show
+	_, err := c.doRequest("GET", recommendationsPath("shows", opts), nil, &shows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommended shows: %w", err)
+	}
+	return shows, nil
+}
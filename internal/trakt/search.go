@@ -0,0 +1,25 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// SearchResult is a movie or show found by an ID lookup
+type SearchResult struct {
+	Type  string `json:"type"`
+	Movie *Movie `json:"movie,omitempty"`
+	Show  *Show  `json:"show,omitempty"`
+}
+
+// LookupIMDB finds the movie or show with an IMDb ID. mediaType is "movie"
+// or "show". No results means Trakt does not know the ID.
+func (c *Client) LookupIMDB(imdbID, mediaType string) ([]SearchResult, error) {
+	path := fmt.Sprintf("/search/imdb/%s?type=%s", url.PathEscape(imdbID), url.QueryEscape(mediaType))
+
+	var results []SearchResult
+	if _, err := c.doRequest("GET", path, nil, &results); err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", imdbID, err)
+	}
+	return results, nil
+}
@@ -3,12 +3,9 @@ package trakt
 import "fmt"
 
 // GetTrendingShows returns trending shows filtered by minimum rating
-func (c *Client) GetTrendingShows(limit int, minRating int) ([]TrendingShow, error) {
+func (c *Client) GetTrendingShows(opts ChartOptions) ([]TrendingShow, error) {
 	var shows []TrendingShow
-	path := fmt.Sprintf("/shows/trending?limit=%d", limit)
-	if minRating > 0 {
-		path += fmt.Sprintf("&ratings=%d-100", minRating)
-	}
+	path := "/shows/trending?" + opts.query()
 	_, err := c.doRequest("GET", path, nil, &shows)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trending shows: %w", err)
@@ -17,12 +14,9 @@ func (c *Client) GetTrendingShows(limit int, minRating int) ([]TrendingShow, err
 }
 
 // GetPopularShows returns popular shows filtered by minimum rating
-func (c *Client) GetPopularShows(limit int, minRating int) ([]Show, error) {
+func (c *Client) GetPopularShows(opts ChartOptions) ([]Show, error) {
 	var shows []Show
-	path := fmt.Sprintf("/shows/popular?limit=%d", limit)
-	if minRating > 0 {
-		path += fmt.Sprintf("&ratings=%d-100", minRating)
-	}
+	path := "/shows/popular?" + opts.query()
 	_, err := c.doRequest("GET", path, nil, &shows)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get popular shows: %w", err)
@@ -30,16 +24,26 @@ func (c *Client) GetPopularShows(limit int, minRating int) ([]Show, error) {
 	return shows, nil
 }
 
-// GetMostWatchedShows returns most watched shows weekly filtered by minimum rating
-func (c *Client) GetMostWatchedShows(limit int, minRating int) ([]WatchedShow, error) {
+// GetMostWatchedShows returns the most watched shows in opts.Period filtered by
+// minimum rating
+func (c *Client) GetMostWatchedShows(opts ChartOptions) ([]WatchedShow, error) {
 	var shows []WatchedShow
-	path := fmt.Sprintf("/shows/watched/weekly?limit=%d", limit)
-	if minRating > 0 {
-		path += fmt.Sprintf("&ratings=%d-100", minRating)
-	}
+	path := "/shows/watched/" + opts.period() + "?" + opts.query()
 	_, err := c.doRequest("GET", path, nil, &shows)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get most watched shows: %w", err)
 	}
 	return shows, nil
 }
+
+// GetAnticipatedShows returns the shows most added to lists ahead of their
+// release, filtered by minimum rating
+func (c *Client) GetAnticipatedShows(opts ChartOptions) ([]AnticipatedShow, error) {
+	var shows []AnticipatedShow
+	path := "/shows/anticipated?" + opts.query()
+	_, err := c.doRequest("GET", path, nil, &shows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anticipated shows: %w", err)
+	}
+	return shows, nil
+}
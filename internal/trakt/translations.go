@@ -0,0 +1,85 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
+	"github.com/rs/zerolog/log"
+)
+
+// Translation is the localized title of a movie or show
+type Translation struct {
+	Title    string `json:"title"`
+	Overview string `json:"overview"`
+	Tagline  string `json:"tagline"`
+	Language string `json:"language"`
+	Country  string `json:"country"`
+}
+
+// GetTranslations retrieves the translations of a movie or show into a
+// language. mediaType is "movies" or "shows".
+func (c *Client) GetTranslations(mediaType string, traktID int, language string) ([]Translation, error) {
+	path := fmt.Sprintf("/%s/%d/translations/%s", url.PathEscape(mediaType), traktID, url.PathEscape(language))
+
+	var translations []Translation
+	if _, err := c.doRequest("GET", path, nil, &translations); err != nil {
+		return nil, fmt.Errorf("failed to get translations: %w", err)
+	}
+	return translations, nil
+}
+
+// Localizer replaces titles of list items with their translation into one
+// language. Translations are cached, so each title is looked up once.
+type Localizer struct {
+	client   *Client
+	language string
+	cache    map[string]string
+}
+
+// NewLocalizer returns a localizer for language. An empty language yields a
+// localizer that keeps the original titles.
+func NewLocalizer(client *Client, language string) *Localizer {
+	return &Localizer{client: client, language: language, cache: make(map[string]string)}
+}
+
+// Localize replaces movie and show titles in place. Items without a
+// translation, or whose lookup fails, keep their original title.
+func (l *Localizer) Localize(items []ListItem) {
+	if l == nil || l.language == "" {
+		return
+	}
+	for i := range items {
+		switch {
+		case items[i].Movie != nil:
+			movie := *items[i].Movie
+			movie.Title = l.title("movies", movie.IDs.Trakt, movie.Title)
+			items[i].Movie = &movie
+		case items[i].Show != nil:
+			show := *items[i].Show
+			show.Title = l.title("shows", show.IDs.Trakt, show.Title)
+			items[i].Show = &show
+		}
+	}
+}
+
+func (l *Localizer) title(mediaType string, traktID int, original string) string {
+	key := fmt.Sprintf("%s/%d", mediaType, traktID)
+	if title, ok := l.cache[key]; ok {
+		return title
+	}
+
+	title := original
+	translations, err := l.client.GetTranslations(mediaType, traktID, l.language)
+	if err != nil {
+		errcode.Log(log.Debug(), err).Str("item", key).Msg("No translated title, keeping the original")
+	}
+	for _, translation := range translations {
+		if translation.Title != "" {
+			title = translation.Title
+			break
+		}
+	}
+	l.cache[key] = title
+	return title
+}
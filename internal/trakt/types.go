@@ -2,7 +2,10 @@ package trakt
 
 import (
 	"fmt"
+	"net/http"
 	"time"
+
+	"github.com/maximilian/trakt-sync/internal/errcode"
 )
 
 // DeviceCodeResponse represents the response from the device code endpoint
@@ -24,11 +27,29 @@ type TokenResponse struct {
 	CreatedAt    int64  `json:"created_at"`
 }
 
+// IssuedAt is when Trakt issued the tokens, by the Trakt clock
+func (t TokenResponse) IssuedAt() time.Time {
+	return time.Unix(t.CreatedAt, 0)
+}
+
+// ExpiresAt is when the access token expires, by the Trakt clock
+func (t TokenResponse) ExpiresAt() time.Time {
+	return t.IssuedAt().Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
 // Movie represents a Trakt movie
 type Movie struct {
 	Title string   `json:"title"`
 	Year  int      `json:"year"`
 	IDs   MediaIDs `json:"ids"`
+	// Genres, Rating, Certification, Language and Country are only populated
+	// when extended info is requested
+	Genres        []string `json:"genres,omitempty"`
+	Rating        float64  `json:"rating,omitempty"`
+	Certification string   `json:"certification,omitempty"`
+	// Language and Country are the original language and country of origin
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
 }
 
 // Show represents a Trakt show
@@ -36,6 +57,14 @@ type Show struct {
 	Title string   `json:"title"`
 	Year  int      `json:"year"`
 	IDs   MediaIDs `json:"ids"`
+	// Genres, Rating, Certification, Language and Country are only populated
+	// when extended info is requested
+	Genres        []string `json:"genres,omitempty"`
+	Rating        float64  `json:"rating,omitempty"`
+	Certification string   `json:"certification,omitempty"`
+	// Language and Country are the original language and country of origin
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
 }
 
 // MediaIDs contains various IDs for media items
@@ -44,6 +73,8 @@ type MediaIDs struct {
 	Slug  string `json:"slug"`
 	IMDB  string `json:"imdb,omitempty"`
 	TMDB  int    `json:"tmdb,omitempty"`
+	// TVDB is set for shows and episodes
+	TVDB int `json:"tvdb,omitempty"`
 }
 
 // TrendingMovie wraps a movie with trending data
@@ -58,6 +89,24 @@ type TrendingShow struct {
 	Show     Show `json:"show"`
 }
 
+// BoxOfficeMovie wraps a movie with its weekend revenue in USD
+type BoxOfficeMovie struct {
+	Revenue int   `json:"revenue"`
+	Movie   Movie `json:"movie"`
+}
+
+// AnticipatedMovie wraps a movie with the number of lists it was added to
+type AnticipatedMovie struct {
+	ListCount int   `json:"list_count"`
+	Movie     Movie `json:"movie"`
+}
+
+// AnticipatedShow wraps a show with the number of lists it was added to
+type AnticipatedShow struct {
+	ListCount int  `json:"list_count"`
+	Show      Show `json:"show"`
+}
+
 // WatchedMovie wraps a movie with watch count
 type WatchedMovie struct {
 	WatcherCount   int   `json:"watcher_count"`
@@ -74,6 +123,19 @@ type WatchedShow struct {
 	Show           Show `json:"show"`
 }
 
+// CalendarShow is a show premiere from the calendar
+type CalendarShow struct {
+	FirstAired time.Time `json:"first_aired"`
+	Show       Show      `json:"show"`
+}
+
+// CalendarMovie is a movie release from the calendar. Released is a date
+// (YYYY-MM-DD).
+type CalendarMovie struct {
+	Released string `json:"released"`
+	Movie    Movie  `json:"movie"`
+}
+
 // List represents a Trakt list
 type List struct {
 	Name           string    `json:"name"`
@@ -99,11 +161,40 @@ type ListIDs struct {
 
 // ListItem represents an item in a list
 type ListItem struct {
+	// ID identifies the entry within the list, for reordering
+	ID       int64     `json:"id"`
 	Rank     int       `json:"rank"`
 	ListedAt time.Time `json:"listed_at"`
 	Type     string    `json:"type"`
 	Movie    *Movie    `json:"movie,omitempty"`
 	Show     *Show     `json:"show,omitempty"`
+	Notes    string    `json:"notes,omitempty"`
+}
+
+func (i ListItem) title() string {
+	switch {
+	case i.Movie != nil:
+		return i.Movie.Title
+	case i.Show != nil:
+		return i.Show.Title
+	}
+	return ""
+}
+
+func (i ListItem) year() int {
+	switch {
+	case i.Movie != nil:
+		return i.Movie.Year
+	case i.Show != nil:
+		return i.Show.Year
+	}
+	return 0
+}
+
+// ListSort is a sort order of list items, e.g. {By: "rank", How: "asc"}
+type ListSort struct {
+	By  string
+	How string
 }
 
 // AddToListRequest represents items to add to a list
@@ -112,14 +203,17 @@ type AddToListRequest struct {
 	Shows  []AddShow  `json:"shows,omitempty"`
 }
 
-// AddMovie represents a movie to add to a list
+// AddMovie represents a movie to add to a list. Notes are shown with the item
+// on Trakt and require VIP.
 type AddMovie struct {
-	IDs MediaIDs `json:"ids"`
+	IDs   MediaIDs `json:"ids"`
+	Notes string   `json:"notes,omitempty"`
 }
 
 // AddShow represents a show to add to a list
 type AddShow struct {
-	IDs MediaIDs `json:"ids"`
+	IDs   MediaIDs `json:"ids"`
+	Notes string   `json:"notes,omitempty"`
 }
 
 // RemoveFromListRequest represents items to remove from a list
@@ -145,6 +239,52 @@ type CreateListRequest struct {
 	Privacy        string `json:"privacy"`
 	DisplayNumbers bool   `json:"display_numbers"`
 	AllowComments  bool   `json:"allow_comments"`
+	SortBy         string `json:"sort_by,omitempty"`
+	SortHow        string `json:"sort_how,omitempty"`
+}
+
+// UpdateListRequest represents a request to update a list. Empty fields are
+// left unchanged.
+type UpdateListRequest struct {
+	Name           string `json:"name,omitempty"`
+	Description    string `json:"description,omitempty"`
+	Privacy        string `json:"privacy,omitempty"`
+	DisplayNumbers *bool  `json:"display_numbers,omitempty"`
+	AllowComments  *bool  `json:"allow_comments,omitempty"`
+	SortBy         string `json:"sort_by,omitempty"`
+	SortHow        string `json:"sort_how,omitempty"`
+}
+
+// Episode represents a Trakt episode
+type Episode struct {
+	Season int      `json:"season"`
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	IDs    MediaIDs `json:"ids"`
+}
+
+// HistoryItem represents a single play in the user's watch history
+type HistoryItem struct {
+	ID        int64     `json:"id"`
+	WatchedAt time.Time `json:"watched_at"`
+	Action    string    `json:"action"`
+	Type      string    `json:"type"`
+	Movie     *Movie    `json:"movie,omitempty"`
+	Show      *Show     `json:"show,omitempty"`
+	Episode   *Episode  `json:"episode,omitempty"`
+}
+
+// RemoveHistoryRequest removes plays by history ID
+type RemoveHistoryRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// RemoveHistoryResponse reports how many plays were removed
+type RemoveHistoryResponse struct {
+	Deleted struct {
+		Movies   int `json:"movies"`
+		Episodes int `json:"episodes"`
+	} `json:"deleted"`
 }
 
 // ErrorResponse represents an error from the Trakt API
@@ -159,6 +299,9 @@ type APIError struct {
 	Code        string
 	Description string
 	RetryAfter  time.Duration
+	// Unavailable is set when Trakt is down for maintenance or Cloudflare
+	// answered with an error or challenge page instead of the API
+	Unavailable bool
 }
 
 func (e *APIError) Error() string {
@@ -170,3 +313,60 @@ func (e *APIError) Error() string {
 	}
 	return fmt.Sprintf("API error: status %d", e.Status)
 }
+
+// ErrorCode returns the stable code for the response status
+func (e *APIError) ErrorCode() errcode.Code {
+	if e == nil {
+		return ""
+	}
+	if e.Unavailable {
+		return errcode.Unavailable
+	}
+	switch status := e.Status; {
+	case status == http.StatusUnauthorized:
+		return errcode.TokensRejected
+	case status == http.StatusForbidden:
+		return errcode.Forbidden
+	case status == http.StatusNotFound:
+		return errcode.NotFound
+	case status == 420:
+		return errcode.AccountLimit
+	case status == http.StatusLocked:
+		return errcode.AccountLocked
+	case status == http.StatusTooManyRequests:
+		return errcode.RateLimited
+	case status >= 500:
+		return errcode.ServerError
+	case status >= 400:
+		return errcode.RequestRejected
+	}
+	return ""
+}
+
+// RatedItem represents an entry in a user's ratings
+type RatedItem struct {
+	RatedAt time.Time `json:"rated_at"`
+	Rating  int       `json:"rating"`
+	Type    string    `json:"type"`
+	Movie   *Movie    `json:"movie,omitempty"`
+	Show    *Show     `json:"show,omitempty"`
+}
+
+// CollectionItem represents a movie or show in the user's collection. Shows
+// carry the time an episode was last collected.
+type CollectionItem struct {
+	CollectedAt     time.Time `json:"collected_at"`
+	LastCollectedAt time.Time `json:"last_collected_at"`
+	Movie           *Movie    `json:"movie,omitempty"`
+	Show            *Show     `json:"show,omitempty"`
+}
+
+// WatchlistItem represents an entry in the user's watchlist
+type WatchlistItem struct {
+	Rank     int       `json:"rank"`
+	ID       int64     `json:"id"`
+	ListedAt time.Time `json:"listed_at"`
+	Type     string    `json:"type"`
+	Movie    *Movie    `json:"movie,omitempty"`
+	Show     *Show     `json:"show,omitempty"`
+}
@@ -0,0 +1,30 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GetUserRatings returns the user's ratings of the given type ("movies" or
+// "shows") with a rating of at least minRating (1-10)
+func (c *Client) GetUserRatings(username, mediaType string, minRating int) ([]RatedItem, error) {
+	user := url.PathEscape(username)
+	path := fmt.Sprintf("/users/%s/ratings/%s", user, url.PathEscape(mediaType))
+
+	if minRating > 1 && minRating <= 10 {
+		ratings := make([]string, 0, 11-minRating)
+		for r := minRating; r <= 10; r++ {
+			ratings = append(ratings, strconv.Itoa(r))
+		}
+		path += "/" + strings.Join(ratings, ",")
+	}
+
+	var items []RatedItem
+	_, err := c.doRequest("GET", path, nil, &items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user ratings: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,34 @@
+package trakt
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/maximilian/trakt-sync/internal/audit"
+)
+
+// GetWatchlist retrieves the authenticated user's watchlist. mediaType can be
+// "movies", "shows" or empty for everything.
+func (c *Client) GetWatchlist(mediaType string) ([]WatchlistItem, error) {
+	path := "/sync/watchlist"
+	if mediaType != "" {
+		path += "/" + url.PathEscape(mediaType)
+	}
+
+	var items []WatchlistItem
+	_, err := c.doRequest("GET", path, nil, &items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+	return items, nil
+}
+
+// RemoveFromWatchlist removes movies and shows from the user's watchlist
+func (c *Client) RemoveFromWatchlist(req RemoveFromListRequest) error {
+	_, err := c.doRequest("POST", "/sync/watchlist/remove", req, nil)
+	c.audit(audit.RemoveWatchlist, "watchlist", req.summary(), err)
+	if err != nil {
+		return fmt.Errorf("failed to remove items from watchlist: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1265 @@
+// Package trakttest provides an in-memory fake of the Trakt API endpoints used
+// by trakt-sync (device auth, token refresh, charts, calendars and list
+// management), for end-to-end tests, benchmarks and local development without
+// real credentials.
+package trakttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maximilian/trakt-sync/internal/config"
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+// Server is a fake Trakt API backed by in-memory state
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	movies []trakt.Movie
+	shows  []trakt.Show
+	lists  map[string]*fakeList
+	// itemSeq numbers list items across lists, like Trakt's list item IDs
+	itemSeq int64
+
+	watchlist []trakt.WatchlistItem
+	history   []trakt.HistoryItem
+	// collection holds the collected movies and shows of the authenticated user
+	collection []trakt.CollectionItem
+
+	devices       map[string]bool // device code -> approved
+	accessTokens  map[string]bool
+	refreshTokens map[string]bool
+	tokenSeq      int
+
+	requests  int
+	throttled int
+
+	// AutoApprove authorizes device codes on the first poll. Without it,
+	// polls return authorization_pending until ApproveDevice is called.
+	AutoApprove bool
+	// RequireAuth rejects user endpoints without a token issued by this server
+	RequireAuth bool
+	// Maintenance answers every request with Trakt's 503 maintenance page
+	Maintenance bool
+	// DropWrites closes the connection on every request adding or removing
+	// list items, as if the network went down mid-run
+	DropWrites bool
+	// OnItemsAdded is called after items were added to a list, e.g. to stop a
+	// run at a known point
+	OnItemsAdded func(user, slug string)
+
+	// RateLimit allows this many requests per RateLimitWindow (0 = unlimited)
+	RateLimit       int
+	RateLimitWindow time.Duration
+	windowStart     time.Time
+	windowRequests  int
+}
+
+type fakeList struct {
+	list     trakt.List
+	items    []trakt.ListItem
+	comments []trakt.Comment
+}
+
+// NewServer starts a fake Trakt API server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		lists:           make(map[string]*fakeList),
+		devices:         make(map[string]bool),
+		accessTokens:    make(map[string]bool),
+		refreshTokens:   make(map[string]bool),
+		RateLimitWindow: time.Second,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// SeedCatalog fills the chart catalog with generated movies and shows. Movie
+// Trakt IDs start at 1, show IDs at 1_000_000.
+func (s *Server) SeedCatalog(movies, shows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.movies = make([]trakt.Movie, 0, movies)
+	for i := 1; i <= movies; i++ {
+		s.movies = append(s.movies, Movie(i))
+	}
+
+	s.shows = make([]trakt.Show, 0, shows)
+	for i := 1; i <= shows; i++ {
+		s.shows = append(s.shows, Show(1_000_000+i))
+	}
+}
+
+// Movie returns the generated movie with the given Trakt ID
+func Movie(id int) trakt.Movie {
+	return trakt.Movie{
+		Title:         fmt.Sprintf("Movie %d", id),
+		Year:          1970 + id%55,
+		IDs:           trakt.MediaIDs{Trakt: id, Slug: fmt.Sprintf("movie-%d", id), IMDB: fmt.Sprintf("tt%07d", id), TMDB: id + 500},
+		Genres:        []string{genres[id%len(genres)]},
+		Rating:        float64(50+id%50) / 10,
+		Certification: movieCertifications[id%len(movieCertifications)],
+		Language:      languages[id%len(languages)],
+		Country:       countries[id%len(countries)],
+	}
+}
+
+// Show returns the generated show with the given Trakt ID
+func Show(id int) trakt.Show {
+	return trakt.Show{
+		Title:         fmt.Sprintf("Show %d", id),
+		Year:          1970 + id%55,
+		IDs:           trakt.MediaIDs{Trakt: id, Slug: fmt.Sprintf("show-%d", id), IMDB: fmt.Sprintf("tt%07d", id), TMDB: id + 500, TVDB: id + 900},
+		Genres:        []string{genres[id%len(genres)]},
+		Rating:        float64(50+id%50) / 10,
+		Certification: showCertifications[id%len(showCertifications)],
+		Language:      languages[id%len(languages)],
+		Country:       countries[id%len(countries)],
+	}
+}
+
+// WatchNow returns the streaming service a generated title is available on,
+// the same in every country
+func WatchNow(id int) string {
+	return streamingServices[id%len(streamingServices)]
+}
+
+var streamingServices = []string{"netflix", "disney_plus", "amazon_prime"}
+
+var (
+	movieCertifications = []string{"g", "pg", "pg-13", "r"}
+	showCertifications  = []string{"tv-y", "tv-g", "tv-pg", "tv-14", "tv-ma"}
+)
+
+var genres = []string{"action", "anime", "comedy", "drama", "horror", "science-fiction"}
+
+var (
+	languages = []string{"en", "de", "fr", "ja"}
+	countries = []string{"us", "de", "fr", "jp"}
+)
+
+// SeedList creates a list for user that already contains the given movies
+func (s *Server) SeedList(user, slug string, movieIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.createList(user, slug, trakt.CreateListRequest{Name: slug, Privacy: "private"})
+	for _, id := range movieIDs {
+		movie := Movie(id)
+		l.items = append(l.items, trakt.ListItem{Type: "movie", Movie: &movie})
+	}
+	s.renumber(l)
+}
+
+// SeedWatchlist adds the given movies to the watchlist of the authenticated user
+func (s *Server) SeedWatchlist(movieIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range movieIDs {
+		movie := Movie(id)
+		s.watchlist = append(s.watchlist, trakt.WatchlistItem{
+			Rank:     len(s.watchlist) + 1,
+			ID:       int64(len(s.watchlist) + 1),
+			ListedAt: time.Now().UTC(),
+			Type:     "movie",
+			Movie:    &movie,
+		})
+	}
+}
+
+// RevokeTokens invalidates every access and refresh token issued so far, as
+// if the user revoked the app on trakt.tv
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessTokens = make(map[string]bool)
+	s.refreshTokens = make(map[string]bool)
+}
+
+// SeedHistory records a play of each of the given movies in the watch
+// history of the authenticated user
+func (s *Server) SeedHistory(movieIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range movieIDs {
+		movie := Movie(id)
+		s.history = append(s.history, trakt.HistoryItem{
+			ID:        int64(len(s.history) + 1),
+			WatchedAt: time.Now().UTC(),
+			Action:    "watch",
+			Type:      "movie",
+			Movie:     &movie,
+		})
+	}
+}
+
+// SeedCollection adds the given movies to the collection of the
+// authenticated user
+func (s *Server) SeedCollection(movieIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range movieIDs {
+		movie := Movie(id)
+		s.collection = append(s.collection, trakt.CollectionItem{
+			CollectedAt: time.Now().UTC(),
+			Movie:       &movie,
+		})
+	}
+}
+
+// Watchlist returns a copy of the watchlist of the authenticated user
+func (s *Server) Watchlist() []trakt.WatchlistItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]trakt.WatchlistItem(nil), s.watchlist...)
+}
+
+// EditList adds and removes movies like a user editing the list on the
+// website would
+func (s *Server) EditList(user, slug string, add, remove []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		return
+	}
+
+	removed := make(map[int]bool)
+	for _, id := range remove {
+		removed[id] = true
+	}
+	kept := l.items[:0]
+	for _, item := range l.items {
+		if item.Movie == nil || !removed[item.Movie.IDs.Trakt] {
+			kept = append(kept, item)
+		}
+	}
+	l.items = kept
+	for _, id := range add {
+		movie := Movie(id)
+		l.items = append(l.items, trakt.ListItem{Type: "movie", Movie: &movie})
+	}
+	s.renumber(l)
+}
+
+// ListItems returns a copy of the items currently in a list
+func (s *Server) ListItems(user, slug string) []trakt.ListItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		return nil
+	}
+	return append([]trakt.ListItem(nil), l.items...)
+}
+
+// List returns the metadata of a list
+func (s *Server) List(user, slug string) (trakt.List, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		return trakt.List{}, false
+	}
+	return l.list, true
+}
+
+// SetListEngagement sets the likes and comment count of a list
+func (s *Server) SetListEngagement(user, slug string, likes, comments int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.lists[listKey(user, slug)]; ok {
+		l.list.Likes = likes
+		l.list.CommentCount = comments
+	}
+}
+
+// AddComments adds comments to a list. Like Trakt, the server refuses to
+// delete comments with replies.
+func (s *Server) AddComments(user, slug string, comments ...trakt.Comment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		return
+	}
+	l.comments = append(l.comments, comments...)
+	l.list.CommentCount = len(l.comments)
+}
+
+// Comments returns the comments of a list
+func (s *Server) Comments(user, slug string) []trakt.Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		return nil
+	}
+	return append([]trakt.Comment(nil), l.comments...)
+}
+
+// HasList reports whether the user has a list with the given slug
+func (s *Server) HasList(user, slug string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.lists[listKey(user, slug)]
+	return ok
+}
+
+// Requests returns the number of requests served
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Throttled returns the number of requests rejected with 429
+func (s *Server) Throttled() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.throttled
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.Maintenance {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html><body><h1>Trakt is down for scheduled maintenance</h1></body></html>")
+		return
+	}
+	if !s.allowRequest(w) {
+		s.throttled++
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if s.DropWrites && r.Method == http.MethodPost && len(parts) >= 5 && parts[0] == "users" && parts[4] == "items" {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+
+	switch {
+	case len(parts) >= 2 && parts[0] == "oauth" && r.Method == http.MethodPost:
+		s.handleOAuth(w, r, parts[1:])
+	case (parts[0] == "sync" || parts[0] == "recommendations" || parts[0] == "comments" || len(parts) >= 3 && parts[0] == "users") && !s.authorized(r):
+		writeError(w, http.StatusUnauthorized, "invalid or missing access token")
+	case len(parts) == 4 && (parts[0] == "movies" || parts[0] == "shows") && parts[2] == "translations" && r.Method == http.MethodGet:
+		s.handleTranslations(w, parts)
+	case len(parts) >= 2 && (parts[0] == "movies" || parts[0] == "shows") && r.Method == http.MethodGet:
+		s.handleChart(w, r, parts)
+	case len(parts) == 2 && parts[0] == "recommendations" && r.Method == http.MethodGet:
+		s.handleRecommendations(w, r, parts[1])
+	case len(parts) >= 5 && parts[0] == "calendars" && parts[1] == "all" && r.Method == http.MethodGet:
+		s.handleCalendar(w, r, parts)
+	case len(parts) == 3 && parts[0] == "search" && parts[1] == "imdb" && r.Method == http.MethodGet:
+		s.handleIMDBLookup(w, r, parts[2])
+	case len(parts) >= 2 && len(parts) <= 3 && parts[0] == "lists" && r.Method == http.MethodGet:
+		s.handlePublicList(w, r, parts[1:])
+	case len(parts) == 3 && parts[0] == "users" && parts[2] == "lists" && r.Method == http.MethodGet:
+		s.handleUserLists(w, parts[1])
+	case len(parts) >= 3 && parts[0] == "users" && parts[2] == "lists":
+		s.handleLists(w, r, parts[1], parts[3:])
+	case len(parts) == 2 && parts[0] == "comments" && r.Method == http.MethodDelete:
+		s.handleDeleteComment(w, parts[1])
+	case len(parts) >= 2 && parts[0] == "sync" && parts[1] == "watchlist" && r.Method == http.MethodGet:
+		s.handleWatchlist(w, parts[2:])
+	case len(parts) == 3 && parts[0] == "sync" && parts[1] == "watchlist" && parts[2] == "remove" && r.Method == http.MethodPost:
+		s.handleWatchlistRemove(w, r)
+	case len(parts) == 3 && parts[0] == "sync" && parts[1] == "collection" && r.Method == http.MethodGet:
+		s.handleCollection(w, parts[2])
+	case len(parts) >= 2 && parts[0] == "sync" && parts[1] == "history" && r.Method == http.MethodGet:
+		s.handleHistory(w, parts[2:])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// allowRequest applies the configured rate limit and sets the rate limit
+// headers the client uses to pace itself
+func (s *Server) allowRequest(w http.ResponseWriter) bool {
+	if s.RateLimit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if s.windowStart.IsZero() || now.Sub(s.windowStart) >= s.RateLimitWindow {
+		s.windowStart = now
+		s.windowRequests = 0
+	}
+
+	s.windowRequests++
+	remaining := s.RateLimit - s.windowRequests
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	reset := s.windowStart.Add(s.RateLimitWindow).Sub(now)
+	resetSeconds := int((reset + time.Second - 1) / time.Second)
+	w.Header().Set("X-Ratelimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-Ratelimit-Reset", strconv.Itoa(resetSeconds))
+
+	if s.windowRequests > s.RateLimit {
+		w.Header().Set("Retry-After", strconv.Itoa(resetSeconds))
+		return false
+	}
+	return true
+}
+
+// ApproveDevice authorizes a pending device code, as if the user entered its
+// user code on the Trakt website
+func (s *Server) ApproveDevice(deviceCode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.devices[deviceCode]; ok {
+		s.devices[deviceCode] = true
+	}
+}
+
+// IssueToken returns a fresh access/refresh token pair accepted by the server
+func (s *Server) IssueToken() trakt.TokenResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueToken()
+}
+
+func (s *Server) issueToken() trakt.TokenResponse {
+	s.tokenSeq++
+	token := trakt.TokenResponse{
+		AccessToken:  fmt.Sprintf("access-%d", s.tokenSeq),
+		RefreshToken: fmt.Sprintf("refresh-%d", s.tokenSeq),
+		TokenType:    "bearer",
+		ExpiresIn:    7776000,
+		Scope:        "public",
+		CreatedAt:    time.Now().Unix(),
+	}
+	s.accessTokens[token.AccessToken] = true
+	s.refreshTokens[token.RefreshToken] = true
+	return token
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if !s.RequireAuth {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return s.accessTokens[token]
+}
+
+func (s *Server) handleOAuth(w http.ResponseWriter, r *http.Request, parts []string) {
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch strings.Join(parts, "/") {
+	case "device/code":
+		deviceCode := fmt.Sprintf("device-%d", len(s.devices)+1)
+		userCode := fmt.Sprintf("USER%04d", len(s.devices)+1)
+		s.devices[deviceCode] = false
+		writeJSON(w, http.StatusOK, trakt.DeviceCodeResponse{
+			DeviceCode:      deviceCode,
+			UserCode:        userCode,
+			VerificationURL: s.URL + "/activate",
+			ExpiresIn:       600,
+			Interval:        1,
+		})
+	case "device/token":
+		approved, ok := s.devices[req["code"]]
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "invalid_grant", "unknown device code")
+			return
+		}
+		if !approved && !s.AutoApprove {
+			writeJSONError(w, http.StatusBadRequest, "authorization_pending", "waiting for the user to authorize")
+			return
+		}
+		delete(s.devices, req["code"])
+		writeJSON(w, http.StatusOK, s.issueToken())
+	case "token":
+		if req["grant_type"] != "refresh_token" || !s.refreshTokens[req["refresh_token"]] {
+			writeJSONError(w, http.StatusUnauthorized, "invalid_grant", "invalid refresh token")
+			return
+		}
+		delete(s.refreshTokens, req["refresh_token"])
+		writeJSON(w, http.StatusOK, s.issueToken())
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// filteredCatalog returns the catalog narrowed down by the years, genres,
+// certifications, languages, countries and watchnow filters of a chart or
+// calendar request. With watchnow, countries select the streaming region.
+func (s *Server) filteredCatalog(r *http.Request) ([]trakt.Movie, []trakt.Show) {
+	movies, shows := s.movies, s.shows
+	if filter := r.URL.Query().Get("years"); filter != "" {
+		from, to, _ := strings.Cut(filter, "-")
+		minYear, _ := strconv.Atoi(from)
+		maxYear, err := strconv.Atoi(to)
+		if err != nil {
+			maxYear = minYear
+		}
+		movies = filterYears(movies, minYear, maxYear, func(m trakt.Movie) int { return m.Year })
+		shows = filterYears(shows, minYear, maxYear, func(sh trakt.Show) int { return sh.Year })
+	}
+	if filter := r.URL.Query().Get("genres"); filter != "" {
+		wanted := strings.Split(filter, ",")
+		movies = filterValues(movies, wanted, func(m trakt.Movie) []string { return m.Genres })
+		shows = filterValues(shows, wanted, func(sh trakt.Show) []string { return sh.Genres })
+	}
+	if filter := r.URL.Query().Get("certifications"); filter != "" {
+		wanted := strings.Split(filter, ",")
+		movies = filterValues(movies, wanted, func(m trakt.Movie) []string { return []string{m.Certification} })
+		shows = filterValues(shows, wanted, func(sh trakt.Show) []string { return []string{sh.Certification} })
+	}
+	if filter := r.URL.Query().Get("languages"); filter != "" {
+		wanted := strings.Split(filter, ",")
+		movies = filterValues(movies, wanted, func(m trakt.Movie) []string { return []string{m.Language} })
+		shows = filterValues(shows, wanted, func(sh trakt.Show) []string { return []string{sh.Language} })
+	}
+	if filter := r.URL.Query().Get("countries"); filter != "" && r.URL.Query().Get("watchnow") == "" {
+		wanted := strings.Split(filter, ",")
+		movies = filterValues(movies, wanted, func(m trakt.Movie) []string { return []string{m.Country} })
+		shows = filterValues(shows, wanted, func(sh trakt.Show) []string { return []string{sh.Country} })
+	}
+	if filter := r.URL.Query().Get("watchnow"); filter != "" {
+		wanted := strings.Split(filter, ",")
+		movies = filterValues(movies, wanted, func(m trakt.Movie) []string { return []string{WatchNow(m.IDs.Trakt)} })
+		shows = filterValues(shows, wanted, func(sh trakt.Show) []string { return []string{WatchNow(sh.IDs.Trakt)} })
+	}
+	return movies, shows
+}
+
+func (s *Server) handleChart(w http.ResponseWriter, r *http.Request, parts []string) {
+	limit := queryInt(r, "limit", 10)
+	movies, shows := s.filteredCatalog(r)
+	if parts[1] == "watched" {
+		period := "weekly"
+		if len(parts) > 2 {
+			period = parts[2]
+		}
+		switch period {
+		case "weekly":
+		case "daily", "monthly", "yearly", "all":
+			// Other periods rank the catalog in reverse so tests can tell
+			// them apart.
+			movies, shows = reversed(movies), reversed(shows)
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+	}
+
+	switch parts[0] + "/" + parts[1] {
+	case "movies/trending":
+		result := make([]trakt.TrendingMovie, 0, limit)
+		for i, movie := range firstN(movies, limit) {
+			result = append(result, trakt.TrendingMovie{Watchers: 1000 - i, Movie: movie})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "movies/watched":
+		result := make([]trakt.WatchedMovie, 0, limit)
+		for i, movie := range firstN(movies, limit) {
+			result = append(result, trakt.WatchedMovie{WatcherCount: 5000 - i, PlayCount: 10000 - i, Movie: movie})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "movies/popular":
+		writeJSON(w, http.StatusOK, firstN(movies, limit))
+	case "shows/popular":
+		writeJSON(w, http.StatusOK, firstN(shows, limit))
+	case "movies/boxoffice":
+		// The box office chart is always the top 10 and ignores filters.
+		result := make([]trakt.BoxOfficeMovie, 0, 10)
+		for i, movie := range firstN(s.movies, 10) {
+			result = append(result, trakt.BoxOfficeMovie{Revenue: 50_000_000 - i*1_000_000, Movie: movie})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "movies/anticipated":
+		result := make([]trakt.AnticipatedMovie, 0, limit)
+		for i, movie := range firstN(movies, limit) {
+			result = append(result, trakt.AnticipatedMovie{ListCount: 2000 - i, Movie: movie})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "shows/anticipated":
+		result := make([]trakt.AnticipatedShow, 0, limit)
+		for i, show := range firstN(shows, limit) {
+			result = append(result, trakt.AnticipatedShow{ListCount: 2000 - i, Show: show})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "shows/trending":
+		result := make([]trakt.TrendingShow, 0, limit)
+		for i, show := range firstN(shows, limit) {
+			result = append(result, trakt.TrendingShow{Watchers: 1000 - i, Show: show})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "shows/watched":
+		result := make([]trakt.WatchedShow, 0, limit)
+		for i, show := range firstN(shows, limit) {
+			result = append(result, trakt.WatchedShow{WatcherCount: 5000 - i, PlayCount: 10000 - i, Show: show})
+		}
+		writeJSON(w, http.StatusOK, result)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleRecommendations recommends the catalog in reverse order, so tests can
+// tell it from the charts, leaving out titles in the history
+func (s *Server) handleRecommendations(w http.ResponseWriter, r *http.Request, mediaType string) {
+	limit := queryInt(r, "limit", 10)
+	watched := make(map[int]bool)
+	for _, item := range s.history {
+		if item.Movie != nil {
+			watched[item.Movie.IDs.Trakt] = true
+		}
+		if item.Show != nil {
+			watched[item.Show.IDs.Trakt] = true
+		}
+	}
+
+	switch mediaType {
+	case "movies":
+		movies := []trakt.Movie{}
+		for _, movie := range reversed(s.movies) {
+			if !watched[movie.IDs.Trakt] {
+				movies = append(movies, movie)
+			}
+		}
+		writeJSON(w, http.StatusOK, firstN(movies, limit))
+	case "shows":
+		shows := []trakt.Show{}
+		for _, show := range reversed(s.shows) {
+			if !watched[show.IDs.Trakt] {
+				shows = append(shows, show)
+			}
+		}
+		writeJSON(w, http.StatusOK, firstN(shows, limit))
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleCalendar serves show premieres and movie releases. The catalog
+// releases one title per day in catalog order, starting on the requested day.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request, parts []string) {
+	start, err := time.Parse("2006-01-02", parts[len(parts)-2])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid start date")
+		return
+	}
+	days, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || days < 1 {
+		writeError(w, http.StatusBadRequest, "invalid days")
+		return
+	}
+	movies, shows := s.filteredCatalog(r)
+
+	switch strings.Join(parts[2:len(parts)-2], "/") {
+	case "shows/premieres":
+		result := make([]trakt.CalendarShow, 0, days)
+		for i, show := range firstN(shows, days) {
+			result = append(result, trakt.CalendarShow{FirstAired: start.AddDate(0, 0, i).Add(20 * time.Hour), Show: show})
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "movies":
+		result := make([]trakt.CalendarMovie, 0, days)
+		for i, movie := range firstN(movies, days) {
+			result = append(result, trakt.CalendarMovie{Released: start.AddDate(0, 0, i).Format("2006-01-02"), Movie: movie})
+		}
+		writeJSON(w, http.StatusOK, result)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleTranslations translates generated titles into German ("Film 1",
+// "Serie 1"); other languages have no translations
+func (s *Server) handleTranslations(w http.ResponseWriter, parts []string) {
+	translations := []trakt.Translation{}
+	if parts[3] == "de" {
+		word := map[string]string{"movies": "Film", "shows": "Serie"}[parts[0]]
+		translations = append(translations, trakt.Translation{Title: word + " " + parts[1], Language: "de", Country: "de"})
+	}
+	writeJSON(w, http.StatusOK, translations)
+}
+
+// handleIMDBLookup finds catalog movies and shows by IMDb ID
+func (s *Server) handleIMDBLookup(w http.ResponseWriter, r *http.Request, imdbID string) {
+	kind := r.URL.Query().Get("type")
+	results := []trakt.SearchResult{}
+	if kind == "" || kind == "movie" {
+		for i := range s.movies {
+			if s.movies[i].IDs.IMDB == imdbID {
+				movie := s.movies[i]
+				results = append(results, trakt.SearchResult{Type: "movie", Movie: &movie})
+			}
+		}
+	}
+	if kind == "" || kind == "show" {
+		for i := range s.shows {
+			if s.shows[i].IDs.IMDB == imdbID {
+				show := s.shows[i]
+				results = append(results, trakt.SearchResult{Type: "show", Show: &show})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) handleLists(w http.ResponseWriter, r *http.Request, user string, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var req trakt.CreateListRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		l := s.createList(user, Slugify(req.Name), req)
+		writeJSON(w, http.StatusCreated, l.list)
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		l, ok := s.lists[listKey(user, rest[0])]
+		if !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, l.list)
+	case len(rest) == 1 && r.Method == http.MethodPut:
+		l, ok := s.lists[listKey(user, rest[0])]
+		if !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		var req trakt.UpdateListRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Name != "" && req.Name != l.list.Name {
+			// Trakt regenerates the slug from the new name
+			delete(s.lists, listKey(user, rest[0]))
+			l.list.Name = req.Name
+			l.list.IDs.Slug = Slugify(req.Name)
+			s.lists[listKey(user, l.list.IDs.Slug)] = l
+		}
+		if req.Description != "" {
+			l.list.Description = req.Description
+		}
+		if req.Privacy != "" {
+			l.list.Privacy = req.Privacy
+		}
+		if req.DisplayNumbers != nil {
+			l.list.DisplayNumbers = *req.DisplayNumbers
+		}
+		if req.AllowComments != nil {
+			l.list.AllowComments = *req.AllowComments
+		}
+		if req.SortBy != "" {
+			l.list.SortBy = req.SortBy
+		}
+		if req.SortHow != "" {
+			l.list.SortHow = req.SortHow
+		}
+		l.list.UpdatedAt = time.Now().UTC()
+		writeJSON(w, http.StatusOK, l.list)
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		if _, ok := s.lists[listKey(user, rest[0])]; !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		delete(s.lists, listKey(user, rest[0]))
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 2 && rest[1] == "items" && r.Method == http.MethodGet:
+		s.handleListItems(w, r, user, rest[0])
+	case len(rest) == 2 && rest[1] == "items" && r.Method == http.MethodPost:
+		s.handleAddItems(w, r, user, rest[0])
+	case len(rest) == 3 && rest[1] == "items" && rest[2] == "remove" && r.Method == http.MethodPost:
+		s.handleRemoveItems(w, r, user, rest[0])
+	case len(rest) == 3 && rest[1] == "items" && rest[2] == "reorder" && r.Method == http.MethodPost:
+		s.handleReorderItems(w, r, user, rest[0])
+	case len(rest) >= 2 && rest[1] == "comments" && r.Method == http.MethodGet:
+		s.handleListComments(w, r, user, rest[0])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) handleUserLists(w http.ResponseWriter, user string) {
+	prefix := listKey(user, "")
+	result := make([]trakt.List, 0)
+	for key, l := range s.lists {
+		if strings.HasPrefix(key, prefix) {
+			result = append(result, l.list)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].IDs.Trakt < result[j].IDs.Trakt })
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleWatchlist(w http.ResponseWriter, rest []string) {
+	items := []trakt.WatchlistItem{}
+	for _, item := range s.watchlist {
+		if len(rest) == 0 || rest[0] == item.Type+"s" {
+			items = append(items, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleWatchlistRemove(w http.ResponseWriter, r *http.Request) {
+	var req trakt.RemoveFromListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	remove := make(map[string]bool)
+	for _, m := range req.Movies {
+		remove[fmt.Sprintf("movie:%d", m.IDs.Trakt)] = true
+	}
+	for _, sh := range req.Shows {
+		remove[fmt.Sprintf("show:%d", sh.IDs.Trakt)] = true
+	}
+
+	kept := s.watchlist[:0]
+	deleted := map[string]int{"movies": 0, "shows": 0}
+	for _, item := range s.watchlist {
+		if remove[itemKey(trakt.ListItem{Movie: item.Movie, Show: item.Show})] {
+			deleted[item.Type+"s"]++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	s.watchlist = kept
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": deleted})
+}
+
+func (s *Server) handleCollection(w http.ResponseWriter, mediaType string) {
+	items := []trakt.CollectionItem{}
+	for _, item := range s.collection {
+		if (mediaType == "movies" && item.Movie != nil) || (mediaType == "shows" && item.Show != nil) {
+			items = append(items, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handleHistory serves the whole history as a single page
+func (s *Server) handleHistory(w http.ResponseWriter, rest []string) {
+	items := []trakt.HistoryItem{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if item := s.history[i]; len(rest) == 0 || rest[0] == item.Type+"s" {
+			items = append(items, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleListItems(w http.ResponseWriter, r *http.Request, user, slug string) {
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeListItems(w, r, l)
+}
+
+// handlePublicList serves a list by its Trakt ID, whoever owns it
+func (s *Server) handlePublicList(w http.ResponseWriter, r *http.Request, rest []string) {
+	id, err := strconv.Atoi(rest[0])
+	var found *fakeList
+	for _, l := range s.lists {
+		if err == nil && l.list.IDs.Trakt == id {
+			found = l
+		}
+	}
+	switch {
+	case found == nil:
+		writeError(w, http.StatusNotFound, "not found")
+	case len(rest) == 1:
+		writeJSON(w, http.StatusOK, found.list)
+	case len(rest) == 2 && rest[1] == "items":
+		writeListItems(w, r, found)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// writeListItems writes a page of list items with Trakt's pagination headers
+func writeListItems(w http.ResponseWriter, r *http.Request, l *fakeList) {
+	page := queryInt(r, "page", 1)
+	limit := queryInt(r, "limit", 10)
+	pageCount := (len(l.items) + limit - 1) / limit
+	if pageCount == 0 {
+		pageCount = 1
+	}
+
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(l.items) {
+		start = len(l.items)
+	}
+	if end > len(l.items) {
+		end = len(l.items)
+	}
+
+	w.Header().Set("X-Pagination-Page", strconv.Itoa(page))
+	w.Header().Set("X-Pagination-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-Pagination-Page-Count", strconv.Itoa(pageCount))
+	w.Header().Set("X-Pagination-Item-Count", strconv.Itoa(len(l.items)))
+	// Like Trakt, items come in rank order whatever the list's sort is.
+	w.Header().Set("X-Sort-By", l.list.SortBy)
+	w.Header().Set("X-Sort-How", l.list.SortHow)
+	w.Header().Set("X-Applied-Sort-By", "rank")
+	w.Header().Set("X-Applied-Sort-How", "asc")
+	writeJSON(w, http.StatusOK, l.items[start:end])
+}
+
+func (s *Server) handleAddItems(w http.ResponseWriter, r *http.Request, user, slug string) {
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var req trakt.AddToListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing := make(map[string]bool, len(l.items))
+	for _, item := range l.items {
+		existing[itemKey(item)] = true
+	}
+
+	added := map[string]int{"movies": 0, "shows": 0}
+	existingCount := map[string]int{"movies": 0, "shows": 0}
+	for _, m := range req.Movies {
+		movie := trakt.Movie{Title: fmt.Sprintf("Movie %d", m.IDs.Trakt), IDs: m.IDs}
+		item := trakt.ListItem{Type: "movie", Movie: &movie, ListedAt: time.Now().UTC(), Notes: m.Notes}
+		if existing[itemKey(item)] {
+			existingCount["movies"]++
+			continue
+		}
+		existing[itemKey(item)] = true
+		l.items = append(l.items, item)
+		added["movies"]++
+	}
+	for _, sh := range req.Shows {
+		show := trakt.Show{Title: fmt.Sprintf("Show %d", sh.IDs.Trakt), IDs: sh.IDs}
+		item := trakt.ListItem{Type: "show", Show: &show, ListedAt: time.Now().UTC(), Notes: sh.Notes}
+		if existing[itemKey(item)] {
+			existingCount["shows"]++
+			continue
+		}
+		existing[itemKey(item)] = true
+		l.items = append(l.items, item)
+		added["shows"]++
+	}
+	s.renumber(l)
+	if s.OnItemsAdded != nil {
+		s.OnItemsAdded(user, slug)
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"added": added, "existing": existingCount})
+}
+
+// handleReorderItems moves the items in the rank order of the request first,
+// keeping the others after them in their current order
+func (s *Server) handleReorderItems(w http.ResponseWriter, r *http.Request, user, slug string) {
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var req struct {
+		Rank []int64 `json:"rank"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	byID := make(map[int64]trakt.ListItem, len(l.items))
+	for _, item := range l.items {
+		byID[item.ID] = item
+	}
+	items := make([]trakt.ListItem, 0, len(l.items))
+	skipped := []int64{}
+	for _, id := range req.Rank {
+		item, ok := byID[id]
+		if !ok {
+			skipped = append(skipped, id)
+			continue
+		}
+		items = append(items, item)
+		delete(byID, id)
+	}
+	for _, item := range l.items {
+		if _, ok := byID[item.ID]; ok {
+			items = append(items, item)
+		}
+	}
+	l.items = items
+	s.renumber(l)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"updated": len(req.Rank) - len(skipped), "skipped_ids": skipped})
+}
+
+// handleListComments serves the comments of a list, newest first
+func (s *Server) handleListComments(w http.ResponseWriter, r *http.Request, user, slug string) {
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	comments := append([]trakt.Comment(nil), l.comments...)
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].CreatedAt.After(comments[j].CreatedAt) })
+
+	page := queryInt(r, "page", 1)
+	limit := queryInt(r, "limit", 10)
+	pageCount := (len(comments) + limit - 1) / limit
+	if pageCount == 0 {
+		pageCount = 1
+	}
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(comments) {
+		start = len(comments)
+	}
+	if end > len(comments) {
+		end = len(comments)
+	}
+
+	w.Header().Set("X-Pagination-Page", strconv.Itoa(page))
+	w.Header().Set("X-Pagination-Page-Count", strconv.Itoa(pageCount))
+	writeJSON(w, http.StatusOK, comments[start:end])
+}
+
+func (s *Server) handleDeleteComment(w http.ResponseWriter, idParam string) {
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	for _, l := range s.lists {
+		for i, comment := range l.comments {
+			if comment.ID != id {
+				continue
+			}
+			if comment.Replies > 0 {
+				writeError(w, http.StatusConflict, "comment has replies")
+				return
+			}
+			l.comments = append(l.comments[:i], l.comments[i+1:]...)
+			l.list.CommentCount = len(l.comments)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+func (s *Server) handleRemoveItems(w http.ResponseWriter, r *http.Request, user, slug string) {
+	l, ok := s.lists[listKey(user, slug)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var req trakt.RemoveFromListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	remove := make(map[string]bool)
+	for _, m := range req.Movies {
+		remove[fmt.Sprintf("movie:%d", m.IDs.Trakt)] = true
+	}
+	for _, sh := range req.Shows {
+		remove[fmt.Sprintf("show:%d", sh.IDs.Trakt)] = true
+	}
+
+	kept := l.items[:0]
+	deleted := map[string]int{"movies": 0, "shows": 0}
+	for _, item := range l.items {
+		if remove[itemKey(item)] {
+			if item.Movie != nil {
+				deleted["movies"]++
+			} else {
+				deleted["shows"]++
+			}
+			continue
+		}
+		kept = append(kept, item)
+	}
+	l.items = kept
+	s.renumber(l)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": deleted})
+}
+
+func (s *Server) createList(user, slug string, req trakt.CreateListRequest) *fakeList {
+	now := time.Now().UTC()
+	l := &fakeList{list: trakt.List{
+		Name:           req.Name,
+		Description:    req.Description,
+		Privacy:        req.Privacy,
+		DisplayNumbers: req.DisplayNumbers,
+		AllowComments:  req.AllowComments,
+		SortBy:         firstNonEmpty(req.SortBy, "rank"),
+		SortHow:        firstNonEmpty(req.SortHow, "asc"),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		IDs:            trakt.ListIDs{Trakt: len(s.lists) + 1, Slug: slug},
+	}}
+	s.lists[listKey(user, slug)] = l
+	return l
+}
+
+func (s *Server) renumber(l *fakeList) {
+	for i := range l.items {
+		l.items[i].Rank = i + 1
+		if l.items[i].ID == 0 {
+			s.itemSeq++
+			l.items[i].ID = s.itemSeq
+		}
+	}
+	l.list.ItemCount = len(l.items)
+	l.list.UpdatedAt = time.Now().UTC()
+}
+
+// Slugify mirrors how Trakt derives list slugs from list names
+func Slugify(name string) string {
+	return config.Slugify(name)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func listKey(user, slug string) string {
+	return strings.ToLower(user) + "/" + slug
+}
+
+func itemKey(item trakt.ListItem) string {
+	if item.Movie != nil {
+		return fmt.Sprintf("movie:%d", item.Movie.IDs.Trakt)
+	}
+	if item.Show != nil {
+		return fmt.Sprintf("show:%d", item.Show.IDs.Trakt)
+	}
+	return ""
+}
+
+// filterValues keeps items with at least one of the wanted values, e.g.
+// genres or certifications
+func filterValues[T any](items []T, wanted []string, genresOf func(T) []string) []T {
+	var filtered []T
+	for _, item := range items {
+		for _, genre := range genresOf(item) {
+			if containsString(wanted, genre) {
+				filtered = append(filtered, item)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func filterYears[T any](items []T, minYear, maxYear int, yearOf func(T) int) []T {
+	var filtered []T
+	for _, item := range items {
+		if year := yearOf(item); year >= minYear && year <= maxYear {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func reversed[T any](items []T) []T {
+	result := make([]T, len(items))
+	for i, item := range items {
+		result[len(items)-1-i] = item
+	}
+	return result
+}
+
+func firstN[T any](items []T, n int) []T {
+	if n < len(items) {
+		return items[:n]
+	}
+	return items
+}
+
+func queryInt(r *http.Request, key string, fallback int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, description string) {
+	code := strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	writeJSONError(w, status, code, description)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
@@ -0,0 +1,79 @@
+package trakttest
+
+import (
+	"testing"
+
+	"github.com/maximilian/trakt-sync/internal/trakt"
+)
+
+func newTestClient(s *Server) *trakt.Client {
+	client := trakt.NewClient("test", "", "token", "")
+	client.SetBaseURL(s.URL)
+	return client
+}
+
+func TestListItemsPagination(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	ids := make([]int, 0, 2500)
+	for i := 1; i <= 2500; i++ {
+		ids = append(ids, i)
+	}
+	server.SeedList("user", "big", ids...)
+
+	items, err := newTestClient(server).GetListItems("user", "big")
+	if err != nil {
+		t.Fatalf("GetListItems: %v", err)
+	}
+	if len(items) != len(ids) {
+		t.Fatalf("got %d items, want %d", len(items), len(ids))
+	}
+	if items[len(items)-1].Movie.IDs.Trakt != 2500 {
+		t.Fatalf("last item = %d, want 2500", items[len(items)-1].Movie.IDs.Trakt)
+	}
+}
+
+func TestRateLimitHeadersPaceClient(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.RateLimit = 2
+	server.SeedCatalog(10, 0)
+
+	client := newTestClient(server)
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetTrendingMovies(trakt.ChartOptions{Limit: 5}); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	if server.Throttled() != 0 {
+		t.Fatalf("client was throttled %d times, want 0", server.Throttled())
+	}
+	if server.Requests() != 3 {
+		t.Fatalf("server saw %d requests, want 3", server.Requests())
+	}
+}
+
+func TestAddAndRemoveItems(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SeedList("user", "list", 1, 2)
+
+	client := newTestClient(server)
+	if err := client.AddItemsToList("user", "list", trakt.AddToListRequest{
+		Movies: []trakt.AddMovie{{IDs: trakt.MediaIDs{Trakt: 2}}, {IDs: trakt.MediaIDs{Trakt: 3}}},
+	}); err != nil {
+		t.Fatalf("AddItemsToList: %v", err)
+	}
+	if err := client.RemoveItemsFromList("user", "list", trakt.RemoveFromListRequest{
+		Movies: []trakt.RemoveMovie{{IDs: trakt.MediaIDs{Trakt: 1}}},
+	}); err != nil {
+		t.Fatalf("RemoveItemsFromList: %v", err)
+	}
+
+	items := server.ListItems("user", "list")
+	if len(items) != 2 || items[0].Movie.IDs.Trakt != 2 || items[1].Movie.IDs.Trakt != 3 {
+		t.Fatalf("unexpected list contents: %+v", items)
+	}
+}
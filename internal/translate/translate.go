@@ -0,0 +1,145 @@
+// Package translate translates text through DeepL or a LibreTranslate server
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Providers
+const (
+	ProviderDeepL          = "deepl"
+	ProviderLibreTranslate = "libretranslate"
+)
+
+// DeepL API endpoints; keys of free accounts end in ":fx" and only work with
+// the free endpoint
+const (
+	DeepLURL     = "https://api.deepl.com"
+	DeepLFreeURL = "https://api-free.deepl.com"
+)
+
+// Client translates text with one provider
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	provider   string
+	baseURL    string
+	apiKey     string
+}
+
+// NewClient returns a client for provider at baseURL. An empty baseURL
+// selects the DeepL endpoint matching apiKey; LibreTranslate needs a URL.
+func NewClient(provider, baseURL, apiKey string) (*Client, error) {
+	switch provider {
+	case ProviderDeepL:
+		if baseURL == "" {
+			baseURL = DeepLURL
+			if strings.HasSuffix(apiKey, ":fx") {
+				baseURL = DeepLFreeURL
+			}
+		}
+	case ProviderLibreTranslate:
+		if baseURL == "" {
+			return nil, fmt.Errorf("LibreTranslate needs the URL of a server")
+		}
+	default:
+		return nil, fmt.Errorf("unknown translation provider %q", provider)
+	}
+	return &Client{
+		ctx:        context.Background(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		provider:   provider,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+	}, nil
+}
+
+// SetContext makes every request of the client use ctx, so it is aborted
+// once ctx is done
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Translate translates text from the source to the target language, both
+// given as language codes like "de" or "en"
+func (c *Client) Translate(text, source, target string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	if c.provider == ProviderDeepL {
+		return c.deepL(text, source, target)
+	}
+	return c.libreTranslate(text, source, target)
+}
+
+func (c *Client) deepL(text, source, target string) (string, error) {
+	req := map[string]interface{}{
+		"text":        []string{text},
+		"source_lang": strings.ToUpper(source),
+		"target_lang": strings.ToUpper(target),
+	}
+	var resp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := c.post("/v2/translate", req, "DeepL-Auth-Key "+c.apiKey, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Translations) == 0 {
+		return "", fmt.Errorf("DeepL returned no translation")
+	}
+	return resp.Translations[0].Text, nil
+}
+
+func (c *Client) libreTranslate(text, source, target string) (string, error) {
+	req := map[string]string{"q": text, "source": strings.ToLower(source), "target": strings.ToLower(target), "format": "text"}
+	if c.apiKey != "" {
+		req["api_key"] = c.apiKey
+	}
+	var resp struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := c.post("/translate", req, "", &resp); err != nil {
+		return "", err
+	}
+	return resp.TranslatedText, nil
+}
+
+func (c *Client) post(path string, body interface{}, authorization string, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "trakt-sync")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", c.provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", c.provider, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", c.provider, err)
+	}
+	return nil
+}
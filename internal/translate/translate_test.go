@@ -0,0 +1,73 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTranslate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/translate" && r.Header.Get("Authorization") == "DeepL-Auth-Key key:fx" && req["target_lang"] == "EN":
+			json.NewEncoder(w).Encode(map[string]interface{}{"translations": []map[string]string{{"text": "Trending movies"}}})
+		case r.URL.Path == "/translate" && req["q"] == "Angesagte Filme" && req["target"] == "en":
+			json.NewEncoder(w).Encode(map[string]string{"translatedText": "Trending movies"})
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	for _, provider := range []string{ProviderDeepL, ProviderLibreTranslate} {
+		client, err := NewClient(provider, server.URL, "key:fx")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := client.Translate("Angesagte Filme", "de", "en")
+		if err != nil {
+			t.Fatalf("%s: %v", provider, err)
+		}
+		if got != "Trending movies" {
+			t.Errorf("%s: translation = %q", provider, got)
+		}
+	}
+
+	if _, err := NewClient(ProviderLibreTranslate, "", ""); err == nil {
+		t.Error("expected an error for LibreTranslate without a URL")
+	}
+}
+
+func TestTranslateCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(ProviderLibreTranslate, server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client.SetContext(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := client.Translate("Angesagte Filme", "de", "en"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the request to be canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("canceled request took %s", elapsed)
+	}
+}